@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+
+	"wx_channel/internal/config"
+	"wx_channel/internal/database"
+	"wx_channel/internal/services"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+)
+
+var recordsCmd = &cobra.Command{
+	Use:   "records",
+	Short: "下载记录管理",
+}
+
+var recordsImportCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "导入旧版 CSV 或导出的 JSON 下载记录",
+	Long: `将旧版 download_records.csv 或控制台导出的下载记录 JSON 导入数据库。
+未指定文件时导入配置中的 records_file。已存在相同视频 ID 的记录会被跳过。`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg := config.Load()
+		path := cfg.GetRecordsPath()
+		if len(args) > 0 {
+			path = args[0]
+		}
+
+		downloadsDir, err := cfg.GetResolvedDownloadsDir()
+		if err != nil {
+			color.Red("解析下载目录失败: %v\n", err)
+			os.Exit(1)
+		}
+		if err := database.Initialize(&database.Config{DBPath: filepath.Join(downloadsDir, "records.db")}); err != nil {
+			color.Red("初始化数据库失败: %v\n", err)
+			os.Exit(1)
+		}
+		defer database.Close()
+
+		color.Yellow("正在导入 %s ...\n", path)
+		result, err := services.NewRecordImportService().ImportFile(path)
+		if err != nil {
+			color.Red("导入失败: %v\n", err)
+			database.Close()
+			os.Exit(1)
+		}
+
+		color.Green("✓ 导入完成（%s）\n", result.Format)
+		color.White("  共 %d 条：导入 %d，重复跳过 %d，无效 %d，失败 %d\n",
+			result.Total, result.Imported, result.Duplicates, result.Invalid, result.Failed)
+		for _, msg := range result.Errors {
+			color.Yellow("  - %s\n", msg)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(recordsCmd)
+	recordsCmd.AddCommand(recordsImportCmd)
+}
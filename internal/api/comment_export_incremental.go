@@ -0,0 +1,350 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"wx_channel/internal/database"
+)
+
+const (
+	commentExportDiffSuffix       = ".diff.json"
+	commentPreviousSourceDatabase = "comments_table"
+)
+
+// CommentExportDiffSummary 增量导出与上次导出的差异统计，明细写入 diff 文件
+type CommentExportDiffSummary struct {
+	PreviousSource   string `json:"previous_source"`
+	NewCount         int    `json:"new_count"`
+	DeletedCount     int    `json:"deleted_count"`
+	LikeChangedCount int    `json:"like_changed_count"`
+	DiffPath         string `json:"diff_path"`
+}
+
+// CommentDiffEntry diff 文件中新增或删除的评论
+type CommentDiffEntry struct {
+	CommentID  string `json:"comment_id"`
+	ParentID   string `json:"parent_id,omitempty"`
+	Username   string `json:"username"`
+	Nickname   string `json:"nickname"`
+	Content    string `json:"content"`
+	LikeCount  int    `json:"like_count"`
+	CreateTime string `json:"create_time,omitempty"`
+}
+
+// CommentLikeChange diff 文件中点赞数变化的评论
+type CommentLikeChange struct {
+	CommentID     string `json:"comment_id"`
+	ParentID      string `json:"parent_id,omitempty"`
+	PreviousLikes int    `json:"previous_likes"`
+	CurrentLikes  int    `json:"current_likes"`
+}
+
+type commentExportDiffFile struct {
+	ObjectID       string              `json:"object_id"`
+	PreviousSource string              `json:"previous_source"`
+	New            []CommentDiffEntry  `json:"new"`
+	Deleted        []CommentDiffEntry  `json:"deleted"`
+	LikeChanges    []CommentLikeChange `json:"like_changes"`
+	SavedAt        string              `json:"saved_at"`
+}
+
+// previousCommentExport 增量导出的基准：上次导出的评论（顶级评论带 levelTwoComment）
+type previousCommentExport struct {
+	source   string
+	comments []map[string]interface{}
+	byID     map[string]map[string]interface{}
+	// kept 本次未重新抓取、直接沿用上次数据的顶级评论
+	kept map[string]bool
+}
+
+func newPreviousCommentExport(source string, comments []map[string]interface{}) *previousCommentExport {
+	previous := &previousCommentExport{
+		source:   source,
+		comments: comments,
+		byID:     make(map[string]map[string]interface{}, len(comments)),
+		kept:     make(map[string]bool),
+	}
+	for _, comment := range comments {
+		if id := stringValue(comment["commentId"]); id != "" {
+			previous.byID[id] = comment
+		}
+	}
+	return previous
+}
+
+// knownIDs 返回上次导出的顶级评论 ID，用于提前停止翻页
+func (p *previousCommentExport) knownIDs() map[string]struct{} {
+	if p == nil {
+		return nil
+	}
+	known := make(map[string]struct{}, len(p.byID))
+	for id := range p.byID {
+		known[id] = struct{}{}
+	}
+	return known
+}
+
+// mergeTopLevel 合并本次抓取的顶级评论和上次导出的评论
+// 评论列表按时间倒序返回：翻页在遇到已知评论时停止，比该评论更新但本次没有出现的评论视为已删除；
+// 翻到最后一页时，所有没有出现的评论都视为已删除
+func (p *previousCommentExport) mergeTopLevel(fetched []map[string]interface{}, reachedKnown bool) []map[string]interface{} {
+	merged := make([]map[string]interface{}, 0, len(fetched)+len(p.comments))
+	fetchedIDs := make(map[string]struct{}, len(fetched))
+	boundary := int64(-1)
+	for _, comment := range fetched {
+		id := stringValue(comment["commentId"])
+		fetchedIDs[id] = struct{}{}
+		if _, known := p.byID[id]; known && boundary < 0 {
+			boundary = commentCreateTime(comment)
+		}
+		merged = append(merged, comment)
+	}
+	if !reachedKnown {
+		return merged
+	}
+
+	for _, comment := range p.comments {
+		id := stringValue(comment["commentId"])
+		if _, ok := fetchedIDs[id]; ok {
+			continue
+		}
+		if commentCreateTime(comment) > boundary {
+			continue
+		}
+		p.kept[id] = true
+		merged = append(merged, comment)
+	}
+	return merged
+}
+
+// reusableReplies 沿用上次导出的回复：评论未重新抓取，或回复数没有变化
+func (p *previousCommentExport) reusableReplies(comment map[string]interface{}) ([]map[string]interface{}, bool) {
+	id := stringValue(comment["commentId"])
+	previous, ok := p.byID[id]
+	if !ok {
+		return nil, false
+	}
+	replies := extractReplyMaps(previous["levelTwoComment"])
+	if p.kept[id] || intValue(comment["expandCommentCount"]) == len(replies) {
+		return replies, true
+	}
+	return nil, false
+}
+
+func commentCreateTime(comment map[string]interface{}) int64 {
+	seconds, _ := strconv.ParseInt(stringValue(comment["createtime"]), 10, 64)
+	return seconds
+}
+
+// buildCommentExportDiff 对比上次和本次导出的平铺评论
+func buildCommentExportDiff(objectID, source string, previousRows, currentRows []commentTableRow) commentExportDiffFile {
+	diff := commentExportDiffFile{
+		ObjectID:       objectID,
+		PreviousSource: source,
+		New:            []CommentDiffEntry{},
+		Deleted:        []CommentDiffEntry{},
+		LikeChanges:    []CommentLikeChange{},
+		SavedAt:        time.Now().Format(time.RFC3339),
+	}
+
+	previousByID := make(map[string]commentTableRow, len(previousRows))
+	for _, row := range previousRows {
+		previousByID[row.CommentID] = row
+	}
+	currentIDs := make(map[string]struct{}, len(currentRows))
+	for _, row := range currentRows {
+		currentIDs[row.CommentID] = struct{}{}
+		previous, ok := previousByID[row.CommentID]
+		if !ok {
+			diff.New = append(diff.New, newCommentDiffEntry(row))
+			continue
+		}
+		if previous.LikeCount != row.LikeCount {
+			diff.LikeChanges = append(diff.LikeChanges, CommentLikeChange{
+				CommentID:     row.CommentID,
+				ParentID:      row.ParentID,
+				PreviousLikes: previous.LikeCount,
+				CurrentLikes:  row.LikeCount,
+			})
+		}
+	}
+	for _, row := range previousRows {
+		if _, ok := currentIDs[row.CommentID]; !ok {
+			diff.Deleted = append(diff.Deleted, newCommentDiffEntry(row))
+		}
+	}
+	return diff
+}
+
+func newCommentDiffEntry(row commentTableRow) CommentDiffEntry {
+	entry := CommentDiffEntry{
+		CommentID: row.CommentID,
+		ParentID:  row.ParentID,
+		Username:  row.Username,
+		Nickname:  row.Nickname,
+		Content:   row.Content,
+		LikeCount: row.LikeCount,
+	}
+	if row.CreateTime != nil {
+		entry.CreateTime = row.CreateTime.Format("2006-01-02 15:04:05")
+	}
+	return entry
+}
+
+// writeCommentExportDiff 在 JSON 文件旁写入 diff 明细
+func writeCommentExportDiff(jsonPath string, diff commentExportDiffFile) (string, error) {
+	data, err := json.MarshalIndent(diff, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	diffPath := strings.TrimSuffix(jsonPath, ".json") + commentExportDiffSuffix
+	if err := writeCommentExportData(diffPath, data); err != nil {
+		return "", err
+	}
+	return diffPath, nil
+}
+
+// loadPreviousCommentExport 加载增量导出的基准：优先使用最近一次的导出文件，其次使用 comments 表
+// 都没有时返回 nil，此时按完整导出处理
+func (s *SearchService) loadPreviousCommentExport(downloadsDir, objectID string) (*previousCommentExport, error) {
+	previous, err := loadLatestCommentExportFile(downloadsDir, objectID)
+	if err != nil || previous != nil {
+		return previous, err
+	}
+
+	store, err := s.resolveCommentStore()
+	if err != nil {
+		return nil, nil
+	}
+	stored, err := store.ListByObjectID(objectID)
+	if err != nil {
+		return nil, err
+	}
+	if len(stored) == 0 {
+		return nil, nil
+	}
+	return newPreviousCommentExport(commentPreviousSourceDatabase, commentRecordsToMaps(stored)), nil
+}
+
+// loadLatestCommentExportFile 从 comment_data/<date>/ 中由新到旧查找该视频最近一次的完整导出
+func loadLatestCommentExportFile(downloadsDir, objectID string) (*previousCommentExport, error) {
+	dateDirs, err := os.ReadDir(filepath.Join(downloadsDir, "comment_data"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(dateDirs, func(i, j int) bool { return dateDirs[i].Name() > dateDirs[j].Name() })
+
+	for _, dateDir := range dateDirs {
+		if !dateDir.IsDir() {
+			continue
+		}
+		dir := filepath.Join(downloadsDir, "comment_data", dateDir.Name())
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		var latestPath string
+		var latest *commentExportSnapshot
+		for _, file := range files {
+			name := file.Name()
+			if file.IsDir() || !strings.HasSuffix(name, ".json") ||
+				strings.HasSuffix(name, commentExportCheckpointSuffix) || strings.HasSuffix(name, commentExportDiffSuffix) {
+				continue
+			}
+			path := filepath.Join(dir, name)
+			snapshot, err := readCommentExportSnapshot(path)
+			if err != nil || snapshot.ObjectID != objectID {
+				continue
+			}
+			if latest == nil || snapshot.SavedAt > latest.SavedAt {
+				latest = snapshot
+				latestPath = path
+			}
+		}
+		if latest != nil {
+			if latest.CommentInfo == nil {
+				latest.CommentInfo = []map[string]interface{}{}
+			}
+			return newPreviousCommentExport(latestPath, latest.CommentInfo), nil
+		}
+	}
+	return nil, nil
+}
+
+// commentExportSnapshot 读取导出文件或断点时需要的字段，评论保留为原始结构以便与新抓取的评论合并
+type commentExportSnapshot struct {
+	ObjectID    string                   `json:"objectId"`
+	SavedAt     string                   `json:"savedAt"`
+	CommentInfo []map[string]interface{} `json:"commentInfo"`
+	// 以下字段仅断点文件中有意义
+	OriginalCommentCount int      `json:"originalCommentCount"`
+	CompletedReplies     []string `json:"completedReplies"`
+}
+
+func readCommentExportSnapshot(path string) (*commentExportSnapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var snapshot commentExportSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to parse comment export: %w", err)
+	}
+	return &snapshot, nil
+}
+
+// commentRecordsToMaps 将数据库中的评论还原为导出文件的嵌套结构
+func commentRecordsToMaps(records []database.Comment) []map[string]interface{} {
+	topLevel := make([]map[string]interface{}, 0, len(records))
+	byID := make(map[string]map[string]interface{})
+	for _, record := range records {
+		if record.Level != 1 {
+			continue
+		}
+		comment := commentRecordToMap(record)
+		topLevel = append(topLevel, comment)
+		byID[record.CommentID] = comment
+	}
+	for _, record := range records {
+		if record.Level == 1 {
+			continue
+		}
+		parent, ok := byID[record.ParentID]
+		if !ok {
+			continue
+		}
+		replies := append(parent["levelTwoComment"].([]map[string]interface{}), commentRecordToMap(record))
+		parent["levelTwoComment"] = replies
+		parent["expandCommentCount"] = len(replies)
+	}
+	return topLevel
+}
+
+func commentRecordToMap(record database.Comment) map[string]interface{} {
+	comment := map[string]interface{}{
+		"commentId":          record.CommentID,
+		"replyCommentId":     record.ReplyCommentID,
+		"username":           record.Username,
+		"nickname":           record.Nickname,
+		"content":            record.Content,
+		"likeCount":          record.LikeCount,
+		"ipRegionInfo":       map[string]interface{}{"regionText": record.Region},
+		"levelTwoComment":    []map[string]interface{}{},
+		"expandCommentCount": 0,
+	}
+	if record.CommentTime != nil {
+		comment["createtime"] = strconv.FormatInt(record.CommentTime.Unix(), 10)
+	}
+	return comment
+}
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"wx_channel/internal/websocket"
+)
+
+func TestExportFeedCommentsIncrementalStopsAtKnownComments(t *testing.T) {
+	tempDir := t.TempDir()
+	run := 1
+	calls := 0
+
+	service := &SearchService{
+		callAPI: func(key string, body interface{}, timeout time.Duration) ([]byte, error) {
+			req := body.(websocket.FeedCommentListBody)
+			calls++
+			switch {
+			case run == 1 && req.CommentID == "":
+				return []byte(`{"errCode":0,"data":{"commentInfo":[{"commentId":"c0","content":"later deleted","createtime":"300"},{"commentId":"c1","content":"has reply","createtime":"200","likeCount":5,"expandCommentCount":1},{"commentId":"c2","content":"oldest","createtime":"100"}],"countInfo":{"commentCount":3},"lastBuffer":""}}`), nil
+			case run == 1 && req.CommentID == "c1":
+				return []byte(`{"errCode":0,"data":{"commentInfo":[{"commentId":"r1","content":"reply","createtime":"250","replyCommentId":"c1"}],"lastBuffer":""}}`), nil
+			case run == 2 && req.CommentID == "" && req.NextMarker == "":
+				return []byte(`{"errCode":0,"data":{"commentInfo":[{"commentId":"c3","content":"new","createtime":"400"},{"commentId":"c1","content":"has reply","createtime":"200","likeCount":9,"expandCommentCount":1}],"countInfo":{"commentCount":3},"lastBuffer":"page-2"}}`), nil
+			default:
+				t.Fatalf("unexpected request in run %d: %+v", run, req)
+				return nil, nil
+			}
+		},
+		resolveDownloadsDir: func() (string, error) {
+			return tempDir, nil
+		},
+	}
+
+	req := ExportFeedCommentsRequest{ObjectID: "oid-1", NonceID: "nid-1", Title: "增量测试", Incremental: true}
+	first, err := service.exportFeedComments(req)
+	if err != nil {
+		t.Fatalf("first export error = %v", err)
+	}
+	if first.Incremental || first.TotalCount != 4 {
+		t.Fatalf("first export should be a full export of 4 comments, got %+v", first)
+	}
+
+	run = 2
+	calls = 0
+	second, err := service.exportFeedComments(req)
+	if err != nil {
+		t.Fatalf("incremental export error = %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("incremental export made %d calls, want 1", calls)
+	}
+	if !second.Incremental || second.TopLevelCount != 3 || second.ReplyCount != 1 {
+		t.Fatalf("unexpected incremental result: %+v", second)
+	}
+	if second.Diff == nil || second.Diff.PreviousSource != first.SavedPath ||
+		second.Diff.NewCount != 1 || second.Diff.DeletedCount != 1 || second.Diff.LikeChangedCount != 1 {
+		t.Fatalf("unexpected diff summary: %+v", second.Diff)
+	}
+
+	raw, err := os.ReadFile(second.Diff.DiffPath)
+	if err != nil {
+		t.Fatalf("ReadFile(diff) error = %v", err)
+	}
+	var diff commentExportDiffFile
+	if err := json.Unmarshal(raw, &diff); err != nil {
+		t.Fatalf("Unmarshal(diff) error = %v", err)
+	}
+	if diff.New[0].CommentID != "c3" || diff.Deleted[0].CommentID != "c0" ||
+		diff.LikeChanges[0].PreviousLikes != 5 || diff.LikeChanges[0].CurrentLikes != 9 {
+		t.Fatalf("unexpected diff file: %+v", diff)
+	}
+
+	raw, err = os.ReadFile(second.SavedPath)
+	if err != nil {
+		t.Fatalf("ReadFile(export) error = %v", err)
+	}
+	var saved commentExportFile
+	if err := json.Unmarshal(raw, &saved); err != nil {
+		t.Fatalf("Unmarshal(export) error = %v", err)
+	}
+	ids := []string{}
+	for _, comment := range saved.CommentInfo {
+		ids = append(ids, comment.CommentID)
+	}
+	if len(ids) != 3 || ids[0] != "c3" || ids[1] != "c1" || ids[2] != "c2" || len(saved.CommentInfo[1].LevelTwoComment) != 1 {
+		t.Fatalf("unexpected merged comments: %v", ids)
+	}
+}
+
+func TestLoadPreviousCommentExportFallsBackToCommentStore(t *testing.T) {
+	store := &fakeCommentStore{}
+	store.UpsertMany(commentRowsToRecords([]commentTableRow{
+		{ObjectID: "oid-1", CommentID: "c1", Level: 1, LikeCount: 2},
+		{ObjectID: "oid-1", CommentID: "r1", ParentID: "c1", Level: 2},
+	}, "", "", time.Now()))
+	service := &SearchService{commentStore: store}
+
+	previous, err := service.loadPreviousCommentExport(t.TempDir(), "oid-1")
+	if err != nil || previous == nil {
+		t.Fatalf("loadPreviousCommentExport() = %v, %v", previous, err)
+	}
+	if previous.source != commentPreviousSourceDatabase || len(previous.comments) != 1 {
+		t.Fatalf("unexpected previous export: %+v", previous)
+	}
+	replies, ok := previous.reusableReplies(map[string]interface{}{"commentId": "c1", "expandCommentCount": 1})
+	if !ok || len(replies) != 1 {
+		t.Fatalf("reusableReplies() = %v, %v", replies, ok)
+	}
+}
@@ -2,11 +2,16 @@ package api
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"wx_channel/internal/database"
 	"wx_channel/internal/utils"
 )
 
@@ -15,7 +20,20 @@ const (
 	commentExportRunning  = "running"
 	commentExportSuccess  = "succeeded"
 	commentExportFailed   = "failed"
+	commentExportCanceled = "canceled"
 	commentExportQueueMax = 4
+
+	// commentExportJobRetention 已结束的任务在数据库中保留的时间
+	commentExportJobRetention = 7 * 24 * time.Hour
+	// commentExportClientPoll 恢复的任务等待页面连接的检查间隔
+	commentExportClientPoll = 5 * time.Second
+)
+
+var (
+	// ErrCommentExportJobNotFound 任务不存在
+	ErrCommentExportJobNotFound = errors.New("comment export job not found")
+	// ErrCommentExportJobFinished 任务已经结束，无法取消
+	ErrCommentExportJobFinished = errors.New("comment export job already finished")
 )
 
 // CommentExportProgress describes the durable part of an export's progress.
@@ -26,6 +44,8 @@ type CommentExportProgress struct {
 	ReportedCount    int    `json:"reported_count"`
 	CompletedReplies int    `json:"completed_replies"`
 	TotalReplies     int    `json:"total_replies"`
+	// CheckpointPath 断点文件，重启后从这里继续导出
+	CheckpointPath string `json:"checkpoint_path,omitempty"`
 }
 
 // CommentExportJobStatus is returned to the browser while an export runs.
@@ -45,6 +65,18 @@ type commentExportJob struct {
 	cancel  context.CancelFunc
 	request ExportFeedCommentsRequest
 	status  CommentExportJobStatus
+	// resumed 重启后恢复的任务，需要等页面重新连接后再执行
+	resumed         bool
+	cancelRequested bool
+}
+
+// commentExportJobStore 任务持久化接口，默认由 database.CommentExportJobRepository 实现
+type commentExportJobStore interface {
+	Save(job *database.CommentExportJob) error
+	GetByID(id string) (*database.CommentExportJob, error)
+	List(limit int) ([]database.CommentExportJob, error)
+	ListByStatus(statuses ...string) ([]database.CommentExportJob, error)
+	DeleteUpdatedBefore(before time.Time, statuses ...string) (int64, error)
 }
 
 // CommentExportJobManager serializes comment exports against the page client.
@@ -54,21 +86,35 @@ type CommentExportJobManager struct {
 	ctx     context.Context
 	cancel  context.CancelFunc
 	queue   chan *commentExportJob
+	store   commentExportJobStore
+	// waitForClient 恢复的任务执行前等待可用的页面连接
+	waitForClient func(ctx context.Context) error
 
 	mu   sync.RWMutex
 	jobs map[string]*commentExportJob
 	seq  uint64
 }
 
+// NewCommentExportJobManager 创建评论导出任务管理器，数据库可用时任务状态会持久化
 func NewCommentExportJobManager(service *SearchService) *CommentExportJobManager {
+	var store commentExportJobStore
+	if database.GetDB() != nil {
+		store = database.NewCommentExportJobRepository()
+	}
+	return newCommentExportJobManager(service, store)
+}
+
+func newCommentExportJobManager(service *SearchService, store commentExportJobStore) *CommentExportJobManager {
 	ctx, cancel := context.WithCancel(context.Background())
 	m := &CommentExportJobManager{
 		service: service,
 		ctx:     ctx,
 		cancel:  cancel,
 		queue:   make(chan *commentExportJob, commentExportQueueMax),
+		store:   store,
 		jobs:    make(map[string]*commentExportJob),
 	}
+	m.waitForClient = m.waitForCommentClient
 	go m.worker()
 	return m
 }
@@ -101,9 +147,11 @@ func (m *CommentExportJobManager) Submit(req ExportFeedCommentsRequest) (Comment
 
 	select {
 	case m.queue <- job:
+		m.persist(job)
 		return job.snapshot(), nil
 	default:
 		job.setFailed("comment export queue is full")
+		m.persist(job)
 		return CommentExportJobStatus{}, fmt.Errorf("comment export queue is full, please retry later")
 	}
 }
@@ -115,10 +163,161 @@ func (m *CommentExportJobManager) Get(jobID string) (CommentExportJobStatus, boo
 	m.mu.RLock()
 	job, ok := m.jobs[jobID]
 	m.mu.RUnlock()
-	if !ok {
+	if ok {
+		return job.snapshot(), true
+	}
+
+	// 内存中已清理或重启前结束的任务从数据库读取
+	if m.store == nil {
 		return CommentExportJobStatus{}, false
 	}
-	return job.snapshot(), true
+	record, err := m.store.GetByID(jobID)
+	if err != nil || record == nil {
+		return CommentExportJobStatus{}, false
+	}
+	return commentExportStatusFromRecord(record), true
+}
+
+// List 返回最近的任务，最新创建的在前
+func (m *CommentExportJobManager) List(limit int) []CommentExportJobStatus {
+	byID := make(map[string]CommentExportJobStatus)
+	if m.store != nil {
+		records, err := m.store.List(limit)
+		if err != nil {
+			utils.Warn("[评论导出] 读取任务列表失败: %v", err)
+		}
+		for i := range records {
+			byID[records[i].ID] = commentExportStatusFromRecord(&records[i])
+		}
+	}
+	m.mu.RLock()
+	for id, job := range m.jobs {
+		byID[id] = job.snapshot()
+	}
+	m.mu.RUnlock()
+
+	jobs := make([]CommentExportJobStatus, 0, len(byID))
+	for _, status := range byID {
+		jobs = append(jobs, status)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if jobs[i].CreatedAt != jobs[j].CreatedAt {
+			return jobs[i].CreatedAt > jobs[j].CreatedAt
+		}
+		return jobs[i].JobID > jobs[j].JobID
+	})
+	if limit > 0 && len(jobs) > limit {
+		jobs = jobs[:limit]
+	}
+	return jobs
+}
+
+// Cancel 取消排队中或正在执行的任务，执行中的任务会在当前请求返回后停止并删除断点文件
+func (m *CommentExportJobManager) Cancel(jobID string) (CommentExportJobStatus, error) {
+	m.mu.RLock()
+	job, ok := m.jobs[jobID]
+	m.mu.RUnlock()
+	if !ok {
+		if _, exists := m.Get(jobID); exists {
+			return CommentExportJobStatus{}, ErrCommentExportJobFinished
+		}
+		return CommentExportJobStatus{}, ErrCommentExportJobNotFound
+	}
+
+	if !job.requestCancel() {
+		return CommentExportJobStatus{}, ErrCommentExportJobFinished
+	}
+	m.persist(job)
+	return job.snapshot(), nil
+}
+
+// Resume 重新排队上次退出时未完成的任务，有断点文件的从断点继续
+// 返回恢复的任务数
+func (m *CommentExportJobManager) Resume() int {
+	if m.store == nil {
+		return 0
+	}
+	if _, err := m.store.DeleteUpdatedBefore(time.Now().Add(-commentExportJobRetention),
+		commentExportSuccess, commentExportFailed, commentExportCanceled); err != nil {
+		utils.Warn("[评论导出] 清理历史任务失败: %v", err)
+	}
+
+	records, err := m.store.ListByStatus(commentExportQueued, commentExportRunning)
+	if err != nil {
+		utils.Warn("[评论导出] 读取未完成的任务失败: %v", err)
+		return 0
+	}
+
+	resumed := make([]*commentExportJob, 0, len(records))
+	for i := range records {
+		record := &records[i]
+		status := commentExportStatusFromRecord(record)
+
+		var req ExportFeedCommentsRequest
+		if err := json.Unmarshal([]byte(record.Request), &req); err != nil {
+			record.Status = commentExportFailed
+			record.Error = "failed to restore request: " + err.Error()
+			record.UpdatedAt = time.Now()
+			if err := m.store.Save(record); err != nil {
+				utils.Warn("[评论导出] 保存任务状态失败: %v", err)
+			}
+			continue
+		}
+		if path := status.Progress.CheckpointPath; path != "" {
+			if _, err := os.Stat(path); err == nil {
+				req.resumeFrom = path
+			}
+		}
+
+		ctx, cancel := context.WithCancel(m.ctx)
+		status.Status = commentExportQueued
+		status.Progress.Stage = "resuming"
+		status.UpdatedAt = time.Now().Format(time.RFC3339)
+		job := &commentExportJob{
+			ctx:     ctx,
+			cancel:  cancel,
+			request: req,
+			status:  status,
+			resumed: true,
+		}
+		m.mu.Lock()
+		m.jobs[job.status.JobID] = job
+		m.mu.Unlock()
+		m.persist(job)
+		resumed = append(resumed, job)
+	}
+
+	// 队列容量有限，按原来的顺序逐个放入
+	go func() {
+		for _, job := range resumed {
+			select {
+			case m.queue <- job:
+			case <-m.ctx.Done():
+				return
+			}
+		}
+	}()
+	return len(resumed)
+}
+
+// waitForCommentClient 等待支持评论接口的页面连接
+func (m *CommentExportJobManager) waitForCommentClient(ctx context.Context) error {
+	hub := m.service.hub
+	if hub == nil {
+		return nil
+	}
+	ticker := time.NewTicker(commentExportClientPoll)
+	defer ticker.Stop()
+	for {
+		if _, err := hub.GetClientForKey("key:channels:fetch_feed_comment_list"); err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
 }
 
 func (m *CommentExportJobManager) worker() {
@@ -136,18 +335,106 @@ func (m *CommentExportJobManager) worker() {
 }
 
 func (m *CommentExportJobManager) run(job *commentExportJob) {
+	if job.isCancelRequested() {
+		m.finishInterrupted(job)
+		return
+	}
+	if job.resumed {
+		if err := m.waitForClient(job.ctx); err != nil {
+			m.finishInterrupted(job)
+			return
+		}
+	}
+
 	job.setStatus(commentExportRunning, CommentExportProgress{Stage: "starting"})
+	m.persist(job)
 	result, err := m.service.exportFeedCommentsContext(job.ctx, job.request, func(progress CommentExportProgress) {
 		job.setProgress(progress)
+		m.persist(job)
 	})
 	if err != nil {
+		if job.ctx.Err() != nil {
+			m.finishInterrupted(job)
+			return
+		}
 		message := normalizePageContextAPIError(err).Error()
 		job.setFailed(message)
+		m.persist(job)
 		status := job.snapshot()
 		utils.LogComment(status.JobID, job.request.Title, status.Progress.TopLevelCount+status.Progress.ReplyCount, false)
 		return
 	}
 	job.setSuccess(result)
+	m.persist(job)
+}
+
+// finishInterrupted 处理被中断的任务：用户取消的删除断点文件；程序退出的保持原状态，下次启动时恢复
+func (m *CommentExportJobManager) finishInterrupted(job *commentExportJob) {
+	if !job.isCancelRequested() {
+		return
+	}
+	job.setCanceled()
+	if path := job.snapshot().Progress.CheckpointPath; path != "" {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			utils.Warn("[评论导出] 删除断点文件失败: %v", err)
+		}
+	}
+	m.persist(job)
+}
+
+// persist 将任务状态写入数据库，失败时只记录日志
+func (m *CommentExportJobManager) persist(job *commentExportJob) {
+	if m.store == nil {
+		return
+	}
+	job.mu.RLock()
+	req := job.request
+	status := job.status
+	job.mu.RUnlock()
+
+	record := &database.CommentExportJob{
+		ID:       status.JobID,
+		ObjectID: req.ObjectID,
+		Title:    req.Title,
+		Status:   status.Status,
+		Error:    status.Error,
+	}
+	if data, err := json.Marshal(req); err == nil {
+		record.Request = string(data)
+	}
+	if data, err := json.Marshal(status.Progress); err == nil {
+		record.Progress = string(data)
+	}
+	if status.Result != nil {
+		if data, err := json.Marshal(status.Result); err == nil {
+			record.Result = string(data)
+		}
+	}
+	record.CreatedAt, _ = time.Parse(time.RFC3339, status.CreatedAt)
+	record.UpdatedAt, _ = time.Parse(time.RFC3339, status.UpdatedAt)
+	if err := m.store.Save(record); err != nil {
+		utils.Warn("[评论导出] 保存任务状态失败: %v", err)
+	}
+}
+
+func commentExportStatusFromRecord(record *database.CommentExportJob) CommentExportJobStatus {
+	status := CommentExportJobStatus{
+		JobID:     record.ID,
+		Status:    record.Status,
+		Error:     record.Error,
+		CreatedAt: record.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: record.UpdatedAt.Format(time.RFC3339),
+	}
+	if record.Progress != "" {
+		_ = json.Unmarshal([]byte(record.Progress), &status.Progress)
+	}
+	if record.Result != "" {
+		var result ExportFeedCommentsResult
+		if err := json.Unmarshal([]byte(record.Result), &result); err == nil {
+			status.Result = &result
+		}
+	}
+	return status
 }
 
 func (j *commentExportJob) snapshot() CommentExportJobStatus {
@@ -181,6 +468,42 @@ func (j *commentExportJob) setSuccess(result *ExportFeedCommentsResult) {
 	j.cancel()
 }
 
+// requestCancel 标记取消，排队中的任务直接结束；已结束的任务返回 false
+func (j *commentExportJob) requestCancel() bool {
+	j.mu.Lock()
+	switch j.status.Status {
+	case commentExportQueued:
+		j.cancelRequested = true
+		j.status.Status = commentExportCanceled
+		j.status.Progress.Stage = "canceled"
+		j.status.UpdatedAt = time.Now().Format(time.RFC3339)
+	case commentExportRunning:
+		j.cancelRequested = true
+		j.status.Progress.Stage = "canceling"
+		j.status.UpdatedAt = time.Now().Format(time.RFC3339)
+	default:
+		j.mu.Unlock()
+		return false
+	}
+	j.mu.Unlock()
+	j.cancel()
+	return true
+}
+
+func (j *commentExportJob) isCancelRequested() bool {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.cancelRequested
+}
+
+func (j *commentExportJob) setCanceled() {
+	j.mu.Lock()
+	j.status.Status = commentExportCanceled
+	j.status.Progress.Stage = "canceled"
+	j.status.UpdatedAt = time.Now().Format(time.RFC3339)
+	j.mu.Unlock()
+}
+
 func (j *commentExportJob) setFailed(message string) {
 	j.mu.Lock()
 	j.status.Status = commentExportFailed
@@ -195,7 +518,7 @@ func (m *CommentExportJobManager) pruneLocked(now time.Time) {
 	cutoff := now.Add(-30 * time.Minute)
 	for id, job := range m.jobs {
 		status := job.snapshot()
-		if status.Status != commentExportSuccess && status.Status != commentExportFailed {
+		if status.Status != commentExportSuccess && status.Status != commentExportFailed && status.Status != commentExportCanceled {
 			continue
 		}
 		updated, err := time.Parse(time.RFC3339, status.UpdatedAt)
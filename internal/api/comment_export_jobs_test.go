@@ -1,10 +1,17 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 	"time"
+
+	"wx_channel/internal/database"
+	"wx_channel/internal/websocket"
 )
 
 func TestCommentExportJobManagerCompletesAndPersistsResult(t *testing.T) {
@@ -70,3 +77,171 @@ func TestCommentExportJobManagerCompletesAndPersistsResult(t *testing.T) {
 		t.Fatalf("unexpected saved comments: %#v", saved.CommentInfo)
 	}
 }
+
+// memoryCommentExportJobStore 内存中的任务库
+type memoryCommentExportJobStore struct {
+	mu   sync.Mutex
+	jobs map[string]database.CommentExportJob
+}
+
+func newMemoryCommentExportJobStore() *memoryCommentExportJobStore {
+	return &memoryCommentExportJobStore{jobs: make(map[string]database.CommentExportJob)}
+}
+
+func (s *memoryCommentExportJobStore) Save(job *database.CommentExportJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[job.ID] = *job
+	return nil
+}
+
+func (s *memoryCommentExportJobStore) GetByID(id string) (*database.CommentExportJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, nil
+	}
+	return &job, nil
+}
+
+func (s *memoryCommentExportJobStore) List(limit int) ([]database.CommentExportJob, error) {
+	return s.ListByStatus(commentExportQueued, commentExportRunning, commentExportSuccess, commentExportFailed, commentExportCanceled)
+}
+
+func (s *memoryCommentExportJobStore) ListByStatus(statuses ...string) ([]database.CommentExportJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := []database.CommentExportJob{}
+	for _, job := range s.jobs {
+		for _, status := range statuses {
+			if job.Status == status {
+				jobs = append(jobs, job)
+			}
+		}
+	}
+	return jobs, nil
+}
+
+func (s *memoryCommentExportJobStore) DeleteUpdatedBefore(before time.Time, statuses ...string) (int64, error) {
+	return 0, nil
+}
+
+func waitForCommentExportStatus(t *testing.T, manager *CommentExportJobManager, jobID string, statuses ...string) CommentExportJobStatus {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		status, ok := manager.Get(jobID)
+		if ok {
+			for _, want := range statuses {
+				if status.Status == want {
+					return status
+				}
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %q did not reach %v", jobID, statuses)
+	return CommentExportJobStatus{}
+}
+
+func TestCommentExportJobManagerCancelsRunningJob(t *testing.T) {
+	t.Parallel()
+
+	started := make(chan struct{}, 1)
+	service := &SearchService{
+		callAPIContext: func(ctx context.Context, key string, body interface{}, timeout time.Duration) ([]byte, error) {
+			started <- struct{}{}
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+		resolveDownloadsDir: func() (string, error) {
+			return t.TempDir(), nil
+		},
+	}
+	store := newMemoryCommentExportJobStore()
+	manager := newCommentExportJobManager(service, store)
+	job, err := manager.Submit(ExportFeedCommentsRequest{ObjectID: "oid-1", NonceID: "nid-1"})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+
+	<-started
+	if _, err := manager.Cancel(job.JobID); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+	waitForCommentExportStatus(t, manager, job.JobID, commentExportCanceled)
+
+	record, _ := store.GetByID(job.JobID)
+	if record == nil || record.Status != commentExportCanceled {
+		t.Fatalf("persisted job = %#v, want canceled", record)
+	}
+	if _, err := manager.Cancel(job.JobID); !errors.Is(err, ErrCommentExportJobFinished) {
+		t.Fatalf("second Cancel() error = %v, want ErrCommentExportJobFinished", err)
+	}
+	if _, err := manager.Cancel("missing"); !errors.Is(err, ErrCommentExportJobNotFound) {
+		t.Fatalf("Cancel(missing) error = %v, want ErrCommentExportJobNotFound", err)
+	}
+	if jobs := manager.List(10); len(jobs) != 1 || jobs[0].JobID != job.JobID {
+		t.Fatalf("List() = %#v", jobs)
+	}
+}
+
+func TestCommentExportJobManagerResumesFromCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+	saveDir := filepath.Join(tempDir, "comment_data", "2024-05-15")
+	if err := os.MkdirAll(saveDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	savePath := filepath.Join(saveDir, "中断的导出.json")
+	checkpointPath := savePath + commentExportCheckpointSuffix
+	checkpoint := `{"objectId":"oid-1","originalCommentCount":2,"completedReplies":["c1"],"commentInfo":[` +
+		`{"commentId":"c1","content":"done","expandCommentCount":1,"levelTwoComment":[{"commentId":"r1","content":"reply-1"}]},` +
+		`{"commentId":"c2","content":"pending","expandCommentCount":1,"levelTwoComment":[]}]}`
+	if err := os.WriteFile(checkpointPath, []byte(checkpoint), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store := newMemoryCommentExportJobStore()
+	request, _ := json.Marshal(ExportFeedCommentsRequest{ObjectID: "oid-1", NonceID: "nid-1", Title: "中断的导出"})
+	progress, _ := json.Marshal(CommentExportProgress{Stage: "replies", CheckpointPath: checkpointPath})
+	now := time.Now()
+	store.Save(&database.CommentExportJob{
+		ID: "comment-resume", ObjectID: "oid-1", Request: string(request), Status: commentExportRunning,
+		Progress: string(progress), CreatedAt: now, UpdatedAt: now,
+	})
+
+	service := &SearchService{
+		callAPI: func(key string, body interface{}, timeout time.Duration) ([]byte, error) {
+			req := body.(websocket.FeedCommentListBody)
+			if req.CommentID != "c2" {
+				t.Errorf("unexpected request after resume: %+v", req)
+			}
+			return []byte(`{"errCode":0,"data":{"commentInfo":[{"commentId":"r2","content":"reply-2"}],"lastBuffer":""}}`), nil
+		},
+		resolveDownloadsDir: func() (string, error) {
+			return tempDir, nil
+		},
+	}
+	manager := newCommentExportJobManager(service, store)
+	manager.waitForClient = func(ctx context.Context) error { return nil }
+
+	if count := manager.Resume(); count != 1 {
+		t.Fatalf("Resume() = %d, want 1", count)
+	}
+	status := waitForCommentExportStatus(t, manager, "comment-resume", commentExportSuccess, commentExportFailed)
+	if status.Status != commentExportSuccess {
+		t.Fatalf("resumed job failed: %s", status.Error)
+	}
+	if status.Result.SavedPath != savePath || status.Result.TopLevelCount != 2 || status.Result.ReplyCount != 2 {
+		t.Fatalf("unexpected resumed result: %#v", status.Result)
+	}
+	if _, err := os.Stat(checkpointPath); !os.IsNotExist(err) {
+		t.Fatalf("checkpoint should be removed after completion, stat err = %v", err)
+	}
+	if record, _ := store.GetByID("comment-resume"); record.Status != commentExportSuccess {
+		t.Fatalf("persisted status = %q, want succeeded", record.Status)
+	}
+}
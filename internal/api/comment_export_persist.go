@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"wx_channel/internal/utils"
@@ -40,10 +41,27 @@ func newCommentExportPersistence(downloadsDir string, req ExportFeedCommentsRequ
 	}, nil
 }
 
-func (p *commentExportPersistence) SaveCheckpoint(req ExportFeedCommentsRequest, comments []map[string]interface{}, reportedCount, replyCount int, source string) error {
+// resumeCommentExportPersistence 继续写入断点文件对应的导出文件
+func resumeCommentExportPersistence(downloadsDir, checkpointPath string) *commentExportPersistence {
+	savePath := strings.TrimSuffix(checkpointPath, commentExportCheckpointSuffix)
+	relativePath, _ := filepath.Rel(downloadsDir, savePath)
+	return &commentExportPersistence{
+		downloadsDir: downloadsDir,
+		saveDir:      filepath.Dir(savePath),
+		savePath:     savePath,
+		relativePath: relativePath,
+	}
+}
+
+func (p *commentExportPersistence) checkpointPath() string {
+	return p.savePath + commentExportCheckpointSuffix
+}
+
+// SaveCheckpoint 保存断点，completedReplies 为已抓完回复的评论 ID，恢复时跳过
+func (p *commentExportPersistence) SaveCheckpoint(req ExportFeedCommentsRequest, comments []map[string]interface{}, reportedCount, replyCount int, source string, completedReplies []string) error {
 	payload := buildCommentExportFile(req, comments, reportedCount, replyCount, source)
-	checkpointPath := p.savePath + commentExportCheckpointSuffix
-	return writeCommentExportFile(checkpointPath, payload)
+	payload.CompletedReplies = completedReplies
+	return writeCommentExportFile(p.checkpointPath(), payload)
 }
 
 func (p *commentExportPersistence) Finalize(req ExportFeedCommentsRequest, comments []map[string]interface{}, reportedCount, replyCount int, source string) (string, string, error) {
@@ -52,8 +70,7 @@ func (p *commentExportPersistence) Finalize(req ExportFeedCommentsRequest, comme
 		return "", "", err
 	}
 
-	checkpointPath := p.savePath + commentExportCheckpointSuffix
-	if err := os.Remove(checkpointPath); err != nil && !os.IsNotExist(err) {
+	if err := os.Remove(p.checkpointPath()); err != nil && !os.IsNotExist(err) {
 		return "", "", err
 	}
 
@@ -82,7 +99,11 @@ func writeCommentExportFile(savePath string, payload commentExportFile) error {
 	if err != nil {
 		return err
 	}
+	return writeCommentExportData(savePath, data)
+}
 
+// writeCommentExportData 先写临时文件再替换，避免中断时留下不完整的文件
+func writeCommentExportData(savePath string, data []byte) error {
 	tmpPath := utils.BuildTempDownloadPath(savePath, "comment-export")
 	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
 		return err
@@ -0,0 +1,210 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"wx_channel/internal/database"
+)
+
+// 评论导出支持的格式，json 始终会写入
+const (
+	commentExportFormatJSON = "json"
+	commentExportFormatCSV  = "csv"
+	commentExportFormatTSV  = "tsv"
+)
+
+// commentTableRow 评论平铺表中的一行，顶级评论和回复各占一行
+type commentTableRow struct {
+	ObjectID       string
+	CommentID      string
+	ParentID       string
+	ReplyCommentID string
+	Level          int
+	Username       string
+	Nickname       string
+	Content        string
+	Region         string
+	LikeCount      int
+	CreateTime     *time.Time
+}
+
+var commentTableHeader = []string{
+	"ObjectID", "CommentID", "ParentID", "ReplyCommentID", "Level", "Username", "Nickname",
+	"Content", "Region", "LikeCount", "CreateTime",
+}
+
+// normalizeCommentExportFormats 校验并去重导出格式，json 不需要额外处理
+func normalizeCommentExportFormats(formats []string) ([]string, error) {
+	normalized := make([]string, 0, len(formats))
+	seen := make(map[string]bool)
+	for _, format := range formats {
+		format = strings.ToLower(strings.TrimSpace(format))
+		switch format {
+		case commentExportFormatJSON:
+			continue
+		case commentExportFormatCSV, commentExportFormatTSV:
+			if !seen[format] {
+				seen[format] = true
+				normalized = append(normalized, format)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported export format: %s", format)
+		}
+	}
+	return normalized, nil
+}
+
+// flattenCommentEntries 将嵌套的评论展开为平铺的行，回复紧跟在所属评论之后
+func flattenCommentEntries(objectID string, entries []formattedCommentEntry) []commentTableRow {
+	rows := make([]commentTableRow, 0, len(entries))
+	for _, entry := range entries {
+		rows = append(rows, newCommentTableRow(objectID, "", 1, entry))
+		for _, reply := range entry.LevelTwoComment {
+			rows = append(rows, newCommentTableRow(objectID, entry.CommentID, 2, reply))
+		}
+	}
+	return rows
+}
+
+func newCommentTableRow(objectID, parentID string, level int, entry formattedCommentEntry) commentTableRow {
+	row := commentTableRow{
+		ObjectID:       objectID,
+		CommentID:      entry.CommentID,
+		ParentID:       parentID,
+		ReplyCommentID: entry.ReplyCommentID,
+		Level:          level,
+		Username:       entry.Username,
+		Nickname:       entry.Nickname,
+		Content:        entry.Content,
+		Region:         entry.IPRegionInfo.RegionText,
+		LikeCount:      entry.LikeCount,
+	}
+	if seconds, err := strconv.ParseInt(entry.Createtime, 10, 64); err == nil && seconds > 0 {
+		t := time.Unix(seconds, 0)
+		row.CreateTime = &t
+	}
+	return row
+}
+
+func (row commentTableRow) values() []string {
+	createTime := ""
+	if row.CreateTime != nil {
+		createTime = row.CreateTime.Format("2006-01-02 15:04:05")
+	}
+	return []string{
+		row.ObjectID,
+		row.CommentID,
+		row.ParentID,
+		row.ReplyCommentID,
+		strconv.Itoa(row.Level),
+		row.Username,
+		row.Nickname,
+		row.Content,
+		row.Region,
+		strconv.Itoa(row.LikeCount),
+		createTime,
+	}
+}
+
+// buildCommentTable 生成 CSV 或 TSV 表格
+func buildCommentTable(rows []commentTableRow, format string) ([]byte, error) {
+	var buf bytes.Buffer
+	// 写入 UTF-8 BOM 以兼容 Excel
+	buf.Write([]byte{0xEF, 0xBB, 0xBF})
+
+	writer := csv.NewWriter(&buf)
+	sanitize := func(values []string) []string { return values }
+	if format == commentExportFormatTSV {
+		writer.Comma = '\t'
+		// TSV 按行和制表符切分，字段中的换行和制表符替换为空格
+		replacer := strings.NewReplacer("\t", " ", "\r\n", " ", "\n", " ", "\r", " ")
+		sanitize = func(values []string) []string {
+			for i, value := range values {
+				values[i] = replacer.Replace(value)
+			}
+			return values
+		}
+	}
+
+	if err := writer.Write(commentTableHeader); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		if err := writer.Write(sanitize(row.values())); err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeCommentTables 在 JSON 文件旁写入所需格式的表格，返回格式到文件路径的映射
+func writeCommentTables(jsonPath string, rows []commentTableRow, formats []string) (map[string]string, error) {
+	files := make(map[string]string, len(formats))
+	base := strings.TrimSuffix(jsonPath, ".json")
+	for _, format := range formats {
+		data, err := buildCommentTable(rows, format)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build %s export: %w", format, err)
+		}
+		path := base + "." + format
+		if err := writeCommentExportData(path, data); err != nil {
+			return nil, err
+		}
+		files[format] = path
+	}
+	return files, nil
+}
+
+// commentRowsToRecords 将平铺的评论行转换为数据库记录
+func commentRowsToRecords(rows []commentTableRow, title, author string, exportedAt time.Time) []database.Comment {
+	records := make([]database.Comment, 0, len(rows))
+	for _, row := range rows {
+		if row.CommentID == "" {
+			continue
+		}
+		records = append(records, database.Comment{
+			ObjectID:       row.ObjectID,
+			CommentID:      row.CommentID,
+			ParentID:       row.ParentID,
+			ReplyCommentID: row.ReplyCommentID,
+			Level:          row.Level,
+			Username:       row.Username,
+			Nickname:       row.Nickname,
+			Content:        row.Content,
+			Region:         row.Region,
+			LikeCount:      row.LikeCount,
+			CommentTime:    row.CreateTime,
+			VideoTitle:     title,
+			VideoAuthor:    author,
+			ExportedAt:     exportedAt,
+		})
+	}
+	return records
+}
+
+// commentStore 评论数据库的读写接口，默认由 database.CommentRepository 实现
+type commentStore interface {
+	UpsertMany(comments []database.Comment) (int, error)
+	ListByObjectID(objectID string) ([]database.Comment, error)
+	DeleteByCommentIDs(objectID string, commentIDs []string) (int64, error)
+}
+
+// resolveCommentStore 返回评论数据库，数据库未初始化时返回错误
+func (s *SearchService) resolveCommentStore() (commentStore, error) {
+	if s.commentStore != nil {
+		return s.commentStore, nil
+	}
+	if database.GetDB() == nil {
+		return nil, fmt.Errorf("database is not initialized")
+	}
+	return database.NewCommentRepository(), nil
+}
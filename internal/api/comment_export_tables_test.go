@@ -0,0 +1,116 @@
+package api
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"wx_channel/internal/database"
+	"wx_channel/internal/websocket"
+)
+
+func TestExportFeedCommentsWritesTablesAndImports(t *testing.T) {
+	tempDir := t.TempDir()
+	store := &fakeCommentStore{}
+
+	service := &SearchService{
+		callAPI: func(key string, body interface{}, timeout time.Duration) ([]byte, error) {
+			if body.(websocket.FeedCommentListBody).CommentID == "c1" {
+				return []byte(`{"errCode":0,"errMsg":"ok","data":{"commentInfo":[{"commentId":"r1","content":"回复\t内容","replyCommentId":"c1","username":"user_b","createtime":"1715760600","likeCount":3}],"lastBuffer":""}}`), nil
+			}
+			return []byte(`{"errCode":0,"errMsg":"ok","data":{"commentInfo":[{"commentId":"c1","content":"第一行\n第二行","expandCommentCount":1,"nickname":"用户A","username":"user_a","createtime":"1715760000","likeCount":12,"ipRegionInfo":{"regionText":"广东"}}],"countInfo":{"commentCount":1},"lastBuffer":""}}`), nil
+		},
+		resolveDownloadsDir: func() (string, error) {
+			return tempDir, nil
+		},
+		commentStore: store,
+	}
+
+	result, err := service.exportFeedComments(ExportFeedCommentsRequest{
+		ObjectID: "oid-1",
+		NonceID:  "nid-1",
+		Title:    "测试标题",
+		Formats:  []string{"json", "CSV", "tsv"},
+		SaveToDB: true,
+	})
+	if err != nil {
+		t.Fatalf("exportFeedComments() error = %v", err)
+	}
+
+	if result.Files["csv"] != strings.TrimSuffix(result.SavedPath, ".json")+".csv" {
+		t.Fatalf("csv path = %q, saved path %q", result.Files["csv"], result.SavedPath)
+	}
+	csvData, err := os.ReadFile(result.Files["csv"])
+	if err != nil {
+		t.Fatalf("ReadFile(csv) error = %v", err)
+	}
+	if !bytes.HasPrefix(csvData, []byte{0xEF, 0xBB, 0xBF}) {
+		t.Fatalf("csv file is missing the UTF-8 BOM")
+	}
+	if !strings.Contains(string(csvData), "oid-1,r1,c1,c1,2,user_b,,回复\t内容,,3,") {
+		t.Fatalf("csv file is missing the reply row:\n%s", csvData)
+	}
+
+	tsvData, err := os.ReadFile(result.Files["tsv"])
+	if err != nil {
+		t.Fatalf("ReadFile(tsv) error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(tsvData)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("tsv line count = %d, want 3:\n%s", len(lines), tsvData)
+	}
+	if fields := strings.Split(lines[1], "\t"); len(fields) != len(commentTableHeader) || fields[7] != "第一行 第二行" || fields[8] != "广东" {
+		t.Fatalf("unexpected tsv row: %q", lines[1])
+	}
+
+	imported := store.comments
+	if result.ImportedCount != 2 || len(imported) != 2 {
+		t.Fatalf("ImportedCount = %d, imported %d, want 2", result.ImportedCount, len(imported))
+	}
+	if imported[1].ParentID != "c1" || imported[1].Level != 2 || imported[1].CommentTime == nil || imported[1].VideoTitle != "测试标题" {
+		t.Fatalf("unexpected imported reply: %+v", imported[1])
+	}
+}
+
+// fakeCommentStore 内存中的评论库，按 comment_id 覆盖
+type fakeCommentStore struct {
+	comments []database.Comment
+	deleted  []string
+}
+
+func (f *fakeCommentStore) UpsertMany(comments []database.Comment) (int, error) {
+	for _, comment := range comments {
+		replaced := false
+		for i := range f.comments {
+			if f.comments[i].CommentID == comment.CommentID {
+				f.comments[i] = comment
+				replaced = true
+			}
+		}
+		if !replaced {
+			f.comments = append(f.comments, comment)
+		}
+	}
+	return len(comments), nil
+}
+
+func (f *fakeCommentStore) ListByObjectID(objectID string) ([]database.Comment, error) {
+	return f.comments, nil
+}
+
+func (f *fakeCommentStore) DeleteByCommentIDs(objectID string, commentIDs []string) (int64, error) {
+	f.deleted = append(f.deleted, commentIDs...)
+	return int64(len(commentIDs)), nil
+}
+
+func TestNormalizeCommentExportFormatsRejectsUnknown(t *testing.T) {
+	if _, err := normalizeCommentExportFormats([]string{"csv", "xlsx"}); err == nil {
+		t.Fatalf("expected unsupported format to be rejected")
+	}
+	formats, err := normalizeCommentExportFormats([]string{"json", "csv", "CSV"})
+	if err != nil || len(formats) != 1 || formats[0] != "csv" {
+		t.Fatalf("normalizeCommentExportFormats() = %v, %v", formats, err)
+	}
+}
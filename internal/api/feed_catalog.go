@@ -0,0 +1,277 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"wx_channel/internal/response"
+	"wx_channel/internal/services"
+	"wx_channel/internal/utils"
+	"wx_channel/internal/websocket"
+)
+
+const (
+	// feedCatalogMaxPages 单次抓取的默认翻页上限，未抓完时保留断点下次继续
+	feedCatalogMaxPages = 1000
+	// feedCatalogPageInterval 翻页间隔，避免连续请求触发风控
+	feedCatalogPageInterval = 2 * time.Second
+)
+
+// FeedCatalogRequest 账号作品目录抓取请求
+type FeedCatalogRequest struct {
+	Username string `json:"username"`
+	Author   string `json:"author"`    // 账号昵称，为空时取作品中的昵称
+	MaxPages int    `json:"max_pages"` // 本次最多翻页数，默认 1000
+	Restart  bool   `json:"restart"`   // 忽略已有断点，从第一页重新抓取
+	Enqueue  bool   `json:"enqueue"`   // 抓取完成后将未下载的作品加入下载队列
+}
+
+// FeedCatalogItem 归一化后的作品信息
+type FeedCatalogItem struct {
+	VideoID      string `json:"video_id"`
+	NonceID      string `json:"nonce_id"`
+	Title        string `json:"title"`
+	Author       string `json:"author"`
+	CreateTime   int64  `json:"create_time"`
+	PublishedAt  string `json:"published_at"`
+	Duration     int64  `json:"duration"`
+	FileSize     int64  `json:"file_size"`
+	Resolution   string `json:"resolution"`
+	VideoURL     string `json:"video_url"`
+	CoverURL     string `json:"cover_url"`
+	DecryptKey   string `json:"decrypt_key"`
+	LikeCount    int64  `json:"like_count"`
+	CommentCount int64  `json:"comment_count"`
+	ForwardCount int64  `json:"forward_count"`
+	FavCount     int64  `json:"fav_count"`
+}
+
+// FeedCatalogProgress 抓取进度
+type FeedCatalogProgress struct {
+	Stage      string `json:"stage"`
+	Pages      int    `json:"pages"`
+	ItemCount  int    `json:"item_count"`
+	Resumed    bool   `json:"resumed"`
+	NextMarker string `json:"next_marker,omitempty"`
+}
+
+// FeedCatalogResult 抓取结果
+type FeedCatalogResult struct {
+	Username     string `json:"username"`
+	Author       string `json:"author"`
+	TotalCount   int    `json:"total_count"`
+	Pages        int    `json:"pages"`
+	Complete     bool   `json:"complete"` // 为 false 表示达到翻页上限，可再次提交从断点继续
+	Resumed      bool   `json:"resumed"`
+	SavedPath    string `json:"saved_path"`
+	CSVPath      string `json:"csv_path"`
+	RelativePath string `json:"relative_path"`
+	QueuedCount  int    `json:"queued_count"`
+	SkippedCount int    `json:"skipped_count"` // 已下载或已在队列中而未入队的作品数
+
+	items []FeedCatalogItem
+}
+
+// CrawlFeedCatalog 提交账号作品目录抓取任务
+func (s *SearchService) CrawlFeedCatalog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		response.ErrorWithStatus(w, http.StatusMethodNotAllowed, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req FeedCatalogRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.Error(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.Username = strings.TrimSpace(req.Username)
+	if req.Username == "" {
+		response.Error(w, http.StatusBadRequest, "username is required")
+		return
+	}
+	if req.MaxPages < 0 {
+		response.Error(w, http.StatusBadRequest, "max_pages must not be negative")
+		return
+	}
+
+	job, err := s.ensureFeedCatalogJobs().Submit(req)
+	if err != nil {
+		response.ErrorWithStatus(w, http.StatusServiceUnavailable, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	response.SuccessWithStatus(w, http.StatusAccepted, job)
+}
+
+// FeedCatalogStatus 查询作品目录抓取任务状态
+func (s *SearchService) FeedCatalogStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorWithStatus(w, http.StatusMethodNotAllowed, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	jobID := strings.TrimSpace(r.URL.Query().Get("job_id"))
+	if jobID == "" {
+		response.Error(w, http.StatusBadRequest, "job_id is required")
+		return
+	}
+	s.feedCatalogJobsMu.RLock()
+	manager := s.feedCatalogJobs
+	s.feedCatalogJobsMu.RUnlock()
+	job, ok := manager.Get(jobID)
+	if !ok {
+		response.ErrorWithStatus(w, http.StatusNotFound, http.StatusNotFound, "feed catalog job not found")
+		return
+	}
+	response.Success(w, job)
+}
+
+// crawlFeedCatalogContext 逐页抓取账号作品，每页写入断点，结束后输出 JSON/CSV 目录
+func (s *SearchService) crawlFeedCatalogContext(ctx context.Context, req FeedCatalogRequest, pageInterval time.Duration, onProgress func(FeedCatalogProgress)) (*FeedCatalogResult, error) {
+	downloadsDir, err := s.resolveDownloadsDir()
+	if err != nil {
+		return nil, err
+	}
+	persistence, err := newFeedCatalogPersistence(downloadsDir, req.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &feedCatalogCheckpoint{Username: req.Username, Author: req.Author}
+	resumed := false
+	if req.Restart {
+		if err := persistence.DiscardCheckpoint(); err != nil {
+			return nil, err
+		}
+	} else if checkpoint, err := persistence.LoadCheckpoint(); err != nil {
+		utils.LogWarn("[FeedCatalog] 读取断点失败，从第一页开始: %v", err)
+	} else if checkpoint != nil {
+		state = checkpoint
+		resumed = true
+		if req.Author != "" {
+			state.Author = req.Author
+		}
+	}
+
+	seen := make(map[string]struct{}, len(state.Items))
+	for _, item := range state.Items {
+		seen[item.VideoID] = struct{}{}
+	}
+
+	maxPages := req.MaxPages
+	if maxPages <= 0 {
+		maxPages = feedCatalogMaxPages
+	}
+	report := func(stage string) {
+		if onProgress != nil {
+			onProgress(FeedCatalogProgress{
+				Stage:      stage,
+				Pages:      state.Pages,
+				ItemCount:  len(state.Items),
+				Resumed:    resumed,
+				NextMarker: state.NextMarker,
+			})
+		}
+	}
+
+	complete := false
+	for pages := 0; pages < maxPages; pages++ {
+		if pages > 0 && pageInterval > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(pageInterval):
+			}
+		}
+
+		objects, nextMarker, err := s.fetchFeedCatalogPage(ctx, req.Username, state.NextMarker)
+		if err != nil {
+			return nil, err
+		}
+		state.Pages++
+		for _, obj := range objects {
+			item, ok := normalizeFeedCatalogItem(obj)
+			if !ok {
+				continue
+			}
+			if _, exists := seen[item.VideoID]; exists {
+				continue
+			}
+			seen[item.VideoID] = struct{}{}
+			if state.Author == "" {
+				state.Author = item.Author
+			}
+			state.Items = append(state.Items, item)
+		}
+
+		state.NextMarker = nextMarker
+		if nextMarker == "" || len(objects) == 0 {
+			state.NextMarker = ""
+			complete = true
+			break
+		}
+		if err := persistence.SaveCheckpoint(state); err != nil {
+			return nil, err
+		}
+		report("pages")
+	}
+
+	report("saving")
+	if err := persistence.Finalize(state, complete); err != nil {
+		return nil, err
+	}
+
+	return &FeedCatalogResult{
+		Username:     req.Username,
+		Author:       state.Author,
+		TotalCount:   len(state.Items),
+		Pages:        state.Pages,
+		Complete:     complete,
+		Resumed:      resumed,
+		SavedPath:    persistence.jsonPath,
+		CSVPath:      persistence.csvPath,
+		RelativePath: persistence.relativePath,
+		items:        state.Items,
+	}, nil
+}
+
+// fetchFeedCatalogPage 拉取一页 feed_list，返回作品列表和下一页游标
+func (s *SearchService) fetchFeedCatalogPage(ctx context.Context, username, marker string) ([]interface{}, string, error) {
+	objects, nextMarker, err := services.FetchFeedListPage(func(body websocket.FeedListBody) ([]byte, error) {
+		return s.callAPIWithContext(ctx, "key:channels:feed_list", body, 60*time.Second)
+	}, username, marker)
+	if err != nil {
+		return nil, "", normalizePageContextAPIError(err)
+	}
+	return objects, nextMarker, nil
+}
+
+// normalizeFeedCatalogItem 从 feed_list 作品对象提取目录字段
+func normalizeFeedCatalogItem(obj interface{}) (FeedCatalogItem, bool) {
+	feed, ok := services.ParseFeedObject(obj)
+	if !ok {
+		return FeedCatalogItem{}, false
+	}
+	item := FeedCatalogItem{
+		VideoID:      feed.ID,
+		NonceID:      feed.NonceID,
+		Title:        feed.Title,
+		Author:       feed.Author,
+		CreateTime:   feed.CreateTime,
+		Duration:     feed.Duration,
+		FileSize:     feed.FileSize,
+		Resolution:   feed.Resolution,
+		VideoURL:     feed.VideoURL,
+		CoverURL:     feed.CoverURL,
+		DecryptKey:   feed.DecryptKey,
+		LikeCount:    feed.Counts.LikeCount,
+		CommentCount: feed.Counts.CommentCount,
+		ForwardCount: feed.Counts.ForwardCount,
+		FavCount:     feed.Counts.FavCount,
+	}
+	if item.CreateTime > 0 {
+		item.PublishedAt = time.Unix(item.CreateTime, 0).Format("2006-01-02 15:04:05")
+	}
+	return item, true
+}
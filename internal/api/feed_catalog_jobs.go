@@ -0,0 +1,273 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"wx_channel/internal/database"
+	"wx_channel/internal/services"
+	"wx_channel/internal/utils"
+)
+
+const feedCatalogQueueMax = 4
+
+// FeedCatalogJobStatus 作品目录抓取任务状态，状态值与评论导出任务一致
+type FeedCatalogJobStatus struct {
+	JobID     string              `json:"job_id"`
+	Username  string              `json:"username"`
+	Status    string              `json:"status"`
+	Progress  FeedCatalogProgress `json:"progress"`
+	Result    *FeedCatalogResult  `json:"result,omitempty"`
+	Error     string              `json:"error,omitempty"`
+	CreatedAt string              `json:"created_at"`
+	UpdatedAt string              `json:"updated_at"`
+}
+
+type feedCatalogJob struct {
+	mu      sync.RWMutex
+	ctx     context.Context
+	cancel  context.CancelFunc
+	request FeedCatalogRequest
+	status  FeedCatalogJobStatus
+}
+
+// FeedCatalogJobManager 串行执行作品目录抓取，与评论导出一样共用页面客户端，不能并发翻页
+type FeedCatalogJobManager struct {
+	service      *SearchService
+	ctx          context.Context
+	cancel       context.CancelFunc
+	queue        chan *feedCatalogJob
+	pageInterval time.Duration
+	// enqueue 将作品加入下载队列，返回入队数和跳过数，测试中可替换
+	enqueue func(items []FeedCatalogItem) (int, int, error)
+
+	mu   sync.RWMutex
+	jobs map[string]*feedCatalogJob
+	seq  uint64
+}
+
+func NewFeedCatalogJobManager(service *SearchService) *FeedCatalogJobManager {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &FeedCatalogJobManager{
+		service:      service,
+		ctx:          ctx,
+		cancel:       cancel,
+		queue:        make(chan *feedCatalogJob, feedCatalogQueueMax),
+		pageInterval: feedCatalogPageInterval,
+		enqueue:      enqueueFeedCatalogItems,
+		jobs:         make(map[string]*feedCatalogJob),
+	}
+	go m.worker()
+	return m
+}
+
+func (s *SearchService) ensureFeedCatalogJobs() *FeedCatalogJobManager {
+	s.feedCatalogJobsMu.Lock()
+	defer s.feedCatalogJobsMu.Unlock()
+	if s.feedCatalogJobs == nil {
+		s.feedCatalogJobs = NewFeedCatalogJobManager(s)
+	}
+	return s.feedCatalogJobs
+}
+
+// Submit 提交抓取任务；同一账号已有未结束的任务时直接返回该任务，避免两个任务写同一个断点
+func (m *FeedCatalogJobManager) Submit(req FeedCatalogRequest) (FeedCatalogJobStatus, error) {
+	if m == nil || m.service == nil {
+		return FeedCatalogJobStatus{}, fmt.Errorf("feed catalog service is not available")
+	}
+
+	now := time.Now()
+	m.mu.Lock()
+	m.pruneLocked(now)
+	for _, existing := range m.jobs {
+		status := existing.snapshot()
+		if status.Username == req.Username && !feedCatalogJobFinished(status.Status) {
+			m.mu.Unlock()
+			return status, nil
+		}
+	}
+	jobID := fmt.Sprintf("catalog-%d-%d", now.UnixNano(), atomic.AddUint64(&m.seq, 1))
+	ctx, cancel := context.WithCancel(m.ctx)
+	job := &feedCatalogJob{
+		ctx:     ctx,
+		cancel:  cancel,
+		request: req,
+		status: FeedCatalogJobStatus{
+			JobID:     jobID,
+			Username:  req.Username,
+			Status:    commentExportQueued,
+			Progress:  FeedCatalogProgress{Stage: "queued"},
+			CreatedAt: now.Format(time.RFC3339),
+			UpdatedAt: now.Format(time.RFC3339),
+		},
+	}
+	m.jobs[jobID] = job
+	m.mu.Unlock()
+
+	select {
+	case m.queue <- job:
+		return job.snapshot(), nil
+	default:
+		job.setFailed("feed catalog queue is full")
+		return FeedCatalogJobStatus{}, fmt.Errorf("feed catalog queue is full, please retry later")
+	}
+}
+
+func (m *FeedCatalogJobManager) Get(jobID string) (FeedCatalogJobStatus, bool) {
+	if m == nil {
+		return FeedCatalogJobStatus{}, false
+	}
+	m.mu.RLock()
+	job, ok := m.jobs[jobID]
+	m.mu.RUnlock()
+	if !ok {
+		return FeedCatalogJobStatus{}, false
+	}
+	return job.snapshot(), true
+}
+
+func (m *FeedCatalogJobManager) worker() {
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case job := <-m.queue:
+			if job == nil {
+				continue
+			}
+			m.run(job)
+		}
+	}
+}
+
+func (m *FeedCatalogJobManager) run(job *feedCatalogJob) {
+	job.setStatus(commentExportRunning, FeedCatalogProgress{Stage: "starting"})
+	result, err := m.service.crawlFeedCatalogContext(job.ctx, job.request, m.pageInterval, job.setProgress)
+	if err != nil {
+		utils.LogWarn("[FeedCatalog] 抓取账号 [%s] 作品目录失败: %v", job.request.Username, err)
+		job.setFailed(err.Error())
+		return
+	}
+
+	if job.request.Enqueue && m.enqueue != nil {
+		queued, skipped, err := m.enqueue(result.items)
+		if err != nil {
+			job.setFailed(fmt.Sprintf("catalog saved to %s, but failed to enqueue videos: %v", result.SavedPath, err))
+			return
+		}
+		result.QueuedCount = queued
+		result.SkippedCount = skipped
+	}
+	utils.LogInfo("[FeedCatalog] 账号 [%s] 作品目录已保存: %d 个作品, %s", result.Author, result.TotalCount, result.SavedPath)
+	job.setSuccess(result)
+}
+
+func (j *feedCatalogJob) snapshot() FeedCatalogJobStatus {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.status
+}
+
+func (j *feedCatalogJob) setStatus(status string, progress FeedCatalogProgress) {
+	j.mu.Lock()
+	j.status.Status = status
+	j.status.Progress = progress
+	j.status.UpdatedAt = time.Now().Format(time.RFC3339)
+	j.mu.Unlock()
+}
+
+func (j *feedCatalogJob) setProgress(progress FeedCatalogProgress) {
+	j.mu.Lock()
+	j.status.Progress = progress
+	j.status.UpdatedAt = time.Now().Format(time.RFC3339)
+	j.mu.Unlock()
+}
+
+func (j *feedCatalogJob) setSuccess(result *FeedCatalogResult) {
+	j.mu.Lock()
+	j.status.Status = commentExportSuccess
+	j.status.Progress.Stage = "completed"
+	j.status.Result = result
+	j.status.UpdatedAt = time.Now().Format(time.RFC3339)
+	j.mu.Unlock()
+	j.cancel()
+}
+
+func (j *feedCatalogJob) setFailed(message string) {
+	j.mu.Lock()
+	j.status.Status = commentExportFailed
+	j.status.Error = message
+	j.status.Progress.Stage = "failed"
+	j.status.UpdatedAt = time.Now().Format(time.RFC3339)
+	j.mu.Unlock()
+	j.cancel()
+}
+
+func feedCatalogJobFinished(status string) bool {
+	return status == commentExportSuccess || status == commentExportFailed
+}
+
+func (m *FeedCatalogJobManager) pruneLocked(now time.Time) {
+	cutoff := now.Add(-30 * time.Minute)
+	for id, job := range m.jobs {
+		status := job.snapshot()
+		if !feedCatalogJobFinished(status.Status) {
+			continue
+		}
+		updated, err := time.Parse(time.RFC3339, status.UpdatedAt)
+		if err == nil && updated.Before(cutoff) {
+			delete(m.jobs, id)
+		}
+	}
+}
+
+// enqueueFeedCatalogItems 将未下载、不在队列中的作品加入下载队列
+func enqueueFeedCatalogItems(items []FeedCatalogItem) (int, int, error) {
+	queueService := services.NewQueueService()
+	downloads := database.NewDownloadRecordRepository()
+
+	videos := make([]services.VideoInfo, 0, len(items))
+	skipped := 0
+	for _, item := range items {
+		if item.VideoURL == "" {
+			skipped++
+			continue
+		}
+		if record, _ := downloads.GetByVideoID(item.VideoID); record != nil &&
+			(record.Status == database.DownloadStatusCompleted || record.Status == database.DownloadStatusInProgress) {
+			skipped++
+			continue
+		}
+		if queued, _ := queueService.GetByVideoID(item.VideoID); queued != nil &&
+			(queued.Status == database.QueueStatusPending || queued.Status == database.QueueStatusDownloading || queued.Status == database.QueueStatusCompleted) {
+			skipped++
+			continue
+		}
+		title := item.Title
+		if title == "" {
+			title = "Catalog_" + item.VideoID
+		}
+		videos = append(videos, services.VideoInfo{
+			VideoID:    item.VideoID,
+			Title:      title,
+			Author:     item.Author,
+			CoverURL:   item.CoverURL,
+			VideoURL:   item.VideoURL,
+			DecryptKey: item.DecryptKey,
+			Duration:   item.Duration,
+			Resolution: item.Resolution,
+			Size:       item.FileSize,
+		})
+	}
+	if len(videos) == 0 {
+		return 0, skipped, nil
+	}
+	added, err := queueService.AddToQueue(videos)
+	if err != nil {
+		return 0, skipped, err
+	}
+	return len(added), skipped, nil
+}
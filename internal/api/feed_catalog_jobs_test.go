@@ -0,0 +1,126 @@
+package api
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"wx_channel/internal/websocket"
+)
+
+func waitFeedCatalogJob(t *testing.T, manager *FeedCatalogJobManager, jobID string) FeedCatalogJobStatus {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		status, ok := manager.Get(jobID)
+		if !ok {
+			t.Fatalf("job %q disappeared", jobID)
+		}
+		if feedCatalogJobFinished(status.Status) {
+			return status
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %q did not finish", jobID)
+	return FeedCatalogJobStatus{}
+}
+
+func TestFeedCatalogJobResumesFromCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	pages := map[string]string{
+		"":       `{"data":{"BaseResponse":{"Ret":0},"object":[{"id":"v1","objectNonceId":"n1","nickname":"作者","createtime":1700000000,"likeCount":3,"objectDesc":{"description":"第一条","media":[{"url":"http://example.invalid/v1?","urlToken":"t=1","decodeKey":"123","videoDuration":30}]}},{"id":"v2","objectDesc":{"description":"第二条"}}],"lastBuffer":"page 2"}}`,
+		"page+2": `{"data":{"BaseResponse":{"Ret":0},"object":[{"id":"v2","objectDesc":{"description":"第二条"}},{"id":"v3","objectDesc":{"description":"第三条"}}],"lastBuffer":""}}`,
+	}
+	var mu sync.Mutex
+	var markers []string
+	downloadsDir := t.TempDir()
+	service := &SearchService{
+		callAPI: func(key string, body interface{}, timeout time.Duration) ([]byte, error) {
+			if key != "key:channels:feed_list" {
+				t.Fatalf("unexpected API key: %s", key)
+			}
+			req := body.(websocket.FeedListBody)
+			mu.Lock()
+			markers = append(markers, req.NextMarker)
+			mu.Unlock()
+			return []byte(pages[req.NextMarker]), nil
+		},
+		resolveDownloadsDir: func() (string, error) {
+			return downloadsDir, nil
+		},
+	}
+	manager := NewFeedCatalogJobManager(service)
+	manager.pageInterval = 0
+	var enqueued []FeedCatalogItem
+	manager.enqueue = func(items []FeedCatalogItem) (int, int, error) {
+		enqueued = items
+		return 1, len(items) - 1, nil
+	}
+
+	// 达到翻页上限时保留断点
+	job, err := manager.Submit(FeedCatalogRequest{Username: "user@finder", MaxPages: 1})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	first := waitFeedCatalogJob(t, manager, job.JobID)
+	if first.Status != commentExportSuccess || first.Result == nil || first.Result.Complete || first.Result.TotalCount != 2 {
+		t.Fatalf("unexpected partial result: %#v (error %q)", first.Result, first.Error)
+	}
+	checkpointPath := strings.TrimSuffix(first.Result.SavedPath, ".json") + feedCatalogCheckpointSuffix
+	if _, err := os.Stat(checkpointPath); err != nil {
+		t.Fatalf("checkpoint missing: %v", err)
+	}
+
+	// 再次提交从断点继续，重复的作品只保留一条
+	job, err = manager.Submit(FeedCatalogRequest{Username: "user@finder", Enqueue: true})
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	second := waitFeedCatalogJob(t, manager, job.JobID)
+	if second.Status != commentExportSuccess {
+		t.Fatalf("job status = %q, error = %q", second.Status, second.Error)
+	}
+	result := second.Result
+	if !result.Complete || !result.Resumed || result.TotalCount != 3 || result.Pages != 2 || result.Author != "作者" {
+		t.Fatalf("unexpected result: %#v", result)
+	}
+	if result.QueuedCount != 1 || result.SkippedCount != 2 || len(enqueued) != 3 {
+		t.Fatalf("unexpected enqueue result: %#v, items %d", result, len(enqueued))
+	}
+	if len(markers) != 2 || markers[1] != "page+2" {
+		t.Fatalf("unexpected markers: %q", markers)
+	}
+	if _, err := os.Stat(checkpointPath); !os.IsNotExist(err) {
+		t.Fatalf("expected checkpoint to be removed, got %v", err)
+	}
+	if filepath.Dir(result.SavedPath) != filepath.Join(downloadsDir, "feed_catalog", "user@finder") {
+		t.Fatalf("unexpected catalog path: %s", result.SavedPath)
+	}
+
+	raw, err := os.ReadFile(result.SavedPath)
+	if err != nil {
+		t.Fatalf("read catalog: %v", err)
+	}
+	var catalog feedCatalogFile
+	if err := json.Unmarshal(raw, &catalog); err != nil {
+		t.Fatalf("parse catalog: %v", err)
+	}
+	item := catalog.Items[0]
+	if len(catalog.Items) != 3 || item.VideoURL != "http://example.invalid/v1?t=1" || item.NonceID != "n1" ||
+		item.CreateTime != 1700000000 || item.LikeCount != 3 || item.Duration != 30 {
+		t.Fatalf("unexpected catalog items: %#v", catalog.Items)
+	}
+
+	csvData, err := os.ReadFile(result.CSVPath)
+	if err != nil {
+		t.Fatalf("read csv: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(csvData)), "\n"); len(lines) != 4 || !strings.Contains(lines[3], "第三条") {
+		t.Fatalf("unexpected csv: %s", csvData)
+	}
+}
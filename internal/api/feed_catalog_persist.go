@@ -0,0 +1,195 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"wx_channel/internal/utils"
+)
+
+const (
+	feedCatalogFileName         = "catalog"
+	feedCatalogCheckpointSuffix = ".partial.json"
+)
+
+// feedCatalogCheckpoint 抓取断点，记录下一页游标和已抓取的作品
+type feedCatalogCheckpoint struct {
+	Username   string            `json:"username"`
+	Author     string            `json:"author"`
+	NextMarker string            `json:"next_marker"`
+	Pages      int               `json:"pages"`
+	Items      []FeedCatalogItem `json:"items"`
+	SavedAt    string            `json:"saved_at"`
+}
+
+// feedCatalogFile 最终输出的作品目录
+type feedCatalogFile struct {
+	Username   string            `json:"username"`
+	Author     string            `json:"author"`
+	Complete   bool              `json:"complete"`
+	Pages      int               `json:"pages"`
+	TotalCount int               `json:"total_count"`
+	Items      []FeedCatalogItem `json:"items"`
+	SavedAt    string            `json:"saved_at"`
+}
+
+// feedCatalogPersistence 目录文件固定在 feed_catalog/<username>/ 下，同一账号重复抓取时覆盖
+type feedCatalogPersistence struct {
+	jsonPath       string
+	csvPath        string
+	checkpointPath string
+	relativePath   string
+}
+
+func newFeedCatalogPersistence(downloadsDir, username string) (*feedCatalogPersistence, error) {
+	dirName := utils.CleanFilename(username)
+	if dirName == "" {
+		return nil, fmt.Errorf("invalid username: %q", username)
+	}
+	saveDir := filepath.Join(downloadsDir, "feed_catalog", dirName)
+	if err := utils.EnsureDir(saveDir); err != nil {
+		return nil, err
+	}
+
+	jsonPath := filepath.Join(saveDir, feedCatalogFileName+".json")
+	relativePath, _ := filepath.Rel(downloadsDir, jsonPath)
+	return &feedCatalogPersistence{
+		jsonPath:       jsonPath,
+		csvPath:        filepath.Join(saveDir, feedCatalogFileName+".csv"),
+		checkpointPath: filepath.Join(saveDir, feedCatalogFileName+feedCatalogCheckpointSuffix),
+		relativePath:   relativePath,
+	}, nil
+}
+
+// LoadCheckpoint 读取断点，不存在时返回 nil
+func (p *feedCatalogPersistence) LoadCheckpoint() (*feedCatalogCheckpoint, error) {
+	data, err := os.ReadFile(p.checkpointPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var checkpoint feedCatalogCheckpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint: %w", err)
+	}
+	return &checkpoint, nil
+}
+
+func (p *feedCatalogPersistence) SaveCheckpoint(state *feedCatalogCheckpoint) error {
+	state.SavedAt = time.Now().Format(time.RFC3339)
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFeedCatalogFile(p.checkpointPath, data)
+}
+
+func (p *feedCatalogPersistence) DiscardCheckpoint() error {
+	if err := os.Remove(p.checkpointPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Finalize 写入 JSON 和 CSV 目录；已抓完时删除断点，否则保留断点供下次继续
+func (p *feedCatalogPersistence) Finalize(state *feedCatalogCheckpoint, complete bool) error {
+	catalog := feedCatalogFile{
+		Username:   state.Username,
+		Author:     state.Author,
+		Complete:   complete,
+		Pages:      state.Pages,
+		TotalCount: len(state.Items),
+		Items:      state.Items,
+		SavedAt:    time.Now().Format(time.RFC3339),
+	}
+	if catalog.Items == nil {
+		catalog.Items = []FeedCatalogItem{}
+	}
+	data, err := json.MarshalIndent(catalog, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFeedCatalogFile(p.jsonPath, data); err != nil {
+		return err
+	}
+
+	csvData, err := buildFeedCatalogCSV(state.Items)
+	if err != nil {
+		return err
+	}
+	if err := writeFeedCatalogFile(p.csvPath, csvData); err != nil {
+		return err
+	}
+
+	if complete {
+		return p.DiscardCheckpoint()
+	}
+	return p.SaveCheckpoint(state)
+}
+
+func buildFeedCatalogCSV(items []FeedCatalogItem) ([]byte, error) {
+	var buf bytes.Buffer
+	// 写入 UTF-8 BOM 以兼容 Excel
+	buf.Write([]byte{0xEF, 0xBB, 0xBF})
+
+	writer := csv.NewWriter(&buf)
+	header := []string{
+		"VideoID", "NonceID", "Title", "Author", "PublishedAt", "Duration", "Size", "Resolution",
+		"LikeCount", "CommentCount", "ForwardCount", "FavCount", "VideoURL", "CoverURL", "DecryptKey",
+	}
+	if err := writer.Write(header); err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		row := []string{
+			item.VideoID,
+			item.NonceID,
+			item.Title,
+			item.Author,
+			item.PublishedAt,
+			strconv.FormatInt(item.Duration, 10),
+			strconv.FormatInt(item.FileSize, 10),
+			item.Resolution,
+			strconv.FormatInt(item.LikeCount, 10),
+			strconv.FormatInt(item.CommentCount, 10),
+			strconv.FormatInt(item.ForwardCount, 10),
+			strconv.FormatInt(item.FavCount, 10),
+			item.VideoURL,
+			item.CoverURL,
+			item.DecryptKey,
+		}
+		if err := writer.Write(row); err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeFeedCatalogFile 先写临时文件再替换，避免中断时留下不完整的文件
+func writeFeedCatalogFile(path string, data []byte) error {
+	tmpPath := utils.BuildTempDownloadPath(path, "feed-catalog")
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
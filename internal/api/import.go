@@ -0,0 +1,139 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"wx_channel/internal/config"
+	"wx_channel/internal/response"
+	"wx_channel/internal/services"
+)
+
+// maxImportFileSize 导入文件大小上限
+const maxImportFileSize = 64 << 20
+
+type ImportAPI struct {
+	service *services.ImportService
+	cfg     *config.Config
+}
+
+func NewImportAPI(cfg *config.Config) *ImportAPI {
+	return &ImportAPI{
+		service: services.NewImportService(),
+		cfg:     cfg,
+	}
+}
+
+// HandleImportBrowseHistory 导入浏览历史
+func (h *ImportAPI) HandleImportBrowseHistory(w http.ResponseWriter, r *http.Request) {
+	data, opts, ok := h.parseImportRequest(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.ImportBrowseHistory(data, opts)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response.Success(w, result)
+}
+
+// HandleImportDownloadRecords 导入下载记录
+func (h *ImportAPI) HandleImportDownloadRecords(w http.ResponseWriter, r *http.Request) {
+	data, opts, ok := h.parseImportRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// 本机下载目录，用于改写导出机器上的文件路径
+	if opts.SourceDir != "" {
+		cfg := h.cfg
+		if cfg == nil {
+			cfg = config.Get()
+		}
+		if cfg != nil {
+			if dir, err := cfg.GetResolvedDownloadsDir(); err == nil {
+				opts.TargetDir = dir
+			}
+		}
+	}
+
+	result, err := h.service.ImportDownloadRecords(data, opts)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	response.Success(w, result)
+}
+
+// parseImportRequest 读取导入文件和选项
+// 文件可以是 multipart 表单的 file 字段，也可以直接作为请求体；
+// 选项 format、strategy、dry_run、source_dir 从查询参数或表单字段读取
+func (h *ImportAPI) parseImportRequest(w http.ResponseWriter, r *http.Request) ([]byte, services.ImportOptions, bool) {
+	var opts services.ImportOptions
+	if r.Method != http.MethodPost {
+		response.ErrorWithStatus(w, http.StatusMethodNotAllowed, http.StatusMethodNotAllowed, "Method not allowed")
+		return nil, opts, false
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportFileSize)
+	var data []byte
+	var filename string
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(maxImportFileSize); err != nil {
+			response.Error(w, http.StatusBadRequest, fmt.Sprintf("invalid form: %v", err))
+			return nil, opts, false
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			response.Error(w, http.StatusBadRequest, "file is required")
+			return nil, opts, false
+		}
+		defer file.Close()
+		filename = header.Filename
+		if data, err = io.ReadAll(file); err != nil {
+			response.Error(w, http.StatusBadRequest, fmt.Sprintf("failed to read file: %v", err))
+			return nil, opts, false
+		}
+	} else {
+		var err error
+		if data, err = io.ReadAll(r.Body); err != nil {
+			response.Error(w, http.StatusBadRequest, fmt.Sprintf("failed to read body: %v", err))
+			return nil, opts, false
+		}
+	}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		response.Error(w, http.StatusBadRequest, "import data is empty")
+		return nil, opts, false
+	}
+
+	// 格式：参数 > 文件扩展名 > Content-Type > 内容
+	format := strings.ToLower(r.FormValue("format"))
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(filename)), ".")
+	}
+	if format == "" && strings.Contains(r.Header.Get("Content-Type"), "csv") {
+		format = string(services.ExportFormatCSV)
+	}
+	opts.Format = services.ExportFormat(format)
+	if opts.Format != "" && opts.Format != services.ExportFormatJSON && opts.Format != services.ExportFormatCSV {
+		response.Error(w, http.StatusBadRequest, fmt.Sprintf("unsupported import format: %s", format))
+		return nil, opts, false
+	}
+
+	strategy, err := services.ParseImportStrategy(r.FormValue("strategy"))
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return nil, opts, false
+	}
+	opts.Strategy = strategy
+	opts.DryRun, _ = strconv.ParseBool(r.FormValue("dry_run"))
+	opts.SourceDir = strings.TrimSpace(r.FormValue("source_dir"))
+
+	return data, opts, true
+}
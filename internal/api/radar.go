@@ -1,34 +1,40 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
+	"time"
 
 	"wx_channel/internal/database"
 	"wx_channel/internal/response"
+	"wx_channel/internal/services"
 )
 
 // RadarServiceAPI 处理雷达监控相关的 API
 type RadarServiceAPI struct {
-	repo *database.RadarRepository
+	repo    *database.RadarRepository
+	targets *services.RadarTargetService
 }
 
 // NewRadarServiceAPI 创建雷达服务 API 处理器
 func NewRadarServiceAPI() *RadarServiceAPI {
 	return &RadarServiceAPI{
-		repo: database.NewRadarRepository(),
+		repo:    database.NewRadarRepository(),
+		targets: services.NewRadarTargetService(),
 	}
 }
 
 // GetTargets 获取所有监控目标
 func (h *RadarServiceAPI) GetTargets(w http.ResponseWriter, r *http.Request) {
-	targets, err := h.repo.GetAll()
+	targets, err := h.targets.List()
 	if err != nil {
 		response.Error(w, http.StatusInternalServerError, "获取监控目标失败")
 		return
 	}
-	response.Success(w, targets)
+	response.Success(w, database.RedactRadarTargets(targets))
 }
 
 // AddTarget 添加监控目标
@@ -39,33 +45,12 @@ func (h *RadarServiceAPI) AddTarget(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	target.Username = strings.TrimSpace(target.Username)
-	target.AuthorName = strings.TrimSpace(target.AuthorName)
-
-	if target.Username == "" || target.AuthorName == "" {
-		response.Error(w, http.StatusBadRequest, "账号ID和账号名称不能为空")
-		return
-	}
-
-	if target.IntervalMinutes < 5 {
-		target.IntervalMinutes = 5 // 最少5分钟
-	}
-
-	if target.Status == "" {
-		target.Status = database.RadarStatusActive
-	}
-
-	if err := h.repo.Add(&target); err != nil {
-		// 判断是否是唯一键冲突
-		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
-			response.Error(w, http.StatusConflict, "该账号已在监控列表中")
-			return
-		}
-		response.Error(w, http.StatusInternalServerError, "添加监控目标失败")
+	if err := h.targets.Add(&target); err != nil {
+		writeRadarTargetError(w, err, "添加监控目标失败")
 		return
 	}
 
-	response.Success(w, target)
+	response.Success(w, target.Redacted())
 }
 
 // UpdateTarget 更新监控目标
@@ -80,27 +65,31 @@ func (h *RadarServiceAPI) UpdateTarget(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	target.ID = id
-	if target.IntervalMinutes < 5 {
-		target.IntervalMinutes = 5
-	}
-
-	// 保留之前的检测时间
-	existing, err := h.repo.GetByID(id)
-	if err == nil && existing != nil {
-		target.LastCheckTime = existing.LastCheckTime
-	}
-
-	if err := h.repo.Update(&target); err != nil {
-		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+	if err := h.targets.Update(id, &target); err != nil {
+		if errors.Is(err, services.ErrRadarTargetExists) {
 			response.Error(w, http.StatusConflict, "该账号已被其他记录占用")
 			return
 		}
-		response.Error(w, http.StatusInternalServerError, "更新监控目标失败")
+		writeRadarTargetError(w, err, "更新监控目标失败")
 		return
 	}
 
-	response.Success(w, target)
+	response.Success(w, target.Redacted())
+}
+
+// writeRadarTargetError 将监控目标服务的错误转换为 HTTP 响应
+func writeRadarTargetError(w http.ResponseWriter, err error, fallback string) {
+	var invalid *services.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		response.Error(w, http.StatusBadRequest, invalid.Error())
+	case errors.Is(err, services.ErrRadarTargetExists):
+		response.Error(w, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrRadarTargetNotFound):
+		response.Error(w, http.StatusNotFound, err.Error())
+	default:
+		response.Error(w, http.StatusInternalServerError, fallback)
+	}
 }
 
 // UpdateTargetStatus 更新监控状态 (暂停/恢复)
@@ -122,13 +111,70 @@ func (h *RadarServiceAPI) UpdateTargetStatus(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	if req.Status != database.RadarStatusActive && req.Status != database.RadarStatusPaused {
-		response.Error(w, http.StatusBadRequest, "无效的状态值")
+	if err := h.targets.SetStatus(id, req.Status); err != nil {
+		writeRadarTargetError(w, err, "更新状态失败")
 		return
 	}
 
-	if err := h.repo.UpdateStatus(id, req.Status); err != nil {
-		response.Error(w, http.StatusInternalServerError, "更新状态失败")
+	response.Success(w, nil)
+}
+
+// StartBackfill 从最新一页重新开始回填历史视频
+func (h *RadarServiceAPI) StartBackfill(w http.ResponseWriter, r *http.Request) {
+	// /api/v1/radar/targets/{id}/backfill
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 2 {
+		response.Error(w, http.StatusBadRequest, "无效的请求路径")
+		return
+	}
+	id := pathParts[len(pathParts)-2]
+
+	target, err := h.repo.GetByID(id)
+	if err != nil || target == nil {
+		response.Error(w, http.StatusNotFound, "监控目标不存在")
+		return
+	}
+
+	if err := h.repo.UpdateBackfill(id, true, ""); err != nil {
+		response.Error(w, http.StatusInternalServerError, "开启回填失败")
+		return
+	}
+
+	response.Success(w, nil)
+}
+
+// TestNotify 向监控目标配置的所有通知渠道发送一条测试通知
+func (h *RadarServiceAPI) TestNotify(w http.ResponseWriter, r *http.Request) {
+	// /api/v1/radar/targets/{id}/notify/test
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) < 3 {
+		response.Error(w, http.StatusBadRequest, "无效的请求路径")
+		return
+	}
+	id := pathParts[len(pathParts)-3]
+
+	target, err := h.repo.GetByID(id)
+	if err != nil || target == nil {
+		response.Error(w, http.StatusNotFound, "监控目标不存在")
+		return
+	}
+	if len(target.Notify.Channels) == 0 {
+		response.Error(w, http.StatusBadRequest, "未配置通知渠道")
+		return
+	}
+
+	event := services.NotificationEvent{
+		Type:       database.NotifyEventNewVideo,
+		Time:       time.Now(),
+		TargetID:   target.ID,
+		AuthorName: target.AuthorName,
+		Username:   target.Username,
+		Videos:     []database.RadarVideoSummary{{VideoID: "test", Title: "测试通知", IsNew: true}},
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+	if err := services.GetNotificationService().Deliver(ctx, target.Notify, event); err != nil {
+		response.Error(w, http.StatusBadGateway, "发送测试通知失败: "+err.Error())
 		return
 	}
 
@@ -141,7 +187,7 @@ func (h *RadarServiceAPI) DeleteTarget(w http.ResponseWriter, r *http.Request) {
 	pathParts := strings.Split(r.URL.Path, "/")
 	id := pathParts[len(pathParts)-1]
 
-	if err := h.repo.Delete(id); err != nil {
+	if err := h.targets.Delete(id); err != nil {
 		response.Error(w, http.StatusInternalServerError, "删除监控目标失败")
 		return
 	}
@@ -159,16 +205,12 @@ func (h *RadarServiceAPI) GetRadarLogs(w http.ResponseWriter, r *http.Request) {
 	}
 	id := pathParts[len(pathParts)-2]
 
-	logs, err := h.repo.GetLogsByTargetID(id, 50)
+	logs, err := h.targets.Logs(id, 50)
 	if err != nil {
 		response.Error(w, http.StatusInternalServerError, "获取日志失败")
 		return
 	}
 
-	if logs == nil {
-		logs = []database.RadarLog{}
-	}
-
 	response.Success(w, logs)
 }
 
@@ -186,7 +228,7 @@ func (h *RadarServiceAPI) RegisterRoutes(mux *http.ServeMux) {
 	})
 
 	mux.HandleFunc("/api/v1/radar/targets/", func(w http.ResponseWriter, r *http.Request) {
-		// 处理 /api/v1/radar/targets/{id} 和 /api/v1/radar/targets/{id}/status 和 /logs
+		// 处理 /api/v1/radar/targets/{id} 和 /api/v1/radar/targets/{id}/status、/logs、/backfill、/notify/test
 		path := r.URL.Path
 		if strings.HasSuffix(path, "/status") && r.Method == http.MethodPut {
 			h.UpdateTargetStatus(w, r)
@@ -196,6 +238,14 @@ func (h *RadarServiceAPI) RegisterRoutes(mux *http.ServeMux) {
 			h.GetRadarLogs(w, r)
 			return
 		}
+		if strings.HasSuffix(path, "/backfill") && r.Method == http.MethodPost {
+			h.StartBackfill(w, r)
+			return
+		}
+		if strings.HasSuffix(path, "/notify/test") && r.Method == http.MethodPost {
+			h.TestNotify(w, r)
+			return
+		}
 
 		switch r.Method {
 		case http.MethodPut:
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"wx_channel/internal/database"
+	"wx_channel/internal/response"
+	"wx_channel/internal/services"
+	"wx_channel/internal/websocket"
+)
+
+// SavedSearchAPI 处理保存的关键词搜索相关的 API
+type SavedSearchAPI struct {
+	searches *services.SavedSearchService
+}
+
+// NewSavedSearchAPI 创建保存的搜索 API 处理器
+func NewSavedSearchAPI(hub *websocket.Hub) *SavedSearchAPI {
+	return &SavedSearchAPI{searches: services.NewSavedSearchService(hub)}
+}
+
+// writeSavedSearchError 将保存的搜索服务的错误转换为 HTTP 响应
+func writeSavedSearchError(w http.ResponseWriter, err error, fallback string) {
+	var invalid *services.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		response.Error(w, http.StatusBadRequest, invalid.Error())
+	case errors.Is(err, services.ErrSavedSearchExists):
+		response.Error(w, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrSavedSearchNotFound):
+		response.Error(w, http.StatusNotFound, err.Error())
+	default:
+		response.Error(w, http.StatusInternalServerError, fallback)
+	}
+}
+
+// ListSearches 获取所有保存的搜索
+func (h *SavedSearchAPI) ListSearches(w http.ResponseWriter, r *http.Request) {
+	searches, err := h.searches.List()
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, "获取保存的搜索失败")
+		return
+	}
+	response.Success(w, searches)
+}
+
+// CreateSearch 添加保存的搜索，启用时由调度器立即执行第一次
+func (h *SavedSearchAPI) CreateSearch(w http.ResponseWriter, r *http.Request) {
+	var search database.SavedSearch
+	if err := json.NewDecoder(r.Body).Decode(&search); err != nil {
+		response.Error(w, http.StatusBadRequest, "请求参数解析失败")
+		return
+	}
+	if err := h.searches.Create(&search); err != nil {
+		writeSavedSearchError(w, err, "添加保存的搜索失败")
+		return
+	}
+	response.Success(w, search)
+}
+
+// GetSearch 获取单个保存的搜索
+func (h *SavedSearchAPI) GetSearch(w http.ResponseWriter, r *http.Request, id string) {
+	search, err := h.searches.Get(id)
+	if err != nil {
+		writeSavedSearchError(w, err, "获取保存的搜索失败")
+		return
+	}
+	response.Success(w, search)
+}
+
+// UpdateSearch 更新搜索条件和调度设置
+func (h *SavedSearchAPI) UpdateSearch(w http.ResponseWriter, r *http.Request, id string) {
+	var search database.SavedSearch
+	if err := json.NewDecoder(r.Body).Decode(&search); err != nil {
+		response.Error(w, http.StatusBadRequest, "请求参数解析失败")
+		return
+	}
+	if err := h.searches.Update(id, &search); err != nil {
+		writeSavedSearchError(w, err, "更新保存的搜索失败")
+		return
+	}
+	response.Success(w, search)
+}
+
+// DeleteSearch 删除保存的搜索及其结果
+func (h *SavedSearchAPI) DeleteSearch(w http.ResponseWriter, r *http.Request, id string) {
+	if err := h.searches.Delete(id); err != nil {
+		response.Error(w, http.StatusInternalServerError, "删除保存的搜索失败")
+		return
+	}
+	response.Success(w, nil)
+}
+
+// RunSearch 安排搜索尽快执行（调度器每分钟检查一次）
+func (h *SavedSearchAPI) RunSearch(w http.ResponseWriter, r *http.Request, id string) {
+	search, err := h.searches.RunNow(id)
+	if err != nil {
+		writeSavedSearchError(w, err, "安排搜索执行失败")
+		return
+	}
+	response.SuccessWithStatus(w, http.StatusAccepted, search)
+}
+
+// GetResults 分页获取搜索结果，new_only=true 时只返回最近一次执行新发现的结果
+func (h *SavedSearchAPI) GetResults(w http.ResponseWriter, r *http.Request, id string) {
+	query := r.URL.Query()
+	page, _ := strconv.Atoi(query.Get("page"))
+	pageSize, _ := strconv.Atoi(query.Get("page_size"))
+	newOnly, _ := strconv.ParseBool(query.Get("new_only"))
+
+	results, err := h.searches.Results(id, newOnly, page, pageSize)
+	if err != nil {
+		writeSavedSearchError(w, err, "获取搜索结果失败")
+		return
+	}
+	response.Success(w, results)
+}
+
+// RegisterRoutes 注册保存的搜索相关的 API 路由
+func (h *SavedSearchAPI) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/api/v1/search/saved", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			h.ListSearches(w, r)
+		case http.MethodPost:
+			h.CreateSearch(w, r)
+		default:
+			response.Error(w, http.StatusMethodNotAllowed, "不允许的请求方法")
+		}
+	})
+
+	mux.HandleFunc("/api/v1/search/saved/", func(w http.ResponseWriter, r *http.Request) {
+		// 处理 /api/v1/search/saved/{id}、/{id}/run 和 /{id}/results
+		parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/search/saved/"), "/"), "/")
+		id := parts[0]
+		if id == "" || len(parts) > 2 {
+			response.Error(w, http.StatusBadRequest, "无效的请求路径")
+			return
+		}
+
+		action := ""
+		if len(parts) == 2 {
+			action = parts[1]
+		}
+		switch {
+		case action == "run" && r.Method == http.MethodPost:
+			h.RunSearch(w, r, id)
+		case action == "results" && r.Method == http.MethodGet:
+			h.GetResults(w, r, id)
+		case action == "" && r.Method == http.MethodGet:
+			h.GetSearch(w, r, id)
+		case action == "" && r.Method == http.MethodPut:
+			h.UpdateSearch(w, r, id)
+		case action == "" && r.Method == http.MethodDelete:
+			h.DeleteSearch(w, r, id)
+		default:
+			response.Error(w, http.StatusMethodNotAllowed, "不允许的请求方法")
+		}
+	})
+}
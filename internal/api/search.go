@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -26,6 +27,9 @@ type SearchService struct {
 	runtimeDiagnostics  *RuntimeDiagnostics
 	commentJobsMu       sync.RWMutex
 	commentJobs         *CommentExportJobManager
+	feedCatalogJobsMu   sync.RWMutex
+	feedCatalogJobs     *FeedCatalogJobManager
+	commentStore        commentStore
 }
 
 // NewSearchService 创建搜索服务
@@ -388,20 +392,33 @@ type ExportFeedCommentsRequest struct {
 	NonceID  string `json:"nonce_id"`
 	Title    string `json:"title"`
 	Author   string `json:"author"`
+	// Formats 额外导出的平铺表格格式（csv、tsv），JSON 始终会保存
+	Formats []string `json:"formats,omitempty"`
+	// SaveToDB 为 true 时同时导入 comments 表，供控制台按视频查询
+	SaveToDB bool `json:"save_to_db,omitempty"`
+	// Incremental 为 true 时以上次导出为基准只抓取新评论，并记录两次导出的差异
+	Incremental bool `json:"incremental,omitempty"`
+
+	// resumeFrom 重启后恢复任务时使用的断点文件
+	resumeFrom string
 }
 
 // ExportFeedCommentsResult 评论导出结果
 type ExportFeedCommentsResult struct {
-	ObjectID      string `json:"object_id"`
-	TopLevelCount int    `json:"top_level_count"`
-	ReplyCount    int    `json:"reply_count"`
-	TotalCount    int    `json:"total_count"`
-	ReportedCount int    `json:"reported_count"`
-	SavedPath     string `json:"saved_path"`
-	RelativePath  string `json:"relative_path"`
-	Title         string `json:"title"`
-	Author        string `json:"author"`
-	Source        string `json:"source"`
+	ObjectID      string                    `json:"object_id"`
+	TopLevelCount int                       `json:"top_level_count"`
+	ReplyCount    int                       `json:"reply_count"`
+	TotalCount    int                       `json:"total_count"`
+	ReportedCount int                       `json:"reported_count"`
+	SavedPath     string                    `json:"saved_path"`
+	RelativePath  string                    `json:"relative_path"`
+	Title         string                    `json:"title"`
+	Author        string                    `json:"author"`
+	Source        string                    `json:"source"`
+	Files         map[string]string         `json:"files,omitempty"`
+	ImportedCount int                       `json:"imported_count,omitempty"`
+	Incremental   bool                      `json:"incremental,omitempty"`
+	Diff          *CommentExportDiffSummary `json:"diff,omitempty"`
 }
 
 type feedCommentAPIResponse struct {
@@ -431,6 +448,7 @@ type commentExportFile struct {
 	OriginalCommentCount int                        `json:"originalCommentCount"`
 	SavedAt              string                     `json:"savedAt"`
 	Source               string                     `json:"source"`
+	CompletedReplies     []string                   `json:"completedReplies,omitempty"`
 }
 
 type formattedCommentEntry struct {
@@ -610,6 +628,12 @@ func (s *SearchService) ExportFeedComments(w http.ResponseWriter, r *http.Reques
 		response.Error(w, http.StatusBadRequest, "nonce_id is required")
 		return
 	}
+	formats, err := normalizeCommentExportFormats(req.Formats)
+	if err != nil {
+		response.Error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	req.Formats = formats
 
 	// Production services use a bounded background task so the browser does not
 	// have to keep a single HTTP request open for every comment/reply page.
@@ -649,14 +673,7 @@ func (s *SearchService) CommentExportStatus(w http.ResponseWriter, r *http.Reque
 		response.Error(w, http.StatusBadRequest, "job_id is required")
 		return
 	}
-	s.commentJobsMu.RLock()
-	manager := s.commentJobs
-	s.commentJobsMu.RUnlock()
-	if manager == nil {
-		response.ErrorWithStatus(w, http.StatusNotFound, http.StatusNotFound, "comment export job not found")
-		return
-	}
-	job, ok := manager.Get(jobID)
+	job, ok := s.ensureCommentExportJobs().Get(jobID)
 	if !ok {
 		response.ErrorWithStatus(w, http.StatusNotFound, http.StatusNotFound, "comment export job not found")
 		return
@@ -664,33 +681,135 @@ func (s *SearchService) CommentExportStatus(w http.ResponseWriter, r *http.Reque
 	response.Success(w, job)
 }
 
+// ListCommentExportJobs returns recent comment export jobs, newest first.
+func (s *SearchService) ListCommentExportJobs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		response.ErrorWithStatus(w, http.StatusMethodNotAllowed, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 200 {
+		limit = 200
+	}
+	response.Success(w, s.ensureCommentExportJobs().List(limit))
+}
+
+// CancelCommentExport cancels a queued or running comment export.
+func (s *SearchService) CancelCommentExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		response.ErrorWithStatus(w, http.StatusMethodNotAllowed, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	jobID := strings.TrimSpace(r.URL.Query().Get("job_id"))
+	if jobID == "" {
+		var req struct {
+			JobID string `json:"job_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err == nil {
+			jobID = strings.TrimSpace(req.JobID)
+		}
+	}
+	if jobID == "" {
+		response.Error(w, http.StatusBadRequest, "job_id is required")
+		return
+	}
+
+	job, err := s.ensureCommentExportJobs().Cancel(jobID)
+	switch {
+	case errors.Is(err, ErrCommentExportJobNotFound):
+		response.ErrorWithStatus(w, http.StatusNotFound, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrCommentExportJobFinished):
+		response.ErrorWithStatus(w, http.StatusConflict, http.StatusConflict, err.Error())
+	case err != nil:
+		response.Error(w, http.StatusInternalServerError, err.Error())
+	default:
+		response.Success(w, job)
+	}
+}
+
+// ResumeCommentExportJobs re-queues exports interrupted by the last shutdown.
+func (s *SearchService) ResumeCommentExportJobs() int {
+	return s.ensureCommentExportJobs().Resume()
+}
+
 func (s *SearchService) exportFeedComments(req ExportFeedCommentsRequest) (*ExportFeedCommentsResult, error) {
 	return s.exportFeedCommentsContext(context.Background(), req, nil)
 }
 
 func (s *SearchService) exportFeedCommentsContext(ctx context.Context, req ExportFeedCommentsRequest, onProgress func(CommentExportProgress)) (*ExportFeedCommentsResult, error) {
-	downloadsDir, err := s.resolveDownloadsDir()
+	formats, err := normalizeCommentExportFormats(req.Formats)
 	if err != nil {
 		return nil, err
 	}
 
-	persistence, err := newCommentExportPersistence(downloadsDir, req)
+	downloadsDir, err := s.resolveDownloadsDir()
 	if err != nil {
 		return nil, err
 	}
 
-	topLevelComments, reportedCount, err := s.fetchCommentPagesContext(ctx, req.ObjectID, req.NonceID, "")
-	if err != nil {
-		return nil, err
+	// 从断点恢复时沿用原导出文件，并跳过已抓取的顶级评论和回复
+	var persistence *commentExportPersistence
+	var resumed *commentExportSnapshot
+	if req.resumeFrom != "" {
+		if snapshot, err := readCommentExportSnapshot(req.resumeFrom); err == nil && snapshot.ObjectID == req.ObjectID {
+			resumed = snapshot
+			persistence = resumeCommentExportPersistence(downloadsDir, req.resumeFrom)
+		}
+	}
+	if persistence == nil {
+		persistence, err = newCommentExportPersistence(downloadsDir, req)
+		if err != nil {
+			return nil, err
+		}
+	}
+	checkpointPath := persistence.checkpointPath()
+
+	// 增量模式以上次导出为基准，遇到已知评论时停止翻页
+	var previous *previousCommentExport
+	var previousRows []commentTableRow
+	if req.Incremental {
+		previous, err = s.loadPreviousCommentExport(downloadsDir, req.ObjectID)
+		if err != nil {
+			return nil, err
+		}
+		if previous != nil {
+			previousRows = flattenCommentEntries(req.ObjectID, formatCommentsForExport(previous.comments))
+		}
+	}
+
+	var topLevelComments []map[string]interface{}
+	var reportedCount int
+	completed := make(map[string]bool)
+	var completedReplies []string
+	if resumed != nil {
+		topLevelComments = resumed.CommentInfo
+		reportedCount = resumed.OriginalCommentCount
+		for _, commentID := range resumed.CompletedReplies {
+			completed[commentID] = true
+		}
+		completedReplies = append(completedReplies, resumed.CompletedReplies...)
+	} else {
+		fetched, count, reachedKnown, err := s.fetchCommentPagesUntilContext(ctx, req.ObjectID, req.NonceID, "", previous.knownIDs())
+		if err != nil {
+			return nil, err
+		}
+		topLevelComments, reportedCount = fetched, count
+		if previous != nil {
+			topLevelComments = previous.mergeTopLevel(topLevelComments, reachedKnown)
+		}
 	}
 	if onProgress != nil {
 		onProgress(CommentExportProgress{
-			Stage:         "top_level_comments",
-			TopLevelCount: len(topLevelComments),
-			ReportedCount: reportedCount,
+			Stage:          "top_level_comments",
+			TopLevelCount:  len(topLevelComments),
+			ReportedCount:  reportedCount,
+			CheckpointPath: checkpointPath,
 		})
 	}
-	if err := persistence.SaveCheckpoint(req, topLevelComments, reportedCount, 0, "finderGetCommentList.partial"); err != nil {
+	if err := persistence.SaveCheckpoint(req, topLevelComments, reportedCount, 0, "finderGetCommentList.partial", completedReplies); err != nil {
 		return nil, err
 	}
 
@@ -700,6 +819,19 @@ func (s *SearchService) exportFeedCommentsContext(ctx context.Context, req Expor
 		if commentID == "" || !commentHasReplies(comment) {
 			continue
 		}
+		if completed[commentID] {
+			replies := extractReplyMaps(comment["levelTwoComment"])
+			comment["levelTwoComment"] = replies
+			replyCount += len(replies)
+			continue
+		}
+		if previous != nil {
+			if replies, ok := previous.reusableReplies(comment); ok {
+				comment["levelTwoComment"] = replies
+				replyCount += len(replies)
+				continue
+			}
+		}
 
 		replies, _, err := s.fetchCommentPagesContext(ctx, req.ObjectID, "", commentID)
 		if err != nil {
@@ -707,6 +839,7 @@ func (s *SearchService) exportFeedCommentsContext(ctx context.Context, req Expor
 		}
 		comment["levelTwoComment"] = replies
 		replyCount += len(replies)
+		completedReplies = append(completedReplies, commentID)
 		if onProgress != nil {
 			onProgress(CommentExportProgress{
 				Stage:            "replies",
@@ -715,10 +848,11 @@ func (s *SearchService) exportFeedCommentsContext(ctx context.Context, req Expor
 				ReportedCount:    reportedCount,
 				CompletedReplies: replyCount,
 				TotalReplies:     countReplyTargets(topLevelComments),
+				CheckpointPath:   checkpointPath,
 			})
 		}
 
-		if err := persistence.SaveCheckpoint(req, topLevelComments, reportedCount, replyCount, "finderGetCommentList.partial"); err != nil {
+		if err := persistence.SaveCheckpoint(req, topLevelComments, reportedCount, replyCount, "finderGetCommentList.partial", completedReplies); err != nil {
 			return nil, err
 		}
 	}
@@ -739,6 +873,50 @@ func (s *SearchService) exportFeedCommentsContext(ctx context.Context, req Expor
 		Title:         req.Title,
 		Author:        req.Author,
 		Source:        "finderGetCommentList",
+		Incremental:   previous != nil,
+	}
+
+	if len(formats) > 0 || req.SaveToDB || previous != nil {
+		rows := flattenCommentEntries(req.ObjectID, formatCommentsForExport(topLevelComments))
+		var deletedIDs []string
+		if previous != nil {
+			diff := buildCommentExportDiff(req.ObjectID, previous.source, previousRows, rows)
+			diffPath, err := writeCommentExportDiff(savedPath, diff)
+			if err != nil {
+				return nil, err
+			}
+			for _, deleted := range diff.Deleted {
+				deletedIDs = append(deletedIDs, deleted.CommentID)
+			}
+			result.Diff = &CommentExportDiffSummary{
+				PreviousSource:   previous.source,
+				NewCount:         len(diff.New),
+				DeletedCount:     len(diff.Deleted),
+				LikeChangedCount: len(diff.LikeChanges),
+				DiffPath:         diffPath,
+			}
+		}
+		if len(formats) > 0 {
+			files, err := writeCommentTables(savedPath, rows, formats)
+			if err != nil {
+				return nil, err
+			}
+			result.Files = files
+		}
+		if req.SaveToDB {
+			store, err := s.resolveCommentStore()
+			if err != nil {
+				return nil, fmt.Errorf("failed to import comments: %w", err)
+			}
+			imported, err := store.UpsertMany(commentRowsToRecords(rows, req.Title, req.Author, time.Now()))
+			if err != nil {
+				return nil, fmt.Errorf("failed to import comments: %w", err)
+			}
+			if _, err := store.DeleteByCommentIDs(req.ObjectID, deletedIDs); err != nil {
+				return nil, fmt.Errorf("failed to remove deleted comments: %w", err)
+			}
+			result.ImportedCount = imported
+		}
 	}
 
 	utils.LogComment(req.ObjectID, req.Title, result.TotalCount, true)
@@ -750,6 +928,12 @@ func (s *SearchService) fetchCommentPages(objectID, nonceID, commentID string) (
 }
 
 func (s *SearchService) fetchCommentPagesContext(ctx context.Context, objectID, nonceID, commentID string) ([]map[string]interface{}, int, error) {
+	items, reportedCount, _, err := s.fetchCommentPagesUntilContext(ctx, objectID, nonceID, commentID, nil)
+	return items, reportedCount, err
+}
+
+// fetchCommentPagesUntilContext 翻页获取评论，抓到包含 known 中评论的页面后停止，第三个返回值表示是否因此提前停止
+func (s *SearchService) fetchCommentPagesUntilContext(ctx context.Context, objectID, nonceID, commentID string, known map[string]struct{}) ([]map[string]interface{}, int, bool, error) {
 	items := make([]map[string]interface{}, 0, 32)
 	seen := make(map[string]struct{})
 	nextMarker := ""
@@ -761,7 +945,7 @@ func (s *SearchService) fetchCommentPagesContext(ctx context.Context, objectID,
 	for {
 		pageCount++
 		if pageCount > maxCommentPages {
-			return nil, 0, fmt.Errorf("comment pagination exceeded %d pages", maxCommentPages)
+			return nil, 0, false, fmt.Errorf("comment pagination exceeded %d pages", maxCommentPages)
 		}
 		body := websocket.FeedCommentListBody{
 			ObjectID:   objectID,
@@ -772,18 +956,18 @@ func (s *SearchService) fetchCommentPagesContext(ctx context.Context, objectID,
 
 		raw, err := s.callAPIWithContext(ctx, "key:channels:fetch_feed_comment_list", body, 60*time.Second)
 		if err != nil {
-			return nil, 0, err
+			return nil, 0, false, err
 		}
 
 		var resp feedCommentAPIResponse
 		if err := json.Unmarshal(raw, &resp); err != nil {
-			return nil, 0, fmt.Errorf("parse feed comment list failed: %w", err)
+			return nil, 0, false, fmt.Errorf("parse feed comment list failed: %w", err)
 		}
 		if resp.ErrCode != 0 {
 			if resp.ErrMsg == "" {
 				resp.ErrMsg = "unknown error"
 			}
-			return nil, 0, fmt.Errorf("feed comment list failed: %s", resp.ErrMsg)
+			return nil, 0, false, fmt.Errorf("feed comment list failed: %s", resp.ErrMsg)
 		}
 
 		if resp.Data.CountInfo.CommentCount > 0 {
@@ -791,11 +975,15 @@ func (s *SearchService) fetchCommentPagesContext(ctx context.Context, objectID,
 		}
 
 		pageNewCount := 0
+		reachedKnown := false
 		for _, item := range resp.Data.CommentInfo {
 			commentKey := stringValue(item["commentId"])
 			if commentKey == "" {
 				commentKey = fmt.Sprintf("idx-%d", len(items))
 			}
+			if _, ok := known[commentKey]; ok {
+				reachedKnown = true
+			}
 			if _, exists := seen[commentKey]; exists {
 				continue
 			}
@@ -805,18 +993,21 @@ func (s *SearchService) fetchCommentPagesContext(ctx context.Context, objectID,
 			}
 			items = append(items, item)
 			if len(items) > maxCommentsPerBranch {
-				return nil, 0, fmt.Errorf("comment export exceeded %d comments in one branch", maxCommentsPerBranch)
+				return nil, 0, false, fmt.Errorf("comment export exceeded %d comments in one branch", maxCommentsPerBranch)
 			}
 			pageNewCount++
 		}
 
+		if reachedKnown {
+			return items, reportedCount, true, nil
+		}
 		if resp.Data.LastBuffer == "" || pageNewCount == 0 {
 			break
 		}
 		nextMarker = resp.Data.LastBuffer
 	}
 
-	return items, reportedCount, nil
+	return items, reportedCount, false, nil
 }
 
 func countReplyTargets(comments []map[string]interface{}) int {
@@ -1012,6 +1203,10 @@ func (s *SearchService) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/v1/search/feed/comments", s.GetFeedCommentList)
 	mux.HandleFunc("/api/v1/search/feed/comments/export", s.ExportFeedComments)
 	mux.HandleFunc("/api/v1/search/feed/comments/export/status", s.CommentExportStatus)
+	mux.HandleFunc("/api/v1/search/feed/comments/export/jobs", s.ListCommentExportJobs)
+	mux.HandleFunc("/api/v1/search/feed/comments/export/cancel", s.CancelCommentExport)
+	mux.HandleFunc("/api/v1/search/feed/catalog", s.CrawlFeedCatalog)
+	mux.HandleFunc("/api/v1/search/feed/catalog/status", s.FeedCatalogStatus)
 	mux.HandleFunc("/api/v1/status", s.GetStatus)
 
 	// 兼容旧路由
@@ -1024,6 +1219,10 @@ func (s *SearchService) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/search/feed/comments", s.GetFeedCommentList)
 	mux.HandleFunc("/api/search/feed/comments/export", s.ExportFeedComments)
 	mux.HandleFunc("/api/search/feed/comments/export/status", s.CommentExportStatus)
+	mux.HandleFunc("/api/search/feed/comments/export/jobs", s.ListCommentExportJobs)
+	mux.HandleFunc("/api/search/feed/comments/export/cancel", s.CancelCommentExport)
+	mux.HandleFunc("/api/search/feed/catalog", s.CrawlFeedCatalog)
+	mux.HandleFunc("/api/search/feed/catalog/status", s.FeedCatalogStatus)
 	mux.HandleFunc("/api/status", s.GetStatus)
 
 	// 兼容 /api/channels 路由 (WebSocket服务器原有的路由)
@@ -1036,5 +1235,9 @@ func (s *SearchService) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/channels/feed/comment/list", s.GetFeedCommentList)
 	mux.HandleFunc("/api/channels/feed/comment/export", s.ExportFeedComments)
 	mux.HandleFunc("/api/channels/feed/comment/export/status", s.CommentExportStatus)
+	mux.HandleFunc("/api/channels/feed/comment/export/jobs", s.ListCommentExportJobs)
+	mux.HandleFunc("/api/channels/feed/comment/export/cancel", s.CancelCommentExport)
+	mux.HandleFunc("/api/channels/feed/catalog", s.CrawlFeedCatalog)
+	mux.HandleFunc("/api/channels/feed/catalog/status", s.FeedCatalogStatus)
 	mux.HandleFunc("/api/channels/status", s.GetStatus)
 }
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"wx_channel/internal/response"
@@ -218,6 +219,9 @@ func int64Value(v interface{}) int64 {
 	case json.Number:
 		n, _ := value.Int64()
 		return n
+	case string:
+		n, _ := strconv.ParseInt(value, 10, 64)
+		return n
 	default:
 		return 0
 	}
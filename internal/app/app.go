@@ -60,8 +60,10 @@ type App struct {
 	// 服务
 	WSHub              *websocket.Hub
 	SearchService      *api.SearchService
-	RadarService       *services.RadarService  // 自动轮询雷达
-	GopeedService      *services.GopeedService // Add GopeedService
+	RadarService       *services.RadarService       // 自动轮询雷达
+	QueueWorker        *services.QueueWorker        // 下载队列调度
+	SavedSearchService *services.SavedSearchService // 保存的关键词搜索调度
+	GopeedService      *services.GopeedService      // Add GopeedService
 	CloudConnector     *cloud.Connector
 	RuntimeDiagnostics *api.RuntimeDiagnostics
 
@@ -144,6 +146,11 @@ func (app *App) Run() {
 		sig := <-signalChan
 		color.Red("\n正在关闭服务...%v\n\n", sig)
 		utils.LogSystemShutdown(fmt.Sprintf("收到信号: %v", sig))
+		// 队列调度需要在关闭数据库前保存进度
+		if app.QueueWorker != nil {
+			services.GetRetentionService().Stop()
+			app.QueueWorker.Stop()
+		}
 		database.Close()
 		if os_env == "darwin" {
 			proxy.DisableProxyInMacOS(proxy.ProxySettings{
@@ -195,6 +202,10 @@ func (app *App) Run() {
 	queueService := services.NewQueueService()
 	radarRepo := database.NewRadarRepository()
 	app.RadarService = services.NewRadarService(radarRepo, queueService, app.WSHub)
+	if database.GetDB() != nil {
+		app.QueueWorker = services.NewQueueWorker(queueService)
+		app.SavedSearchService = services.NewSavedSearchService(app.WSHub)
+	}
 	app.ConsoleAPIHandler = handlers.NewConsoleAPIHandler(app.Cfg, app.WSHub, app.RadarService)
 
 	// 初始化新的 API 路由器
@@ -236,10 +247,13 @@ func (app *App) Run() {
 	app.requestInterceptors = []router.Interceptor{
 		app.StaticFileHandler,
 		app.APIRouter,
-		app.APIHandler,
-		app.UploadHandler,
-		app.RecordHandler,
-		app.BatchHandler,
+		// /__wx_channels_api/ 的授权、来源、限流等策略统一在中间件中处理
+		router.NewLocalAPIInterceptor(app.Cfg,
+			app.APIHandler,
+			app.UploadHandler,
+			app.RecordHandler,
+			app.BatchHandler,
+		),
 	}
 	app.responseInterceptors = []router.Interceptor{
 		app.ScriptHandler,
@@ -314,6 +328,7 @@ func (app *App) Run() {
 	// 启动云端连接器（如果启用）
 	if app.Cfg.CloudEnabled && strings.TrimSpace(app.Cfg.CloudHubURL) != "" {
 		app.CloudConnector = cloud.NewConnector(app.Cfg, app.WSHub)
+		app.CloudConnector.SetVideoDownloader(app.UploadHandler)
 		app.CloudConnector.Start()
 		utils.Info("✓ 云端管理功能已启用，Hub: %s", app.Cfg.CloudHubURL)
 	} else {
@@ -330,6 +345,28 @@ func (app *App) Run() {
 		utils.Info("雷达服务未启用 (radar_enabled: false)")
 	}
 
+	// 启动下载队列调度（数据库不可用时跳过）
+	if app.QueueWorker != nil {
+		handlers.GetWebSocketHub().StartProgressForwarder(app.QueueWorker.ProgressChannel())
+		app.QueueWorker.Start()
+		utils.Info("✓ 下载队列调度已启动")
+
+		// 按保留策略定期清理下载目录，磁盘空间不足时暂停下载队列
+		services.GetRetentionService().Start(app.QueueWorker)
+	}
+
+	// 启动保存的关键词搜索调度（仅执行已启用且到期的搜索）
+	if app.SavedSearchService != nil {
+		app.SavedSearchService.Start()
+	}
+
+	// 恢复上次退出时未完成的评论导出任务（等待页面连接后从断点继续）
+	if app.APIRouter != nil && database.GetDB() != nil {
+		if count := app.APIRouter.ResumeCommentExportJobs(); count > 0 {
+			utils.Info("✓ 已恢复 %d 个未完成的评论导出任务", count)
+		}
+	}
+
 	// 4. 【异步】处理 Windows 进程注入和连通性检查 (不阻塞主线程)
 	go func() {
 		// 如果是 Windows，尝试启动注入引擎
@@ -378,6 +415,9 @@ func (app *App) Run() {
 	if app.RadarService != nil {
 		app.RadarService.Stop()
 	}
+	if app.SavedSearchService != nil {
+		app.SavedSearchService.Stop()
+	}
 }
 
 // GlobalHttpCallback 桥接到单例 app 实例
@@ -45,6 +45,15 @@ type Connector struct {
 
 	// 同步推送器
 	syncPusher *SyncPusher
+
+	// 队列、雷达、设置和统计的远程管理指令
+	commands *managementCommands
+
+	// 消息签名校验和指令白名单
+	guard *commandGuard
+
+	// 断线期间暂存的响应、事件和同步批次
+	outbox *outbox
 }
 
 // NewConnector 创建云端连接器
@@ -72,7 +81,12 @@ func NewConnector(cfg *config.Config, localHub *hubws.Hub) *Connector {
 			New: func() interface{} { return gzip.NewWriter(nil) },
 		},
 		metricsClient: &http.Client{Timeout: 5 * time.Second},
+		commands:      newManagementCommands(cfg),
 	}
+	c.guard = newCommandGuard(cfg, c.commands.actions())
+	c.outbox = newOutbox()
+	c.outbox.onFlushed = c.outboxFlushed
+	c.commands.jobs.emit = c.emitJobEvent
 
 	if c.clientID == "" {
 		hostname, _ := os.Hostname()
@@ -169,6 +183,12 @@ func (c *Connector) Start() {
 	}
 
 	utils.LogInfo("正在启动云端连接器 (ID: %s, URL: %s)", c.clientID, c.cfg.CloudHubURL)
+	if !c.guard.signingEnabled() {
+		utils.LogWarn("未配置 cloud_secret，云端消息不做签名校验，仅按白名单过滤指令")
+	}
+	if _, err := c.guard.audit.DeleteBefore(time.Now().AddDate(0, 0, -30)); err != nil {
+		utils.LogWarn("清理云端指令审计记录失败: %v", err)
+	}
 
 	// 启动同步推送器（如果启用了 Hub 同步和推送功能）
 	if c.cfg.HubSync.Enabled && c.cfg.HubSync.PushEnabled {
@@ -284,6 +304,12 @@ func (c *Connector) connect() error {
 }
 
 func (c *Connector) handleConnection() {
+	defer func() {
+		c.mu.Lock()
+		c.conn = nil
+		c.mu.Unlock()
+	}()
+
 	// 检查是否有绑定任务
 	if c.cfg.BindToken != "" {
 		utils.LogInfo("检测到绑定码，正在发送绑定请求...")
@@ -303,6 +329,12 @@ func (c *Connector) handleConnection() {
 		}
 	}
 
+	// 重放连接断开前未被确认的同步批次，并按顺序补发断线期间暂存的消息
+	if c.syncPusher != nil {
+		c.syncPusher.Reconnected()
+	}
+	go c.flushOutbox()
+
 	// 创建连接级上下文
 	ctx, cancel := context.WithCancel(c.ctx)
 	defer cancel()
@@ -441,6 +473,7 @@ func (c *Connector) sendHeartbeat() error {
 		Payload:   payloadData,
 		Timestamp: time.Now().Unix(),
 	}
+	c.guard.sign(&msg)
 
 	// 设置写入超时
 	c.mu.Lock()
@@ -478,7 +511,8 @@ func (c *Connector) send(msg CloudMessage) error {
 		return fmt.Errorf("connection closed")
 	}
 
-	// 1. 序列化消息
+	// 1. 签名并序列化消息
+	c.guard.sign(&msg)
 	data, err := json.Marshal(msg)
 	if err != nil {
 		return err
@@ -536,7 +570,15 @@ func (c *Connector) compressData(data []byte) ([]byte, error) {
 func (c *Connector) processMessage(msg CloudMessage) {
 	metrics.WSMessagesReceived.WithLabelValues(string(msg.Type)).Inc()
 
-	if msg.Type != MsgTypeCommand {
+	if msg.Type != MsgTypeResponse && msg.Type != MsgTypeCommand {
+		return
+	}
+	if err := c.guard.verify(msg); err != nil {
+		c.reject(msg, "", "", err)
+		return
+	}
+	if msg.Type == MsgTypeResponse {
+		c.handleResponse(msg)
 		return
 	}
 
@@ -549,24 +591,95 @@ func (c *Connector) processMessage(msg CloudMessage) {
 
 	utils.LogInfo("收到云端指令: %s", cmd.Action)
 
+	if err := c.guard.checkAction(cmd.Action); err != nil {
+		c.reject(msg, cmd.Action, "", err)
+		return
+	}
+
+	if handler, ok := c.commands.handler(cmd.Action); ok {
+		c.handleManagementCommand(msg.ID, cmd.Action, handler, cmd.Data)
+		return
+	}
+
 	if mappedData, mapped, err := mapCommandToAPICall(cmd.Action, cmd.Data); err != nil {
 		utils.LogError("指令参数转换失败: %v", err)
 		c.sendError(msg.ID, "Invalid command parameters")
 		return
 	} else if mapped {
-		c.handleAPICall(msg.ID, mappedData)
+		c.handleGuardedAPICall(msg, cmd.Action, mappedData)
 		return
 	}
 
 	switch cmd.Action {
 	case "api_call":
-		c.handleAPICall(msg.ID, cmd.Data)
+		c.handleGuardedAPICall(msg, cmd.Action, cmd.Data)
 	default:
 		utils.LogError("未知操作: %s", cmd.Action)
 		c.sendError(msg.ID, fmt.Sprintf("Unknown action: %s", cmd.Action))
 	}
 }
 
+// reject 记录被拒绝的消息；指令会收到带 rejected 错误码的响应
+func (c *Connector) reject(msg CloudMessage, action, apiKey string, err error) {
+	rej, ok := err.(*rejection)
+	if !ok {
+		rej = &rejection{Reason: RejectBadSignature, Detail: err.Error()}
+	}
+	c.guard.record(msg, action, apiKey, rej)
+	if msg.Type == MsgTypeCommand {
+		c.sendResponsePayload(ResponsePayload{RequestID: msg.ID, Error: rej.Error(), ErrorCode: CommandErrRejected})
+	}
+}
+
+// handleGuardedAPICall 检查页面接口白名单后调用本地 API
+func (c *Connector) handleGuardedAPICall(msg CloudMessage, action string, data json.RawMessage) {
+	var call struct {
+		Key string `json:"key"`
+	}
+	if err := json.Unmarshal(data, &call); err != nil {
+		utils.LogError("API 参数解析失败: %v", err)
+		c.sendError(msg.ID, "Invalid API call parameters")
+		return
+	}
+	if err := c.guard.checkAPIKey(call.Key); err != nil {
+		c.reject(msg, action, call.Key, err)
+		return
+	}
+	c.handleAPICall(msg.ID, data)
+}
+
+// handleResponse 处理 Hub 发来的响应，目前用于确认 sync_data 批次
+func (c *Connector) handleResponse(msg CloudMessage) {
+	var resp ResponsePayload
+	if err := json.Unmarshal(msg.Payload, &resp); err != nil {
+		utils.LogError("响应载荷解析失败: %v", err)
+		return
+	}
+	if c.syncPusher != nil && c.syncPusher.HandleAck(resp) {
+		return
+	}
+	utils.LogWarn("收到未知请求的响应: %s", resp.RequestID)
+}
+
+// handleManagementCommand 执行管理指令并返回结果，失败时附带错误码
+func (c *Connector) handleManagementCommand(reqID, action string, handler commandHandler, data json.RawMessage) {
+	result, err := handler(data)
+	if err != nil {
+		cmdErr := classifyCommandError(err)
+		utils.LogWarn("指令 %s 执行失败 (%s): %v", action, cmdErr.Code, err)
+		c.sendResponsePayload(ResponsePayload{RequestID: reqID, Error: cmdErr.Message, ErrorCode: cmdErr.Code})
+		return
+	}
+
+	respData, err := json.Marshal(result)
+	if err != nil {
+		utils.LogWarn("指令 %s 结果序列化失败: %v", action, err)
+		c.sendResponsePayload(ResponsePayload{RequestID: reqID, Error: commandInternalMessage, ErrorCode: CommandErrInternal})
+		return
+	}
+	c.sendResponse(reqID, true, respData, "")
+}
+
 func (c *Connector) handleAPICall(reqID string, data json.RawMessage) {
 	var call struct {
 		Key  string          `json:"key"`
@@ -686,12 +799,16 @@ func (c *Connector) transformSearchResponse(requestBody, responseData json.RawMe
 }
 
 func (c *Connector) sendResponse(reqID string, success bool, data json.RawMessage, errMsg string) {
-	resp := ResponsePayload{
+	c.sendResponsePayload(ResponsePayload{
 		RequestID: reqID,
 		Success:   success,
 		Data:      data,
 		Error:     errMsg,
-	}
+	})
+}
+
+func (c *Connector) sendResponsePayload(resp ResponsePayload) {
+	reqID := resp.RequestID
 	respData, err := json.Marshal(resp)
 	if err != nil {
 		utils.LogError("响应序列化失败: %v", err)
@@ -706,11 +823,60 @@ func (c *Connector) sendResponse(reqID string, success bool, data json.RawMessag
 		Timestamp: time.Now().Unix(),
 	}
 
-	if err := c.send(msg); err != nil {
+	if err := c.deliver(msg); err != nil {
 		utils.LogError("发送响应失败: %v", err)
 	}
 }
 
+// SetVideoDownloader 设置后 download_video 指令立即返回任务 ID，进度以事件形式推送
+// 需在 Start 之前调用
+func (c *Connector) SetVideoDownloader(downloader VideoDownloader) {
+	c.commands.enableDownloadJobs(downloader)
+}
+
+// emitJobEvent 发送任务事件，进度和阶段事件断线时直接丢弃
+func (c *Connector) emitJobEvent(event string, job Job) {
+	queue := event != JobEventProgress && event != JobEventStage
+	if err := c.sendEvent(event, job, queue); err != nil && queue {
+		utils.LogWarn("发送任务事件 %s 失败: %v", event, err)
+	}
+}
+
+// SendEvent 向 Hub 发送事件，断线期间写入待发送队列
+func (c *Connector) SendEvent(event string, data interface{}) error {
+	return c.sendEvent(event, data, true)
+}
+
+func (c *Connector) sendEvent(event string, data interface{}, queue bool) error {
+	eventData, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event data: %w", err)
+	}
+	payload, err := json.Marshal(EventPayload{Event: event, Data: eventData})
+	if err != nil {
+		return fmt.Errorf("failed to marshal event payload: %w", err)
+	}
+
+	msg := CloudMessage{
+		ID:        fmt.Sprintf("evt-%d", time.Now().UnixNano()),
+		Type:      MsgTypeEvent,
+		ClientID:  c.clientID,
+		Payload:   payload,
+		Timestamp: time.Now().Unix(),
+	}
+	if !queue {
+		return c.send(msg)
+	}
+	return c.deliver(msg)
+}
+
+// outboxFlushed 补发成功后更新同步批次的发送时间，避免重连后再次重放
+func (c *Connector) outboxFlushed(msg CloudMessage) {
+	if msg.Type == MsgTypeSyncData && c.syncPusher != nil {
+		c.syncPusher.markSent(msg.ID)
+	}
+}
+
 func (c *Connector) sendError(reqID string, errMsg string) {
 	c.sendResponse(reqID, false, nil, errMsg)
 }
@@ -0,0 +1,287 @@
+package cloud
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"wx_channel/internal/handlers"
+
+	json "github.com/json-iterator/go"
+)
+
+// JobStatus 云端任务状态
+type JobStatus string
+
+const (
+	JobStatusRunning   JobStatus = "running"   // 执行中
+	JobStatusCompleted JobStatus = "completed" // 已完成
+	JobStatusSkipped   JobStatus = "skipped"   // 视频已存在，未重新下载
+	JobStatusFailed    JobStatus = "failed"    // 失败
+	JobStatusCanceled  JobStatus = "canceled"  // 已取消
+)
+
+// 任务事件名称，事件数据为 Job
+const (
+	JobEventProgress  = "job_progress"  // 下载进度，断线时不暂存
+	JobEventStage     = "job_stage"     // 阶段变化，断线时不暂存
+	JobEventCompleted = "job_completed" // 任务完成
+	JobEventFailed    = "job_failed"    // 任务失败
+	JobEventCanceled  = "job_canceled"  // 任务已取消
+)
+
+// jobRetention 已结束的任务保留多久，期间可通过 job_status 查询
+const jobRetention = time.Hour
+
+// Job 云端发起的长时间任务
+type Job struct {
+	ID        string                     `json:"job_id"`
+	Type      string                     `json:"type"` // 目前只有 download_video
+	Status    JobStatus                  `json:"status"`
+	Stage     string                     `json:"stage,omitempty"` // 见 handlers.DownloadStage* 常量
+	VideoID   string                     `json:"video_id,omitempty"`
+	Title     string                     `json:"title,omitempty"`
+	Progress  *handlers.DownloadProgress `json:"progress,omitempty"`
+	Result    *handlers.DownloadResult   `json:"result,omitempty"`
+	Error     string                     `json:"error,omitempty"`
+	CreatedAt time.Time                  `json:"created_at"`
+	UpdatedAt time.Time                  `json:"updated_at"`
+}
+
+func (j *Job) finished() bool {
+	return j.Status != JobStatusRunning
+}
+
+// JobCommand cancel_job / job_status 参数
+type JobCommand struct {
+	JobID string `json:"job_id,omitempty"` // job_status 为空时返回全部任务
+}
+
+// VideoDownloader 在后台下载视频并报告进度，由 handlers.UploadHandler 实现
+type VideoDownloader interface {
+	StartVideoDownload(ctx context.Context, req handlers.DownloadVideoRequest, opts handlers.DownloadOptions) (*handlers.DownloadStart, error)
+}
+
+type jobEntry struct {
+	job    Job
+	cancel context.CancelFunc
+}
+
+// jobManager 管理云端下载任务，进度和结果以事件形式发送给 Hub
+type jobManager struct {
+	downloader VideoDownloader
+	emit       func(event string, job Job) // 由连接器设置
+
+	mu   sync.Mutex
+	jobs map[string]*jobEntry
+	seq  int64
+}
+
+func newJobManager() *jobManager {
+	return &jobManager{jobs: make(map[string]*jobEntry)}
+}
+
+// startDownload 创建下载任务并立即返回，视频已存在时任务直接以 skipped 结束
+func (m *jobManager) startDownload(req handlers.DownloadVideoRequest) (Job, error) {
+	if req.VideoURL == "" {
+		return Job{}, invalidParams("videoUrl is required")
+	}
+
+	now := time.Now()
+	ctx, cancel := context.WithCancel(context.Background())
+	entry := &jobEntry{
+		job: Job{
+			Type:      "download_video",
+			Status:    JobStatusRunning,
+			VideoID:   req.VideoID,
+			Title:     req.Title,
+			CreatedAt: now,
+			UpdatedAt: now,
+		},
+		cancel: cancel,
+	}
+
+	m.mu.Lock()
+	m.pruneLocked(now)
+	m.seq++
+	entry.job.ID = fmt.Sprintf("job-%d-%d", now.Unix(), m.seq)
+	m.jobs[entry.job.ID] = entry
+	m.mu.Unlock()
+
+	start, err := m.downloader.StartVideoDownload(ctx, req, handlers.DownloadOptions{
+		Reporter:    &jobReporter{m: m, id: entry.job.ID},
+		ComputeHash: true,
+	})
+	if err == nil && start.InProgress {
+		err = &CommandError{Code: CommandErrConflict, Message: "该视频已在下载中"}
+	}
+	if err != nil {
+		cancel()
+		m.mu.Lock()
+		delete(m.jobs, entry.job.ID)
+		m.mu.Unlock()
+		return Job{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if start.Skipped {
+		entry.job.Status = JobStatusSkipped
+		entry.job.Result = start.Result
+		entry.job.UpdatedAt = time.Now()
+		cancel()
+	}
+	return entry.job, nil
+}
+
+// cancelJob 取消执行中的任务
+func (m *jobManager) cancelJob(id string) (Job, error) {
+	m.mu.Lock()
+	entry, ok := m.jobs[id]
+	if !ok {
+		m.mu.Unlock()
+		return Job{}, &CommandError{Code: CommandErrNotFound, Message: "任务不存在"}
+	}
+	if entry.job.finished() {
+		m.mu.Unlock()
+		return Job{}, &CommandError{Code: CommandErrConflict, Message: fmt.Sprintf("任务已结束 (%s)", entry.job.Status)}
+	}
+	entry.cancel()
+	entry.job.Status = JobStatusCanceled
+	entry.job.UpdatedAt = time.Now()
+	job := entry.job
+	m.mu.Unlock()
+
+	m.notify(JobEventCanceled, job)
+	return job, nil
+}
+
+// get 返回任务快照
+func (m *jobManager) get(id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.jobs[id]
+	if !ok {
+		return Job{}, &CommandError{Code: CommandErrNotFound, Message: "任务不存在"}
+	}
+	return entry.job, nil
+}
+
+// list 按创建时间返回所有任务快照
+func (m *jobManager) list() []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	jobs := make([]Job, 0, len(m.jobs))
+	for _, entry := range m.jobs {
+		jobs = append(jobs, entry.job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.Before(jobs[j].CreatedAt) })
+	return jobs
+}
+
+// update 修改执行中的任务并发送事件，任务已结束（例如已取消）时忽略
+func (m *jobManager) update(id, event string, apply func(job *Job)) {
+	m.mu.Lock()
+	entry, ok := m.jobs[id]
+	if !ok || entry.job.finished() {
+		m.mu.Unlock()
+		return
+	}
+	apply(&entry.job)
+	entry.job.UpdatedAt = time.Now()
+	job := entry.job
+	m.mu.Unlock()
+
+	m.notify(event, job)
+}
+
+func (m *jobManager) notify(event string, job Job) {
+	if m.emit != nil {
+		m.emit(event, job)
+	}
+}
+
+// pruneLocked 删除结束超过保留时间的任务，调用方需持有 mu
+func (m *jobManager) pruneLocked(now time.Time) {
+	for id, entry := range m.jobs {
+		if entry.job.finished() && now.Sub(entry.job.UpdatedAt) > jobRetention {
+			delete(m.jobs, id)
+		}
+	}
+}
+
+// jobReporter 将下载进度写入任务
+type jobReporter struct {
+	m  *jobManager
+	id string
+}
+
+func (r *jobReporter) Progress(p handlers.DownloadProgress) {
+	r.m.update(r.id, JobEventProgress, func(job *Job) { job.Progress = &p })
+}
+
+func (r *jobReporter) Stage(stage string) {
+	r.m.update(r.id, JobEventStage, func(job *Job) { job.Stage = stage })
+}
+
+func (r *jobReporter) Completed(result handlers.DownloadResult) {
+	r.m.update(r.id, JobEventCompleted, func(job *Job) {
+		job.Status = JobStatusCompleted
+		job.Result = &result
+	})
+}
+
+func (r *jobReporter) Failed(err error) {
+	r.m.update(r.id, JobEventFailed, func(job *Job) {
+		job.Status = JobStatusFailed
+		job.Error = err.Error()
+	})
+}
+
+// 任务指令
+
+func (m *managementCommands) downloadVideo(data json.RawMessage) (interface{}, error) {
+	var cmd mappedDownloadCommand
+	if err := decodeCommand(data, &cmd); err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(normalizeDownloadCommand(cmd))
+	if err != nil {
+		return nil, err
+	}
+	var req handlers.DownloadVideoRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, invalidParams("invalid download command: %v", err)
+	}
+	return m.jobs.startDownload(req)
+}
+
+func (m *managementCommands) cancelJob(data json.RawMessage) (interface{}, error) {
+	var req JobCommand
+	if err := decodeCommand(data, &req); err != nil {
+		return nil, err
+	}
+	if req.JobID == "" {
+		return nil, invalidParams("job_id is required")
+	}
+	return m.jobs.cancelJob(req.JobID)
+}
+
+func (m *managementCommands) jobStatus(data json.RawMessage) (interface{}, error) {
+	var req JobCommand
+	if err := decodeCommand(data, &req); err != nil {
+		return nil, err
+	}
+	if req.JobID == "" {
+		return m.jobs.list(), nil
+	}
+	return m.jobs.get(req.JobID)
+}
+
+// enableDownloadJobs 设置下载器后，download_video 指令改为创建后台任务，不再经由页面执行
+func (m *managementCommands) enableDownloadJobs(downloader VideoDownloader) {
+	m.jobs.downloader = downloader
+	m.handlers["download_video"] = m.downloadVideo
+}
@@ -0,0 +1,109 @@
+package cloud
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"wx_channel/internal/handlers"
+)
+
+// fakeDownloader 记录下载请求，由测试驱动进度回调
+type fakeDownloader struct {
+	mu        sync.Mutex
+	ctxs      []context.Context
+	reporters []handlers.DownloadReporter
+	start     handlers.DownloadStart
+}
+
+func (f *fakeDownloader) StartVideoDownload(ctx context.Context, req handlers.DownloadVideoRequest, opts handlers.DownloadOptions) (*handlers.DownloadStart, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.ctxs = append(f.ctxs, ctx)
+	f.reporters = append(f.reporters, opts.Reporter)
+	start := f.start
+	return &start, nil
+}
+
+func TestManagementCommands_DownloadJobs(t *testing.T) {
+	m := setupManagementCommandsTest(t)
+	downloader := &fakeDownloader{}
+	m.enableDownloadJobs(downloader)
+
+	var events []string
+	m.jobs.emit = func(event string, job Job) { events = append(events, event+":"+string(job.Status)) }
+
+	if _, err := runCommand(t, m, "download_video", `{"videoId":"v1"}`); err == nil || err.Code != CommandErrInvalidParams {
+		t.Fatalf("expected invalid_params without videoUrl, got %+v", err)
+	}
+
+	result, cmdErr := runCommand(t, m, "download_video", `{"url":"http://example.invalid/v1","videoId":"v1","title":"视频"}`)
+	if cmdErr != nil {
+		t.Fatalf("download_video: %+v", cmdErr)
+	}
+	job := result.(Job)
+	if job.ID == "" || job.Status != JobStatusRunning || job.VideoID != "v1" {
+		t.Fatalf("unexpected job: %+v", job)
+	}
+
+	reporter := downloader.reporters[0]
+	reporter.Stage(handlers.DownloadStageDownloading)
+	reporter.Progress(handlers.DownloadProgress{VideoID: "v1", Percentage: 50, Downloaded: 50, Total: 100, Speed: 10})
+	reporter.Completed(handlers.DownloadResult{Path: "/tmp/v1.mp4", Size: 100, SHA256: "abc"})
+	reporter.Failed(context.Canceled) // 任务结束后的回调被忽略
+
+	result, cmdErr = runCommand(t, m, "job_status", `{"job_id":"`+job.ID+`"}`)
+	if cmdErr != nil {
+		t.Fatalf("job_status: %+v", cmdErr)
+	}
+	done := result.(Job)
+	if done.Status != JobStatusCompleted || done.Stage != handlers.DownloadStageDownloading ||
+		done.Progress == nil || done.Progress.Percentage != 50 || done.Result == nil || done.Result.SHA256 != "abc" {
+		t.Fatalf("unexpected completed job: %+v", done)
+	}
+	if _, err := runCommand(t, m, "cancel_job", `{"job_id":"`+job.ID+`"}`); err == nil || err.Code != CommandErrConflict {
+		t.Fatalf("expected conflict when canceling a finished job, got %+v", err)
+	}
+
+	// 取消执行中的任务会中止下载上下文
+	result, cmdErr = runCommand(t, m, "download_video", `{"videoUrl":"http://example.invalid/v2","videoId":"v2"}`)
+	if cmdErr != nil {
+		t.Fatalf("download_video: %+v", cmdErr)
+	}
+	second := result.(Job)
+	if result, err := runCommand(t, m, "cancel_job", `{"job_id":"`+second.ID+`"}`); err != nil || result.(Job).Status != JobStatusCanceled {
+		t.Fatalf("cancel_job: %v, %+v", result, err)
+	}
+	if downloader.ctxs[1].Err() == nil {
+		t.Fatalf("expected download context to be canceled")
+	}
+	downloader.reporters[1].Failed(context.Canceled)
+
+	expected := []string{"job_stage:running", "job_progress:running", "job_completed:completed", "job_canceled:canceled"}
+	if len(events) != len(expected) {
+		t.Fatalf("expected events %v, got %v", expected, events)
+	}
+	for i := range expected {
+		if events[i] != expected[i] {
+			t.Fatalf("expected events %v, got %v", expected, events)
+		}
+	}
+
+	// 已存在的视频直接以 skipped 结束；同一视频正在下载时返回冲突
+	downloader.start = handlers.DownloadStart{Skipped: true, Result: &handlers.DownloadResult{Path: "/tmp/v3.mp4"}}
+	if result, err := runCommand(t, m, "download_video", `{"videoUrl":"http://example.invalid/v3"}`); err != nil || result.(Job).Status != JobStatusSkipped {
+		t.Fatalf("expected skipped job: %v, %+v", result, err)
+	}
+	downloader.start = handlers.DownloadStart{InProgress: true}
+	if _, err := runCommand(t, m, "download_video", `{"videoUrl":"http://example.invalid/v4"}`); err == nil || err.Code != CommandErrConflict {
+		t.Fatalf("expected conflict for a download in progress, got %+v", err)
+	}
+
+	result, cmdErr = runCommand(t, m, "job_status", ``)
+	if cmdErr != nil {
+		t.Fatalf("job_status: %+v", cmdErr)
+	}
+	if jobs := result.([]Job); len(jobs) != 3 || jobs[0].ID != job.ID {
+		t.Fatalf("unexpected job list: %+v", jobs)
+	}
+}
@@ -0,0 +1,422 @@
+package cloud
+
+import (
+	"errors"
+	"fmt"
+
+	"wx_channel/internal/config"
+	"wx_channel/internal/database"
+	"wx_channel/internal/services"
+
+	json "github.com/json-iterator/go"
+)
+
+// 指令错误码，通过 ResponsePayload.ErrorCode 返回给 Hub
+const (
+	CommandErrInvalidParams = "invalid_params" // 参数缺失或无效
+	CommandErrNotFound      = "not_found"      // 目标不存在
+	CommandErrConflict      = "conflict"       // 与现有数据冲突
+	CommandErrInternal      = "internal"       // 本地执行失败
+)
+
+// commandInternalMessage 本地执行失败时返回给 Hub 的通用错误信息
+const commandInternalMessage = "internal error"
+
+// CommandError 带错误码的指令错误
+type CommandError struct {
+	Code    string
+	Message string
+}
+
+func (e *CommandError) Error() string { return e.Message }
+
+func invalidParams(format string, args ...interface{}) error {
+	return &CommandError{Code: CommandErrInvalidParams, Message: fmt.Sprintf(format, args...)}
+}
+
+// classifyCommandError 将服务层错误转换为带错误码的指令错误
+func classifyCommandError(err error) *CommandError {
+	var cmdErr *CommandError
+	var invalid *services.ValidationError
+	switch {
+	case errors.As(err, &cmdErr):
+		return cmdErr
+	case errors.As(err, &invalid):
+		return &CommandError{Code: CommandErrInvalidParams, Message: invalid.Error()}
+	case errors.Is(err, services.ErrRadarTargetNotFound):
+		return &CommandError{Code: CommandErrNotFound, Message: err.Error()}
+	case errors.Is(err, services.ErrRadarTargetExists):
+		return &CommandError{Code: CommandErrConflict, Message: err.Error()}
+	case errors.Is(err, services.ErrQueueItemNotFound):
+		return &CommandError{Code: CommandErrNotFound, Message: err.Error()}
+	case errors.Is(err, services.ErrQueueItemStatus):
+		return &CommandError{Code: CommandErrConflict, Message: err.Error()}
+	default:
+		// 内部错误的详细信息只记录在本地日志中
+		return &CommandError{Code: CommandErrInternal, Message: commandInternalMessage}
+	}
+}
+
+// 管理指令载荷
+
+// QueueListCommand queue_list 参数
+type QueueListCommand struct {
+	Status string `json:"status,omitempty"` // 为空时返回全部
+}
+
+// QueueAddCommand queue_add 参数
+type QueueAddCommand struct {
+	Videos []services.VideoInfo `json:"videos"`
+}
+
+// QueueItemCommand queue_pause / queue_resume 参数
+type QueueItemCommand struct {
+	ID string `json:"id"`
+}
+
+// QueueRemoveCommand queue_remove 参数
+type QueueRemoveCommand struct {
+	IDs []string `json:"ids"`
+}
+
+// RadarTargetCommand radar_get / radar_remove / radar_logs 参数
+type RadarTargetCommand struct {
+	ID    string `json:"id"`
+	Limit int    `json:"limit,omitempty"` // 仅 radar_logs 使用
+}
+
+// RadarUpdateCommand radar_update 参数
+type RadarUpdateCommand struct {
+	ID     string               `json:"id"`
+	Target database.RadarTarget `json:"target"`
+}
+
+// RadarStatusCommand radar_set_status 参数
+type RadarStatusCommand struct {
+	ID     string                     `json:"id"`
+	Status database.RadarTargetStatus `json:"status"`
+}
+
+// StatsCommand stats_get 参数
+type StatsCommand struct {
+	ChartDays int `json:"chart_days,omitempty"` // >0 时同时返回最近 N 天的下载图表
+}
+
+// QueueListResult queue_list 返回值
+type QueueListResult struct {
+	Items []database.QueueItem `json:"items"`
+	Stats *services.QueueStats `json:"stats"`
+}
+
+// StatsResult stats_get 返回值
+type StatsResult struct {
+	Statistics *services.Statistics `json:"statistics"`
+	Chart      *services.ChartData  `json:"chart,omitempty"`
+}
+
+// commandHandler 执行一个管理指令，返回值序列化后作为响应数据
+type commandHandler func(data json.RawMessage) (interface{}, error)
+
+// managementCommands 队列、雷达、设置和统计的远程管理指令
+type managementCommands struct {
+	cfg      *config.Config
+	queue    *services.QueueService
+	radar    *services.RadarTargetService
+	settings *database.SettingsRepository
+	stats    *services.StatisticsService
+	jobs     *jobManager
+	handlers map[string]commandHandler
+}
+
+func newManagementCommands(cfg *config.Config) *managementCommands {
+	m := &managementCommands{
+		cfg:      cfg,
+		queue:    services.NewQueueService(),
+		radar:    services.NewRadarTargetService(),
+		settings: database.NewSettingsRepository(),
+		stats:    services.NewStatisticsService(),
+		jobs:     newJobManager(),
+	}
+	m.handlers = map[string]commandHandler{
+		"queue_list":       m.queueList,
+		"queue_add":        m.queueAdd,
+		"queue_pause":      m.queuePause,
+		"queue_resume":     m.queueResume,
+		"queue_remove":     m.queueRemove,
+		"radar_list":       m.radarList,
+		"radar_get":        m.radarGet,
+		"radar_add":        m.radarAdd,
+		"radar_update":     m.radarUpdate,
+		"radar_set_status": m.radarSetStatus,
+		"radar_remove":     m.radarRemove,
+		"radar_logs":       m.radarLogs,
+		"settings_get":     m.settingsGet,
+		"settings_update":  m.settingsUpdate,
+		"stats_get":        m.statsGet,
+		"cancel_job":       m.cancelJob,
+		"job_status":       m.jobStatus,
+	}
+	return m
+}
+
+// handler 返回指令对应的处理函数
+func (m *managementCommands) handler(action string) (commandHandler, bool) {
+	h, ok := m.handlers[action]
+	return h, ok
+}
+
+// actions 返回所有管理指令名称
+func (m *managementCommands) actions() []string {
+	actions := make([]string, 0, len(m.handlers))
+	for action := range m.handlers {
+		actions = append(actions, action)
+	}
+	return actions
+}
+
+// decodeCommand 解析指令参数，data 为空时保持零值
+func decodeCommand(data json.RawMessage, v interface{}) error {
+	if len(data) == 0 || string(data) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return invalidParams("invalid command data: %v", err)
+	}
+	return nil
+}
+
+func (m *managementCommands) queueList(data json.RawMessage) (interface{}, error) {
+	var req QueueListCommand
+	if err := decodeCommand(data, &req); err != nil {
+		return nil, err
+	}
+
+	var items []database.QueueItem
+	var err error
+	if req.Status != "" {
+		items, err = m.queue.GetByStatus(req.Status)
+	} else {
+		items, err = m.queue.GetQueue()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if items == nil {
+		items = []database.QueueItem{}
+	}
+	stats, err := m.queue.GetQueueStats()
+	if err != nil {
+		return nil, err
+	}
+	return QueueListResult{Items: items, Stats: stats}, nil
+}
+
+func (m *managementCommands) queueAdd(data json.RawMessage) (interface{}, error) {
+	var req QueueAddCommand
+	if err := decodeCommand(data, &req); err != nil {
+		return nil, err
+	}
+	if len(req.Videos) == 0 {
+		return nil, invalidParams("videos is required")
+	}
+	for i, v := range req.Videos {
+		if v.VideoID == "" || v.VideoURL == "" {
+			return nil, invalidParams("videos[%d]: videoId and videoUrl are required", i)
+		}
+	}
+	return m.queue.AddToQueue(req.Videos)
+}
+
+func (m *managementCommands) queuePause(data json.RawMessage) (interface{}, error) {
+	var req QueueItemCommand
+	if err := decodeCommand(data, &req); err != nil {
+		return nil, err
+	}
+	if req.ID == "" {
+		return nil, invalidParams("id is required")
+	}
+	return nil, m.queue.Pause(req.ID)
+}
+
+func (m *managementCommands) queueResume(data json.RawMessage) (interface{}, error) {
+	var req QueueItemCommand
+	if err := decodeCommand(data, &req); err != nil {
+		return nil, err
+	}
+	if req.ID == "" {
+		return nil, invalidParams("id is required")
+	}
+	return nil, m.queue.Resume(req.ID)
+}
+
+func (m *managementCommands) queueRemove(data json.RawMessage) (interface{}, error) {
+	var req QueueRemoveCommand
+	if err := decodeCommand(data, &req); err != nil {
+		return nil, err
+	}
+	if len(req.IDs) == 0 {
+		return nil, invalidParams("ids is required")
+	}
+	removed, err := m.queue.RemoveMany(req.IDs)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]int64{"removed": removed}, nil
+}
+
+// 雷达指令返回的监控目标均隐藏通知密钥和 SMTP 密码
+func (m *managementCommands) radarList(data json.RawMessage) (interface{}, error) {
+	targets, err := m.radar.List()
+	if err != nil {
+		return nil, err
+	}
+	return database.RedactRadarTargets(targets), nil
+}
+
+func (m *managementCommands) radarGet(data json.RawMessage) (interface{}, error) {
+	var req RadarTargetCommand
+	if err := decodeCommand(data, &req); err != nil {
+		return nil, err
+	}
+	if req.ID == "" {
+		return nil, invalidParams("id is required")
+	}
+	target, err := m.radar.Get(req.ID)
+	if err != nil {
+		return nil, err
+	}
+	return target.Redacted(), nil
+}
+
+func (m *managementCommands) radarAdd(data json.RawMessage) (interface{}, error) {
+	var target database.RadarTarget
+	if err := decodeCommand(data, &target); err != nil {
+		return nil, err
+	}
+	if err := m.radar.Add(&target); err != nil {
+		return nil, err
+	}
+	return target.Redacted(), nil
+}
+
+func (m *managementCommands) radarUpdate(data json.RawMessage) (interface{}, error) {
+	var req RadarUpdateCommand
+	if err := decodeCommand(data, &req); err != nil {
+		return nil, err
+	}
+	if req.ID == "" {
+		return nil, invalidParams("id is required")
+	}
+	if _, err := m.radar.Get(req.ID); err != nil {
+		return nil, err
+	}
+	if err := m.radar.Update(req.ID, &req.Target); err != nil {
+		return nil, err
+	}
+	return req.Target.Redacted(), nil
+}
+
+func (m *managementCommands) radarSetStatus(data json.RawMessage) (interface{}, error) {
+	var req RadarStatusCommand
+	if err := decodeCommand(data, &req); err != nil {
+		return nil, err
+	}
+	if req.ID == "" {
+		return nil, invalidParams("id is required")
+	}
+	if _, err := m.radar.Get(req.ID); err != nil {
+		return nil, err
+	}
+	return nil, m.radar.SetStatus(req.ID, req.Status)
+}
+
+func (m *managementCommands) radarRemove(data json.RawMessage) (interface{}, error) {
+	var req RadarTargetCommand
+	if err := decodeCommand(data, &req); err != nil {
+		return nil, err
+	}
+	if req.ID == "" {
+		return nil, invalidParams("id is required")
+	}
+	if _, err := m.radar.Get(req.ID); err != nil {
+		return nil, err
+	}
+	return nil, m.radar.Delete(req.ID)
+}
+
+func (m *managementCommands) radarLogs(data json.RawMessage) (interface{}, error) {
+	var req RadarTargetCommand
+	if err := decodeCommand(data, &req); err != nil {
+		return nil, err
+	}
+	if req.ID == "" {
+		return nil, invalidParams("id is required")
+	}
+	return m.radar.Logs(req.ID, req.Limit)
+}
+
+func (m *managementCommands) settingsGet(data json.RawMessage) (interface{}, error) {
+	return m.loadSettings()
+}
+
+// settingsUpdate 只更新指令中出现的字段，由配置文件管理的字段保持不变
+func (m *managementCommands) settingsUpdate(data json.RawMessage) (interface{}, error) {
+	settings, err := m.loadSettings()
+	if err != nil {
+		return nil, err
+	}
+	if err := decodeCommand(data, settings); err != nil {
+		return nil, err
+	}
+	settings = m.applyConfigOwnedSettings(settings)
+
+	if err := m.settings.Validate(settings); err != nil {
+		return nil, invalidParams("%v", err)
+	}
+	if err := m.settings.Save(settings); err != nil {
+		return nil, err
+	}
+	// 带宽限制立即生效
+	services.GetBandwidthLimiter().Apply(settings)
+	return settings, nil
+}
+
+func (m *managementCommands) loadSettings() (*database.Settings, error) {
+	settings, err := m.settings.Load()
+	if err != nil {
+		return nil, err
+	}
+	return m.applyConfigOwnedSettings(settings), nil
+}
+
+// applyConfigOwnedSettings 使用配置文件中的值覆盖由配置文件管理的字段
+func (m *managementCommands) applyConfigOwnedSettings(settings *database.Settings) *database.Settings {
+	if m.cfg == nil {
+		return settings
+	}
+	copied := *settings
+	copied.RadarEnabled = m.cfg.RadarEnabled
+	copied.DownloadFilenameTemplate = m.cfg.DownloadFilenameTemplate
+	return &copied
+}
+
+func (m *managementCommands) statsGet(data json.RawMessage) (interface{}, error) {
+	var req StatsCommand
+	if err := decodeCommand(data, &req); err != nil {
+		return nil, err
+	}
+	stats, err := m.stats.GetStatistics()
+	if err != nil {
+		return nil, err
+	}
+	result := StatsResult{Statistics: stats}
+	if req.ChartDays > 0 {
+		if req.ChartDays > 30 {
+			return nil, invalidParams("chart_days must be between 1 and 30")
+		}
+		if result.Chart, err = m.stats.GetChartData(req.ChartDays); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,177 @@
+package cloud
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"wx_channel/internal/config"
+	"wx_channel/internal/database"
+	"wx_channel/internal/services"
+
+	json "github.com/json-iterator/go"
+)
+
+func setupManagementCommandsTest(t *testing.T) *managementCommands {
+	t.Helper()
+	if err := database.Initialize(&database.Config{DBPath: filepath.Join(t.TempDir(), "records.db")}); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	return newManagementCommands(&config.Config{RadarEnabled: true})
+}
+
+func runCommand(t *testing.T, m *managementCommands, action, data string) (interface{}, *CommandError) {
+	t.Helper()
+	handler, ok := m.handler(action)
+	if !ok {
+		t.Fatalf("unknown action %s", action)
+	}
+	result, err := handler(json.RawMessage(data))
+	if err != nil {
+		return nil, classifyCommandError(err)
+	}
+	return result, nil
+}
+
+func TestManagementCommands_Radar(t *testing.T) {
+	m := setupManagementCommandsTest(t)
+
+	if _, err := runCommand(t, m, "radar_add", `{"username":"","author_name":"作者"}`); err == nil || err.Code != CommandErrInvalidParams {
+		t.Fatalf("expected invalid_params, got %+v", err)
+	}
+
+	result, cmdErr := runCommand(t, m, "radar_add", `{"username":"u1","author_name":"作者","interval_minutes":1,
+		"notify":{"events":["new_video"],"channels":[{"type":"dingtalk","url":"https://oapi.dingtalk.com/robot/send","secret":"SEC123"}]}}`)
+	if cmdErr != nil {
+		t.Fatalf("radar_add: %+v", cmdErr)
+	}
+	target := result.(database.RadarTarget)
+	if target.ID == "" || target.IntervalMinutes != 5 || target.Status != database.RadarStatusActive {
+		t.Fatalf("unexpected target: %+v", target)
+	}
+
+	if _, err := runCommand(t, m, "radar_add", `{"username":"u1","author_name":"作者"}`); err == nil || err.Code != CommandErrConflict {
+		t.Fatalf("expected conflict, got %+v", err)
+	}
+	if _, err := runCommand(t, m, "radar_update", `{"id":"missing","target":{"username":"u2","author_name":"x"}}`); err == nil || err.Code != CommandErrNotFound {
+		t.Fatalf("expected not_found, got %+v", err)
+	}
+	if _, err := runCommand(t, m, "radar_set_status", `{"id":"`+target.ID+`","status":"stopped"}`); err == nil || err.Code != CommandErrInvalidParams {
+		t.Fatalf("expected invalid_params for bad status, got %+v", err)
+	}
+	if _, err := runCommand(t, m, "radar_set_status", `{"id":"`+target.ID+`","status":"paused"}`); err != nil {
+		t.Fatalf("radar_set_status: %+v", err)
+	}
+
+	result, cmdErr = runCommand(t, m, "radar_list", ``)
+	if cmdErr != nil {
+		t.Fatalf("radar_list: %+v", cmdErr)
+	}
+	if targets := result.([]database.RadarTarget); len(targets) != 1 || targets[0].Status != database.RadarStatusPaused {
+		t.Fatalf("unexpected targets: %+v", targets)
+	}
+
+	// 返回给 Hub 的监控目标不包含通知密钥
+	for _, action := range []string{"radar_list", "radar_get"} {
+		result, cmdErr = runCommand(t, m, action, `{"id":"`+target.ID+`"}`)
+		if cmdErr != nil {
+			t.Fatalf("%s: %+v", action, cmdErr)
+		}
+		if data, _ := json.Marshal(result); strings.Contains(string(data), "SEC123") {
+			t.Fatalf("%s leaked notify secret: %s", action, data)
+		}
+	}
+	if saved, _ := database.NewRadarRepository().GetByID(target.ID); saved.Notify.Channels[0].Secret != "SEC123" {
+		t.Fatalf("stored secret changed: %+v", saved.Notify)
+	}
+
+	if result, err := runCommand(t, m, "radar_logs", `{"id":"`+target.ID+`"}`); err != nil || len(result.([]database.RadarLog)) != 0 {
+		t.Fatalf("radar_logs: %v, %+v", result, err)
+	}
+	if _, err := runCommand(t, m, "radar_remove", `{"id":"`+target.ID+`"}`); err != nil {
+		t.Fatalf("radar_remove: %+v", err)
+	}
+	if _, err := runCommand(t, m, "radar_get", `{"id":"`+target.ID+`"}`); err == nil || err.Code != CommandErrNotFound {
+		t.Fatalf("expected not_found after remove, got %+v", err)
+	}
+}
+
+func TestManagementCommands_QueueSettingsAndStats(t *testing.T) {
+	m := setupManagementCommandsTest(t)
+
+	if _, err := runCommand(t, m, "queue_add", `{"videos":[{"videoId":"v1"}]}`); err == nil || err.Code != CommandErrInvalidParams {
+		t.Fatalf("expected invalid_params, got %+v", err)
+	}
+	result, cmdErr := runCommand(t, m, "queue_add", `{"videos":[{"videoId":"v1","title":"视频","videoUrl":"http://example.invalid/v1"}]}`)
+	if cmdErr != nil {
+		t.Fatalf("queue_add: %+v", cmdErr)
+	}
+	added := result.([]database.QueueItem)
+	if len(added) != 1 {
+		t.Fatalf("expected 1 queued item, got %d", len(added))
+	}
+
+	result, cmdErr = runCommand(t, m, "queue_list", `{"status":"pending"}`)
+	if cmdErr != nil {
+		t.Fatalf("queue_list: %+v", cmdErr)
+	}
+	if list := result.(QueueListResult); len(list.Items) != 1 || list.Stats.Pending != 1 {
+		t.Fatalf("unexpected queue list: %+v", list)
+	}
+	if _, err := runCommand(t, m, "queue_pause", `{"id":"`+added[0].ID+`"}`); err == nil || err.Code != CommandErrConflict {
+		t.Fatalf("expected conflict when pausing a pending item, got %+v", err)
+	}
+	if _, err := runCommand(t, m, "queue_resume", `{"id":"missing"}`); err == nil || err.Code != CommandErrNotFound {
+		t.Fatalf("expected not_found, got %+v", err)
+	}
+	if result, err := runCommand(t, m, "queue_remove", `{"ids":["`+added[0].ID+`"]}`); err != nil || result.(map[string]int64)["removed"] != 1 {
+		t.Fatalf("queue_remove: %v, %+v", result, err)
+	}
+
+	// 只更新出现的字段
+	result, cmdErr = runCommand(t, m, "settings_update", `{"theme":"dark","radarEnabled":false}`)
+	if cmdErr != nil {
+		t.Fatalf("settings_update: %+v", cmdErr)
+	}
+	settings := result.(*database.Settings)
+	defaults := database.DefaultSettings()
+	if settings.Theme != "dark" || settings.ConcurrentLimit != defaults.ConcurrentLimit || !settings.RadarEnabled {
+		t.Fatalf("unexpected settings: %+v", settings)
+	}
+	if _, err := runCommand(t, m, "settings_update", `{"concurrentLimit":9}`); err == nil || err.Code != CommandErrInvalidParams {
+		t.Fatalf("expected invalid_params, got %+v", err)
+	}
+
+	result, cmdErr = runCommand(t, m, "stats_get", `{"chart_days":7}`)
+	if cmdErr != nil {
+		t.Fatalf("stats_get: %+v", cmdErr)
+	}
+	if stats := result.(StatsResult); stats.Statistics == nil || stats.Chart == nil || len(stats.Chart.Labels) != 7 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestClassifyCommandError(t *testing.T) {
+	tests := []struct {
+		err  error
+		code string
+	}{
+		{fmt.Errorf("failed to pause: %w", services.ErrQueueItemNotFound), CommandErrNotFound},
+		{fmt.Errorf("%w: can only resume paused items", services.ErrQueueItemStatus), CommandErrConflict},
+		{services.ErrRadarTargetExists, CommandErrConflict},
+		{errors.New("item not found in cache"), CommandErrInternal},
+	}
+	for _, tt := range tests {
+		if got := classifyCommandError(tt.err); got.Code != tt.code {
+			t.Errorf("classifyCommandError(%v) = %s, want %s", tt.err, got.Code, tt.code)
+		}
+	}
+
+	// 内部错误不把本地错误信息发给 Hub
+	if got := classifyCommandError(errors.New("open C:/Users/a/records.db: locked")); got.Message != commandInternalMessage {
+		t.Fatalf("internal error message = %q", got.Message)
+	}
+}
@@ -22,6 +22,8 @@ type CloudMessage struct {
 	Payload    json.RawMessage `json:"payload"`              // 载荷
 	Timestamp  int64           `json:"timestamp"`            // 时间戳
 	Compressed bool            `json:"compressed,omitempty"` // 是否压缩
+	Nonce      string          `json:"nonce,omitempty"`      // 随机串，防重放
+	Signature  string          `json:"signature,omitempty"`  // HMAC-SHA256 签名，见 signingContent
 }
 
 // HeartbeatPayload 心跳载荷
@@ -52,15 +54,23 @@ type CommandPayload struct {
 
 // ResponsePayload 响应载荷
 type ResponsePayload struct {
-	RequestID string          `json:"request_id"` // 原始指令 ID
-	Success   bool            `json:"success"`    // 是否成功
-	Data      json.RawMessage `json:"data"`       // 返回数据
-	Error     string          `json:"error"`      // 错误信息
+	RequestID string          `json:"request_id"`           // 原始指令 ID
+	Success   bool            `json:"success"`              // 是否成功
+	Data      json.RawMessage `json:"data"`                 // 返回数据
+	Error     string          `json:"error"`                // 错误信息
+	ErrorCode string          `json:"error_code,omitempty"` // 错误码，见 CommandErr* 常量
+}
+
+// EventPayload 事件载荷
+type EventPayload struct {
+	Event string          `json:"event"` // 事件名称
+	Data  json.RawMessage `json:"data"`  // 事件数据
 }
 
 // SyncDataPayload 同步数据载荷
 type SyncDataPayload struct {
 	SyncType string          `json:"sync_type"` // "browse" or "download"
+	BatchID  string          `json:"batch_id"`  // 批次 ID，Hub 以此 ID 作为 ResponsePayload.RequestID 确认
 	Records  json.RawMessage `json:"records"`   // 记录数组
 	Count    int             `json:"count"`     // 记录数量
 	HasMore  bool            `json:"has_more"`  // 是否还有更多数据
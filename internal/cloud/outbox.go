@@ -0,0 +1,158 @@
+package cloud
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"wx_channel/internal/database"
+	"wx_channel/internal/utils"
+
+	json "github.com/json-iterator/go"
+)
+
+const (
+	// outboxMaxMessages 待发送消息上限，超出时丢弃最早的消息
+	outboxMaxMessages = 1000
+	// outboxFlushBatch 每次从数据库读取的待发送消息数
+	outboxFlushBatch = 50
+)
+
+// outboxTTL 各类消息在待发送队列中的保留时间，未列出的类型不入队
+var outboxTTL = map[MessageType]time.Duration{
+	MsgTypeResponse: time.Hour,      // 长时间运行的下载指令在网络抖动后仍能返回结果
+	MsgTypeEvent:    6 * time.Hour,  // 事件告警
+	MsgTypeSyncData: 24 * time.Hour, // 同步批次另有 sync_batches 兜底重放
+}
+
+// outbox 与 Hub 断开期间暂存响应、事件和同步批次，重连后按顺序补发
+type outbox struct {
+	repo *database.CloudOutboxRepository
+
+	mu       sync.Mutex  // 串行化直接发送、入队和补发，保证消息顺序
+	backlog  bool        // 队列中还有未补发的消息，期间新消息也需入队
+	flushing atomic.Bool // 是否正在补发
+
+	onFlushed func(msg CloudMessage) // 消息补发成功后回调
+}
+
+func newOutbox() *outbox {
+	o := &outbox{repo: database.NewCloudOutboxRepository()}
+	if count, err := o.repo.Count(); err != nil {
+		utils.LogWarn("读取云端待发送消息失败: %v", err)
+	} else {
+		o.backlog = count > 0
+	}
+	return o
+}
+
+// deliver 发送消息；连接不可用或仍有未补发的消息时，可入队的消息写入待发送队列
+// 消息入队后返回 nil，由重连后的补发负责送达
+func (c *Connector) deliver(msg CloudMessage) error {
+	ttl, queueable := outboxTTL[msg.Type]
+	if !queueable || c.outbox == nil {
+		return c.send(msg)
+	}
+
+	c.outbox.mu.Lock()
+	defer c.outbox.mu.Unlock()
+
+	if !c.outbox.backlog {
+		err := c.send(msg)
+		if err == nil {
+			return nil
+		}
+		utils.LogWarn("发送云端消息 %s 失败，写入待发送队列: %v", msg.ID, err)
+	} else if c.connected() {
+		go c.flushOutbox()
+	}
+	return c.enqueue(msg, ttl)
+}
+
+// enqueue 写入待发送队列，调用方需持有 outbox.mu
+func (c *Connector) enqueue(msg CloudMessage, ttl time.Duration) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	dropped, err := c.outbox.repo.Enqueue(&database.CloudOutboxMessage{
+		MessageID:   msg.ID,
+		MessageType: string(msg.Type),
+		Message:     string(data),
+		ExpiresAt:   time.Now().Add(ttl),
+	}, outboxMaxMessages)
+	if err != nil {
+		return err
+	}
+	if dropped > 0 {
+		utils.LogWarn("云端待发送队列已满，丢弃最早的 %d 条消息", dropped)
+	}
+	c.outbox.backlog = true
+	return nil
+}
+
+// flushOutbox 按入队顺序补发待发送消息，发送失败时停止，等待下次重连
+func (c *Connector) flushOutbox() {
+	if c.outbox == nil || !c.outbox.flushing.CompareAndSwap(false, true) {
+		return
+	}
+	defer c.outbox.flushing.Store(false)
+
+	c.outbox.mu.Lock()
+	defer c.outbox.mu.Unlock()
+
+	if expired, err := c.outbox.repo.DeleteExpired(time.Now()); err != nil {
+		utils.LogWarn("清理过期的云端待发送消息失败: %v", err)
+	} else if expired > 0 {
+		utils.LogWarn("丢弃 %d 条已过期的云端待发送消息", expired)
+	}
+
+	sent := 0
+	for {
+		pending, err := c.outbox.repo.List(outboxFlushBatch)
+		if err != nil {
+			utils.LogWarn("读取云端待发送消息失败: %v", err)
+			return
+		}
+		if len(pending) == 0 {
+			c.outbox.backlog = false
+			if sent > 0 {
+				utils.LogInfo("已补发 %d 条云端消息", sent)
+			}
+			return
+		}
+
+		for _, item := range pending {
+			var msg CloudMessage
+			if err := json.Unmarshal([]byte(item.Message), &msg); err != nil {
+				utils.LogWarn("丢弃无法解析的云端待发送消息 %s: %v", item.MessageID, err)
+				c.deleteOutboxMessage(item.MessageID)
+				continue
+			}
+			// 时间戳按实际发送时间刷新，否则会被 Hub 当作过期消息拒绝；消息 ID 不变，Hub 可据此去重
+			msg.Timestamp = time.Now().Unix()
+			if err := c.send(msg); err != nil {
+				utils.LogWarn("补发云端消息失败，剩余消息等待下次重连: %v", err)
+				return
+			}
+			c.deleteOutboxMessage(item.MessageID)
+			sent++
+			if c.outbox.onFlushed != nil {
+				c.outbox.onFlushed(msg)
+			}
+		}
+	}
+}
+
+func (c *Connector) deleteOutboxMessage(messageID string) {
+	if err := c.outbox.repo.Delete(messageID); err != nil {
+		utils.LogWarn("删除云端待发送消息失败: %v", err)
+	}
+}
+
+// connected 是否已建立到 Hub 的连接
+func (c *Connector) connected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn != nil
+}
@@ -0,0 +1,112 @@
+package cloud
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"wx_channel/internal/config"
+	"wx_channel/internal/database"
+
+	"github.com/coder/websocket"
+	json "github.com/json-iterator/go"
+)
+
+// startTestHub 启动一个只接收消息的 Hub，收到的消息写入返回的通道
+func startTestHub(t *testing.T) (string, <-chan CloudMessage) {
+	t.Helper()
+	received := make(chan CloudMessage, 100)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.CloseNow()
+		for {
+			_, data, err := conn.Read(r.Context())
+			if err != nil {
+				return
+			}
+			var msg CloudMessage
+			if err := json.Unmarshal(data, &msg); err == nil {
+				received <- msg
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http"), received
+}
+
+func TestConnector_OutboxQueuesWhileDisconnected(t *testing.T) {
+	if err := database.Initialize(&database.Config{DBPath: filepath.Join(t.TempDir(), "records.db")}); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	hubURL, received := startTestHub(t)
+	cfg := &config.Config{CloudHubURL: hubURL, CloudSecret: "s3cret"}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	c := &Connector{cfg: cfg, clientID: "client-1", ctx: ctx, cancel: cancel}
+	c.guard = newCommandGuard(cfg, nil)
+	c.outbox = newOutbox()
+
+	// 未连接时响应和事件入队，心跳等其他类型直接失败
+	c.sendResponse("cmd-1", true, json.RawMessage(`{"ok":true}`), "")
+	if err := c.SendEvent("download_progress", map[string]int{"percent": 50}); err != nil {
+		t.Fatalf("SendEvent: %v", err)
+	}
+	c.sendResponse("cmd-1", true, json.RawMessage(`{"ok":true,"retry":1}`), "") // 同一消息 ID 只保留一条
+	if err := c.deliver(CloudMessage{ID: "metrics-1", Type: "metrics"}); err == nil {
+		t.Fatalf("expected non-queueable message to fail while disconnected")
+	}
+
+	repo := database.NewCloudOutboxRepository()
+	if count, _ := repo.Count(); count != 2 {
+		t.Fatalf("expected 2 queued messages, got %d", count)
+	}
+	// 已过期的消息不会补发
+	if _, err := repo.Enqueue(&database.CloudOutboxMessage{
+		MessageID: "resp-old", MessageType: string(MsgTypeResponse), Message: `{"id":"resp-old"}`,
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}, outboxMaxMessages); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	// 仍有积压时，新消息排在积压之后
+	if err := c.connect(); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	c.sendResponse("cmd-2", false, nil, "failed")
+	c.flushOutbox()
+
+	var ids []string
+	for len(ids) < 3 {
+		select {
+		case msg := <-received:
+			if err := c.guard.verify(msg); err != nil {
+				t.Fatalf("flushed message %s failed verification: %v", msg.ID, err)
+			}
+			ids = append(ids, msg.ID)
+			if msg.ID == "resp-cmd-1" && !strings.Contains(string(msg.Payload), `"retry":1`) {
+				t.Fatalf("expected the latest payload for a duplicated message, got %s", msg.Payload)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for flushed messages, got %v", ids)
+		}
+	}
+	if ids[0] != "resp-cmd-1" || !strings.HasPrefix(ids[1], "evt-") || ids[2] != "resp-cmd-2" {
+		t.Fatalf("unexpected flush order: %v", ids)
+	}
+	// 补发可能在后台进行，等待其结束
+	c.outbox.mu.Lock()
+	backlog := c.outbox.backlog
+	c.outbox.mu.Unlock()
+	if count, _ := repo.Count(); count != 0 || backlog {
+		t.Fatalf("expected empty outbox after flush, got %d (backlog=%v)", count, backlog)
+	}
+}
@@ -0,0 +1,206 @@
+package cloud
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"wx_channel/internal/config"
+	"wx_channel/internal/database"
+	"wx_channel/internal/utils"
+)
+
+// 消息被拒绝的原因，写入审计记录
+const (
+	RejectMissingSignature = "missing_signature"   // 缺少签名或 nonce
+	RejectBadSignature     = "bad_signature"       // 签名校验失败
+	RejectStaleTimestamp   = "stale_timestamp"     // 时间戳超出允许偏差
+	RejectReplayedNonce    = "replayed_nonce"      // nonce 已使用过
+	RejectActionNotAllowed = "action_not_allowed"  // 指令不在白名单中
+	RejectAPIKeyNotAllowed = "api_key_not_allowed" // 页面接口不在白名单中
+)
+
+// CommandErrRejected 消息未通过签名或白名单校验
+const CommandErrRejected = "rejected"
+
+const defaultMaxClockSkew = 5 * time.Minute
+
+// defaultAllowedAPIKeys api_call 默认允许调用的页面接口
+var defaultAllowedAPIKeys = []string{
+	"key:channels:contact_list",
+	"key:channels:download_video",
+	"key:channels:feed_list",
+	"key:channels:feed_profile",
+	"key:channels:fetch_feed_comment_list",
+	"key:channels:shared_feed_profile",
+	"key:channels:shared_feed_resolve",
+}
+
+// mappedActions 由 mapCommandToAPICall 转换为页面接口调用的指令
+var mappedActions = []string{"search_channels", "search_videos", "download_video", "api_call"}
+
+// rejection 消息校验失败
+type rejection struct {
+	Reason string
+	Detail string
+}
+
+func (r *rejection) Error() string {
+	if r.Detail == "" {
+		return r.Reason
+	}
+	return r.Reason + ": " + r.Detail
+}
+
+// signingContent 返回参与签名的规范化内容
+func signingContent(msg CloudMessage) []byte {
+	header := fmt.Sprintf("%s\n%s\n%s\n%d\n%s\n", msg.ID, msg.Type, msg.ClientID, msg.Timestamp, msg.Nonce)
+	return append([]byte(header), msg.Payload...)
+}
+
+// computeSignature 计算消息的 HMAC-SHA256 签名（十六进制）
+func computeSignature(secret string, msg CloudMessage) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(signingContent(msg))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// newNonce 生成 16 字节随机 nonce
+func newNonce() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// commandGuard 校验云端消息的签名、时间戳和 nonce，并按白名单过滤指令
+type commandGuard struct {
+	secret  string
+	maxSkew time.Duration
+	actions map[string]bool
+	apiKeys map[string]bool
+	audit   *database.CloudAuditRepository
+	now     func() time.Time
+
+	mu     sync.Mutex
+	nonces map[string]time.Time // nonce -> 过期时间
+}
+
+func newCommandGuard(cfg *config.Config, managementActions []string) *commandGuard {
+	g := &commandGuard{
+		secret:  cfg.CloudSecret,
+		maxSkew: cfg.CloudCommand.MaxClockSkew,
+		actions: make(map[string]bool),
+		apiKeys: make(map[string]bool),
+		audit:   database.NewCloudAuditRepository(),
+		now:     time.Now,
+		nonces:  make(map[string]time.Time),
+	}
+	if g.maxSkew <= 0 {
+		g.maxSkew = defaultMaxClockSkew
+	}
+
+	actions := cfg.CloudCommand.AllowedActions
+	if len(actions) == 0 {
+		actions = append(append([]string{}, managementActions...), mappedActions...)
+	}
+	for _, action := range actions {
+		g.actions[action] = true
+	}
+
+	keys := cfg.CloudCommand.AllowedAPIKeys
+	if len(keys) == 0 {
+		keys = defaultAllowedAPIKeys
+	}
+	for _, key := range keys {
+		g.apiKeys[key] = true
+	}
+	return g
+}
+
+// signingEnabled 是否配置了签名密钥
+func (g *commandGuard) signingEnabled() bool {
+	return g.secret != ""
+}
+
+// sign 为发出的消息填充 nonce 和签名，未配置密钥时不做处理
+func (g *commandGuard) sign(msg *CloudMessage) {
+	if !g.signingEnabled() {
+		return
+	}
+	msg.Nonce = newNonce()
+	msg.Signature = computeSignature(g.secret, *msg)
+}
+
+// verify 校验收到的消息，未配置密钥时直接通过
+func (g *commandGuard) verify(msg CloudMessage) error {
+	if !g.signingEnabled() {
+		return nil
+	}
+	if msg.Signature == "" || msg.Nonce == "" {
+		return &rejection{Reason: RejectMissingSignature}
+	}
+	expected := computeSignature(g.secret, msg)
+	if !hmac.Equal([]byte(expected), []byte(msg.Signature)) {
+		return &rejection{Reason: RejectBadSignature}
+	}
+
+	now := g.now()
+	skew := now.Sub(time.Unix(msg.Timestamp, 0))
+	if skew > g.maxSkew || skew < -g.maxSkew {
+		return &rejection{Reason: RejectStaleTimestamp, Detail: fmt.Sprintf("skew %v", skew.Round(time.Second))}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for nonce, expires := range g.nonces {
+		if now.After(expires) {
+			delete(g.nonces, nonce)
+		}
+	}
+	if _, seen := g.nonces[msg.Nonce]; seen {
+		return &rejection{Reason: RejectReplayedNonce}
+	}
+	// 超出偏差窗口的消息会因时间戳被拒绝，nonce 只需保留两倍窗口
+	g.nonces[msg.Nonce] = now.Add(2 * g.maxSkew)
+	return nil
+}
+
+// checkAction 检查指令是否在白名单中
+func (g *commandGuard) checkAction(action string) error {
+	if !g.actions[action] {
+		return &rejection{Reason: RejectActionNotAllowed, Detail: action}
+	}
+	return nil
+}
+
+// checkAPIKey 检查页面接口是否在白名单中
+func (g *commandGuard) checkAPIKey(key string) error {
+	if !g.apiKeys[key] {
+		return &rejection{Reason: RejectAPIKeyNotAllowed, Detail: key}
+	}
+	return nil
+}
+
+// record 记录被拒绝的消息
+func (g *commandGuard) record(msg CloudMessage, action, apiKey string, rej *rejection) {
+	utils.LogWarn("拒绝云端消息 %s (type=%s, action=%s, key=%s): %v", msg.ID, msg.Type, action, apiKey, rej)
+	entry := &database.CloudCommandAudit{
+		MessageID:   msg.ID,
+		MessageType: string(msg.Type),
+		Action:      action,
+		APIKey:      apiKey,
+		Reason:      rej.Reason,
+		Detail:      rej.Detail,
+		MessageTime: msg.Timestamp,
+	}
+	if err := g.audit.Add(entry); err != nil {
+		utils.LogError("写入云端指令审计记录失败: %v", err)
+	}
+}
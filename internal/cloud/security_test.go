@@ -0,0 +1,147 @@
+package cloud
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"wx_channel/internal/config"
+	"wx_channel/internal/database"
+)
+
+func setupCommandGuardTest(t *testing.T, cfg *config.Config) *Connector {
+	t.Helper()
+	if err := database.Initialize(&database.Config{DBPath: filepath.Join(t.TempDir(), "records.db")}); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	c := &Connector{cfg: cfg, clientID: "client-1", commands: newManagementCommands(cfg)}
+	c.guard = newCommandGuard(cfg, c.commands.actions())
+	return c
+}
+
+func signedCommand(secret, id, action, data string, ts int64) CloudMessage {
+	msg := CloudMessage{
+		ID:        id,
+		Type:      MsgTypeCommand,
+		ClientID:  "client-1",
+		Payload:   []byte(`{"action":"` + action + `","data":` + data + `}`),
+		Timestamp: ts,
+	}
+	(&commandGuard{secret: secret}).sign(&msg)
+	return msg
+}
+
+func auditReasons(t *testing.T) []string {
+	t.Helper()
+	entries, err := database.NewCloudAuditRepository().List(100)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	reasons := make([]string, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		reasons = append(reasons, entries[i].Reason)
+	}
+	return reasons
+}
+
+func TestCommandGuard_VerifySignature(t *testing.T) {
+	c := setupCommandGuardTest(t, &config.Config{CloudSecret: "s3cret"})
+	now := time.Now()
+	c.guard.now = func() time.Time { return now }
+
+	msg := signedCommand("s3cret", "cmd-1", "radar_list", "null", now.Unix())
+	if err := c.guard.verify(msg); err != nil {
+		t.Fatalf("expected valid signature, got %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		msg    CloudMessage
+		reason string
+	}{
+		{"replayed nonce", msg, RejectReplayedNonce},
+		{"unsigned", CloudMessage{ID: "cmd-2", Type: MsgTypeCommand, Timestamp: now.Unix()}, RejectMissingSignature},
+		{"wrong secret", signedCommand("other", "cmd-3", "radar_list", "null", now.Unix()), RejectBadSignature},
+		{"stale", signedCommand("s3cret", "cmd-4", "radar_list", "null", now.Add(-10*time.Minute).Unix()), RejectStaleTimestamp},
+	}
+	tampered := signedCommand("s3cret", "cmd-5", "radar_list", "null", now.Unix())
+	tampered.Payload = []byte(`{"action":"radar_remove","data":{"id":"x"}}`)
+	cases = append(cases, struct {
+		name   string
+		msg    CloudMessage
+		reason string
+	}{"tampered payload", tampered, RejectBadSignature})
+
+	for _, tc := range cases {
+		err := c.guard.verify(tc.msg)
+		rej, ok := err.(*rejection)
+		if !ok || rej.Reason != tc.reason {
+			t.Errorf("%s: expected %s, got %v", tc.name, tc.reason, err)
+		}
+	}
+
+	// 过期 nonce 被清理后不影响新消息
+	now = now.Add(time.Hour)
+	if err := c.guard.verify(signedCommand("s3cret", "cmd-6", "radar_list", "null", now.Unix())); err != nil {
+		t.Fatalf("expected valid signature after clock advance, got %v", err)
+	}
+	if len(c.guard.nonces) != 1 {
+		t.Fatalf("expected expired nonces to be pruned, got %d", len(c.guard.nonces))
+	}
+}
+
+func TestConnector_RejectsAndAuditsCommands(t *testing.T) {
+	cfg := &config.Config{
+		CloudSecret:  "s3cret",
+		CloudCommand: config.CloudCommandConfig{AllowedActions: []string{"radar_list", "api_call"}},
+	}
+	c := setupCommandGuardTest(t, cfg)
+	now := time.Now().Unix()
+
+	// 未签名、不在白名单的指令和页面接口都被拒绝并记录
+	c.processMessage(CloudMessage{ID: "cmd-1", Type: MsgTypeCommand, Payload: []byte(`{"action":"radar_list"}`), Timestamp: now})
+	c.processMessage(signedCommand("s3cret", "cmd-2", "queue_remove", `{"ids":["a"]}`, now))
+	c.processMessage(signedCommand("s3cret", "cmd-3", "api_call", `{"key":"key:channels:delete_feed","body":{}}`, now))
+	c.processMessage(signedCommand("s3cret", "cmd-4", "radar_list", "null", now))
+
+	reasons := auditReasons(t)
+	expected := []string{RejectMissingSignature, RejectActionNotAllowed, RejectAPIKeyNotAllowed}
+	if len(reasons) != len(expected) {
+		t.Fatalf("expected audit reasons %v, got %v", expected, reasons)
+	}
+	for i := range expected {
+		if reasons[i] != expected[i] {
+			t.Fatalf("expected audit reasons %v, got %v", expected, reasons)
+		}
+	}
+
+	entries, _ := database.NewCloudAuditRepository().List(1)
+	if entries[0].MessageID != "cmd-3" || entries[0].APIKey != "key:channels:delete_feed" {
+		t.Fatalf("unexpected audit entry: %+v", entries[0])
+	}
+}
+
+func TestCommandGuard_DefaultAllowlist(t *testing.T) {
+	c := setupCommandGuardTest(t, &config.Config{})
+	for _, action := range []string{"queue_list", "radar_add", "search_videos", "download_video", "api_call"} {
+		if err := c.guard.checkAction(action); err != nil {
+			t.Errorf("expected %s to be allowed: %v", action, err)
+		}
+	}
+	if err := c.guard.checkAction("shell_exec"); err == nil {
+		t.Errorf("expected unknown action to be rejected")
+	}
+	if err := c.guard.checkAPIKey("key:channels:contact_list"); err != nil {
+		t.Errorf("expected contact_list to be allowed: %v", err)
+	}
+	if err := c.guard.checkAPIKey("key:channels:unknown"); err == nil {
+		t.Errorf("expected unknown api key to be rejected")
+	}
+
+	// 未配置密钥时不校验签名
+	if err := c.guard.verify(CloudMessage{ID: "cmd-1", Type: MsgTypeCommand}); err != nil {
+		t.Errorf("expected unsigned message to pass without secret: %v", err)
+	}
+}
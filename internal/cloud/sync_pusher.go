@@ -1,48 +1,75 @@
 package cloud
 
 import (
-	"encoding/json"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"wx_channel/internal/database"
 	"wx_channel/internal/utils"
+
+	json "github.com/json-iterator/go"
+)
+
+const (
+	// syncAckTimeout 批次发送后等待 Hub 确认的时间，超时后重发
+	syncAckTimeout = 2 * time.Minute
+	// syncBatchPrefix 同步批次 ID 前缀，用于识别 Hub 的确认响应
+	syncBatchPrefix = "sync-"
+	// syncMaxBatchSize 单批最多记录数，与仓库查询的上限一致
+	syncMaxBatchSize = 1000
 )
 
+// syncTypes 按顺序推送的同步类型
+var syncTypes = []string{"browse", "download"}
+
 // SyncPusher 同步数据推送器
+// 游标保存在 SQLite 中，只有 Hub 通过 ResponsePayload 确认批次后才前进；
+// 每种类型同一时间只有一个待确认批次，重连后重放未确认的批次
 type SyncPusher struct {
-	connector         *Connector
-	browseRepo        *database.BrowseHistoryRepository
-	downloadRepo      *database.DownloadRecordRepository
-	syncInterval      time.Duration
-	lastBrowseSync    time.Time
-	lastDownloadSync  time.Time
-	running           bool
-	stopChan          chan struct{}
-	batchSize         int
+	connector    *Connector
+	send         func(msg CloudMessage) error
+	browseRepo   *database.BrowseHistoryRepository
+	downloadRepo *database.DownloadRecordRepository
+	syncRepo     *database.SyncRepository
+	syncInterval time.Duration
+	ackTimeout   time.Duration
+	running      bool
+	stopChan     chan struct{}
+	wake         chan struct{}
+	batchSize    int
+
+	mu           sync.Mutex // 串行化推送和确认
+	replay       bool       // 下次推送时立即重发未确认的批次
+	replayBefore time.Time  // 只重放在此之前发送的批次，之后发送的已由待发送队列补发
 }
 
 // NewSyncPusher 创建同步推送器
 func NewSyncPusher(connector *Connector) *SyncPusher {
 	cfg := connector.cfg
-	
+
 	// 从配置读取同步间隔和批量大小
 	syncInterval := cfg.HubSync.PushInterval
 	if syncInterval == 0 {
 		syncInterval = 5 * time.Minute // 默认5分钟
 	}
-	
+
 	batchSize := cfg.HubSync.PushBatchSize
-	if batchSize == 0 {
-		batchSize = 1000 // 默认1000条
+	if batchSize <= 0 || batchSize > syncMaxBatchSize {
+		batchSize = syncMaxBatchSize // 默认1000条
 	}
-	
+
 	return &SyncPusher{
 		connector:    connector,
+		send:         connector.deliver,
 		browseRepo:   database.NewBrowseHistoryRepository(),
 		downloadRepo: database.NewDownloadRecordRepository(),
+		syncRepo:     database.NewSyncRepository(),
 		syncInterval: syncInterval,
+		ackTimeout:   syncAckTimeout,
 		stopChan:     make(chan struct{}),
+		wake:         make(chan struct{}, 1),
 		batchSize:    batchSize,
 	}
 }
@@ -57,16 +84,18 @@ func (sp *SyncPusher) Start() {
 	utils.LogInfo("[SyncPusher] 启动同步推送器 (间隔: %v)", sp.syncInterval)
 
 	// 立即执行一次同步
-	go sp.pushSyncData()
+	sp.pushSyncData()
 
-	// 定时推送
+	// 定时推送；收到确认且还有更多数据、或重连后立即推送
 	ticker := time.NewTicker(sp.syncInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
-			go sp.pushSyncData()
+			sp.pushSyncData()
+		case <-sp.wake:
+			sp.pushSyncData()
 		case <-sp.stopChan:
 			utils.LogInfo("[SyncPusher] 停止同步推送器")
 			sp.running = false
@@ -83,117 +112,198 @@ func (sp *SyncPusher) Stop() {
 	close(sp.stopChan)
 }
 
-// pushSyncData 推送同步数据
-func (sp *SyncPusher) pushSyncData() {
-	// 推送浏览记录
-	if err := sp.pushBrowseHistory(); err != nil {
-		utils.LogWarn("[SyncPusher] 推送浏览记录失败: %v", err)
+// Wake 触发一次立即推送
+func (sp *SyncPusher) Wake() {
+	select {
+	case sp.wake <- struct{}{}:
+	default:
 	}
+}
 
-	// 推送下载记录
-	if err := sp.pushDownloadRecords(); err != nil {
-		utils.LogWarn("[SyncPusher] 推送下载记录失败: %v", err)
-	}
+// Reconnected 连接建立后调用，立即重放未确认的批次
+func (sp *SyncPusher) Reconnected() {
+	sp.mu.Lock()
+	sp.replay = true
+	sp.replayBefore = time.Now()
+	sp.mu.Unlock()
+	sp.Wake()
 }
 
-// pushBrowseHistory 推送浏览记录
-func (sp *SyncPusher) pushBrowseHistory() error {
-	// 获取增量数据
-	records, err := sp.browseRepo.GetRecordsSince(sp.lastBrowseSync, sp.batchSize)
-	if err != nil {
-		return fmt.Errorf("获取浏览记录失败: %w", err)
+// markSent 记录批次已由待发送队列补发
+func (sp *SyncPusher) markSent(batchID string) {
+	if err := sp.syncRepo.MarkBatchSent(batchID, time.Now()); err != nil {
+		utils.LogWarn("[SyncPusher] 更新批次发送时间失败: %v", err)
 	}
+}
 
-	if len(records) == 0 {
-		return nil
+// HandleAck 处理 Hub 对同步批次的确认，返回该响应是否属于同步批次
+func (sp *SyncPusher) HandleAck(resp ResponsePayload) bool {
+	if !strings.HasPrefix(resp.RequestID, syncBatchPrefix) {
+		return false
+	}
+	if !resp.Success {
+		utils.LogWarn("[SyncPusher] Hub 拒绝同步批次 %s: %s，将在 %v 后重发", resp.RequestID, resp.Error, sp.ackTimeout)
+		return true
 	}
 
-	// 构建消息
-	recordsJSON, err := json.Marshal(records)
+	sp.mu.Lock()
+	batch, err := sp.syncRepo.AckBatch(resp.RequestID)
+	sp.mu.Unlock()
 	if err != nil {
-		return fmt.Errorf("序列化浏览记录失败: %w", err)
+		utils.LogWarn("[SyncPusher] 处理同步确认失败: %v", err)
+		return true
 	}
-
-	payload := SyncDataPayload{
-		SyncType: "browse",
-		Records:  recordsJSON,
-		Count:    len(records),
-		HasMore:  len(records) >= sp.batchSize,
+	if batch == nil {
+		return true // 重复确认
 	}
 
-	payloadJSON, err := json.Marshal(payload)
-	if err != nil {
-		return fmt.Errorf("序列化同步载荷失败: %w", err)
+	utils.LogInfo("[SyncPusher] Hub 已确认 %d 条%s记录", batch.Count, syncTypeName(batch.SyncType))
+	if batch.HasMore {
+		sp.Wake()
 	}
+	return true
+}
 
-	// 发送消息
-	msg := CloudMessage{
-		ID:        fmt.Sprintf("sync-browse-%d", time.Now().UnixNano()),
-		Type:      MsgTypeSyncData,
-		ClientID:  sp.connector.clientID,
-		Payload:   payloadJSON,
-		Timestamp: time.Now().Unix(),
+// pushSyncData 推送同步数据
+func (sp *SyncPusher) pushSyncData() {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	replay := sp.replay
+	sp.replay = false
+	for _, syncType := range syncTypes {
+		if err := sp.pushType(syncType, replay); err != nil {
+			utils.LogWarn("[SyncPusher] 推送%s记录失败: %v", syncTypeName(syncType), err)
+		}
 	}
+}
 
-	if err := sp.connector.send(msg); err != nil {
-		return fmt.Errorf("发送浏览记录失败: %w", err)
+// pushType 推送一种类型的下一批记录
+// 存在未确认批次时不生成新批次，仅在需要重放或等待确认超时时重发
+func (sp *SyncPusher) pushType(syncType string, replay bool) error {
+	pending, err := sp.syncRepo.GetPendingBatch(syncType)
+	if err != nil {
+		return err
+	}
+	if pending != nil {
+		if pending.SentAt == nil || time.Since(*pending.SentAt) >= sp.ackTimeout ||
+			(replay && pending.SentAt.Before(sp.replayBefore)) {
+			return sp.sendBatch(pending)
+		}
+		return nil
 	}
 
-	// 更新最后同步时间
-	sp.lastBrowseSync = time.Now()
-	utils.LogInfo("[SyncPusher] 推送 %d 条浏览记录", len(records))
-
-	return nil
+	cursor, err := sp.syncRepo.GetCursor(syncType)
+	if err != nil {
+		return err
+	}
+	batch, err := sp.buildBatch(syncType, cursor)
+	if err != nil || batch == nil {
+		return err
+	}
+	if err := sp.syncRepo.AddBatch(batch); err != nil {
+		return err
+	}
+	return sp.sendBatch(batch)
 }
 
-// pushDownloadRecords 推送下载记录
-func (sp *SyncPusher) pushDownloadRecords() error {
-	// 获取增量数据
-	records, err := sp.downloadRepo.GetRecordsSince(sp.lastDownloadSync, sp.batchSize)
-	if err != nil {
-		return fmt.Errorf("获取下载记录失败: %w", err)
+// buildBatch 读取游标之后的记录并生成批次，没有新记录时返回 nil
+func (sp *SyncPusher) buildBatch(syncType string, cursor *database.SyncCursor) (*database.SyncBatch, error) {
+	now := time.Now()
+	batch := &database.SyncBatch{
+		ID:        fmt.Sprintf("%s%s-%d", syncBatchPrefix, syncType, now.UnixNano()),
+		SyncType:  syncType,
+		CreatedAt: now,
 	}
 
-	if len(records) == 0 {
-		return nil
+	var records interface{}
+	switch syncType {
+	case "browse":
+		list, err := sp.browseRepo.GetRecordsAfter(cursor.Time, cursor.ID, sp.batchSize)
+		if err != nil {
+			return nil, fmt.Errorf("获取浏览记录失败: %w", err)
+		}
+		if len(list) == 0 {
+			return nil, nil
+		}
+		last := list[len(list)-1]
+		batch.Count, batch.CursorTime, batch.CursorID = len(list), last.UpdatedAt, last.ID
+		records = list
+	case "download":
+		list, err := sp.downloadRepo.GetRecordsAfter(cursor.Time, cursor.ID, sp.batchSize)
+		if err != nil {
+			return nil, fmt.Errorf("获取下载记录失败: %w", err)
+		}
+		if len(list) == 0 {
+			return nil, nil
+		}
+		last := list[len(list)-1]
+		batch.Count, batch.CursorTime, batch.CursorID = len(list), last.UpdatedAt, last.ID
+		records = list
+	default:
+		return nil, fmt.Errorf("unknown sync type: %s", syncType)
 	}
 
-	// 构建消息
 	recordsJSON, err := json.Marshal(records)
 	if err != nil {
-		return fmt.Errorf("序列化下载记录失败: %w", err)
+		return nil, fmt.Errorf("序列化%s记录失败: %w", syncTypeName(syncType), err)
 	}
+	batch.HasMore = batch.Count >= sp.batchSize
 
 	payload := SyncDataPayload{
-		SyncType: "download",
+		SyncType: syncType,
+		BatchID:  batch.ID,
 		Records:  recordsJSON,
-		Count:    len(records),
-		HasMore:  len(records) >= sp.batchSize,
+		Count:    batch.Count,
+		HasMore:  batch.HasMore,
 	}
-
 	payloadJSON, err := json.Marshal(payload)
 	if err != nil {
-		return fmt.Errorf("序列化同步载荷失败: %w", err)
+		return nil, fmt.Errorf("序列化同步载荷失败: %w", err)
 	}
+	batch.Payload = string(payloadJSON)
+	return batch, nil
+}
 
-	// 发送消息
+// sendBatch 发送批次，消息 ID 与批次 ID 相同，重放时 Hub 可据此去重
+func (sp *SyncPusher) sendBatch(batch *database.SyncBatch) error {
 	msg := CloudMessage{
-		ID:        fmt.Sprintf("sync-download-%d", time.Now().UnixNano()),
+		ID:        batch.ID,
 		Type:      MsgTypeSyncData,
-		ClientID:  sp.connector.clientID,
-		Payload:   payloadJSON,
+		ClientID:  sp.clientID(),
+		Payload:   json.RawMessage(batch.Payload),
 		Timestamp: time.Now().Unix(),
 	}
+	if err := sp.send(msg); err != nil {
+		return fmt.Errorf("发送%s记录失败: %w", syncTypeName(batch.SyncType), err)
+	}
+	if err := sp.syncRepo.MarkBatchSent(batch.ID, time.Now()); err != nil {
+		return err
+	}
 
-	if err := sp.connector.send(msg); err != nil {
-		return fmt.Errorf("发送下载记录失败: %w", err)
+	if batch.Attempts > 0 {
+		utils.LogInfo("[SyncPusher] 重发 %d 条%s记录 (第 %d 次)", batch.Count, syncTypeName(batch.SyncType), batch.Attempts+1)
+	} else {
+		utils.LogInfo("[SyncPusher] 推送 %d 条%s记录", batch.Count, syncTypeName(batch.SyncType))
 	}
+	return nil
+}
 
-	// 更新最后同步时间
-	sp.lastDownloadSync = time.Now()
-	utils.LogInfo("[SyncPusher] 推送 %d 条下载记录", len(records))
+func (sp *SyncPusher) clientID() string {
+	if sp.connector == nil {
+		return ""
+	}
+	return sp.connector.clientID
+}
 
-	return nil
+func syncTypeName(syncType string) string {
+	switch syncType {
+	case "browse":
+		return "浏览"
+	case "download":
+		return "下载"
+	}
+	return syncType
 }
 
 // SetSyncInterval 设置同步间隔
@@ -203,5 +313,8 @@ func (sp *SyncPusher) SetSyncInterval(interval time.Duration) {
 
 // SetBatchSize 设置批量大小
 func (sp *SyncPusher) SetBatchSize(size int) {
+	if size <= 0 || size > syncMaxBatchSize {
+		size = syncMaxBatchSize
+	}
 	sp.batchSize = size
 }
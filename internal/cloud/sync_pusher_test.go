@@ -0,0 +1,119 @@
+package cloud
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"wx_channel/internal/config"
+	"wx_channel/internal/database"
+
+	json "github.com/json-iterator/go"
+)
+
+// recordingSender 记录 SyncPusher 发出的消息
+type recordingSender struct {
+	messages []CloudMessage
+}
+
+func (r *recordingSender) send(msg CloudMessage) error {
+	r.messages = append(r.messages, msg)
+	return nil
+}
+
+func (r *recordingSender) payload(t *testing.T, i int) SyncDataPayload {
+	t.Helper()
+	var payload SyncDataPayload
+	if err := json.Unmarshal(r.messages[i].Payload, &payload); err != nil {
+		t.Fatalf("unmarshal payload: %v", err)
+	}
+	return payload
+}
+
+func newTestSyncPusher(t *testing.T, sender *recordingSender) *SyncPusher {
+	t.Helper()
+	cfg := &config.Config{HubSync: config.HubSyncConfig{Enabled: true, PushEnabled: true, PushBatchSize: 2}}
+	sp := NewSyncPusher(&Connector{cfg: cfg, clientID: "client-1"})
+	sp.send = sender.send
+	return sp
+}
+
+func TestSyncPusher_AckAdvancesPersistedCursor(t *testing.T) {
+	if err := database.Initialize(&database.Config{DBPath: filepath.Join(t.TempDir(), "records.db")}); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	// 三条记录的更新时间相同，批次边界不能跳过记录
+	repo := database.NewDownloadRecordRepository()
+	for i := 1; i <= 3; i++ {
+		if err := repo.Create(&database.DownloadRecord{ID: fmt.Sprintf("r%d", i), VideoID: fmt.Sprintf("v%d", i), Status: "completed"}); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+	if _, err := database.GetDB().Exec("UPDATE download_records SET updated_at = ?", time.Now()); err != nil {
+		t.Fatalf("update timestamps: %v", err)
+	}
+
+	sender := &recordingSender{}
+	sp := newTestSyncPusher(t, sender)
+	sp.pushSyncData()
+	if len(sender.messages) != 1 {
+		t.Fatalf("expected 1 batch, got %d", len(sender.messages))
+	}
+	first := sender.payload(t, 0)
+	if first.SyncType != "download" || first.Count != 2 || !first.HasMore || first.BatchID != sender.messages[0].ID {
+		t.Fatalf("unexpected first batch: %+v", first)
+	}
+
+	// 未确认前不推送新批次，也不在超时前重发
+	sp.pushSyncData()
+	if len(sender.messages) != 1 {
+		t.Fatalf("expected no new batch before ack, got %d messages", len(sender.messages))
+	}
+
+	// 模拟重启：新的推送器在重连后重放同一批次
+	sp = newTestSyncPusher(t, sender)
+	sp.Reconnected()
+	sp.pushSyncData()
+	if len(sender.messages) != 2 || sender.messages[1].ID != sender.messages[0].ID {
+		t.Fatalf("expected replay of the unacked batch, got %d messages", len(sender.messages))
+	}
+
+	if sp.HandleAck(ResponsePayload{RequestID: "resp-other", Success: true}) {
+		t.Fatalf("non-sync response should not be handled as an ack")
+	}
+	if !sp.HandleAck(ResponsePayload{RequestID: first.BatchID, Success: true}) {
+		t.Fatalf("expected sync ack to be handled")
+	}
+	select {
+	case <-sp.wake:
+	default:
+		t.Fatalf("expected immediate push after ack with has_more")
+	}
+
+	cursor, err := database.NewSyncRepository().GetCursor("download")
+	if err != nil || cursor.ID != "r2" {
+		t.Fatalf("expected cursor at r2, got %+v (%v)", cursor, err)
+	}
+
+	sp.pushSyncData()
+	if len(sender.messages) != 3 {
+		t.Fatalf("expected second batch, got %d messages", len(sender.messages))
+	}
+	second := sender.payload(t, 2)
+	var records []database.DownloadRecord
+	if err := json.Unmarshal(second.Records, &records); err != nil {
+		t.Fatalf("unmarshal records: %v", err)
+	}
+	if second.Count != 1 || second.HasMore || len(records) != 1 || records[0].ID != "r3" {
+		t.Fatalf("unexpected second batch: %+v", second)
+	}
+
+	sp.HandleAck(ResponsePayload{RequestID: second.BatchID, Success: true})
+	sp.pushSyncData()
+	if len(sender.messages) != 3 {
+		t.Fatalf("expected nothing left to sync, got %d messages", len(sender.messages))
+	}
+}
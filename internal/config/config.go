@@ -74,6 +74,9 @@ type Config struct {
 	MachineID    string `mapstructure:"machine_id"`    // 机器学习 ID (用于在云端唯一标识此实例)
 	BindToken    string `mapstructure:"bind_token"`    // 临时绑定码
 
+	// 云端指令安全配置
+	CloudCommand CloudCommandConfig `mapstructure:"cloud_command"`
+
 	// 第二阶段优化配置
 	LoadBalancerStrategy string `mapstructure:"load_balancer_strategy"` // 负载均衡策略: roundrobin, leastconn, weighted, random
 	CompressionEnabled   bool   `mapstructure:"compression_enabled"`    // 是否启用数据压缩
@@ -96,6 +99,14 @@ type HubSyncConfig struct {
 	PushBatchSize int           `mapstructure:"push_batch_size"` // 推送批量大小
 }
 
+// CloudCommandConfig 云端指令安全配置
+// 配置 cloud_secret 后，收发的每条消息都使用 HMAC-SHA256 签名并校验时间戳和 nonce
+type CloudCommandConfig struct {
+	MaxClockSkew   time.Duration `mapstructure:"max_clock_skew"`   // 消息时间戳允许的最大偏差
+	AllowedActions []string      `mapstructure:"allowed_actions"`  // 允许执行的指令，为空时使用内置列表
+	AllowedAPIKeys []string      `mapstructure:"allowed_api_keys"` // 允许调用的页面接口，为空时使用内置列表
+}
+
 var globalConfig *Config
 
 // DefaultCloudHubURL is the local Hub endpoint used when no endpoint is
@@ -239,6 +250,7 @@ func setDefaults() {
 	viper.SetDefault("cloud_hub_url", DefaultCloudHubURL)
 	viper.SetDefault("cloud_secret", "")
 	viper.SetDefault("machine_id", GetMachineID())
+	viper.SetDefault("cloud_command.max_clock_skew", 5*time.Minute)
 
 	// 第二阶段优化默认值
 	viper.SetDefault("load_balancer_strategy", "leastconn")
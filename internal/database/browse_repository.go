@@ -22,9 +22,23 @@ func (r *BrowseHistoryRepository) Create(record *BrowseRecord) error {
 	now := time.Now()
 	record.CreatedAt = now
 	record.UpdatedAt = now
+	return r.insert("INSERT", record)
+}
 
-	query := `
-		INSERT INTO browse_history (
+// Import 写入从其他机器导入的浏览记录，保留原有的创建和更新时间，ID 相同的记录被替换
+func (r *BrowseHistoryRepository) Import(record *BrowseRecord) error {
+	now := time.Now()
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = now
+	}
+	if record.UpdatedAt.IsZero() {
+		record.UpdatedAt = record.CreatedAt
+	}
+	return r.insert("INSERT OR REPLACE", record)
+}
+
+func (r *BrowseHistoryRepository) insert(verb string, record *BrowseRecord) error {
+	query := verb + ` INTO browse_history (
 			id, title, author, author_id, duration, size, resolution, cover_url, video_url,
 			decrypt_key, browse_time, like_count, comment_count, fav_count, forward_count, page_url,
 			created_at, updated_at
@@ -434,8 +448,9 @@ func (r *BrowseHistoryRepository) GetByIDs(ids []string) ([]BrowseRecord, error)
 	return records, nil
 }
 
-// GetRecordsSince 获取指定时间之后的浏览记录（用于增量同步）
-func (r *BrowseHistoryRepository) GetRecordsSince(since time.Time, limit int) ([]BrowseRecord, error) {
+// GetRecordsAfter 获取 (updated_at, id) 位于游标之后的浏览记录，按同样顺序返回（用于增量同步）
+// 以 id 作为同一时间戳的次序，批次边界上更新时间相同的记录不会被跳过
+func (r *BrowseHistoryRepository) GetRecordsAfter(since time.Time, afterID string, limit int) ([]BrowseRecord, error) {
 	if limit < 1 {
 		limit = 100
 	}
@@ -449,12 +464,12 @@ func (r *BrowseHistoryRepository) GetRecordsSince(since time.Time, limit int) ([
 			COALESCE(fav_count, 0) as fav_count, COALESCE(forward_count, 0) as forward_count, page_url,
 			created_at, updated_at
 		FROM browse_history
-		WHERE updated_at > ?
-		ORDER BY updated_at ASC
+		WHERE updated_at > ? OR (updated_at = ? AND id > ?)
+		ORDER BY updated_at ASC, id ASC
 		LIMIT ?
 	`
 
-	rows, err := r.db.Query(query, since, limit)
+	rows, err := r.db.Query(query, since, since, afterID, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get records since: %w", err)
 	}
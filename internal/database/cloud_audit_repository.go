@@ -0,0 +1,69 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// CloudAuditRepository 处理云端指令审计记录的数据库操作
+type CloudAuditRepository struct {
+	db *sql.DB
+}
+
+// NewCloudAuditRepository 创建一个新的 CloudAuditRepository
+func NewCloudAuditRepository() *CloudAuditRepository {
+	return &CloudAuditRepository{db: GetDB()}
+}
+
+// Add 记录一条被拒绝的云端消息
+func (r *CloudAuditRepository) Add(entry *CloudCommandAudit) error {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	result, err := r.db.Exec(`
+		INSERT INTO cloud_command_audit (
+			message_id, message_type, action, api_key, reason, detail, message_time, created_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.MessageID, entry.MessageType, entry.Action, entry.APIKey,
+		entry.Reason, entry.Detail, entry.MessageTime, entry.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add cloud command audit: %w", err)
+	}
+	entry.ID, _ = result.LastInsertId()
+	return nil
+}
+
+// List 返回最近的审计记录，按时间倒序
+func (r *CloudAuditRepository) List(limit int) ([]CloudCommandAudit, error) {
+	rows, err := r.db.Query(`
+		SELECT id, message_id, message_type, action, api_key, reason, detail, message_time, created_at
+		FROM cloud_command_audit
+		ORDER BY created_at DESC, id DESC
+		LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cloud command audit: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []CloudCommandAudit
+	for rows.Next() {
+		var e CloudCommandAudit
+		if err := rows.Scan(&e.ID, &e.MessageID, &e.MessageType, &e.Action, &e.APIKey,
+			&e.Reason, &e.Detail, &e.MessageTime, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan cloud command audit: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// DeleteBefore 删除指定时间之前的审计记录
+func (r *CloudAuditRepository) DeleteBefore(before time.Time) (int64, error) {
+	result, err := r.db.Exec("DELETE FROM cloud_command_audit WHERE created_at < ?", before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete cloud command audit: %w", err)
+	}
+	return result.RowsAffected()
+}
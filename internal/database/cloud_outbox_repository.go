@@ -0,0 +1,110 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// CloudOutboxRepository 处理云端待发送消息的数据库操作
+type CloudOutboxRepository struct {
+	db *sql.DB
+}
+
+// NewCloudOutboxRepository 创建一个新的 CloudOutboxRepository
+func NewCloudOutboxRepository() *CloudOutboxRepository {
+	return &CloudOutboxRepository{db: GetDB()}
+}
+
+// Enqueue 保存待发送消息，消息 ID 已存在时覆盖内容并保留原有顺序
+// 超过 maxMessages 时丢弃最早的消息，返回丢弃的条数
+func (r *CloudOutboxRepository) Enqueue(msg *CloudOutboxMessage, maxMessages int) (int64, error) {
+	if msg.CreatedAt.IsZero() {
+		msg.CreatedAt = time.Now()
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO cloud_outbox (message_id, message_type, message, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(message_id) DO UPDATE SET
+			message = excluded.message,
+			expires_at = excluded.expires_at`,
+		msg.MessageID, msg.MessageType, msg.Message, msg.CreatedAt, msg.ExpiresAt,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue outbox message: %w", err)
+	}
+
+	var dropped int64
+	if maxMessages > 0 {
+		result, err := tx.Exec(`
+			DELETE FROM cloud_outbox
+			WHERE seq NOT IN (SELECT seq FROM cloud_outbox ORDER BY seq DESC LIMIT ?)`,
+			maxMessages,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("failed to trim outbox: %w", err)
+		}
+		dropped, _ = result.RowsAffected()
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return dropped, nil
+}
+
+// List 按入队顺序返回待发送消息
+func (r *CloudOutboxRepository) List(limit int) ([]CloudOutboxMessage, error) {
+	rows, err := r.db.Query(`
+		SELECT seq, message_id, message_type, message, created_at, expires_at
+		FROM cloud_outbox
+		ORDER BY seq ASC
+		LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list outbox messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []CloudOutboxMessage
+	for rows.Next() {
+		var m CloudOutboxMessage
+		if err := rows.Scan(&m.Seq, &m.MessageID, &m.MessageType, &m.Message, &m.CreatedAt, &m.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox message: %w", err)
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+// Delete 删除已发送的消息
+func (r *CloudOutboxRepository) Delete(messageID string) error {
+	if _, err := r.db.Exec("DELETE FROM cloud_outbox WHERE message_id = ?", messageID); err != nil {
+		return fmt.Errorf("failed to delete outbox message: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired 删除已过期的消息
+func (r *CloudOutboxRepository) DeleteExpired(now time.Time) (int64, error) {
+	result, err := r.db.Exec("DELETE FROM cloud_outbox WHERE expires_at <= ?", now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired outbox messages: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// Count 返回待发送消息数量
+func (r *CloudOutboxRepository) Count() (int64, error) {
+	var count int64
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM cloud_outbox").Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count outbox messages: %w", err)
+	}
+	return count, nil
+}
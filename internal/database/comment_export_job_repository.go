@@ -0,0 +1,113 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CommentExportJobRepository 处理评论导出任务的数据库操作
+type CommentExportJobRepository struct {
+	db *sql.DB
+}
+
+// NewCommentExportJobRepository 创建一个新的 CommentExportJobRepository
+func NewCommentExportJobRepository() *CommentExportJobRepository {
+	return &CommentExportJobRepository{db: GetDB()}
+}
+
+const commentExportJobColumns = `id, object_id, title, request, status, progress, result, error, created_at, updated_at`
+
+// Save 写入任务的当前状态，已存在时覆盖
+func (r *CommentExportJobRepository) Save(job *CommentExportJob) error {
+	_, err := r.db.Exec(`
+		INSERT INTO comment_export_jobs (`+commentExportJobColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			request = excluded.request,
+			status = excluded.status,
+			progress = excluded.progress,
+			result = excluded.result,
+			error = excluded.error,
+			updated_at = excluded.updated_at`,
+		job.ID, job.ObjectID, job.Title, job.Request, job.Status, job.Progress, job.Result, job.Error,
+		job.CreatedAt, job.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save comment export job: %w", err)
+	}
+	return nil
+}
+
+// GetByID 获取任务，不存在时返回 nil
+func (r *CommentExportJobRepository) GetByID(id string) (*CommentExportJob, error) {
+	jobs, err := r.query(`SELECT `+commentExportJobColumns+` FROM comment_export_jobs WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, nil
+	}
+	return &jobs[0], nil
+}
+
+// List 返回最近创建的任务
+func (r *CommentExportJobRepository) List(limit int) ([]CommentExportJob, error) {
+	return r.query(`SELECT `+commentExportJobColumns+` FROM comment_export_jobs ORDER BY created_at DESC, id DESC LIMIT ?`, limit)
+}
+
+// ListByStatus 按创建时间返回指定状态的任务
+func (r *CommentExportJobRepository) ListByStatus(statuses ...string) ([]CommentExportJob, error) {
+	if len(statuses) == 0 {
+		return []CommentExportJob{}, nil
+	}
+	placeholders := make([]string, len(statuses))
+	args := make([]interface{}, len(statuses))
+	for i, status := range statuses {
+		placeholders[i] = "?"
+		args[i] = status
+	}
+	query := fmt.Sprintf(`SELECT `+commentExportJobColumns+` FROM comment_export_jobs WHERE status IN (%s) ORDER BY created_at ASC`,
+		strings.Join(placeholders, ","))
+	return r.query(query, args...)
+}
+
+// DeleteUpdatedBefore 删除指定状态中在 before 之前更新过的任务
+func (r *CommentExportJobRepository) DeleteUpdatedBefore(before time.Time, statuses ...string) (int64, error) {
+	if len(statuses) == 0 {
+		return 0, nil
+	}
+	placeholders := make([]string, len(statuses))
+	args := make([]interface{}, 0, len(statuses)+1)
+	args = append(args, before)
+	for i, status := range statuses {
+		placeholders[i] = "?"
+		args = append(args, status)
+	}
+	query := fmt.Sprintf("DELETE FROM comment_export_jobs WHERE updated_at < ? AND status IN (%s)", strings.Join(placeholders, ","))
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete comment export jobs: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func (r *CommentExportJobRepository) query(query string, args ...interface{}) ([]CommentExportJob, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list comment export jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []CommentExportJob{}
+	for rows.Next() {
+		var job CommentExportJob
+		if err := rows.Scan(&job.ID, &job.ObjectID, &job.Title, &job.Request, &job.Status, &job.Progress, &job.Result,
+			&job.Error, &job.CreatedAt, &job.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan comment export job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
@@ -0,0 +1,273 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// CommentRepository 处理导入评论的数据库操作
+type CommentRepository struct {
+	db *sql.DB
+}
+
+// NewCommentRepository 创建一个新的 CommentRepository
+func NewCommentRepository() *CommentRepository {
+	return &CommentRepository{db: GetDB()}
+}
+
+// UpsertMany 按 (object_id, comment_id) 导入评论，已存在的评论更新内容和点赞数
+func (r *CommentRepository) UpsertMany(comments []Comment) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO comments (
+			object_id, comment_id, parent_id, reply_comment_id, level, username, nickname, content, region,
+			like_count, comment_time, video_title, video_author, exported_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(object_id, comment_id) DO UPDATE SET
+			parent_id = excluded.parent_id,
+			reply_comment_id = excluded.reply_comment_id,
+			level = excluded.level,
+			username = excluded.username,
+			nickname = excluded.nickname,
+			content = excluded.content,
+			region = excluded.region,
+			like_count = excluded.like_count,
+			comment_time = excluded.comment_time,
+			video_title = excluded.video_title,
+			video_author = excluded.video_author,
+			exported_at = excluded.exported_at`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare comment upsert: %w", err)
+	}
+	defer stmt.Close()
+
+	now := time.Now()
+	for i := range comments {
+		c := &comments[i]
+		if c.ExportedAt.IsZero() {
+			c.ExportedAt = now
+		}
+		_, err := stmt.Exec(c.ObjectID, c.CommentID, c.ParentID, c.ReplyCommentID, c.Level, c.Username, c.Nickname,
+			c.Content, c.Region, c.LikeCount, nullableTime(c.CommentTime), c.VideoTitle, c.VideoAuthor, c.ExportedAt)
+		if err != nil {
+			return 0, fmt.Errorf("failed to upsert comment %s: %w", c.CommentID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return len(comments), nil
+}
+
+func scanComment(rows *sql.Rows) (*Comment, error) {
+	var c Comment
+	var commentTime sql.NullTime
+	if err := rows.Scan(&c.ID, &c.ObjectID, &c.CommentID, &c.ParentID, &c.ReplyCommentID, &c.Level, &c.Username,
+		&c.Nickname, &c.Content, &c.Region, &c.LikeCount, &commentTime, &c.VideoTitle, &c.VideoAuthor, &c.ExportedAt); err != nil {
+		return nil, err
+	}
+	if commentTime.Valid {
+		c.CommentTime = &commentTime.Time
+	}
+	return &c, nil
+}
+
+// commentSortColumns 允许排序的列
+var commentSortColumns = map[string]string{
+	"comment_time": "comment_time",
+	"like_count":   "like_count",
+	"exported_at":  "exported_at",
+}
+
+// List 按条件分页查询评论，默认按评论时间倒序
+func (r *CommentRepository) List(filter *CommentFilter) (*PagedResult[Comment], error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 {
+		filter.PageSize = 20
+	}
+
+	var conditions []string
+	var args []interface{}
+	if filter.ObjectID != "" {
+		conditions = append(conditions, "object_id = ?")
+		args = append(args, filter.ObjectID)
+	}
+	if filter.ParentID != "" {
+		conditions = append(conditions, "parent_id = ?")
+		args = append(args, filter.ParentID)
+	}
+	if filter.Level > 0 {
+		conditions = append(conditions, "level = ?")
+		args = append(args, filter.Level)
+	}
+	if filter.Username != "" {
+		conditions = append(conditions, "username = ?")
+		args = append(args, filter.Username)
+	}
+	if filter.Query != "" {
+		conditions = append(conditions, "(content LIKE ? OR nickname LIKE ?)")
+		pattern := "%" + filter.Query + "%"
+		args = append(args, pattern, pattern)
+	}
+	if filter.MinLikes > 0 {
+		conditions = append(conditions, "like_count >= ?")
+		args = append(args, filter.MinLikes)
+	}
+	if filter.StartDate != nil {
+		conditions = append(conditions, "comment_time >= ?")
+		args = append(args, *filter.StartDate)
+	}
+	if filter.EndDate != nil {
+		conditions = append(conditions, "comment_time <= ?")
+		args = append(args, *filter.EndDate)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int64
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM comments "+where, args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count comments: %w", err)
+	}
+
+	sortColumn, ok := commentSortColumns[filter.SortBy]
+	if !ok {
+		sortColumn = "comment_time"
+	}
+	order := "DESC"
+	if !filter.SortDesc && filter.SortBy != "" {
+		order = "ASC"
+	}
+
+	rows, err := r.db.Query(`
+		SELECT id, object_id, comment_id, parent_id, reply_comment_id, level, username, nickname, content, region,
+			like_count, comment_time, video_title, video_author, exported_at
+		FROM comments `+where+`
+		ORDER BY `+sortColumn+` `+order+`, id ASC
+		LIMIT ? OFFSET ?`,
+		append(args, filter.PageSize, (filter.Page-1)*filter.PageSize)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list comments: %w", err)
+	}
+	defer rows.Close()
+
+	items := []Comment{}
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		items = append(items, *c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return NewPagedResult(items, total, filter.Page, filter.PageSize), nil
+}
+
+// ListVideos 按最近导入时间返回已导入评论的视频
+func (r *CommentRepository) ListVideos() ([]CommentVideoSummary, error) {
+	rows, err := r.db.Query(`
+		SELECT object_id, MAX(video_title), MAX(video_author),
+			SUM(CASE WHEN level = 1 THEN 1 ELSE 0 END), SUM(CASE WHEN level = 2 THEN 1 ELSE 0 END),
+			MAX(exported_at)
+		FROM comments
+		GROUP BY object_id
+		ORDER BY MAX(exported_at) DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list comment videos: %w", err)
+	}
+	defer rows.Close()
+
+	videos := []CommentVideoSummary{}
+	for rows.Next() {
+		var v CommentVideoSummary
+		var lastExported string
+		if err := rows.Scan(&v.ObjectID, &v.VideoTitle, &v.VideoAuthor, &v.CommentCount, &v.ReplyCount, &lastExported); err != nil {
+			return nil, fmt.Errorf("failed to scan comment video: %w", err)
+		}
+		v.LastExportedAt = parseSQLiteTime(lastExported)
+		videos = append(videos, v)
+	}
+	return videos, rows.Err()
+}
+
+// ListByObjectID 返回某个视频的全部评论，顶级评论在前，同级按评论时间倒序
+func (r *CommentRepository) ListByObjectID(objectID string) ([]Comment, error) {
+	rows, err := r.db.Query(`
+		SELECT id, object_id, comment_id, parent_id, reply_comment_id, level, username, nickname, content, region,
+			like_count, comment_time, video_title, video_author, exported_at
+		FROM comments
+		WHERE object_id = ?
+		ORDER BY level ASC, comment_time DESC, id ASC`, objectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list comments: %w", err)
+	}
+	defer rows.Close()
+
+	comments := []Comment{}
+	for rows.Next() {
+		c, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		comments = append(comments, *c)
+	}
+	return comments, rows.Err()
+}
+
+// DeleteByObjectID 删除某个视频的全部评论
+func (r *CommentRepository) DeleteByObjectID(objectID string) (int64, error) {
+	result, err := r.db.Exec("DELETE FROM comments WHERE object_id = ?", objectID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete comments: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// DeleteByCommentIDs 删除某个视频中指定的评论
+func (r *CommentRepository) DeleteByCommentIDs(objectID string, commentIDs []string) (int64, error) {
+	if len(commentIDs) == 0 {
+		return 0, nil
+	}
+	placeholders := make([]string, len(commentIDs))
+	args := make([]interface{}, 0, len(commentIDs)+1)
+	args = append(args, objectID)
+	for i, id := range commentIDs {
+		placeholders[i] = "?"
+		args = append(args, id)
+	}
+
+	query := fmt.Sprintf("DELETE FROM comments WHERE object_id = ? AND comment_id IN (%s)", strings.Join(placeholders, ","))
+	result, err := r.db.Exec(query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete comments: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// parseSQLiteTime 解析聚合查询返回的时间文本（聚合列没有声明类型，驱动不会自动转换）
+func parseSQLiteTime(s string) time.Time {
+	s = strings.TrimSuffix(s, "Z")
+	for _, layout := range sqlite3.SQLiteTimestampFormats {
+		if t, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+			return t.In(time.Local)
+		}
+	}
+	return time.Time{}
+}
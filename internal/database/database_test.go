@@ -320,3 +320,352 @@ func TestSettingsRepository(t *testing.T) {
 		t.Error("Expected validation error for high concurrent limit")
 	}
 }
+
+func TestSettingsRepository_BandwidthSchedule(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSettingsRepository()
+	settings := DefaultSettings()
+	settings.BandwidthLimit = 1024 * 1024
+	settings.BandwidthSchedule = []BandwidthWindow{{Start: "01:00", End: "07:00"}}
+	if err := repo.SaveAndValidate(settings); err != nil {
+		t.Fatalf("Failed to save settings: %v", err)
+	}
+
+	loaded, err := repo.Load()
+	if err != nil {
+		t.Fatalf("Failed to load settings: %v", err)
+	}
+	if len(loaded.BandwidthSchedule) != 1 || loaded.BandwidthSchedule[0].Start != "01:00" {
+		t.Fatalf("Unexpected schedule: %+v", loaded.BandwidthSchedule)
+	}
+
+	night := time.Date(2024, 1, 1, 3, 0, 0, 0, time.Local)
+	day := time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)
+	if global, _ := loaded.BandwidthAt(night); global != 0 {
+		t.Errorf("Expected full speed at night, got %d", global)
+	}
+	if global, _ := loaded.BandwidthAt(day); global != 1024*1024 {
+		t.Errorf("Expected 1MB/s during the day, got %d", global)
+	}
+
+	// 跨越午夜的时间段
+	overnight := BandwidthWindow{Start: "23:00", End: "02:00"}
+	if !overnight.Contains(time.Date(2024, 1, 1, 0, 30, 0, 0, time.Local)) {
+		t.Error("Expected overnight window to contain 00:30")
+	}
+	if overnight.Contains(day) {
+		t.Error("Expected overnight window not to contain 12:00")
+	}
+
+	settings.BandwidthSchedule = []BandwidthWindow{{Start: "25:00", End: "07:00"}}
+	if err := repo.Validate(settings); err == nil {
+		t.Error("Expected validation error for invalid schedule time")
+	}
+}
+
+func TestRadarRepository_FilterRules(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewRadarRepository()
+	target := &RadarTarget{
+		Username:        "u1",
+		AuthorName:      "作者",
+		IntervalMinutes: 5,
+		Status:          RadarStatusActive,
+		Filters: RadarFilterRules{
+			ExcludeKeywords:     []string{"广告"},
+			ExcludeRegex:        `(?i)^live`,
+			MinDuration:         10,
+			MaxFileSize:         100 << 20,
+			PreferredResolution: "720p",
+		},
+	}
+	if err := repo.Add(target); err != nil {
+		t.Fatalf("Failed to add target: %v", err)
+	}
+
+	loaded, err := repo.GetByID(target.ID)
+	if err != nil {
+		t.Fatalf("Failed to get target: %v", err)
+	}
+	if len(loaded.Filters.ExcludeKeywords) != 1 || loaded.Filters.PreferredResolution != "720p" {
+		t.Fatalf("Unexpected filters: %+v", loaded.Filters)
+	}
+
+	filter, err := loaded.Filters.Compile()
+	if err != nil {
+		t.Fatalf("Failed to compile filters: %v", err)
+	}
+	cases := []struct {
+		in   RadarFilterInput
+		want string
+	}{
+		{RadarFilterInput{Title: "日常 vlog", Duration: 60, FileSize: 1 << 20, Resolution: "1920x1080"}, ""},
+		{RadarFilterInput{Title: "这是广告"}, RadarRuleExcludeKeywords},
+		{RadarFilterInput{Title: "LIVE 回放"}, RadarRuleExcludeRegex},
+		{RadarFilterInput{Title: "短片", Duration: 5}, RadarRuleMinDuration},
+		{RadarFilterInput{Title: "大文件", FileSize: 200 << 20}, RadarRuleMaxFileSize},
+		{RadarFilterInput{Title: "低清", Resolution: "640x360"}, RadarRuleResolution},
+		{RadarFilterInput{Title: "未知信息"}, ""},
+	}
+	for _, c := range cases {
+		if got := filter.Check(c.in); got != c.want {
+			t.Errorf("Check(%+v) = %q, want %q", c.in, got, c.want)
+		}
+	}
+
+	if err := (RadarFilterRules{IncludeRegex: "("}).Validate(); err == nil {
+		t.Error("Expected validation error for invalid regex")
+	}
+	if err := (RadarFilterRules{PreferredResolution: "hd"}).Validate(); err == nil {
+		t.Error("Expected validation error for unknown resolution")
+	}
+}
+
+func TestEngagementRepository(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewEngagementRepository()
+	now := time.Now()
+	snapshots := []EngagementSnapshot{
+		{VideoID: "fresh", NonceID: "n1", Author: "作者", LikeCount: 1, CapturedAt: now.Add(-time.Hour)},
+		{VideoID: "stale", NonceID: "n2", Author: "作者", LikeCount: 2, CapturedAt: now.Add(-48 * time.Hour)},
+		{VideoID: "stale", NonceID: "n2", Author: "作者", LikeCount: 5, CapturedAt: now.Add(-24 * time.Hour)},
+		{VideoID: "expired", NonceID: "n3", Author: "作者", LikeCount: 3, CapturedAt: now.AddDate(0, 0, -40)},
+		{VideoID: "no-nonce", Author: "作者", LikeCount: 4, CapturedAt: now.Add(-24 * time.Hour)},
+	}
+	for i := range snapshots {
+		if err := repo.Add(&snapshots[i]); err != nil {
+			t.Fatalf("Failed to add snapshot: %v", err)
+		}
+	}
+
+	latest, err := repo.GetLatest("stale")
+	if err != nil || latest == nil || latest.LikeCount != 5 {
+		t.Fatalf("Unexpected latest snapshot: %+v, %v", latest, err)
+	}
+	if missing, err := repo.GetLatest("missing"); err != nil || missing != nil {
+		t.Fatalf("Expected nil for missing video, got %+v, %v", missing, err)
+	}
+
+	byAuthor, err := repo.ListByAuthor("作者", now.AddDate(0, 0, -7))
+	if err != nil || len(byAuthor) != 4 || byAuthor[0].VideoID != "stale" {
+		t.Fatalf("Unexpected author snapshots: %d, %v", len(byAuthor), err)
+	}
+
+	// 只有 30 天内首次出现、6 小时未更新且带 nonce 的视频需要刷新
+	stale, err := repo.ListStale(now.AddDate(0, 0, -30), now.Add(-6*time.Hour), 10)
+	if err != nil {
+		t.Fatalf("Failed to list stale snapshots: %v", err)
+	}
+	if len(stale) != 1 || stale[0].VideoID != "stale" || stale[0].LikeCount != 5 {
+		t.Fatalf("Unexpected stale snapshots: %+v", stale)
+	}
+
+	deleted, err := repo.DeleteBefore(now.AddDate(0, 0, -30))
+	if err != nil || deleted != 1 {
+		t.Fatalf("Expected 1 deleted snapshot, got %d, %v", deleted, err)
+	}
+}
+
+func TestCloudOutboxRepository(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewCloudOutboxRepository()
+	expires := time.Now().Add(time.Hour)
+	for _, id := range []string{"a", "b", "c", "b"} {
+		msg := &CloudOutboxMessage{MessageID: id, MessageType: "event", Message: `{"id":"` + id + `"}`, ExpiresAt: expires}
+		if _, err := repo.Enqueue(msg, 2); err != nil {
+			t.Fatalf("Failed to enqueue: %v", err)
+		}
+	}
+
+	// 超出上限时丢弃最早的消息，重复入队保留原有顺序
+	messages, err := repo.List(10)
+	if err != nil {
+		t.Fatalf("Failed to list outbox: %v", err)
+	}
+	if len(messages) != 2 || messages[0].MessageID != "b" || messages[1].MessageID != "c" {
+		t.Fatalf("Unexpected outbox messages: %+v", messages)
+	}
+
+	if _, err := repo.Enqueue(&CloudOutboxMessage{MessageID: "old", MessageType: "response", Message: "{}", ExpiresAt: time.Now().Add(-time.Minute)}, 0); err != nil {
+		t.Fatalf("Failed to enqueue: %v", err)
+	}
+	expired, err := repo.DeleteExpired(time.Now())
+	if err != nil || expired != 1 {
+		t.Fatalf("Expected 1 expired message, got %d, %v", expired, err)
+	}
+	if err := repo.Delete("b"); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	if count, err := repo.Count(); err != nil || count != 1 {
+		t.Fatalf("Expected 1 message left, got %d, %v", count, err)
+	}
+}
+
+func TestSavedSearchRepository(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewSavedSearchRepository()
+	now := time.Now()
+	search := &SavedSearch{Keyword: "美食", SearchType: SavedSearchTypeVideo, MaxResults: 10, Enabled: true, NextRunAt: &now}
+	if err := repo.Create(search); err != nil {
+		t.Fatalf("Failed to create saved search: %v", err)
+	}
+	if err := repo.Create(&SavedSearch{Keyword: "美食", SearchType: SavedSearchTypeVideo}); err == nil {
+		t.Fatalf("Expected duplicate keyword and type to be rejected")
+	}
+
+	due, err := repo.ListDue(now.Add(time.Second))
+	if err != nil || len(due) != 1 || due[0].ID != search.ID {
+		t.Fatalf("Expected saved search to be due, got %+v, %v", due, err)
+	}
+
+	first := now.Add(-time.Hour)
+	newCount, err := repo.SaveResults(search, first, []SavedSearchResult{{ResultKey: "a", Title: "A"}, {ResultKey: "b", Title: "B"}})
+	if err != nil || newCount != 2 {
+		t.Fatalf("Expected 2 new results, got %d, %v", newCount, err)
+	}
+	// 再次出现的结果只刷新内容
+	newCount, err = repo.SaveResults(search, now, []SavedSearchResult{{ResultKey: "b", Title: "B2"}, {ResultKey: "c", Title: "C"}})
+	if err != nil || newCount != 1 {
+		t.Fatalf("Expected 1 new result, got %d, %v", newCount, err)
+	}
+	if err := repo.RecordRun(search.ID, now, 2, 1, "", nil); err != nil {
+		t.Fatalf("Failed to record run: %v", err)
+	}
+
+	got, err := repo.GetByID(search.ID)
+	if err != nil || got == nil || got.LastRunAt == nil || got.NextRunAt != nil || got.LastNewCount != 1 {
+		t.Fatalf("Unexpected saved search after run: %+v, %v", got, err)
+	}
+	if due, _ := repo.ListDue(now.Add(time.Hour)); len(due) != 0 {
+		t.Fatalf("Expected no due searches without next run time, got %+v", due)
+	}
+
+	all, err := repo.ListResults(search.ID, nil, &PaginationParams{Page: 1, PageSize: 10})
+	if err != nil || all.Total != 3 {
+		t.Fatalf("Expected 3 results, got %+v, %v", all, err)
+	}
+	fresh, err := repo.ListResults(search.ID, got.LastRunAt, &PaginationParams{Page: 1, PageSize: 10})
+	if err != nil || fresh.Total != 1 || fresh.Items[0].ResultKey != "c" {
+		t.Fatalf("Expected only the new result, got %+v, %v", fresh, err)
+	}
+
+	// 删除搜索时一并删除结果
+	if err := repo.Delete(search.ID); err != nil {
+		t.Fatalf("Failed to delete saved search: %v", err)
+	}
+	if all, _ := repo.ListResults(search.ID, nil, &PaginationParams{Page: 1, PageSize: 10}); all.Total != 0 {
+		t.Fatalf("Expected results to be deleted, got %d", all.Total)
+	}
+}
+
+func TestCommentRepository(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewCommentRepository()
+	day := time.Date(2024, 5, 15, 10, 0, 0, 0, time.Local)
+	later := day.Add(time.Hour)
+	comments := []Comment{
+		{ObjectID: "oid-1", CommentID: "c1", Level: 1, Username: "user_a", Content: "第一条评论", LikeCount: 12, CommentTime: &day, VideoTitle: "视频"},
+		{ObjectID: "oid-1", CommentID: "r1", ParentID: "c1", Level: 2, Username: "user_b", Content: "回复", LikeCount: 3, CommentTime: &later, VideoTitle: "视频"},
+		{ObjectID: "oid-2", CommentID: "c1", Level: 1, Username: "user_a", Content: "另一个视频", LikeCount: 1},
+	}
+	if count, err := repo.UpsertMany(comments); err != nil || count != 3 {
+		t.Fatalf("Expected 3 imported comments, got %d, %v", count, err)
+	}
+	// 重复导入时更新已有评论
+	if _, err := repo.UpsertMany([]Comment{{ObjectID: "oid-1", CommentID: "c1", Level: 1, Username: "user_a", Content: "第一条评论", LikeCount: 20, CommentTime: &day}}); err != nil {
+		t.Fatalf("Failed to re-import comment: %v", err)
+	}
+
+	result, err := repo.List(&CommentFilter{ObjectID: "oid-1"})
+	if err != nil {
+		t.Fatalf("Failed to list comments: %v", err)
+	}
+	if result.Total != 2 || result.Items[0].CommentID != "r1" {
+		t.Fatalf("Expected 2 comments ordered by time desc, got %+v", result)
+	}
+
+	result, err = repo.List(&CommentFilter{ObjectID: "oid-1", MinLikes: 15})
+	if err != nil || result.Total != 1 || result.Items[0].LikeCount != 20 {
+		t.Fatalf("Expected updated comment to match min likes, got %+v, %v", result, err)
+	}
+	result, err = repo.List(&CommentFilter{ParentID: "c1", Level: 2})
+	if err != nil || result.Total != 1 || result.Items[0].Username != "user_b" {
+		t.Fatalf("Expected 1 reply, got %+v, %v", result, err)
+	}
+	result, err = repo.List(&CommentFilter{Query: "另一个"})
+	if err != nil || result.Total != 1 || result.Items[0].ObjectID != "oid-2" {
+		t.Fatalf("Expected content search to match, got %+v, %v", result, err)
+	}
+
+	videos, err := repo.ListVideos()
+	if err != nil || len(videos) != 2 {
+		t.Fatalf("Expected 2 videos, got %+v, %v", videos, err)
+	}
+	for _, video := range videos {
+		if video.ObjectID == "oid-1" && (video.CommentCount != 1 || video.ReplyCount != 1 || video.LastExportedAt.IsZero()) {
+			t.Fatalf("Unexpected video summary: %+v", video)
+		}
+	}
+
+	stored, err := repo.ListByObjectID("oid-1")
+	if err != nil || len(stored) != 2 || stored[0].Level != 1 {
+		t.Fatalf("Expected top-level comment first, got %+v, %v", stored, err)
+	}
+	if deleted, err := repo.DeleteByCommentIDs("oid-1", []string{"r1"}); err != nil || deleted != 1 {
+		t.Fatalf("Expected 1 deleted reply, got %d, %v", deleted, err)
+	}
+
+	if deleted, err := repo.DeleteByObjectID("oid-1"); err != nil || deleted != 1 {
+		t.Fatalf("Expected 1 deleted comment, got %d, %v", deleted, err)
+	}
+}
+
+func TestCommentExportJobRepository(t *testing.T) {
+	cleanup := setupTestDB(t)
+	defer cleanup()
+
+	repo := NewCommentExportJobRepository()
+	old := time.Now().Add(-48 * time.Hour)
+	now := time.Now()
+	jobs := []*CommentExportJob{
+		{ID: "job-old", ObjectID: "oid-1", Request: "{}", Status: "succeeded", CreatedAt: old, UpdatedAt: old},
+		{ID: "job-running", ObjectID: "oid-2", Request: "{}", Status: "queued", CreatedAt: now, UpdatedAt: now},
+	}
+	for _, job := range jobs {
+		if err := repo.Save(job); err != nil {
+			t.Fatalf("Failed to save job: %v", err)
+		}
+	}
+	jobs[1].Status = "running"
+	jobs[1].Progress = `{"stage":"replies"}`
+	if err := repo.Save(jobs[1]); err != nil {
+		t.Fatalf("Failed to update job: %v", err)
+	}
+
+	job, err := repo.GetByID("job-running")
+	if err != nil || job == nil || job.Status != "running" || job.Progress != `{"stage":"replies"}` {
+		t.Fatalf("Expected updated job, got %+v, %v", job, err)
+	}
+	unfinished, err := repo.ListByStatus("queued", "running")
+	if err != nil || len(unfinished) != 1 || unfinished[0].ID != "job-running" {
+		t.Fatalf("Expected 1 unfinished job, got %+v, %v", unfinished, err)
+	}
+	if listed, err := repo.List(10); err != nil || len(listed) != 2 || listed[0].ID != "job-running" {
+		t.Fatalf("Expected newest job first, got %+v, %v", listed, err)
+	}
+	if deleted, err := repo.DeleteUpdatedBefore(now.Add(-time.Hour), "succeeded", "failed"); err != nil || deleted != 1 {
+		t.Fatalf("Expected 1 pruned job, got %d, %v", deleted, err)
+	}
+}
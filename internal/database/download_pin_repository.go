@@ -0,0 +1,76 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// DownloadPin 表示一条不会被保留策略删除的下载记录
+type DownloadPin struct {
+	RecordID  string    `json:"recordId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// DownloadPinRepository 处理下载记录置顶（永不删除）的数据库操作
+type DownloadPinRepository struct {
+	db *sql.DB
+}
+
+// NewDownloadPinRepository 创建一个新的 DownloadPinRepository
+func NewDownloadPinRepository() *DownloadPinRepository {
+	return &DownloadPinRepository{db: GetDB()}
+}
+
+// Pin 将记录标记为永不删除，重复标记时保持原有时间
+func (r *DownloadPinRepository) Pin(recordID string) error {
+	_, err := r.db.Exec(
+		"INSERT OR IGNORE INTO download_pins (record_id, created_at) VALUES (?, ?)",
+		recordID, time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to pin download record: %w", err)
+	}
+	return nil
+}
+
+// Unpin 取消记录的永不删除标记
+func (r *DownloadPinRepository) Unpin(recordID string) error {
+	_, err := r.db.Exec("DELETE FROM download_pins WHERE record_id = ?", recordID)
+	if err != nil {
+		return fmt.Errorf("failed to unpin download record: %w", err)
+	}
+	return nil
+}
+
+// List 获取所有永不删除的记录，按标记时间倒序
+func (r *DownloadPinRepository) List() ([]DownloadPin, error) {
+	rows, err := r.db.Query("SELECT record_id, created_at FROM download_pins ORDER BY created_at DESC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list download pins: %w", err)
+	}
+	defer rows.Close()
+
+	pins := []DownloadPin{}
+	for rows.Next() {
+		var pin DownloadPin
+		if err := rows.Scan(&pin.RecordID, &pin.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan download pin: %w", err)
+		}
+		pins = append(pins, pin)
+	}
+	return pins, rows.Err()
+}
+
+// IDs 获取所有永不删除的记录 ID
+func (r *DownloadPinRepository) IDs() (map[string]bool, error) {
+	pins, err := r.List()
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]bool, len(pins))
+	for _, pin := range pins {
+		ids[pin.RecordID] = true
+	}
+	return ids, nil
+}
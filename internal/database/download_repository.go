@@ -22,21 +22,38 @@ func (r *DownloadRecordRepository) Create(record *DownloadRecord) error {
 	now := time.Now()
 	record.CreatedAt = now
 	record.UpdatedAt = now
+	return r.insert(record)
+}
+
+// Import 写入从其他机器导入的下载记录，保留原有的创建和更新时间，ID 相同的记录被替换
+func (r *DownloadRecordRepository) Import(record *DownloadRecord) error {
+	now := time.Now()
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = now
+	}
+	if record.UpdatedAt.IsZero() {
+		record.UpdatedAt = record.CreatedAt
+	}
+	return r.insert(record)
+}
 
+func (r *DownloadRecordRepository) insert(record *DownloadRecord) error {
 	query := `
 		INSERT OR REPLACE INTO download_records (
 			id, video_id, title, author, cover_url, duration, file_size, file_path,
 			format, resolution, status, download_time, error_message,
-			like_count, comment_count, forward_count, fav_count,
+			like_count, comment_count, forward_count, fav_count, play_count, ip_region,
+			fingerprint, content_hash,
 			created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 	_, err := r.db.Exec(query,
 		record.ID, record.VideoID, record.Title, record.Author, record.CoverURL,
 		record.Duration, record.FileSize, record.FilePath, record.Format,
 		record.Resolution, record.Status, record.DownloadTime,
 		record.ErrorMessage,
-		record.LikeCount, record.CommentCount, record.ForwardCount, record.FavCount,
+		record.LikeCount, record.CommentCount, record.ForwardCount, record.FavCount, record.PlayCount, record.IPRegion,
+		record.Fingerprint, record.ContentHash,
 		record.CreatedAt, record.UpdatedAt,
 	)
 	if err != nil {
@@ -50,7 +67,8 @@ func (r *DownloadRecordRepository) GetByID(id string) (*DownloadRecord, error) {
 	query := `
 		SELECT id, video_id, title, author, COALESCE(cover_url, '') as cover_url, duration, file_size, file_path,
 			format, resolution, status, download_time, error_message,
-			like_count, comment_count, forward_count, fav_count,
+			like_count, comment_count, forward_count, fav_count, COALESCE(play_count, 0), COALESCE(ip_region, ''),
+			COALESCE(fingerprint, ''), COALESCE(content_hash, ''),
 			created_at, updated_at
 		FROM download_records WHERE id = ?
 	`
@@ -61,7 +79,8 @@ func (r *DownloadRecordRepository) GetByID(id string) (*DownloadRecord, error) {
 		&record.Duration, &record.FileSize, &filePath, &format,
 		&resolution, &record.Status, &record.DownloadTime,
 		&errorMessage,
-		&record.LikeCount, &record.CommentCount, &record.ForwardCount, &record.FavCount,
+		&record.LikeCount, &record.CommentCount, &record.ForwardCount, &record.FavCount, &record.PlayCount, &record.IPRegion,
+		&record.Fingerprint, &record.ContentHash,
 		&record.CreatedAt, &record.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
@@ -83,7 +102,8 @@ func (r *DownloadRecordRepository) GetByVideoID(videoID string) (*DownloadRecord
 	query := `
 		SELECT id, video_id, title, author, COALESCE(cover_url, '') as cover_url, duration, file_size, file_path,
 			format, resolution, status, download_time, error_message,
-			like_count, comment_count, forward_count, fav_count,
+			like_count, comment_count, forward_count, fav_count, COALESCE(play_count, 0), COALESCE(ip_region, ''),
+			COALESCE(fingerprint, ''), COALESCE(content_hash, ''),
 			created_at, updated_at
 		FROM download_records WHERE video_id = ? LIMIT 1
 	`
@@ -94,7 +114,8 @@ func (r *DownloadRecordRepository) GetByVideoID(videoID string) (*DownloadRecord
 		&record.Duration, &record.FileSize, &filePath, &format,
 		&resolution, &record.Status, &record.DownloadTime,
 		&errorMessage,
-		&record.LikeCount, &record.CommentCount, &record.ForwardCount, &record.FavCount,
+		&record.LikeCount, &record.CommentCount, &record.ForwardCount, &record.FavCount, &record.PlayCount, &record.IPRegion,
+		&record.Fingerprint, &record.ContentHash,
 		&record.CreatedAt, &record.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
@@ -252,7 +273,7 @@ func (r *DownloadRecordRepository) List(params *FilterParams) (*PagedResult[Down
 	query := fmt.Sprintf(`
 		SELECT id, video_id, title, author, COALESCE(cover_url, '') as cover_url, duration, file_size, file_path,
 			format, resolution, status, download_time, error_message,
-			like_count, comment_count, forward_count, fav_count,
+			like_count, comment_count, forward_count, fav_count, COALESCE(play_count, 0), COALESCE(ip_region, ''),
 			created_at, updated_at
 		FROM download_records
 		%s
@@ -276,7 +297,7 @@ func (r *DownloadRecordRepository) List(params *FilterParams) (*PagedResult[Down
 			&record.Duration, &record.FileSize, &filePath, &format,
 			&resolution, &record.Status, &record.DownloadTime,
 			&errorMessage,
-			&record.LikeCount, &record.CommentCount, &record.ForwardCount, &record.FavCount,
+			&record.LikeCount, &record.CommentCount, &record.ForwardCount, &record.FavCount, &record.PlayCount, &record.IPRegion,
 			&record.CreatedAt, &record.UpdatedAt,
 		)
 		if err != nil {
@@ -340,7 +361,7 @@ func (r *DownloadRecordRepository) GetRecent(limit int) ([]DownloadRecord, error
 	query := `
 		SELECT id, video_id, title, author, COALESCE(cover_url, '') as cover_url, duration, file_size, file_path,
 			format, resolution, status, download_time, error_message,
-			like_count, comment_count, forward_count, fav_count,
+			like_count, comment_count, forward_count, fav_count, COALESCE(play_count, 0), COALESCE(ip_region, ''),
 			created_at, updated_at
 		FROM download_records
 		ORDER BY download_time DESC
@@ -362,7 +383,7 @@ func (r *DownloadRecordRepository) GetRecent(limit int) ([]DownloadRecord, error
 			&record.Duration, &record.FileSize, &filePath, &format,
 			&resolution, &record.Status, &record.DownloadTime,
 			&errorMessage,
-			&record.LikeCount, &record.CommentCount, &record.ForwardCount, &record.FavCount,
+			&record.LikeCount, &record.CommentCount, &record.ForwardCount, &record.FavCount, &record.PlayCount, &record.IPRegion,
 			&record.CreatedAt, &record.UpdatedAt,
 		)
 		if err != nil {
@@ -397,7 +418,7 @@ func (r *DownloadRecordRepository) GetAll() ([]DownloadRecord, error) {
 	query := `
 		SELECT id, video_id, title, author, COALESCE(cover_url, '') as cover_url, duration, file_size, file_path,
 			format, resolution, status, download_time, error_message,
-			like_count, comment_count, forward_count, fav_count,
+			like_count, comment_count, forward_count, fav_count, COALESCE(play_count, 0), COALESCE(ip_region, ''),
 			created_at, updated_at
 		FROM download_records
 		ORDER BY download_time DESC
@@ -418,7 +439,7 @@ func (r *DownloadRecordRepository) GetAll() ([]DownloadRecord, error) {
 			&record.Duration, &record.FileSize, &filePath, &format,
 			&resolution, &record.Status, &record.DownloadTime,
 			&errorMessage,
-			&record.LikeCount, &record.CommentCount, &record.ForwardCount, &record.FavCount,
+			&record.LikeCount, &record.CommentCount, &record.ForwardCount, &record.FavCount, &record.PlayCount, &record.IPRegion,
 			&record.CreatedAt, &record.UpdatedAt,
 		)
 		if err != nil {
@@ -455,7 +476,7 @@ func (r *DownloadRecordRepository) GetByIDs(ids []string) ([]DownloadRecord, err
 	query := fmt.Sprintf(`
 		SELECT id, video_id, title, author, COALESCE(cover_url, '') as cover_url, duration, file_size, file_path,
 			format, resolution, status, download_time, error_message,
-			like_count, comment_count, forward_count, fav_count,
+			like_count, comment_count, forward_count, fav_count, COALESCE(play_count, 0), COALESCE(ip_region, ''),
 			created_at, updated_at
 		FROM download_records
 		WHERE id IN (%s)
@@ -477,7 +498,7 @@ func (r *DownloadRecordRepository) GetByIDs(ids []string) ([]DownloadRecord, err
 			&record.Duration, &record.FileSize, &filePath, &format,
 			&resolution, &record.Status, &record.DownloadTime,
 			&errorMessage,
-			&record.LikeCount, &record.CommentCount, &record.ForwardCount, &record.FavCount,
+			&record.LikeCount, &record.CommentCount, &record.ForwardCount, &record.FavCount, &record.PlayCount, &record.IPRegion,
 			&record.CreatedAt, &record.UpdatedAt,
 		)
 		if err != nil {
@@ -539,8 +560,9 @@ func (r *DownloadRecordRepository) GetTotalFileSize() (int64, error) {
 	return total.Int64, nil
 }
 
-// GetRecordsSince 获取指定时间之后的下载记录（用于增量同步）
-func (r *DownloadRecordRepository) GetRecordsSince(since time.Time, limit int) ([]DownloadRecord, error) {
+// GetRecordsAfter 获取 (updated_at, id) 位于游标之后的下载记录，按同样顺序返回（用于增量同步）
+// 以 id 作为同一时间戳的次序，批次边界上更新时间相同的记录不会被跳过
+func (r *DownloadRecordRepository) GetRecordsAfter(since time.Time, afterID string, limit int) ([]DownloadRecord, error) {
 	if limit < 1 {
 		limit = 100
 	}
@@ -551,15 +573,15 @@ func (r *DownloadRecordRepository) GetRecordsSince(since time.Time, limit int) (
 	query := `
 		SELECT id, video_id, title, author, COALESCE(cover_url, '') as cover_url, duration, file_size, file_path,
 			format, resolution, status, download_time, error_message,
-			like_count, comment_count, forward_count, fav_count,
+			like_count, comment_count, forward_count, fav_count, COALESCE(play_count, 0), COALESCE(ip_region, ''),
 			created_at, updated_at
 		FROM download_records
-		WHERE updated_at > ?
-		ORDER BY updated_at ASC
+		WHERE updated_at > ? OR (updated_at = ? AND id > ?)
+		ORDER BY updated_at ASC, id ASC
 		LIMIT ?
 	`
 
-	rows, err := r.db.Query(query, since, limit)
+	rows, err := r.db.Query(query, since, since, afterID, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get records since: %w", err)
 	}
@@ -574,7 +596,7 @@ func (r *DownloadRecordRepository) GetRecordsSince(since time.Time, limit int) (
 			&record.Duration, &record.FileSize, &filePath, &format,
 			&resolution, &record.Status, &record.DownloadTime,
 			&errorMessage,
-			&record.LikeCount, &record.CommentCount, &record.ForwardCount, &record.FavCount,
+			&record.LikeCount, &record.CommentCount, &record.ForwardCount, &record.FavCount, &record.PlayCount, &record.IPRegion,
 			&record.CreatedAt, &record.UpdatedAt,
 		)
 		if err != nil {
@@ -604,3 +626,174 @@ func (r *DownloadRecordRepository) GetLatestTimestamp() (time.Time, error) {
 	}
 	return timestamp, nil
 }
+
+// downloadRecordHashColumns 是带内容哈希的完整列清单
+const downloadRecordHashColumns = `
+	id, video_id, title, author, COALESCE(cover_url, ''), duration, file_size, COALESCE(file_path, ''),
+	COALESCE(format, ''), COALESCE(resolution, ''), status, download_time, COALESCE(error_message, ''),
+	like_count, comment_count, forward_count, fav_count, COALESCE(play_count, 0), COALESCE(ip_region, ''),
+	COALESCE(fingerprint, ''), COALESCE(content_hash, ''),
+	created_at, updated_at
+`
+
+// queryRecordsWithHashes 查询并扫描带内容哈希的下载记录
+func (r *DownloadRecordRepository) queryRecordsWithHashes(where string, args ...interface{}) ([]DownloadRecord, error) {
+	rows, err := r.db.Query("SELECT "+downloadRecordHashColumns+" FROM download_records "+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query download records: %w", err)
+	}
+	defer rows.Close()
+
+	records := []DownloadRecord{}
+	for rows.Next() {
+		var record DownloadRecord
+		err := rows.Scan(
+			&record.ID, &record.VideoID, &record.Title, &record.Author, &record.CoverURL,
+			&record.Duration, &record.FileSize, &record.FilePath, &record.Format,
+			&record.Resolution, &record.Status, &record.DownloadTime,
+			&record.ErrorMessage,
+			&record.LikeCount, &record.CommentCount, &record.ForwardCount, &record.FavCount, &record.PlayCount, &record.IPRegion,
+			&record.Fingerprint, &record.ContentHash,
+			&record.CreatedAt, &record.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan download record: %w", err)
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// UpdateHashes 更新记录的文件指纹和内容哈希
+func (r *DownloadRecordRepository) UpdateHashes(id, fingerprint, contentHash string) error {
+	_, err := r.db.Exec(
+		"UPDATE download_records SET fingerprint = ?, content_hash = ?, updated_at = ? WHERE id = ?",
+		fingerprint, contentHash, time.Now(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update download record hashes: %w", err)
+	}
+	return nil
+}
+
+// UpdateLocation 更新记录的文件路径和状态，用于同步磁盘上已移动或丢失的文件
+func (r *DownloadRecordRepository) UpdateLocation(id, filePath, status string) error {
+	result, err := r.db.Exec(
+		"UPDATE download_records SET file_path = ?, status = ?, updated_at = ? WHERE id = ?",
+		filePath, status, time.Now(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update download record location: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("download record not found: %s", id)
+	}
+	return nil
+}
+
+// ListLibraryRecords 获取带文件路径的已完成和已丢失记录，按下载时间升序
+func (r *DownloadRecordRepository) ListLibraryRecords() ([]DownloadRecord, error) {
+	return r.queryRecordsWithHashes(
+		"WHERE COALESCE(file_path, '') != '' AND status IN (?, ?) ORDER BY download_time ASC",
+		DownloadStatusCompleted, DownloadStatusMissing,
+	)
+}
+
+// FindByFingerprint 获取指纹相同的已完成记录
+func (r *DownloadRecordRepository) FindByFingerprint(fingerprint string) ([]DownloadRecord, error) {
+	if fingerprint == "" {
+		return []DownloadRecord{}, nil
+	}
+	return r.queryRecordsWithHashes(
+		"WHERE fingerprint = ? AND status = ? ORDER BY download_time ASC",
+		fingerprint, DownloadStatusCompleted,
+	)
+}
+
+// FindByContentHash 获取内容哈希相同的已完成记录，按下载时间升序
+func (r *DownloadRecordRepository) FindByContentHash(contentHash string) ([]DownloadRecord, error) {
+	if contentHash == "" {
+		return []DownloadRecord{}, nil
+	}
+	return r.queryRecordsWithHashes(
+		"WHERE content_hash = ? AND status = ? ORDER BY download_time ASC",
+		contentHash, DownloadStatusCompleted,
+	)
+}
+
+// ListWithoutFingerprint 获取尚未计算文件指纹的已完成记录
+func (r *DownloadRecordRepository) ListWithoutFingerprint() ([]DownloadRecord, error) {
+	return r.queryRecordsWithHashes(
+		"WHERE COALESCE(fingerprint, '') = '' AND COALESCE(file_path, '') != '' AND status = ? ORDER BY download_time ASC",
+		DownloadStatusCompleted,
+	)
+}
+
+// ListUnhashedFingerprintCollisions 获取指纹与其他记录相同但尚未计算内容哈希的记录
+func (r *DownloadRecordRepository) ListUnhashedFingerprintCollisions() ([]DownloadRecord, error) {
+	return r.queryRecordsWithHashes(`
+		WHERE COALESCE(content_hash, '') = '' AND status = ? AND fingerprint IN (
+			SELECT fingerprint FROM download_records
+			WHERE COALESCE(fingerprint, '') != '' AND status = ?
+			GROUP BY fingerprint HAVING COUNT(*) > 1
+		)
+		ORDER BY download_time ASC`,
+		DownloadStatusCompleted, DownloadStatusCompleted,
+	)
+}
+
+// ListDuplicateGroups 获取内容哈希相同的记录分组
+func (r *DownloadRecordRepository) ListDuplicateGroups() ([]DuplicateGroup, error) {
+	rows, err := r.db.Query(`
+		SELECT content_hash FROM download_records
+		WHERE COALESCE(content_hash, '') != '' AND status = ?
+		GROUP BY content_hash
+		HAVING COUNT(*) > 1
+		ORDER BY MAX(file_size) * (COUNT(*) - 1) DESC
+	`, DownloadStatusCompleted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list duplicate hashes: %w", err)
+	}
+	var hashes []string
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan duplicate hash: %w", err)
+		}
+		hashes = append(hashes, hash)
+	}
+	rows.Close()
+
+	groups := make([]DuplicateGroup, 0, len(hashes))
+	for _, hash := range hashes {
+		records, err := r.FindByContentHash(hash)
+		if err != nil {
+			return nil, err
+		}
+		if len(records) < 2 {
+			continue
+		}
+		group := DuplicateGroup{
+			ContentHash: hash,
+			FileSize:    records[0].FileSize,
+			Records:     records,
+		}
+		group.WastedBytes = group.FileSize * int64(len(records)-1)
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// UpdateEngagement 用最新的互动数据更新视频的下载记录
+func (r *DownloadRecordRepository) UpdateEngagement(videoID string, likeCount, commentCount, favCount, forwardCount int64) error {
+	_, err := r.db.Exec(
+		`UPDATE download_records SET like_count = ?, comment_count = ?, fav_count = ?, forward_count = ?, updated_at = ?
+		WHERE video_id = ?`,
+		likeCount, commentCount, favCount, forwardCount, time.Now(), videoID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update download record engagement: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// EngagementRepository 处理互动数据快照的数据库操作
+type EngagementRepository struct {
+	db *sql.DB
+}
+
+// NewEngagementRepository 创建一个新的 EngagementRepository
+func NewEngagementRepository() *EngagementRepository {
+	return &EngagementRepository{db: GetDB()}
+}
+
+// Add 写入一条快照
+func (r *EngagementRepository) Add(snapshot *EngagementSnapshot) error {
+	if snapshot.CapturedAt.IsZero() {
+		snapshot.CapturedAt = time.Now()
+	}
+	result, err := r.db.Exec(`
+		INSERT INTO engagement_snapshots (
+			video_id, nonce_id, author, target_id, title,
+			like_count, comment_count, fav_count, forward_count, captured_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		snapshot.VideoID, snapshot.NonceID, snapshot.Author, snapshot.TargetID, snapshot.Title,
+		snapshot.LikeCount, snapshot.CommentCount, snapshot.FavCount, snapshot.ForwardCount, snapshot.CapturedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add engagement snapshot: %w", err)
+	}
+	snapshot.ID, _ = result.LastInsertId()
+	return nil
+}
+
+// GetLatest 获取视频最近一次快照，不存在时返回 nil
+func (r *EngagementRepository) GetLatest(videoID string) (*EngagementSnapshot, error) {
+	snapshots, err := r.query(`WHERE video_id = ? ORDER BY captured_at DESC, id DESC LIMIT 1`, videoID)
+	if err != nil || len(snapshots) == 0 {
+		return nil, err
+	}
+	return &snapshots[0], nil
+}
+
+// ListByVideo 获取视频在 since 之后的快照，按时间升序
+func (r *EngagementRepository) ListByVideo(videoID string, since time.Time) ([]EngagementSnapshot, error) {
+	return r.query(`WHERE video_id = ? AND captured_at >= ? ORDER BY captured_at ASC, id ASC`, videoID, since)
+}
+
+// ListByAuthor 获取作者所有视频在 since 之后的快照，按时间升序
+func (r *EngagementRepository) ListByAuthor(author string, since time.Time) ([]EngagementSnapshot, error) {
+	return r.query(`WHERE author = ? AND captured_at >= ? ORDER BY captured_at ASC, id ASC`, author, since)
+}
+
+// ListStale 获取 firstSeenAfter 之后首次出现、且最近一次快照早于 staleBefore 的视频的最新快照
+// 只返回带 nonce_id 的视频，供 feed_profile 刷新使用
+func (r *EngagementRepository) ListStale(firstSeenAfter, staleBefore time.Time, limit int) ([]EngagementSnapshot, error) {
+	return r.query(`
+		WHERE id IN (
+			SELECT MAX(id) FROM engagement_snapshots
+			WHERE nonce_id != ''
+			GROUP BY video_id
+			HAVING MIN(captured_at) >= ? AND MAX(captured_at) < ?
+		)
+		ORDER BY captured_at ASC
+		LIMIT ?`, firstSeenAfter, staleBefore, limit)
+}
+
+// DeleteBefore 删除早于指定时间的快照
+func (r *EngagementRepository) DeleteBefore(before time.Time) (int64, error) {
+	result, err := r.db.Exec("DELETE FROM engagement_snapshots WHERE captured_at < ?", before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete engagement snapshots: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func (r *EngagementRepository) query(where string, args ...interface{}) ([]EngagementSnapshot, error) {
+	rows, err := r.db.Query(`
+		SELECT id, video_id, COALESCE(nonce_id, ''), COALESCE(author, ''), COALESCE(target_id, ''), COALESCE(title, ''),
+			like_count, comment_count, fav_count, forward_count, captured_at
+		FROM engagement_snapshots `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query engagement snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	var snapshots []EngagementSnapshot
+	for rows.Next() {
+		var s EngagementSnapshot
+		if err := rows.Scan(
+			&s.ID, &s.VideoID, &s.NonceID, &s.Author, &s.TargetID, &s.Title,
+			&s.LikeCount, &s.CommentCount, &s.FavCount, &s.ForwardCount, &s.CapturedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan engagement snapshot: %w", err)
+		}
+		snapshots = append(snapshots, s)
+	}
+	return snapshots, rows.Err()
+}
@@ -283,6 +283,250 @@ CREATE INDEX IF NOT EXISTS idx_radar_logs_check_time ON radar_logs(check_time);
 		Description: "Add video_list column to radar_logs for per-video details",
 		Up:          `ALTER TABLE radar_logs ADD COLUMN video_list TEXT DEFAULT '';`,
 	},
+	{
+		Version:     15,
+		Description: "Add chunks_bitmap column to download_queue for per-chunk resume",
+		Up:          `ALTER TABLE download_queue ADD COLUMN chunks_bitmap TEXT DEFAULT '';`,
+	},
+	{
+		Version:     16,
+		Description: "Add content fingerprint and SHA-256 columns to download_records for dedup",
+		Up: `
+ALTER TABLE download_records ADD COLUMN fingerprint TEXT DEFAULT '';
+ALTER TABLE download_records ADD COLUMN content_hash TEXT DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_download_records_fingerprint ON download_records(fingerprint);
+CREATE INDEX IF NOT EXISTS idx_download_records_content_hash ON download_records(content_hash);
+`,
+	},
+	{
+		Version:     17,
+		Description: "Add feed_list pagination and backfill state to radar targets and logs",
+		Up: `
+ALTER TABLE radar_targets ADD COLUMN page_depth INTEGER DEFAULT 5;
+ALTER TABLE radar_targets ADD COLUMN backfill INTEGER DEFAULT 0;
+ALTER TABLE radar_targets ADD COLUMN backfill_cursor TEXT DEFAULT '';
+
+ALTER TABLE radar_logs ADD COLUMN mode TEXT DEFAULT 'latest';
+ALTER TABLE radar_logs ADD COLUMN pages INTEGER DEFAULT 0;
+ALTER TABLE radar_logs ADD COLUMN stop_reason TEXT DEFAULT '';
+`,
+	},
+	{
+		Version:     18,
+		Description: "Add per-target filter rules to radar targets",
+		Up: `
+ALTER TABLE radar_targets ADD COLUMN filter_rules TEXT DEFAULT '';
+`,
+	},
+	{
+		Version:     19,
+		Description: "Add per-target notification config to radar targets",
+		Up: `
+ALTER TABLE radar_targets ADD COLUMN notify_config TEXT DEFAULT '';
+`,
+	},
+	{
+		Version:     20,
+		Description: "Add adaptive polling state to radar targets",
+		Up: `
+ALTER TABLE radar_targets ADD COLUMN fixed_interval INTEGER DEFAULT 0;
+ALTER TABLE radar_targets ADD COLUMN next_check_time DATETIME;
+ALTER TABLE radar_targets ADD COLUMN post_interval_minutes INTEGER DEFAULT 0;
+ALTER TABLE radar_targets ADD COLUMN fail_count INTEGER DEFAULT 0;
+`,
+	},
+	{
+		Version:     21,
+		Description: "Create engagement_snapshots table for like/comment/fav/forward time series",
+		Up: `
+CREATE TABLE IF NOT EXISTS engagement_snapshots (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    video_id TEXT NOT NULL,
+    nonce_id TEXT DEFAULT '',
+    author TEXT DEFAULT '',
+    target_id TEXT DEFAULT '',
+    title TEXT DEFAULT '',
+    like_count INTEGER DEFAULT 0,
+    comment_count INTEGER DEFAULT 0,
+    fav_count INTEGER DEFAULT 0,
+    forward_count INTEGER DEFAULT 0,
+    captured_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_engagement_video_time ON engagement_snapshots(video_id, captured_at);
+CREATE INDEX IF NOT EXISTS idx_engagement_author_time ON engagement_snapshots(author, captured_at);
+`,
+	},
+	{
+		Version:     22,
+		Description: "Create sync_cursors and sync_batches tables for acknowledged Hub sync",
+		Up: `
+CREATE TABLE IF NOT EXISTS sync_cursors (
+    sync_type TEXT PRIMARY KEY,
+    cursor_time DATETIME NOT NULL,
+    cursor_id TEXT DEFAULT '',
+    updated_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS sync_batches (
+    id TEXT PRIMARY KEY,
+    sync_type TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    count INTEGER DEFAULT 0,
+    has_more INTEGER DEFAULT 0,
+    cursor_time DATETIME NOT NULL,
+    cursor_id TEXT DEFAULT '',
+    attempts INTEGER DEFAULT 0,
+    created_at DATETIME NOT NULL,
+    sent_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_sync_batches_type ON sync_batches(sync_type, created_at);
+`,
+	},
+	{
+		Version:     23,
+		Description: "Create cloud_command_audit table for rejected cloud commands",
+		Up: `
+CREATE TABLE IF NOT EXISTS cloud_command_audit (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    message_id TEXT DEFAULT '',
+    message_type TEXT DEFAULT '',
+    action TEXT DEFAULT '',
+    api_key TEXT DEFAULT '',
+    reason TEXT NOT NULL,
+    detail TEXT DEFAULT '',
+    message_time INTEGER DEFAULT 0,
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_cloud_command_audit_created ON cloud_command_audit(created_at);
+`,
+	},
+	{
+		Version:     24,
+		Description: "Create cloud_outbox table for messages sent while the Hub is unreachable",
+		Up: `
+CREATE TABLE IF NOT EXISTS cloud_outbox (
+    seq INTEGER PRIMARY KEY AUTOINCREMENT,
+    message_id TEXT NOT NULL UNIQUE,
+    message_type TEXT NOT NULL,
+    message TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL
+);
+`,
+	},
+	{
+		Version:     25,
+		Description: "Create saved_searches and saved_search_results tables for keyword search jobs",
+		Up: `
+CREATE TABLE IF NOT EXISTS saved_searches (
+    id TEXT PRIMARY KEY,
+    keyword TEXT NOT NULL,
+    search_type INTEGER NOT NULL,
+    max_results INTEGER NOT NULL DEFAULT 50,
+    interval_minutes INTEGER NOT NULL DEFAULT 0,
+    enabled INTEGER NOT NULL DEFAULT 1,
+    last_run_at DATETIME,
+    next_run_at DATETIME,
+    last_result_count INTEGER NOT NULL DEFAULT 0,
+    last_new_count INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    UNIQUE(keyword, search_type)
+);
+
+CREATE TABLE IF NOT EXISTS saved_search_results (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    search_id TEXT NOT NULL,
+    keyword TEXT NOT NULL,
+    search_type INTEGER NOT NULL,
+    result_key TEXT NOT NULL,
+    title TEXT NOT NULL DEFAULT '',
+    author TEXT NOT NULL DEFAULT '',
+    username TEXT NOT NULL DEFAULT '',
+    object_id TEXT NOT NULL DEFAULT '',
+    nonce_id TEXT NOT NULL DEFAULT '',
+    cover_url TEXT NOT NULL DEFAULT '',
+    raw TEXT NOT NULL DEFAULT '',
+    first_seen_at DATETIME NOT NULL,
+    last_seen_at DATETIME NOT NULL,
+    UNIQUE(search_id, result_key),
+    FOREIGN KEY (search_id) REFERENCES saved_searches(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_saved_search_results_first_seen ON saved_search_results(search_id, first_seen_at);
+`,
+	},
+	{
+		Version:     26,
+		Description: "Create comments table for exported feed comments",
+		Up: `
+CREATE TABLE IF NOT EXISTS comments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    object_id TEXT NOT NULL,
+    comment_id TEXT NOT NULL,
+    parent_id TEXT NOT NULL DEFAULT '',
+    reply_comment_id TEXT NOT NULL DEFAULT '',
+    level INTEGER NOT NULL DEFAULT 1,
+    username TEXT NOT NULL DEFAULT '',
+    nickname TEXT NOT NULL DEFAULT '',
+    content TEXT NOT NULL DEFAULT '',
+    region TEXT NOT NULL DEFAULT '',
+    like_count INTEGER NOT NULL DEFAULT 0,
+    comment_time DATETIME,
+    video_title TEXT NOT NULL DEFAULT '',
+    video_author TEXT NOT NULL DEFAULT '',
+    exported_at DATETIME NOT NULL,
+    UNIQUE(object_id, comment_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_comments_object_time ON comments(object_id, comment_time);
+CREATE INDEX IF NOT EXISTS idx_comments_username ON comments(username);
+`,
+	},
+	{
+		Version:     27,
+		Description: "Create comment_export_jobs table so export jobs survive restarts",
+		Up: `
+CREATE TABLE IF NOT EXISTS comment_export_jobs (
+    id TEXT PRIMARY KEY,
+    object_id TEXT NOT NULL,
+    title TEXT NOT NULL DEFAULT '',
+    request TEXT NOT NULL,
+    status TEXT NOT NULL,
+    progress TEXT NOT NULL DEFAULT '',
+    result TEXT NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_comment_export_jobs_status ON comment_export_jobs(status);
+CREATE INDEX IF NOT EXISTS idx_comment_export_jobs_created ON comment_export_jobs(created_at);
+`,
+	},
+	{
+		Version:     28,
+		Description: "Add play_count and ip_region columns to download_records for legacy CSV import",
+		Up: `
+ALTER TABLE download_records ADD COLUMN play_count INTEGER DEFAULT 0;
+ALTER TABLE download_records ADD COLUMN ip_region TEXT DEFAULT '';
+`,
+	},
+	{
+		Version:     29,
+		Description: "Create download_pins table for records protected from retention cleanup",
+		Up: `
+CREATE TABLE IF NOT EXISTS download_pins (
+    record_id TEXT PRIMARY KEY,
+    created_at DATETIME NOT NULL
+);
+`,
+	},
 }
 
 // runMigrations 执行所有待处理的迁移
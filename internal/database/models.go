@@ -1,6 +1,7 @@
 package database
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -46,16 +47,29 @@ type DownloadRecord struct {
 	CommentCount int64     `json:"commentCount"`
 	ForwardCount int64     `json:"forwardCount"`
 	FavCount     int64     `json:"favCount"`
+	PlayCount    int64     `json:"playCount"`
+	IPRegion     string    `json:"ipRegion"`              // 发布者 IP 属地
+	Fingerprint  string    `json:"fingerprint,omitempty"` // 文件大小与首尾数据的快速指纹
+	ContentHash  string    `json:"contentHash,omitempty"` // 文件内容 SHA-256
 	CreatedAt    time.Time `json:"createdAt"`
 	UpdatedAt    time.Time `json:"updatedAt"`
 }
 
+// DuplicateGroup 表示内容完全相同的一组下载记录
+type DuplicateGroup struct {
+	ContentHash string           `json:"contentHash"`
+	FileSize    int64            `json:"fileSize"`
+	Records     []DownloadRecord `json:"records"`
+	WastedBytes int64            `json:"wastedBytes"` // 除保留的一份外其余副本占用的空间
+}
+
 // DownloadStatus 常量
 const (
 	DownloadStatusPending    = "pending"
 	DownloadStatusInProgress = "in_progress"
 	DownloadStatusCompleted  = "completed"
 	DownloadStatusFailed     = "failed"
+	DownloadStatusMissing    = "missing" // 已完成但文件在磁盘上找不到
 )
 
 // QueueItem 表示下载队列项目
@@ -79,6 +93,7 @@ type QueueItem struct {
 	ChunkSize       int64     `json:"chunkSize"`
 	ChunksTotal     int       `json:"chunksTotal"`
 	ChunksCompleted int       `json:"chunksCompleted"`
+	ChunksBitmap    string    `json:"chunksBitmap,omitempty"` // 已完成分片位图（十六进制编码）
 	RetryCount      int       `json:"retryCount"`
 	ErrorMessage    string    `json:"errorMessage"`
 	CreatedAt       time.Time `json:"createdAt"`
@@ -94,6 +109,21 @@ const (
 	QueueStatusFailed      = "failed"
 )
 
+// EngagementSnapshot 表示某一时刻视频的互动数据快照
+type EngagementSnapshot struct {
+	ID           int64     `json:"id"`
+	VideoID      string    `json:"videoId"`
+	NonceID      string    `json:"nonceId,omitempty"`
+	Author       string    `json:"author"`
+	TargetID     string    `json:"targetId,omitempty"` // 来源雷达监控目标
+	Title        string    `json:"title"`
+	LikeCount    int64     `json:"likeCount"`
+	CommentCount int64     `json:"commentCount"`
+	FavCount     int64     `json:"favCount"`
+	ForwardCount int64     `json:"forwardCount"`
+	CapturedAt   time.Time `json:"capturedAt"`
+}
+
 // Settings 表示应用程序设置
 type Settings struct {
 	DownloadDir                 string `json:"downloadDir"`
@@ -106,6 +136,131 @@ type Settings struct {
 	MaxRetries                  int    `json:"maxRetries"`
 	RadarEnabled                bool   `json:"radarEnabled"`
 	Theme                       string `json:"theme"`
+
+	// 下载限速（字节/秒），0 表示不限速；时间段内以时间段的限速为准
+	BandwidthLimit     int64             `json:"bandwidthLimit"`
+	ItemBandwidthLimit int64             `json:"itemBandwidthLimit"`
+	BandwidthSchedule  []BandwidthWindow `json:"bandwidthSchedule"`
+
+	// 雷达所有监控目标共享的每分钟最多调用次数，0 表示使用默认值
+	RadarCallsPerMinute int `json:"radarCallsPerMinute"`
+
+	// 互动数据快照的保留天数，0 表示使用默认值；自动清理时删除更早的快照
+	EngagementRetentionDays int `json:"engagementRetentionDays"`
+}
+
+// DefaultRadarCallsPerMinute 雷达每分钟调用次数的默认上限
+const DefaultRadarCallsPerMinute = 6
+
+// 互动数据快照保留天数，最短不少于雷达持续跟踪视频的 30 天
+const (
+	DefaultEngagementRetentionDays = 365
+	MinEngagementRetentionDays     = 30
+	MaxEngagementRetentionDays     = 3650
+)
+
+// EngagementRetention 返回互动数据快照的保留天数
+func (s *Settings) EngagementRetention() int {
+	if s.EngagementRetentionDays <= 0 {
+		return DefaultEngagementRetentionDays
+	}
+	return s.EngagementRetentionDays
+}
+
+// BandwidthWindow 表示一个限速时间段
+// Start/End 为本地时间 "HH:MM"，End 不晚于 Start 时表示跨越午夜
+type BandwidthWindow struct {
+	Start     string `json:"start"`
+	End       string `json:"end"`
+	Limit     int64  `json:"limit"`     // 全局限速（字节/秒），0 表示不限速
+	ItemLimit int64  `json:"itemLimit"` // 单个任务限速（字节/秒），0 表示不限速
+}
+
+// Contains 判断时间是否落在该时间段内
+func (w BandwidthWindow) Contains(t time.Time) bool {
+	start, err := parseClockMinutes(w.Start)
+	if err != nil {
+		return false
+	}
+	end, err := parseClockMinutes(w.End)
+	if err != nil {
+		return false
+	}
+	now := t.Hour()*60 + t.Minute()
+	if start < end {
+		return now >= start && now < end
+	}
+	return now >= start || now < end
+}
+
+// BandwidthAt 返回指定时间生效的全局和单任务限速
+func (s *Settings) BandwidthAt(t time.Time) (global int64, item int64) {
+	for _, window := range s.BandwidthSchedule {
+		if window.Contains(t) {
+			return window.Limit, window.ItemLimit
+		}
+	}
+	return s.BandwidthLimit, s.ItemBandwidthLimit
+}
+
+// parseClockMinutes 将 "HH:MM" 解析为当天的分钟数
+func parseClockMinutes(clock string) (int, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", clock)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// RetentionPolicy 下载目录的视频保留策略，各项为 0 表示不限制
+// 策略独立于 Settings 保存，避免整体更新设置时被覆盖
+type RetentionPolicy struct {
+	Enabled           bool           `json:"enabled"`
+	MaxTotalBytes     int64          `json:"maxTotalBytes"`              // 视频总大小上限，超出时从最早的下载开始删除
+	MaxAgeDays        int            `json:"maxAgeDays"`                 // 每个作者文件夹中视频的最长保留天数
+	AuthorMaxAgeDays  map[string]int `json:"authorMaxAgeDays,omitempty"` // 按作者文件夹覆盖 MaxAgeDays，0 表示不过期
+	KeepLastPerAuthor int            `json:"keepLastPerAuthor"`          // 每个作者文件夹只保留最新的 N 个视频
+	IntervalMinutes   int            `json:"intervalMinutes"`            // 自动执行间隔
+
+	// 磁盘剩余空间低于该值时暂停下载队列，0 表示不检查；不受 Enabled 影响
+	MinFreeBytes int64 `json:"minFreeBytes"`
+}
+
+// DefaultRetentionIntervalMinutes 保留策略默认每小时执行一次
+const DefaultRetentionIntervalMinutes = 60
+
+// DefaultRetentionPolicy 返回默认保留策略（不启用）
+func DefaultRetentionPolicy() *RetentionPolicy {
+	return &RetentionPolicy{
+		IntervalMinutes: DefaultRetentionIntervalMinutes,
+	}
+}
+
+// MaxAgeFor 返回作者文件夹适用的最长保留天数
+func (p *RetentionPolicy) MaxAgeFor(author string) int {
+	if days, ok := p.AuthorMaxAgeDays[author]; ok {
+		return days
+	}
+	return p.MaxAgeDays
+}
+
+// Validate 验证保留策略
+func (p *RetentionPolicy) Validate() error {
+	if p.MaxTotalBytes < 0 || p.MinFreeBytes < 0 {
+		return fmt.Errorf("size limits must not be negative")
+	}
+	if p.MaxAgeDays < 0 || p.KeepLastPerAuthor < 0 {
+		return fmt.Errorf("max age days and keep last per author must not be negative")
+	}
+	for author, days := range p.AuthorMaxAgeDays {
+		if days < 0 {
+			return fmt.Errorf("max age days for author %q must not be negative", author)
+		}
+	}
+	if p.IntervalMinutes < 5 || p.IntervalMinutes > 7*24*60 {
+		return fmt.Errorf("interval minutes must be between 5 and 10080")
+	}
+	return nil
 }
 
 // DefaultSettings 返回默认设置
@@ -121,6 +276,7 @@ func DefaultSettings() *Settings {
 		MaxRetries:                  3,
 		RadarEnabled:                false,
 		Theme:                       "light",
+		RadarCallsPerMinute:         DefaultRadarCallsPerMinute,
 	}
 }
 
@@ -174,12 +330,175 @@ type RadarLog struct {
 	NewVideos    int       `json:"new_videos"`
 	Status       string    `json:"status"` // success 或 error
 	ErrorMessage string    `json:"error_message"`
-	VideoList    string    `json:"video_list"` // JSON 数组，存储每个视频的详情摘要
+	VideoList    string    `json:"video_list"`  // JSON 数组，存储每个视频的详情摘要
+	Mode         string    `json:"mode"`        // latest 或 backfill
+	Pages        int       `json:"pages"`       // 本次翻页数
+	StopReason   string    `json:"stop_reason"` // 停止翻页的原因
 }
 
+// 雷达翻页模式
+const (
+	RadarLogModeLatest   = "latest"   // 从最新一页翻到已知视频为止
+	RadarLogModeBackfill = "backfill" // 从保存的游标继续回填历史视频
+)
+
+// 雷达停止翻页的原因
+const (
+	RadarStopReachedKnown = "reached_known" // 遇到已下载、已入队或被过滤规则跳过的视频
+	RadarStopDepth        = "depth"         // 达到配置的翻页深度
+	RadarStopEnd          = "end"           // 已到最后一页
+	RadarStopError        = "error"         // 请求或解析失败
+	RadarStopCancelled    = "cancelled"     // 服务停止
+)
+
 // RadarVideoSummary 单次扫描中某个视频的摘要信息
 type RadarVideoSummary struct {
 	VideoID string `json:"video_id"`
 	Title   string `json:"title"`
-	IsNew   bool   `json:"is_new"` // true=新视频并已加入队列，false=已存在
+	IsNew   bool   `json:"is_new"` // true=新视频，false=已存在
+	// 被过滤规则跳过时记录命中的规则，见 RadarRule* 常量
+	SkipRule string `json:"skip_rule,omitempty"`
+}
+
+// SyncCursor Hub 同步游标，指向最后一条已被 Hub 确认的记录
+type SyncCursor struct {
+	SyncType  string    `json:"sync_type"` // browse 或 download
+	Time      time.Time `json:"cursor_time"`
+	ID        string    `json:"cursor_id"` // 同一时间戳下的记录 ID
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SyncBatch 已发送但尚未被 Hub 确认的同步批次
+type SyncBatch struct {
+	ID         string     `json:"id"` // 同时作为 sync_data 消息 ID
+	SyncType   string     `json:"sync_type"`
+	Payload    string     `json:"payload"` // SyncDataPayload JSON，重放时原样发送
+	Count      int        `json:"count"`
+	HasMore    bool       `json:"has_more"`
+	CursorTime time.Time  `json:"cursor_time"` // 确认后游标前进到的位置
+	CursorID   string     `json:"cursor_id"`
+	Attempts   int        `json:"attempts"`
+	CreatedAt  time.Time  `json:"created_at"`
+	SentAt     *time.Time `json:"sent_at,omitempty"`
+}
+
+// CloudCommandAudit 被拒绝执行的云端消息审计记录
+type CloudCommandAudit struct {
+	ID          int64     `json:"id"`
+	MessageID   string    `json:"message_id"`
+	MessageType string    `json:"message_type"`
+	Action      string    `json:"action,omitempty"`
+	APIKey      string    `json:"api_key,omitempty"`
+	Reason      string    `json:"reason"` // 拒绝原因，如 bad_signature、replayed_nonce、action_not_allowed
+	Detail      string    `json:"detail,omitempty"`
+	MessageTime int64     `json:"message_time"` // 消息自带的 Unix 时间戳
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// CloudOutboxMessage 与 Hub 断开期间暂存的待发送消息
+type CloudOutboxMessage struct {
+	Seq         int64     `json:"seq"`        // 入队顺序，按此顺序补发
+	MessageID   string    `json:"message_id"` // CloudMessage.ID，重复入队时覆盖原消息
+	MessageType string    `json:"message_type"`
+	Message     string    `json:"message"` // CloudMessage JSON
+	CreatedAt   time.Time `json:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// 保存的搜索类型，与 contact_list 的 type 参数一致
+const (
+	SavedSearchTypeAccount = 1 // 账号
+	SavedSearchTypeLive    = 2 // 直播，不支持翻页
+	SavedSearchTypeVideo   = 3 // 视频
+)
+
+// SavedSearch 保存的关键词搜索，按 IntervalMinutes 定期重新执行
+type SavedSearch struct {
+	ID              string     `json:"id"`
+	Keyword         string     `json:"keyword"`
+	SearchType      int        `json:"search_type"`
+	MaxResults      int        `json:"max_results"`      // 每次执行最多收集的结果数
+	IntervalMinutes int        `json:"interval_minutes"` // 0 表示仅手动执行
+	Enabled         bool       `json:"enabled"`
+	LastRunAt       *time.Time `json:"last_run_at"`
+	NextRunAt       *time.Time `json:"next_run_at"`
+	LastResultCount int        `json:"last_result_count"`
+	LastNewCount    int        `json:"last_new_count"` // 上次执行首次出现的结果数
+	LastError       string     `json:"last_error"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// SavedSearchResult 保存的搜索命中的结果，同一搜索内按 ResultKey 去重
+type SavedSearchResult struct {
+	ID          int64     `json:"id"`
+	SearchID    string    `json:"search_id"`
+	Keyword     string    `json:"keyword"`
+	SearchType  int       `json:"search_type"`
+	ResultKey   string    `json:"result_key"` // 账号为 username，视频和直播为 object id
+	Title       string    `json:"title"`
+	Author      string    `json:"author"`
+	Username    string    `json:"username"`
+	ObjectID    string    `json:"object_id"`
+	NonceID     string    `json:"nonce_id"`
+	CoverURL    string    `json:"cover_url"`
+	Raw         string    `json:"raw,omitempty"` // 原始结果 JSON
+	FirstSeenAt time.Time `json:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+}
+
+// Comment 导入数据库的视频评论，顶级评论和回复各占一行
+type Comment struct {
+	ID             int64      `json:"id"`
+	ObjectID       string     `json:"object_id"`
+	CommentID      string     `json:"comment_id"`
+	ParentID       string     `json:"parent_id"` // 回复所属的顶级评论 ID，顶级评论为空
+	ReplyCommentID string     `json:"reply_comment_id"`
+	Level          int        `json:"level"` // 1 顶级评论，2 回复
+	Username       string     `json:"username"`
+	Nickname       string     `json:"nickname"`
+	Content        string     `json:"content"`
+	Region         string     `json:"region"`
+	LikeCount      int        `json:"like_count"`
+	CommentTime    *time.Time `json:"comment_time"`
+	VideoTitle     string     `json:"video_title"`
+	VideoAuthor    string     `json:"video_author"`
+	ExportedAt     time.Time  `json:"exported_at"`
+}
+
+// CommentFilter 评论查询条件
+type CommentFilter struct {
+	PaginationParams
+	ObjectID  string     `json:"object_id"`
+	ParentID  string     `json:"parent_id"`
+	Level     int        `json:"level"` // 0 表示不限
+	Username  string     `json:"username"`
+	Query     string     `json:"query"` // 匹配内容或昵称
+	MinLikes  int        `json:"min_likes"`
+	StartDate *time.Time `json:"start_date"`
+	EndDate   *time.Time `json:"end_date"`
+}
+
+// CommentVideoSummary 已导入评论的视频汇总
+type CommentVideoSummary struct {
+	ObjectID       string    `json:"object_id"`
+	VideoTitle     string    `json:"video_title"`
+	VideoAuthor    string    `json:"video_author"`
+	CommentCount   int       `json:"comment_count"`
+	ReplyCount     int       `json:"reply_count"`
+	LastExportedAt time.Time `json:"last_exported_at"`
+}
+
+// CommentExportJob 持久化的评论导出任务，请求、进度和结果以 JSON 保存
+type CommentExportJob struct {
+	ID        string    `json:"id"`
+	ObjectID  string    `json:"object_id"`
+	Title     string    `json:"title"`
+	Request   string    `json:"request"`
+	Status    string    `json:"status"`
+	Progress  string    `json:"progress"`
+	Result    string    `json:"result"`
+	Error     string    `json:"error"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -50,7 +50,7 @@ func (r *QueueRepository) GetByID(id string) (*QueueItem, error) {
 		SELECT id, video_id, title, author, COALESCE(cover_url, '') as cover_url, video_url, decrypt_key, 
 			COALESCE(duration, 0) as duration, COALESCE(resolution, '') as resolution, total_size, downloaded_size,
 			status, priority, added_time, start_time, speed, chunk_size,
-			chunks_total, chunks_completed, retry_count, error_message,
+			chunks_total, chunks_completed, COALESCE(chunks_bitmap, '') as chunks_bitmap, retry_count, error_message,
 			created_at, updated_at
 		FROM download_queue WHERE id = ?
 	`
@@ -64,7 +64,7 @@ func (r *QueueRepository) GetByID(id string) (*QueueItem, error) {
 		&item.ID, &item.VideoID, &item.Title, &item.Author, &coverURL, &item.VideoURL, &decryptKey,
 		&item.Duration, &resolution, &item.TotalSize, &item.DownloadedSize, &item.Status, &item.Priority,
 		&item.AddedTime, &startTime, &item.Speed, &item.ChunkSize,
-		&item.ChunksTotal, &item.ChunksCompleted, &item.RetryCount,
+		&item.ChunksTotal, &item.ChunksCompleted, &item.ChunksBitmap, &item.RetryCount,
 		&errorMessage, &item.CreatedAt, &item.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
@@ -89,7 +89,7 @@ func (r *QueueRepository) GetByVideoID(videoID string) (*QueueItem, error) {
 		SELECT id, video_id, title, author, COALESCE(cover_url, '') as cover_url, video_url, decrypt_key, 
 			COALESCE(duration, 0) as duration, COALESCE(resolution, '') as resolution, total_size, downloaded_size,
 			status, priority, added_time, start_time, speed, chunk_size,
-			chunks_total, chunks_completed, retry_count, error_message,
+			chunks_total, chunks_completed, COALESCE(chunks_bitmap, '') as chunks_bitmap, retry_count, error_message,
 			created_at, updated_at
 		FROM download_queue WHERE video_id = ? LIMIT 1
 	`
@@ -103,7 +103,7 @@ func (r *QueueRepository) GetByVideoID(videoID string) (*QueueItem, error) {
 		&item.ID, &item.VideoID, &item.Title, &item.Author, &coverURL, &item.VideoURL, &decryptKey,
 		&item.Duration, &resolution, &item.TotalSize, &item.DownloadedSize, &item.Status, &item.Priority,
 		&item.AddedTime, &startTime, &item.Speed, &item.ChunkSize,
-		&item.ChunksTotal, &item.ChunksCompleted, &item.RetryCount,
+		&item.ChunksTotal, &item.ChunksCompleted, &item.ChunksBitmap, &item.RetryCount,
 		&errorMessage, &item.CreatedAt, &item.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
@@ -330,6 +330,24 @@ func (r *QueueRepository) UpdateProgress(id string, downloadedSize int64, chunks
 	return nil
 }
 
+// UpdateChunkProgress 更新下载进度及已完成分片位图
+func (r *QueueRepository) UpdateChunkProgress(id string, downloadedSize int64, chunksCompleted int, chunksBitmap string, speed int64) error {
+	query := `
+		UPDATE download_queue SET
+			downloaded_size = ?, chunks_completed = ?, chunks_bitmap = ?, speed = ?, updated_at = ?
+		WHERE id = ?
+	`
+	result, err := r.db.Exec(query, downloadedSize, chunksCompleted, chunksBitmap, speed, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update queue item chunk progress: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return fmt.Errorf("queue item not found: %s", id)
+	}
+	return nil
+}
+
 // Reorder 根据新顺序更新队列项目的优先级
 func (r *QueueRepository) Reorder(ids []string) error {
 	if len(ids) == 0 {
@@ -361,6 +379,24 @@ func (r *QueueRepository) Reorder(ids []string) error {
 	return nil
 }
 
+// MoveToFront 将待下载、暂停或失败的项目放回待下载状态，优先级设为所有待下载项目中最高
+// 项目已被领取或已完成时不做修改并返回 false
+func (r *QueueRepository) MoveToFront(id string) (bool, error) {
+	result, err := r.db.Exec(`
+		UPDATE download_queue SET
+			status = ?,
+			priority = (SELECT COALESCE(MAX(priority), 0) + 1 FROM download_queue WHERE status = ?),
+			updated_at = ?
+		WHERE id = ? AND status IN (?, ?, ?)
+	`, QueueStatusPending, QueueStatusPending, time.Now(), id,
+		QueueStatusPending, QueueStatusPaused, QueueStatusFailed)
+	if err != nil {
+		return false, fmt.Errorf("failed to move queue item to front: %w", err)
+	}
+	rows, _ := result.RowsAffected()
+	return rows > 0, nil
+}
+
 // Count 返回队列项目的总数
 func (r *QueueRepository) Count() (int64, error) {
 	var count int64
@@ -413,7 +449,7 @@ func (r *QueueRepository) GetNextPending() (*QueueItem, error) {
 		SELECT id, video_id, title, author, COALESCE(cover_url, '') as cover_url, video_url, decrypt_key, 
 			COALESCE(duration, 0) as duration, total_size, downloaded_size,
 			status, priority, added_time, start_time, speed, chunk_size,
-			chunks_total, chunks_completed, retry_count, error_message,
+			chunks_total, chunks_completed, COALESCE(chunks_bitmap, '') as chunks_bitmap, retry_count, error_message,
 			created_at, updated_at
 		FROM download_queue
 		WHERE status = ?
@@ -429,7 +465,7 @@ func (r *QueueRepository) GetNextPending() (*QueueItem, error) {
 		&item.ID, &item.VideoID, &item.Title, &item.Author, &coverURL, &item.VideoURL, &decryptKey,
 		&item.Duration, &item.TotalSize, &item.DownloadedSize, &item.Status, &item.Priority,
 		&item.AddedTime, &startTime, &item.Speed, &item.ChunkSize,
-		&item.ChunksTotal, &item.ChunksCompleted, &item.RetryCount,
+		&item.ChunksTotal, &item.ChunksCompleted, &item.ChunksBitmap, &item.RetryCount,
 		&errorMessage, &item.CreatedAt, &item.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
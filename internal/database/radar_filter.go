@@ -0,0 +1,172 @@
+package database
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 过滤规则名称，记录在 RadarVideoSummary.SkipRule 中
+const (
+	RadarRuleIncludeKeywords = "include_keywords"
+	RadarRuleExcludeKeywords = "exclude_keywords"
+	RadarRuleIncludeRegex    = "include_regex"
+	RadarRuleExcludeRegex    = "exclude_regex"
+	RadarRuleMinDuration     = "min_duration"
+	RadarRuleMaxDuration     = "max_duration"
+	RadarRuleMaxFileSize     = "max_file_size"
+	RadarRuleResolution      = "resolution"
+)
+
+// RadarFilterRules 监控目标的视频过滤规则，未设置的条件不参与过滤
+type RadarFilterRules struct {
+	IncludeKeywords     []string `json:"include_keywords,omitempty"`     // 标题须包含任一关键词（不区分大小写）
+	ExcludeKeywords     []string `json:"exclude_keywords,omitempty"`     // 标题包含任一关键词则跳过
+	IncludeRegex        string   `json:"include_regex,omitempty"`        // 标题须匹配的正则
+	ExcludeRegex        string   `json:"exclude_regex,omitempty"`        // 标题匹配则跳过的正则
+	MinDuration         int64    `json:"min_duration,omitempty"`         // 最短时长（秒）
+	MaxDuration         int64    `json:"max_duration,omitempty"`         // 最长时长（秒）
+	MaxFileSize         int64    `json:"max_file_size,omitempty"`        // 最大文件大小（字节）
+	PreferredResolution string   `json:"preferred_resolution,omitempty"` // 如 "720p"、"1280x720"，低于此分辨率的视频跳过
+}
+
+// RadarFilterInput 参与过滤的视频信息，未知的字段填零值即可
+type RadarFilterInput struct {
+	Title      string
+	Duration   int64 // 秒
+	FileSize   int64 // 字节
+	Resolution string
+}
+
+// RadarFilter 编译后的过滤规则
+type RadarFilter struct {
+	rules         RadarFilterRules
+	include       *regexp.Regexp
+	exclude       *regexp.Regexp
+	minResolution int
+}
+
+// IsEmpty 返回是否未设置任何规则
+func (r RadarFilterRules) IsEmpty() bool {
+	return len(r.IncludeKeywords) == 0 && len(r.ExcludeKeywords) == 0 &&
+		r.IncludeRegex == "" && r.ExcludeRegex == "" &&
+		r.MinDuration == 0 && r.MaxDuration == 0 && r.MaxFileSize == 0 &&
+		r.PreferredResolution == ""
+}
+
+// Validate 校验规则是否有效
+func (r RadarFilterRules) Validate() error {
+	_, err := r.Compile()
+	return err
+}
+
+// Compile 编译正则并解析分辨率
+func (r RadarFilterRules) Compile() (*RadarFilter, error) {
+	if r.MinDuration < 0 || r.MaxDuration < 0 || r.MaxFileSize < 0 {
+		return nil, fmt.Errorf("时长和文件大小不能为负数")
+	}
+	if r.MaxDuration > 0 && r.MinDuration > r.MaxDuration {
+		return nil, fmt.Errorf("最短时长不能大于最长时长")
+	}
+
+	f := &RadarFilter{rules: r}
+	var err error
+	if r.IncludeRegex != "" {
+		if f.include, err = regexp.Compile(r.IncludeRegex); err != nil {
+			return nil, fmt.Errorf("包含正则无效: %w", err)
+		}
+	}
+	if r.ExcludeRegex != "" {
+		if f.exclude, err = regexp.Compile(r.ExcludeRegex); err != nil {
+			return nil, fmt.Errorf("排除正则无效: %w", err)
+		}
+	}
+	if r.PreferredResolution != "" {
+		if f.minResolution = ParseResolutionHeight(r.PreferredResolution); f.minResolution == 0 {
+			return nil, fmt.Errorf("无法识别的分辨率: %s", r.PreferredResolution)
+		}
+	}
+	return f, nil
+}
+
+// Check 返回跳过该视频的规则，空字符串表示通过
+func (f *RadarFilter) Check(in RadarFilterInput) string {
+	if f == nil {
+		return ""
+	}
+	title := strings.ToLower(in.Title)
+
+	if len(f.rules.IncludeKeywords) > 0 && !containsAnyKeyword(title, f.rules.IncludeKeywords) {
+		return RadarRuleIncludeKeywords
+	}
+	if containsAnyKeyword(title, f.rules.ExcludeKeywords) {
+		return RadarRuleExcludeKeywords
+	}
+	if f.include != nil && !f.include.MatchString(in.Title) {
+		return RadarRuleIncludeRegex
+	}
+	if f.exclude != nil && f.exclude.MatchString(in.Title) {
+		return RadarRuleExcludeRegex
+	}
+
+	// 时长、大小和分辨率未知时不过滤
+	if in.Duration > 0 {
+		if f.rules.MinDuration > 0 && in.Duration < f.rules.MinDuration {
+			return RadarRuleMinDuration
+		}
+		if f.rules.MaxDuration > 0 && in.Duration > f.rules.MaxDuration {
+			return RadarRuleMaxDuration
+		}
+	}
+	if f.rules.MaxFileSize > 0 && in.FileSize > f.rules.MaxFileSize {
+		return RadarRuleMaxFileSize
+	}
+	if f.minResolution > 0 {
+		if height := ParseResolutionHeight(in.Resolution); height > 0 && height < f.minResolution {
+			return RadarRuleResolution
+		}
+	}
+	return ""
+}
+
+func containsAnyKeyword(lowerTitle string, keywords []string) bool {
+	for _, keyword := range keywords {
+		keyword = strings.ToLower(strings.TrimSpace(keyword))
+		if keyword != "" && strings.Contains(lowerTitle, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseResolutionHeight 解析分辨率的短边像素数，支持 "720p" 和 "1280x720" 格式
+// 无法识别时返回 0
+func ParseResolutionHeight(resolution string) int {
+	s := strings.ToLower(strings.TrimSpace(resolution))
+	if s == "" {
+		return 0
+	}
+	if strings.HasSuffix(s, "p") {
+		if n, err := strconv.Atoi(strings.TrimSuffix(s, "p")); err == nil && n > 0 {
+			return n
+		}
+		return 0
+	}
+	parts := strings.FieldsFunc(s, func(r rune) bool { return r == 'x' || r == '*' || r == '×' })
+	if len(parts) != 2 {
+		if n, err := strconv.Atoi(s); err == nil && n > 0 {
+			return n
+		}
+		return 0
+	}
+	w, errW := strconv.Atoi(strings.TrimSpace(parts[0]))
+	h, errH := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if errW != nil || errH != nil || w <= 0 || h <= 0 {
+		return 0
+	}
+	if w < h {
+		return w
+	}
+	return h
+}
@@ -0,0 +1,244 @@
+package database
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// 通知事件类型
+const (
+	NotifyEventNewVideo         = "new_video"         // 雷达发现新视频
+	NotifyEventDownloadComplete = "download_complete" // 下载完成
+	NotifyEventDownloadFailed   = "download_failed"   // 重试次数用尽后仍下载失败
+)
+
+// 通知渠道类型
+const (
+	NotifyChannelWebhook  = "webhook"  // 通用 HTTP Webhook，支持自定义 JSON 模板
+	NotifyChannelWeCom    = "wecom"    // 企业微信群机器人
+	NotifyChannelDingTalk = "dingtalk" // 钉钉群机器人
+	NotifyChannelSMTP     = "smtp"     // 邮件
+)
+
+// NotifyChannel 单个通知渠道的配置
+type NotifyChannel struct {
+	Type    string            `json:"type"`
+	URL     string            `json:"url,omitempty"`      // webhook / 机器人地址
+	Headers map[string]string `json:"headers,omitempty"`  // webhook 附加请求头
+	Body    string            `json:"body,omitempty"`     // webhook 请求体模板（text/template），为空时发送事件 JSON
+	Secret  string            `json:"secret,omitempty"`   // 钉钉机器人加签密钥
+	Host    string            `json:"host,omitempty"`     // SMTP 服务器
+	Port    int               `json:"port,omitempty"`     // SMTP 端口，默认 25
+	User    string            `json:"username,omitempty"` // SMTP 用户名，为空时不认证
+	Pass    string            `json:"password,omitempty"` // SMTP 密码
+	From    string            `json:"from,omitempty"`
+	To      []string          `json:"to,omitempty"`
+}
+
+// NotifySecretMask 对外返回通知配置时替代密钥和密码的占位值，更新时原样提交表示保留原值
+const NotifySecretMask = "******"
+
+// RadarNotifyConfig 监控目标的通知配置
+type RadarNotifyConfig struct {
+	Events   []string        `json:"events,omitempty"` // 订阅的事件，为空时不发送通知
+	Channels []NotifyChannel `json:"channels,omitempty"`
+}
+
+// IsEmpty 返回是否未配置通知
+func (c RadarNotifyConfig) IsEmpty() bool {
+	return len(c.Events) == 0 && len(c.Channels) == 0
+}
+
+// Wants 返回是否订阅了指定事件
+func (c RadarNotifyConfig) Wants(event string) bool {
+	if len(c.Channels) == 0 {
+		return false
+	}
+	for _, e := range c.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate 校验通知配置
+func (c RadarNotifyConfig) Validate() error {
+	for _, e := range c.Events {
+		switch e {
+		case NotifyEventNewVideo, NotifyEventDownloadComplete, NotifyEventDownloadFailed:
+		default:
+			return fmt.Errorf("未知的通知事件: %s", e)
+		}
+	}
+	for i, ch := range c.Channels {
+		switch ch.Type {
+		case NotifyChannelWebhook, NotifyChannelWeCom, NotifyChannelDingTalk:
+			if !strings.HasPrefix(ch.URL, "http://") && !strings.HasPrefix(ch.URL, "https://") {
+				return fmt.Errorf("通知渠道 %d 的地址无效", i+1)
+			}
+		case NotifyChannelSMTP:
+			if ch.Host == "" || ch.From == "" || len(ch.To) == 0 {
+				return fmt.Errorf("通知渠道 %d 缺少 SMTP 服务器、发件人或收件人", i+1)
+			}
+		default:
+			return fmt.Errorf("未知的通知渠道类型: %s", ch.Type)
+		}
+	}
+	return nil
+}
+
+// Redacted 返回隐藏了密钥的副本，用于 API 响应
+// 隐藏钉钉加签密钥、SMTP 密码、webhook 请求头的值，以及 webhook / 机器人地址中的查询参数和用户信息
+// （如企业微信的 key=、钉钉的 access_token=）
+func (c RadarNotifyConfig) Redacted() RadarNotifyConfig {
+	if len(c.Channels) == 0 {
+		return c
+	}
+	channels := make([]NotifyChannel, len(c.Channels))
+	for i, ch := range c.Channels {
+		if ch.Secret != "" {
+			ch.Secret = NotifySecretMask
+		}
+		if ch.Pass != "" {
+			ch.Pass = NotifySecretMask
+		}
+		ch.URL = maskNotifyURL(ch.URL)
+		if len(ch.Headers) > 0 {
+			headers := make(map[string]string, len(ch.Headers))
+			for k := range ch.Headers {
+				headers[k] = NotifySecretMask
+			}
+			ch.Headers = headers
+		}
+		channels[i] = ch
+	}
+	c.Channels = channels
+	return c
+}
+
+// RestoreSecrets 将提交回来的占位值替换为已保存渠道的对应值
+// 按类型、地址、服务器和用户名匹配渠道，地址为隐藏后的形式时与已保存地址隐藏后的形式比较；
+// 找不到对应渠道时清空占位值
+func (c *RadarNotifyConfig) RestoreSecrets(existing RadarNotifyConfig) {
+	for i := range c.Channels {
+		ch := &c.Channels[i]
+		if !ch.hasMask() {
+			continue
+		}
+		var saved *NotifyChannel
+		for j := range existing.Channels {
+			if existing.Channels[j].sameEndpoint(*ch) {
+				saved = &existing.Channels[j]
+				break
+			}
+		}
+		if ch.Secret == NotifySecretMask {
+			ch.Secret = ""
+			if saved != nil {
+				ch.Secret = saved.Secret
+			}
+		}
+		if ch.Pass == NotifySecretMask {
+			ch.Pass = ""
+			if saved != nil {
+				ch.Pass = saved.Pass
+			}
+		}
+		if isMaskedNotifyURL(ch.URL) {
+			ch.URL = ""
+			if saved != nil {
+				ch.URL = saved.URL
+			}
+		}
+		for k, v := range ch.Headers {
+			if v != NotifySecretMask {
+				continue
+			}
+			if savedValue, ok := saved.header(k); ok {
+				ch.Headers[k] = savedValue
+			} else {
+				delete(ch.Headers, k)
+			}
+		}
+	}
+}
+
+// hasMask 返回渠道中是否包含占位值
+func (ch NotifyChannel) hasMask() bool {
+	if ch.Secret == NotifySecretMask || ch.Pass == NotifySecretMask || isMaskedNotifyURL(ch.URL) {
+		return true
+	}
+	for _, v := range ch.Headers {
+		if v == NotifySecretMask {
+			return true
+		}
+	}
+	return false
+}
+
+// header 返回已保存渠道的请求头，渠道为空时返回 false
+func (ch *NotifyChannel) header(key string) (string, bool) {
+	if ch == nil {
+		return "", false
+	}
+	v, ok := ch.Headers[key]
+	return v, ok
+}
+
+// sameEndpoint 返回两个渠道是否指向同一个接收端，other 为提交回来的渠道
+// other 的地址被隐藏时只比较隐藏后的形式，否则要求地址完全相同
+func (ch NotifyChannel) sameEndpoint(other NotifyChannel) bool {
+	if ch.Type != other.Type || ch.Host != other.Host || ch.User != other.User {
+		return false
+	}
+	if isMaskedNotifyURL(other.URL) {
+		return maskNotifyURL(ch.URL) == other.URL
+	}
+	return ch.URL == other.URL
+}
+
+// maskNotifyURL 隐藏地址中的查询参数和用户信息，解析失败时整体隐藏
+func maskNotifyURL(raw string) string {
+	if raw == "" {
+		return raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return NotifySecretMask
+	}
+	hasUser, hasQuery := u.User != nil, u.RawQuery != ""
+	if !hasUser && !hasQuery {
+		return raw
+	}
+	u.User, u.RawQuery, u.Fragment = nil, "", ""
+	masked := u.String()
+	if hasUser {
+		masked = strings.Replace(masked, "://", "://"+NotifySecretMask+"@", 1)
+	}
+	if hasQuery {
+		masked += "?" + NotifySecretMask
+	}
+	return masked
+}
+
+// isMaskedNotifyURL 返回地址是否为 maskNotifyURL 隐藏后的形式
+func isMaskedNotifyURL(raw string) bool {
+	return strings.Contains(raw, NotifySecretMask)
+}
+
+// Redacted 返回隐藏了通知密钥的副本，用于 API 响应
+func (t RadarTarget) Redacted() RadarTarget {
+	t.Notify = t.Notify.Redacted()
+	return t
+}
+
+// RedactRadarTargets 隐藏一组监控目标的通知密钥
+func RedactRadarTargets(targets []RadarTarget) []RadarTarget {
+	redacted := make([]RadarTarget, len(targets))
+	for i, t := range targets {
+		redacted[i] = t.Redacted()
+	}
+	return redacted
+}
@@ -2,6 +2,7 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"wx_channel/internal/utils"
@@ -23,10 +24,42 @@ type RadarTarget struct {
 	IntervalMinutes int               `json:"interval_minutes"` // 监控频率 (分钟)
 	LastCheckTime   *time.Time        `json:"last_check_time"`  // 上次检测时间 (可能为 nil)
 	Status          RadarTargetStatus `json:"status"`
-	CreatedAt       time.Time         `json:"created_at"`
-	UpdatedAt       time.Time         `json:"updated_at"`
+	PageDepth       int               `json:"page_depth"`      // 每次检测最多翻页数
+	Backfill        bool              `json:"backfill"`        // 是否回填历史视频
+	BackfillCursor  string            `json:"backfill_cursor"` // 历史回填的翻页游标
+	Filters         RadarFilterRules  `json:"filters"`         // 视频过滤规则
+	Notify          RadarNotifyConfig `json:"notify"`          // 通知配置
+	FixedInterval   bool              `json:"fixed_interval"`  // 关闭自适应，严格按 IntervalMinutes 检测
+
+	// 以下为雷达服务维护的调度状态
+	NextCheckTime       *time.Time `json:"next_check_time"`       // 下次检测时间 (可能为 nil)
+	PostIntervalMinutes int        `json:"post_interval_minutes"` // 观测到的平均发布间隔 (分钟)，0 表示未知
+	FailCount           int        `json:"fail_count"`            // 连续被微信拒绝的次数
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
 }
 
+// 翻页深度范围
+const (
+	DefaultRadarPageDepth = 5
+	MaxRadarPageDepth     = 50
+)
+
+// NormalizePageDepth 将翻页深度限制在有效范围内
+func (t *RadarTarget) NormalizePageDepth() {
+	if t.PageDepth <= 0 {
+		t.PageDepth = DefaultRadarPageDepth
+	}
+	if t.PageDepth > MaxRadarPageDepth {
+		t.PageDepth = MaxRadarPageDepth
+	}
+}
+
+// radarTargetColumns 查询监控目标时的列，顺序与 targetFromRow 一致
+const radarTargetColumns = `id, username, author_name, interval_minutes, last_check_time, status, created_at, updated_at,
+			COALESCE(page_depth, 5), COALESCE(backfill, 0), COALESCE(backfill_cursor, ''), COALESCE(filter_rules, ''), COALESCE(notify_config, ''),
+			COALESCE(fixed_interval, 0), next_check_time, COALESCE(post_interval_minutes, 0), COALESCE(fail_count, 0)`
+
 // RadarRepository 处理雷达配置相关的数据库操作
 type RadarRepository struct{}
 
@@ -40,6 +73,10 @@ func (r *RadarRepository) targetFromRow(scanner interface{ Scan(...interface{})
 	var target RadarTarget
 	var lastCheckTimeStr sql.NullString
 	var createdAtStr, updatedAtStr string
+	var backfill int
+	var filterRules, notifyConfig string
+	var fixedInterval int
+	var nextCheckTimeStr sql.NullString
 
 	err := scanner.Scan(
 		&target.ID,
@@ -50,10 +87,31 @@ func (r *RadarRepository) targetFromRow(scanner interface{ Scan(...interface{})
 		&target.Status,
 		&createdAtStr,
 		&updatedAtStr,
+		&target.PageDepth,
+		&backfill,
+		&target.BackfillCursor,
+		&filterRules,
+		&notifyConfig,
+		&fixedInterval,
+		&nextCheckTimeStr,
+		&target.PostIntervalMinutes,
+		&target.FailCount,
 	)
 	if err != nil {
 		return nil, err
 	}
+	target.Backfill = backfill != 0
+	target.FixedInterval = fixedInterval != 0
+	if filterRules != "" {
+		if err := json.Unmarshal([]byte(filterRules), &target.Filters); err != nil {
+			utils.LogWarn("[Radar] 解析过滤规则失败 [%s]: %v", target.ID, err)
+		}
+	}
+	if notifyConfig != "" {
+		if err := json.Unmarshal([]byte(notifyConfig), &target.Notify); err != nil {
+			utils.LogWarn("[Radar] 解析通知配置失败 [%s]: %v", target.ID, err)
+		}
+	}
 
 	// 转换时间
 	if lastCheckTimeStr.Valid && lastCheckTimeStr.String != "" {
@@ -61,6 +119,11 @@ func (r *RadarRepository) targetFromRow(scanner interface{ Scan(...interface{})
 			target.LastCheckTime = &t
 		}
 	}
+	if nextCheckTimeStr.Valid && nextCheckTimeStr.String != "" {
+		if t, err := time.Parse(time.RFC3339, nextCheckTimeStr.String); err == nil {
+			target.NextCheckTime = &t
+		}
+	}
 
 	target.CreatedAt, _ = time.Parse(time.RFC3339, createdAtStr)
 	target.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAtStr)
@@ -82,8 +145,9 @@ func (r *RadarRepository) Add(target *RadarTarget) error {
 
 	query := `
 		INSERT INTO radar_targets (
-			id, username, author_name, interval_minutes, last_check_time, status, created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			id, username, author_name, interval_minutes, last_check_time, status, created_at, updated_at,
+			page_depth, backfill, backfill_cursor, filter_rules, notify_config, fixed_interval
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 	_, err := db.Exec(query,
 		target.ID,
@@ -94,6 +158,12 @@ func (r *RadarRepository) Add(target *RadarTarget) error {
 		target.Status,
 		now,
 		now,
+		target.PageDepth,
+		target.Backfill,
+		target.BackfillCursor,
+		encodeRadarFilterRules(target.Filters),
+		encodeRadarNotifyConfig(target.Notify),
+		target.FixedInterval,
 	)
 	return err
 }
@@ -108,7 +178,9 @@ func (r *RadarRepository) Update(target *RadarTarget) error {
 
 	query := `
 		UPDATE radar_targets 
-		SET username = ?, author_name = ?, interval_minutes = ?, last_check_time = ?, status = ?, updated_at = ?
+		SET username = ?, author_name = ?, interval_minutes = ?, last_check_time = ?, status = ?, updated_at = ?,
+			page_depth = ?, backfill = ?, backfill_cursor = ?, filter_rules = ?, notify_config = ?,
+			fixed_interval = ?
 		WHERE id = ?
 	`
 	_, err := db.Exec(query,
@@ -118,11 +190,41 @@ func (r *RadarRepository) Update(target *RadarTarget) error {
 		lastCheckTime,
 		target.Status,
 		now,
+		target.PageDepth,
+		target.Backfill,
+		target.BackfillCursor,
+		encodeRadarFilterRules(target.Filters),
+		encodeRadarNotifyConfig(target.Notify),
+		target.FixedInterval,
 		target.ID,
 	)
 	return err
 }
 
+// encodeRadarFilterRules 将过滤规则序列化为 JSON，未设置规则时存空字符串
+func encodeRadarFilterRules(rules RadarFilterRules) string {
+	if rules.IsEmpty() {
+		return ""
+	}
+	b, err := json.Marshal(rules)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// encodeRadarNotifyConfig 将通知配置序列化为 JSON，未配置时存空字符串
+func encodeRadarNotifyConfig(config RadarNotifyConfig) string {
+	if config.IsEmpty() {
+		return ""
+	}
+	b, err := json.Marshal(config)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
 // UpdateLastCheckTime 仅更新上次检测时间
 func (r *RadarRepository) UpdateLastCheckTime(id string, lastCheckTime time.Time) error {
 	now := time.Now().Format(time.RFC3339)
@@ -135,10 +237,30 @@ func (r *RadarRepository) UpdateLastCheckTime(id string, lastCheckTime time.Time
 	return err
 }
 
+// UpdateBackfill 更新历史回填状态和翻页游标
+func (r *RadarRepository) UpdateBackfill(id string, backfill bool, cursor string) error {
+	now := time.Now().Format(time.RFC3339)
+	query := "UPDATE radar_targets SET backfill = ?, backfill_cursor = ?, updated_at = ? WHERE id = ?"
+	_, err := db.Exec(query, backfill, cursor, now, id)
+	return err
+}
+
+// UpdateSchedule 更新雷达服务维护的调度状态
+func (r *RadarRepository) UpdateSchedule(id string, nextCheckTime time.Time, postIntervalMinutes int, failCount int) error {
+	now := time.Now().Format(time.RFC3339)
+	query := `
+		UPDATE radar_targets
+		SET next_check_time = ?, post_interval_minutes = ?, fail_count = ?, updated_at = ?
+		WHERE id = ?
+	`
+	_, err := db.Exec(query, nextCheckTime.Format(time.RFC3339), postIntervalMinutes, failCount, now, id)
+	return err
+}
+
 // GetAll 获取所有监控目标
 func (r *RadarRepository) GetAll() ([]RadarTarget, error) {
 	query := `
-		SELECT id, username, author_name, interval_minutes, last_check_time, status, created_at, updated_at
+		SELECT ` + radarTargetColumns + `
 		FROM radar_targets
 		ORDER BY created_at DESC
 	`
@@ -162,7 +284,7 @@ func (r *RadarRepository) GetAll() ([]RadarTarget, error) {
 // GetActive 获取所有活动状态的监控目标
 func (r *RadarRepository) GetActive() ([]RadarTarget, error) {
 	query := `
-		SELECT id, username, author_name, interval_minutes, last_check_time, status, created_at, updated_at
+		SELECT ` + radarTargetColumns + `
 		FROM radar_targets
 		WHERE status = 'active'
 		ORDER BY created_at DESC
@@ -187,7 +309,7 @@ func (r *RadarRepository) GetActive() ([]RadarTarget, error) {
 // GetByID 通过 ID 获取监控目标
 func (r *RadarRepository) GetByID(id string) (*RadarTarget, error) {
 	query := `
-		SELECT id, username, author_name, interval_minutes, last_check_time, status, created_at, updated_at
+		SELECT ` + radarTargetColumns + `
 		FROM radar_targets
 		WHERE id = ?
 	`
@@ -195,6 +317,23 @@ func (r *RadarRepository) GetByID(id string) (*RadarTarget, error) {
 	return r.targetFromRow(row)
 }
 
+// GetByAuthorName 按账号名称获取监控目标，不存在时返回 nil
+// 雷达入队的视频以监控目标的 AuthorName 作为作者，可据此关联回监控目标
+func (r *RadarRepository) GetByAuthorName(authorName string) (*RadarTarget, error) {
+	query := `
+		SELECT ` + radarTargetColumns + `
+		FROM radar_targets
+		WHERE author_name = ?
+		ORDER BY created_at ASC
+		LIMIT 1
+	`
+	target, err := r.targetFromRow(db.QueryRow(query, authorName))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return target, err
+}
+
 // Delete 删除监控目标
 func (r *RadarRepository) Delete(id string) error {
 	query := "DELETE FROM radar_targets WHERE id = ?"
@@ -223,9 +362,13 @@ func (r *RadarRepository) AddLog(log *RadarLog) error {
 
 	query := `
 		INSERT INTO radar_logs (
-			id, target_id, check_time, found_videos, new_videos, status, error_message, video_list
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			id, target_id, check_time, found_videos, new_videos, status, error_message, video_list,
+			mode, pages, stop_reason
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
+	if log.Mode == "" {
+		log.Mode = RadarLogModeLatest
+	}
 	_, err := db.Exec(query,
 		log.ID,
 		log.TargetID,
@@ -235,6 +378,9 @@ func (r *RadarRepository) AddLog(log *RadarLog) error {
 		log.Status,
 		log.ErrorMessage,
 		log.VideoList,
+		log.Mode,
+		log.Pages,
+		log.StopReason,
 	)
 	return err
 }
@@ -246,7 +392,8 @@ func (r *RadarRepository) GetLogsByTargetID(targetID string, limit int) ([]Radar
 	}
 
 	query := `
-		SELECT id, target_id, check_time, found_videos, new_videos, status, error_message, COALESCE(video_list, '')
+		SELECT id, target_id, check_time, found_videos, new_videos, status, error_message, COALESCE(video_list, ''),
+			COALESCE(mode, 'latest'), COALESCE(pages, 0), COALESCE(stop_reason, '')
 		FROM radar_logs
 		WHERE target_id = ?
 		ORDER BY check_time DESC
@@ -271,6 +418,9 @@ func (r *RadarRepository) GetLogsByTargetID(targetID string, limit int) ([]Radar
 			&log.Status,
 			&log.ErrorMessage,
 			&log.VideoList,
+			&log.Mode,
+			&log.Pages,
+			&log.StopReason,
 		)
 		if err != nil {
 			return nil, err
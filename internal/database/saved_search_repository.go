@@ -0,0 +1,253 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"wx_channel/internal/utils"
+)
+
+// SavedSearchRepository 处理保存的搜索及其结果的数据库操作
+type SavedSearchRepository struct {
+	db *sql.DB
+}
+
+// NewSavedSearchRepository 创建一个新的 SavedSearchRepository
+func NewSavedSearchRepository() *SavedSearchRepository {
+	return &SavedSearchRepository{db: GetDB()}
+}
+
+const savedSearchColumns = `id, keyword, search_type, max_results, interval_minutes, enabled, last_run_at, next_run_at,
+	last_result_count, last_new_count, last_error, created_at, updated_at`
+
+type savedSearchScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSavedSearch(row savedSearchScanner) (*SavedSearch, error) {
+	var s SavedSearch
+	var lastRunAt, nextRunAt sql.NullTime
+	err := row.Scan(&s.ID, &s.Keyword, &s.SearchType, &s.MaxResults, &s.IntervalMinutes, &s.Enabled,
+		&lastRunAt, &nextRunAt, &s.LastResultCount, &s.LastNewCount, &s.LastError, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if lastRunAt.Valid {
+		s.LastRunAt = &lastRunAt.Time
+	}
+	if nextRunAt.Valid {
+		s.NextRunAt = &nextRunAt.Time
+	}
+	return &s, nil
+}
+
+func nullableTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return *t
+}
+
+// Create 添加保存的搜索
+func (r *SavedSearchRepository) Create(search *SavedSearch) error {
+	if search.ID == "" {
+		search.ID = utils.RandomString(12)
+	}
+	now := time.Now()
+	search.CreatedAt = now
+	search.UpdatedAt = now
+
+	_, err := r.db.Exec(`
+		INSERT INTO saved_searches (id, keyword, search_type, max_results, interval_minutes, enabled, next_run_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		search.ID, search.Keyword, search.SearchType, search.MaxResults, search.IntervalMinutes, search.Enabled,
+		nullableTime(search.NextRunAt), search.CreatedAt, search.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create saved search: %w", err)
+	}
+	return nil
+}
+
+// Update 更新搜索条件和调度设置，不修改执行状态
+func (r *SavedSearchRepository) Update(search *SavedSearch) error {
+	search.UpdatedAt = time.Now()
+	_, err := r.db.Exec(`
+		UPDATE saved_searches
+		SET keyword = ?, search_type = ?, max_results = ?, interval_minutes = ?, enabled = ?, next_run_at = ?, updated_at = ?
+		WHERE id = ?`,
+		search.Keyword, search.SearchType, search.MaxResults, search.IntervalMinutes, search.Enabled,
+		nullableTime(search.NextRunAt), search.UpdatedAt, search.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update saved search: %w", err)
+	}
+	return nil
+}
+
+// GetByID 获取保存的搜索，不存在时返回 nil
+func (r *SavedSearchRepository) GetByID(id string) (*SavedSearch, error) {
+	search, err := scanSavedSearch(r.db.QueryRow(`SELECT `+savedSearchColumns+` FROM saved_searches WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saved search: %w", err)
+	}
+	return search, nil
+}
+
+// List 按创建时间返回所有保存的搜索
+func (r *SavedSearchRepository) List() ([]SavedSearch, error) {
+	return r.query(`SELECT ` + savedSearchColumns + ` FROM saved_searches ORDER BY created_at ASC`)
+}
+
+// ListDue 返回已启用且到达执行时间的搜索
+func (r *SavedSearchRepository) ListDue(now time.Time) ([]SavedSearch, error) {
+	return r.query(`
+		SELECT `+savedSearchColumns+`
+		FROM saved_searches
+		WHERE enabled = 1 AND next_run_at IS NOT NULL AND next_run_at <= ?
+		ORDER BY next_run_at ASC`, now)
+}
+
+func (r *SavedSearchRepository) query(query string, args ...interface{}) ([]SavedSearch, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list saved searches: %w", err)
+	}
+	defer rows.Close()
+
+	searches := []SavedSearch{}
+	for rows.Next() {
+		search, err := scanSavedSearch(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan saved search: %w", err)
+		}
+		searches = append(searches, *search)
+	}
+	return searches, rows.Err()
+}
+
+// Delete 删除保存的搜索及其结果
+func (r *SavedSearchRepository) Delete(id string) error {
+	if _, err := r.db.Exec("DELETE FROM saved_searches WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete saved search: %w", err)
+	}
+	return nil
+}
+
+// SetNextRun 设置下次执行时间
+func (r *SavedSearchRepository) SetNextRun(id string, next time.Time) error {
+	if _, err := r.db.Exec("UPDATE saved_searches SET next_run_at = ? WHERE id = ?", next, id); err != nil {
+		return fmt.Errorf("failed to schedule saved search: %w", err)
+	}
+	return nil
+}
+
+// RecordRun 记录一次执行的结果；next 为 nil 时不再自动执行
+func (r *SavedSearchRepository) RecordRun(id string, runAt time.Time, resultCount, newCount int, runErr string, next *time.Time) error {
+	_, err := r.db.Exec(`
+		UPDATE saved_searches
+		SET last_run_at = ?, last_result_count = ?, last_new_count = ?, last_error = ?, next_run_at = ?
+		WHERE id = ?`,
+		runAt, resultCount, newCount, runErr, nullableTime(next), id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record saved search run: %w", err)
+	}
+	return nil
+}
+
+// SaveResults 保存一次执行命中的结果，已存在的结果只刷新内容和 last_seen_at
+// 返回首次出现的结果数
+func (r *SavedSearchRepository) SaveResults(search *SavedSearch, seenAt time.Time, results []SavedSearchResult) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	newCount := 0
+	for _, res := range results {
+		result, err := tx.Exec(`
+			INSERT OR IGNORE INTO saved_search_results (
+				search_id, keyword, search_type, result_key, title, author, username, object_id, nonce_id, cover_url, raw,
+				first_seen_at, last_seen_at
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			search.ID, search.Keyword, search.SearchType, res.ResultKey, res.Title, res.Author, res.Username,
+			res.ObjectID, res.NonceID, res.CoverURL, res.Raw, seenAt, seenAt,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("failed to insert saved search result: %w", err)
+		}
+		if inserted, _ := result.RowsAffected(); inserted > 0 {
+			newCount++
+			continue
+		}
+		_, err = tx.Exec(`
+			UPDATE saved_search_results
+			SET title = ?, author = ?, username = ?, nonce_id = ?, cover_url = ?, raw = ?, last_seen_at = ?
+			WHERE search_id = ? AND result_key = ?`,
+			res.Title, res.Author, res.Username, res.NonceID, res.CoverURL, res.Raw, seenAt, search.ID, res.ResultKey,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("failed to update saved search result: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return newCount, nil
+}
+
+// ListResults 分页返回搜索结果，最新发现的在前；since 不为 nil 时只返回此后首次出现的结果
+func (r *SavedSearchRepository) ListResults(searchID string, since *time.Time, params *PaginationParams) (*PagedResult[SavedSearchResult], error) {
+	if params.Page < 1 {
+		params.Page = 1
+	}
+	if params.PageSize < 1 {
+		params.PageSize = 20
+	}
+
+	where := "WHERE search_id = ?"
+	args := []interface{}{searchID}
+	if since != nil {
+		where += " AND first_seen_at >= ?"
+		args = append(args, *since)
+	}
+
+	var total int64
+	if err := r.db.QueryRow("SELECT COUNT(*) FROM saved_search_results "+where, args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count saved search results: %w", err)
+	}
+
+	rows, err := r.db.Query(`
+		SELECT id, search_id, keyword, search_type, result_key, title, author, username, object_id, nonce_id, cover_url, raw,
+			first_seen_at, last_seen_at
+		FROM saved_search_results `+where+`
+		ORDER BY first_seen_at DESC, id ASC
+		LIMIT ? OFFSET ?`,
+		append(args, params.PageSize, (params.Page-1)*params.PageSize)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list saved search results: %w", err)
+	}
+	defer rows.Close()
+
+	items := []SavedSearchResult{}
+	for rows.Next() {
+		var res SavedSearchResult
+		if err := rows.Scan(&res.ID, &res.SearchID, &res.Keyword, &res.SearchType, &res.ResultKey, &res.Title, &res.Author,
+			&res.Username, &res.ObjectID, &res.NonceID, &res.CoverURL, &res.Raw, &res.FirstSeenAt, &res.LastSeenAt); err != nil {
+			return nil, fmt.Errorf("failed to scan saved search result: %w", err)
+		}
+		items = append(items, res)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return NewPagedResult(items, total, params.Page, params.PageSize), nil
+}
@@ -2,6 +2,7 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"time"
@@ -36,6 +37,12 @@ const (
 	SettingKeyMaxRetries                  = "max_retries"
 	SettingKeyRadarEnabled                = "radar_enabled"
 	SettingKeyTheme                       = "theme"
+	SettingKeyBandwidthLimit              = "bandwidth_limit"
+	SettingKeyItemBandwidthLimit          = "item_bandwidth_limit"
+	SettingKeyBandwidthSchedule           = "bandwidth_schedule"
+	SettingKeyRadarCallsPerMinute         = "radar_calls_per_minute"
+	SettingKeyEngagementRetentionDays     = "engagement_retention_days"
+	SettingKeyRetentionPolicy             = "retention_policy"
 )
 
 // Get 根据键获取设置值
@@ -136,6 +143,32 @@ func (r *SettingsRepository) Load() (*Settings, error) {
 	if v, ok := settingsMap[SettingKeyTheme]; ok && v != "" {
 		settings.Theme = v
 	}
+	if v, ok := settingsMap[SettingKeyBandwidthLimit]; ok && v != "" {
+		if limit, err := strconv.ParseInt(v, 10, 64); err == nil {
+			settings.BandwidthLimit = limit
+		}
+	}
+	if v, ok := settingsMap[SettingKeyItemBandwidthLimit]; ok && v != "" {
+		if limit, err := strconv.ParseInt(v, 10, 64); err == nil {
+			settings.ItemBandwidthLimit = limit
+		}
+	}
+	if v, ok := settingsMap[SettingKeyBandwidthSchedule]; ok && v != "" {
+		var schedule []BandwidthWindow
+		if err := json.Unmarshal([]byte(v), &schedule); err == nil {
+			settings.BandwidthSchedule = schedule
+		}
+	}
+	if v, ok := settingsMap[SettingKeyRadarCallsPerMinute]; ok && v != "" {
+		if calls, err := strconv.Atoi(v); err == nil {
+			settings.RadarCallsPerMinute = calls
+		}
+	}
+	if v, ok := settingsMap[SettingKeyEngagementRetentionDays]; ok && v != "" {
+		if days, err := strconv.Atoi(v); err == nil {
+			settings.EngagementRetentionDays = days
+		}
+	}
 
 	return settings, nil
 }
@@ -155,6 +188,15 @@ func (r *SettingsRepository) Save(settings *Settings) error {
 		ON CONFLICT(key) DO UPDATE SET value = ?, updated_at = ?
 	`
 
+	schedule := settings.BandwidthSchedule
+	if schedule == nil {
+		schedule = []BandwidthWindow{}
+	}
+	scheduleJSON, err := json.Marshal(schedule)
+	if err != nil {
+		return fmt.Errorf("failed to encode bandwidth schedule: %w", err)
+	}
+
 	// Save each setting
 	settingsMap := map[string]string{
 		SettingKeyDownloadDir:                 settings.DownloadDir,
@@ -165,6 +207,11 @@ func (r *SettingsRepository) Save(settings *Settings) error {
 		SettingKeyAutoCleanupDays:             strconv.Itoa(settings.AutoCleanupDays),
 		SettingKeyMaxRetries:                  strconv.Itoa(settings.MaxRetries),
 		SettingKeyTheme:                       settings.Theme,
+		SettingKeyBandwidthLimit:              strconv.FormatInt(settings.BandwidthLimit, 10),
+		SettingKeyItemBandwidthLimit:          strconv.FormatInt(settings.ItemBandwidthLimit, 10),
+		SettingKeyBandwidthSchedule:           string(scheduleJSON),
+		SettingKeyRadarCallsPerMinute:         strconv.Itoa(settings.RadarCallsPerMinute),
+		SettingKeyEngagementRetentionDays:     strconv.Itoa(settings.EngagementRetentionDays),
 	}
 
 	for key, value := range settingsMap {
@@ -185,6 +232,37 @@ func (r *SettingsRepository) Save(settings *Settings) error {
 	return nil
 }
 
+// LoadRetentionPolicy 获取下载目录保留策略，未保存时返回默认策略
+func (r *SettingsRepository) LoadRetentionPolicy() (*RetentionPolicy, error) {
+	value, err := r.Get(SettingKeyRetentionPolicy)
+	if err != nil {
+		return nil, err
+	}
+	policy := DefaultRetentionPolicy()
+	if value == "" {
+		return policy, nil
+	}
+	if err := json.Unmarshal([]byte(value), policy); err != nil {
+		return nil, fmt.Errorf("failed to decode retention policy: %w", err)
+	}
+	if policy.IntervalMinutes <= 0 {
+		policy.IntervalMinutes = DefaultRetentionIntervalMinutes
+	}
+	return policy, nil
+}
+
+// SaveRetentionPolicy 验证并保存下载目录保留策略
+func (r *SettingsRepository) SaveRetentionPolicy(policy *RetentionPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return fmt.Errorf("failed to encode retention policy: %w", err)
+	}
+	return r.Set(SettingKeyRetentionPolicy, string(data))
+}
+
 // Validate 验证设置值
 func (r *SettingsRepository) Validate(settings *Settings) error {
 	// Validate chunk size (1MB to 100MB)
@@ -215,6 +293,36 @@ func (r *SettingsRepository) Validate(settings *Settings) error {
 		return fmt.Errorf("theme must be 'light' or 'dark'")
 	}
 
+	// Validate radar call budget (0 = default, up to 60 calls per minute)
+	if settings.RadarCallsPerMinute < 0 || settings.RadarCallsPerMinute > 60 {
+		return fmt.Errorf("radar calls per minute must be between 0 and 60")
+	}
+
+	// Validate engagement snapshot retention (0 = default)
+	if settings.EngagementRetentionDays != 0 &&
+		(settings.EngagementRetentionDays < MinEngagementRetentionDays || settings.EngagementRetentionDays > MaxEngagementRetentionDays) {
+		return fmt.Errorf("engagement retention days must be 0 or between %d and %d", MinEngagementRetentionDays, MaxEngagementRetentionDays)
+	}
+
+	// Validate bandwidth limits and schedule windows
+	if settings.BandwidthLimit < 0 || settings.ItemBandwidthLimit < 0 {
+		return fmt.Errorf("bandwidth limit must not be negative")
+	}
+	for i, window := range settings.BandwidthSchedule {
+		if _, err := parseClockMinutes(window.Start); err != nil {
+			return fmt.Errorf("bandwidth schedule %d: %w", i+1, err)
+		}
+		if _, err := parseClockMinutes(window.End); err != nil {
+			return fmt.Errorf("bandwidth schedule %d: %w", i+1, err)
+		}
+		if window.Start == window.End {
+			return fmt.Errorf("bandwidth schedule %d: start and end must differ", i+1)
+		}
+		if window.Limit < 0 || window.ItemLimit < 0 {
+			return fmt.Errorf("bandwidth schedule %d: limit must not be negative", i+1)
+		}
+	}
+
 	return nil
 }
 
@@ -0,0 +1,129 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SyncRepository 处理 Hub 同步游标和待确认批次的数据库操作
+type SyncRepository struct {
+	db *sql.DB
+}
+
+// NewSyncRepository 创建一个新的 SyncRepository
+func NewSyncRepository() *SyncRepository {
+	return &SyncRepository{db: GetDB()}
+}
+
+// GetCursor 获取同步类型的游标，从未同步过时返回零值游标
+func (r *SyncRepository) GetCursor(syncType string) (*SyncCursor, error) {
+	cursor := &SyncCursor{SyncType: syncType}
+	err := r.db.QueryRow(
+		"SELECT cursor_time, COALESCE(cursor_id, ''), updated_at FROM sync_cursors WHERE sync_type = ?",
+		syncType,
+	).Scan(&cursor.Time, &cursor.ID, &cursor.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return cursor, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sync cursor: %w", err)
+	}
+	return cursor, nil
+}
+
+// AddBatch 保存一个待确认批次
+func (r *SyncRepository) AddBatch(batch *SyncBatch) error {
+	if batch.CreatedAt.IsZero() {
+		batch.CreatedAt = time.Now()
+	}
+	_, err := r.db.Exec(`
+		INSERT INTO sync_batches (
+			id, sync_type, payload, count, has_more, cursor_time, cursor_id, attempts, created_at, sent_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		batch.ID, batch.SyncType, batch.Payload, batch.Count, batch.HasMore,
+		batch.CursorTime, batch.CursorID, batch.Attempts, batch.CreatedAt, batch.SentAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to add sync batch: %w", err)
+	}
+	return nil
+}
+
+// GetPendingBatch 获取同步类型最早的待确认批次，不存在时返回 nil
+func (r *SyncRepository) GetPendingBatch(syncType string) (*SyncBatch, error) {
+	batch := &SyncBatch{}
+	var sentAt sql.NullTime
+	err := r.db.QueryRow(`
+		SELECT id, sync_type, payload, count, has_more, cursor_time, COALESCE(cursor_id, ''), attempts, created_at, sent_at
+		FROM sync_batches
+		WHERE sync_type = ?
+		ORDER BY created_at ASC
+		LIMIT 1`, syncType,
+	).Scan(&batch.ID, &batch.SyncType, &batch.Payload, &batch.Count, &batch.HasMore,
+		&batch.CursorTime, &batch.CursorID, &batch.Attempts, &batch.CreatedAt, &sentAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending sync batch: %w", err)
+	}
+	if sentAt.Valid {
+		batch.SentAt = &sentAt.Time
+	}
+	return batch, nil
+}
+
+// MarkBatchSent 记录批次的一次发送
+func (r *SyncRepository) MarkBatchSent(id string, sentAt time.Time) error {
+	_, err := r.db.Exec(
+		"UPDATE sync_batches SET attempts = attempts + 1, sent_at = ? WHERE id = ?",
+		sentAt, id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to mark sync batch sent: %w", err)
+	}
+	return nil
+}
+
+// AckBatch 在同一事务中将游标前进到批次末尾并删除批次
+// 批次不存在（例如重复确认）时返回 nil
+func (r *SyncRepository) AckBatch(id string) (*SyncBatch, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	batch := &SyncBatch{ID: id}
+	err = tx.QueryRow(
+		"SELECT sync_type, count, has_more, cursor_time, COALESCE(cursor_id, '') FROM sync_batches WHERE id = ?",
+		id,
+	).Scan(&batch.SyncType, &batch.Count, &batch.HasMore, &batch.CursorTime, &batch.CursorID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sync batch: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO sync_cursors (sync_type, cursor_time, cursor_id, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(sync_type) DO UPDATE SET
+			cursor_time = excluded.cursor_time,
+			cursor_id = excluded.cursor_id,
+			updated_at = excluded.updated_at`,
+		batch.SyncType, batch.CursorTime, batch.CursorID, time.Now(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update sync cursor: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM sync_batches WHERE id = ?", id); err != nil {
+		return nil, fmt.Errorf("failed to delete sync batch: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return batch, nil
+}
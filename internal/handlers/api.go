@@ -2,7 +2,6 @@ package handlers
 
 import (
 	"net/http"
-	"strings"
 
 	"wx_channel/internal/config"
 	"wx_channel/internal/response"
@@ -44,7 +43,7 @@ func (h *APIHandler) GetCurrentURL() string {
 
 // Handle implements router.Interceptor
 func (h *APIHandler) Handle(Conn *SunnyNet.HttpConn) bool {
-	// CORS Preflight for all __wx_channels_api requests
+	// 预检、授权和来源校验由 router.NewLocalAPIInterceptor 统一处理
 	if Conn.Request == nil || Conn.Request.URL == nil {
 		return false
 	}
@@ -56,11 +55,6 @@ func (h *APIHandler) Handle(Conn *SunnyNet.HttpConn) bool {
 		}
 	}()
 
-	if strings.HasPrefix(Conn.Request.URL.Path, "/__wx_channels_api/") && Conn.Request.Method == "OPTIONS" {
-		h.handleCORS(Conn)
-		return true
-	}
-
 	if h.HandleProfile(Conn) {
 		return true
 	}
@@ -80,24 +74,6 @@ func (h *APIHandler) Handle(Conn *SunnyNet.HttpConn) bool {
 	return false
 }
 
-// handleCORS 处理CORS预检请求
-func (h *APIHandler) handleCORS(Conn *SunnyNet.HttpConn) {
-	headers := http.Header{}
-	headers.Set("Access-Control-Allow-Methods", "POST, OPTIONS")
-	headers.Set("Access-Control-Allow-Headers", "Content-Type, X-Local-Auth")
-	if h.getConfig() != nil && len(h.getConfig().AllowedOrigins) > 0 {
-		origin := Conn.Request.Header.Get("Origin")
-		for _, o := range h.getConfig().AllowedOrigins {
-			if o == origin {
-				headers.Set("Access-Control-Allow-Origin", origin)
-				headers.Set("Vary", "Origin")
-				break
-			}
-		}
-	}
-	Conn.StopRequest(204, "", headers)
-}
-
 // sendEmptyResponse 发送空JSON响应
 func (h *APIHandler) sendEmptyResponse(Conn *SunnyNet.HttpConn) {
 	headers := http.Header{}
@@ -209,14 +209,6 @@ func (h *BatchHandler) HandleBatchStart(Conn *SunnyNet.HttpConn) bool {
 		return true
 	}
 
-	// 授权校验
-	if h.getConfig() != nil && h.getConfig().SecretToken != "" {
-		if Conn.Request.Header.Get("X-Local-Auth") != h.getConfig().SecretToken {
-			h.sendErrorResponse(Conn, fmt.Errorf("unauthorized"))
-			return true
-		}
-	}
-
 	utils.Info("📥 [批量下载] 开始读取请求体...")
 
 	// 检查请求体是否为空
@@ -694,9 +686,20 @@ func (h *BatchHandler) downloadVideoOnce(ctx context.Context, task *BatchTask, d
 	}
 	utils.Info("🌐 [批量下载] 请求头: Referer=%s | UA=%s | 连接数=%d", headers["Referer"], headers["User-Agent"], connections)
 
+	// 加密视频经本地解密代理边下载边解密
+	needDecrypt := task.Key != "" || (task.DecryptorPrefix != "" && task.PrefixLen > 0)
+	var decryptor []byte
+	if needDecrypt {
+		var err error
+		decryptor, err = utils.BuildDecryptorPrefix(task.GetKey(), task.DecryptorPrefix, task.PrefixLen)
+		if err != nil {
+			return "", fmt.Errorf("解密失败: %v", err)
+		}
+	}
+
 	createTask := func() error {
 		_ = os.Remove(tmpPath)
-		taskID, err := h.gopeedService.CreateTask(downloadURL, tmpPath, connections, headers)
+		taskID, err := h.gopeedService.CreateDecryptedTask(downloadURL, tmpPath, connections, headers, decryptor)
 		if err != nil {
 			return err
 		}
@@ -720,7 +723,15 @@ func (h *BatchHandler) downloadVideoOnce(ctx context.Context, task *BatchTask, d
 		} else {
 			switch snapshot.Status {
 			case base.DownloadStatusPause, base.DownloadStatusWait, base.DownloadStatusReady:
-				if err := h.gopeedService.ContinueTask(task.GopeedTaskID); err != nil {
+				if err := h.gopeedService.ContinueTask(task.GopeedTaskID); errors.Is(err, services.ErrTaskNotResumable) {
+					// 解密代理地址已失效，丢弃旧任务重新下载
+					utils.Warn("⚠️ [批量下载] Gopeed 任务无法继续，重新创建: %s - %v", task.Title, err)
+					h.cleanupTaskArtifacts(task.GopeedTaskID, tmpPath, true)
+					task.GopeedTaskID = ""
+					if err := createTask(); err != nil {
+						return "", err
+					}
+				} else if err != nil {
 					return "", err
 				}
 			case base.DownloadStatusError:
@@ -768,11 +779,9 @@ func (h *BatchHandler) downloadVideoOnce(ctx context.Context, task *BatchTask, d
 		return "", fmt.Errorf("下载文件无效")
 	}
 
-	// 解密逻辑（如果需要）
-	needDecrypt := task.Key != "" || (task.DecryptorPrefix != "" && task.PrefixLen > 0)
+	// 下载时已解密，这里只校验文件头
 	if needDecrypt {
-		utils.Info("🔐 [批量下载] 开始解密视频...")
-		if err := utils.DecryptFileInPlace(actualPath, task.GetKey(), task.DecryptorPrefix, task.PrefixLen); err != nil {
+		if err := utils.ValidateDecryptedFile(actualPath); err != nil {
 			h.cleanupTaskArtifacts(task.GopeedTaskID, actualPath, true)
 			task.GopeedTaskID = ""
 			return "", fmt.Errorf("解密失败: %v", err)
@@ -981,14 +990,6 @@ func (h *BatchHandler) HandleBatchProgress(Conn *SunnyNet.HttpConn) bool {
 		return true
 	}
 
-	// 授权校验
-	if h.getConfig() != nil && h.getConfig().SecretToken != "" {
-		if Conn.Request.Header.Get("X-Local-Auth") != h.getConfig().SecretToken {
-			h.sendErrorResponse(Conn, fmt.Errorf("unauthorized"))
-			return true
-		}
-	}
-
 	h.mu.RLock()
 	total := len(h.tasks)
 	done, failed, running := 0, 0, 0
@@ -1056,14 +1057,6 @@ func (h *BatchHandler) HandleBatchCancel(Conn *SunnyNet.HttpConn) bool {
 		return true
 	}
 
-	// 授权校验
-	if h.getConfig() != nil && h.getConfig().SecretToken != "" {
-		if Conn.Request.Header.Get("X-Local-Auth") != h.getConfig().SecretToken {
-			h.sendErrorResponse(Conn, fmt.Errorf("unauthorized"))
-			return true
-		}
-	}
-
 	h.mu.Lock()
 	cancel := h.cancelFunc
 	resumeEnabled := h.batchResumeEnabled()
@@ -1114,14 +1107,6 @@ func (h *BatchHandler) HandleBatchFailed(Conn *SunnyNet.HttpConn) bool {
 		return true
 	}
 
-	// 授权校验
-	if h.getConfig() != nil && h.getConfig().SecretToken != "" {
-		if Conn.Request.Header.Get("X-Local-Auth") != h.getConfig().SecretToken {
-			h.sendErrorResponse(Conn, fmt.Errorf("unauthorized"))
-			return true
-		}
-	}
-
 	h.mu.RLock()
 	failedTasks := make([]BatchTask, 0)
 	for _, t := range h.tasks {
@@ -1181,14 +1166,6 @@ func (h *BatchHandler) HandleBatchResume(Conn *SunnyNet.HttpConn) bool {
 		return true
 	}
 
-	// 授权校验
-	if h.getConfig() != nil && h.getConfig().SecretToken != "" {
-		if Conn.Request.Header.Get("X-Local-Auth") != h.getConfig().SecretToken {
-			h.sendErrorResponse(Conn, fmt.Errorf("unauthorized"))
-			return true
-		}
-	}
-
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
@@ -1258,14 +1235,6 @@ func (h *BatchHandler) HandleBatchClear(Conn *SunnyNet.HttpConn) bool {
 		return true
 	}
 
-	// 授权校验
-	if h.getConfig() != nil && h.getConfig().SecretToken != "" {
-		if Conn.Request.Header.Get("X-Local-Auth") != h.getConfig().SecretToken {
-			h.sendErrorResponse(Conn, fmt.Errorf("unauthorized"))
-			return true
-		}
-	}
-
 	h.mu.Lock()
 	cancel := h.cancelFunc
 	busy := h.running || h.cancelFunc != nil
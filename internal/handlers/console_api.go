@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -28,13 +29,19 @@ type ConsoleAPIHandler struct {
 	cfg             *config.Config
 	browseService   *services.BrowseHistoryService
 	downloadService *services.DownloadRecordService
+	dedupService    *services.DedupService
+	libraryService  *services.LibraryService
 	queueService    *services.QueueService
 	settingsRepo    *database.SettingsRepository
+	pinRepo         *database.DownloadPinRepository
 	statsService    *services.StatisticsService
+	engagement      *services.EngagementService
 	exportService   *services.ExportService
 	searchService   *services.SearchService
 	wsHub           *websocket.Hub
 	radarService    *services.RadarService
+	commentRepo     *database.CommentRepository
+	recordImport    *services.RecordImportService
 }
 
 const maxJSONBodyBytes = 8 << 20 // 8MB
@@ -45,13 +52,19 @@ func NewConsoleAPIHandler(cfg *config.Config, wsHub *websocket.Hub, radarService
 		cfg:             cfg,
 		browseService:   services.NewBrowseHistoryService(),
 		downloadService: services.NewDownloadRecordService(),
+		dedupService:    services.NewDedupService(),
+		libraryService:  services.NewLibraryService(),
 		queueService:    services.NewQueueService(),
 		settingsRepo:    database.NewSettingsRepository(),
+		pinRepo:         database.NewDownloadPinRepository(),
 		statsService:    services.NewStatisticsService(),
+		engagement:      services.NewEngagementService(),
 		exportService:   services.NewExportService(),
 		searchService:   services.NewSearchService(),
 		wsHub:           wsHub,
 		radarService:    radarService,
+		commentRepo:     database.NewCommentRepository(),
+		recordImport:    services.NewRecordImportService(),
 	}
 }
 
@@ -481,6 +494,18 @@ func (h *ConsoleAPIHandler) HandleDownloadsAPI(w http.ResponseWriter, r *http.Re
 
 	// 从路径提取 ID
 	id := extractIDFromPath(path, "/api/downloads")
+	if id == "duplicates" {
+		h.HandleDuplicatesAPI(w, r)
+		return
+	}
+	if id == "import-legacy" {
+		h.HandleDownloadsLegacyImport(w, r)
+		return
+	}
+	if id == "library" {
+		h.HandleLibraryAPI(w, r)
+		return
+	}
 
 	switch r.Method {
 	case "GET":
@@ -500,6 +525,167 @@ func (h *ConsoleAPIHandler) HandleDownloadsAPI(w http.ResponseWriter, r *http.Re
 	}
 }
 
+// HandleDuplicatesAPI 路由重复下载 API 请求
+// GET  /api/downloads/duplicates       - 列出内容相同的记录分组
+// POST /api/downloads/duplicates/scan  - 为旧记录补算指纹和哈希
+// POST /api/downloads/duplicates/link  - 用硬链接替换重复文件，body: {"contentHash": ""}，为空时处理全部分组
+func (h *ConsoleAPIHandler) HandleDuplicatesAPI(w http.ResponseWriter, r *http.Request) {
+	action := extractIDFromPath(r.URL.Path, "/api/downloads/duplicates")
+
+	switch {
+	case r.Method == "GET" && action == "":
+		groups, err := h.dedupService.ListDuplicateGroups()
+		if err != nil {
+			h.sendError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		var wasted int64
+		for _, group := range groups {
+			wasted += group.WastedBytes
+		}
+		h.sendSuccess(w, r, map[string]interface{}{
+			"groups":      groups,
+			"total":       len(groups),
+			"wastedBytes": wasted,
+		})
+	case r.Method == "POST" && action == "scan":
+		result, err := h.dedupService.ScanMissingHashes()
+		if err != nil {
+			h.sendError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		h.sendSuccess(w, r, result)
+	case r.Method == "POST" && action == "link":
+		var req struct {
+			ContentHash string `json:"contentHash"`
+		}
+		if r.ContentLength != 0 {
+			if err := h.parseJSON(r, &req); err != nil {
+				h.sendError(w, r, http.StatusBadRequest, "invalid request body")
+				return
+			}
+		}
+		result, err := h.dedupService.LinkDuplicates(req.ContentHash)
+		if err != nil {
+			h.sendError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		h.sendSuccess(w, r, result)
+	default:
+		h.sendError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// HandleLibraryAPI 路由下载目录同步 API 请求
+// GET  /api/downloads/library      - 扫描下载目录，报告已移动、丢失的记录和没有记录的文件
+// POST /api/downloads/library/fix  - 重新扫描并修复，body: {"relink": true, "markMissing": true, "adoptOrphans": false}
+func (h *ConsoleAPIHandler) HandleLibraryAPI(w http.ResponseWriter, r *http.Request) {
+	action := extractIDFromPath(r.URL.Path, "/api/downloads/library")
+
+	downloadsDir, err := h.getConfig().GetResolvedDownloadsDir()
+	if err != nil {
+		h.sendError(w, r, http.StatusInternalServerError, "failed to resolve downloads directory")
+		return
+	}
+
+	switch {
+	case r.Method == "GET" && action == "":
+		report, err := h.libraryService.Scan(downloadsDir)
+		if err != nil {
+			h.sendError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		h.sendSuccess(w, r, report)
+	case r.Method == "POST" && action == "fix":
+		var opts services.LibraryFixOptions
+		if err := h.parseJSON(r, &opts); err != nil {
+			h.sendError(w, r, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		if !opts.Relink && !opts.MarkMissing && !opts.AdoptOrphans {
+			h.sendError(w, r, http.StatusBadRequest, "no fix action selected")
+			return
+		}
+		result, err := h.libraryService.Fix(downloadsDir, opts)
+		if err != nil {
+			h.sendError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		h.sendSuccess(w, r, result)
+	default:
+		h.sendError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// maxRecordImportBytes 上传导入文件的大小上限
+const maxRecordImportBytes = 64 << 20 // 64MB
+
+// HandleDownloadsLegacyImport 处理 POST /api/downloads/import-legacy - 导入旧版 CSV 或导出的 JSON 下载记录
+// multipart 上传时读取 file 字段；否则读取 body: {"path": ""}，path 为空时导入配置中的 records_file
+func (h *ConsoleAPIHandler) HandleDownloadsLegacyImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		h.sendError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		r.Body = http.MaxBytesReader(w, r.Body, maxRecordImportBytes)
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			h.sendError(w, r, http.StatusBadRequest, "file is required")
+			return
+		}
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		if err != nil {
+			h.sendError(w, r, http.StatusBadRequest, "failed to read uploaded file")
+			return
+		}
+		result, err := h.recordImport.Import(data, header.Filename)
+		if err != nil {
+			h.sendError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.sendSuccess(w, r, result)
+		return
+	}
+
+	var req struct {
+		Path string `json:"path"`
+	}
+	if r.ContentLength != 0 {
+		if err := h.parseJSON(r, &req); err != nil {
+			h.sendError(w, r, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+	if req.Path == "" {
+		req.Path = h.getConfig().GetRecordsPath()
+	}
+
+	downloadsDir, err := h.getConfig().GetResolvedDownloadsDir()
+	if err != nil {
+		h.sendError(w, r, http.StatusInternalServerError, "failed to resolve downloads directory")
+		return
+	}
+	absPath, err := validatePathInBase(downloadsDir, req.Path, false)
+	if err != nil {
+		if pe, ok := err.(*pathValidationError); ok {
+			h.sendError(w, r, pe.status, pe.msg)
+			return
+		}
+		h.sendError(w, r, http.StatusInternalServerError, "failed to validate path")
+		return
+	}
+
+	result, err := h.recordImport.ImportFile(absPath)
+	if err != nil {
+		h.sendError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.sendSuccess(w, r, result)
+}
+
 // ============================================================================
 // 下载队列 API 处理器
 // Requirements: 14.3 - 下载队列管理的 REST API 端点
@@ -597,6 +783,34 @@ func (h *ConsoleAPIHandler) HandleQueueResume(w http.ResponseWriter, r *http.Req
 	h.sendSuccessMessage(w, r, "download resumed")
 }
 
+// HandleQueueStart 处理 PUT /api/queue/:id/start - 将项目移到队列最前，由调度器立即下载
+func (h *ConsoleAPIHandler) HandleQueueStart(w http.ResponseWriter, r *http.Request, id string) {
+	if h.HandleCORS(w, r) {
+		return
+	}
+
+	err := h.queueService.StartNow(id)
+	switch {
+	case errors.Is(err, services.ErrQueueItemNotFound):
+		h.sendError(w, r, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, services.ErrQueueItemStatus):
+		h.sendError(w, r, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		h.sendError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// 通过 WebSocket 广播队列更新
+	item, _ := h.queueService.GetByID(id)
+	if item != nil {
+		GetWebSocketHub().BroadcastQueueUpdate(item)
+	}
+
+	h.sendSuccess(w, r, item)
+}
+
 // HandleQueueRemove 处理 DELETE /api/queue/:id - 从队列移除
 func (h *ConsoleAPIHandler) HandleQueueRemove(w http.ResponseWriter, r *http.Request, id string) {
 	if h.HandleCORS(w, r) {
@@ -718,7 +932,7 @@ func (h *ConsoleAPIHandler) HandleQueueAPI(w http.ResponseWriter, r *http.Reques
 	}
 
 	// 从路径提取 ID 和操作
-	// 路径格式: /api/queue/:id 或 /api/queue/:id/pause 或 /api/queue/:id/resume
+	// 路径格式: /api/queue/:id 或 /api/queue/:id/pause 或 /api/queue/:id/resume 或 /api/queue/:id/start
 	pathParts := strings.Split(strings.TrimPrefix(path, "/api/queue/"), "/")
 	id := ""
 	action := ""
@@ -744,6 +958,8 @@ func (h *ConsoleAPIHandler) HandleQueueAPI(w http.ResponseWriter, r *http.Reques
 			h.HandleQueuePause(w, r, id)
 		case "resume":
 			h.HandleQueueResume(w, r, id)
+		case "start":
+			h.HandleQueueStart(w, r, id)
 		case "complete":
 			h.HandleQueueComplete(w, r, id)
 		case "fail":
@@ -802,6 +1018,8 @@ func (h *ConsoleAPIHandler) HandleSettingsUpdate(w http.ResponseWriter, r *http.
 		h.sendError(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
+	// 带宽限制立即生效
+	services.GetBandwidthLimiter().Apply(&settings)
 
 	h.sendSuccessMessage(w, r, "settings updated")
 }
@@ -823,6 +1041,109 @@ func (h *ConsoleAPIHandler) HandleSettingsAPI(w http.ResponseWriter, r *http.Req
 	}
 }
 
+// HandleRetentionAPI 路由下载保留策略 API 请求
+// GET    /api/retention          - 获取保留策略、磁盘空间状态和最近一次清理结果
+// PUT    /api/retention          - 保存保留策略
+// POST   /api/retention/preview  - 预览策略要删除的视频（不删除），body 为空时使用已保存的策略
+// POST   /api/retention/run      - 立即按已保存的策略执行清理
+// GET    /api/retention/pins     - 获取永不删除的下载记录
+// POST   /api/retention/pins     - 标记永不删除，body: {"ids": []}
+// DELETE /api/retention/pins/{id} - 取消永不删除标记
+func (h *ConsoleAPIHandler) HandleRetentionAPI(w http.ResponseWriter, r *http.Request) {
+	if h.HandleCORS(w, r) {
+		return
+	}
+
+	path := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/retention"), "/")
+	retention := services.GetRetentionService()
+
+	switch {
+	case path == "" && r.Method == "GET":
+		status, err := retention.Status()
+		if err != nil {
+			h.sendError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		h.sendSuccess(w, r, status)
+	case path == "" && r.Method == "PUT":
+		var policy database.RetentionPolicy
+		if err := h.parseJSON(r, &policy); err != nil {
+			h.sendError(w, r, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		if err := policy.Validate(); err != nil {
+			h.sendError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := h.settingsRepo.SaveRetentionPolicy(&policy); err != nil {
+			h.sendError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		h.sendSuccess(w, r, policy)
+	case path == "/preview" && r.Method == "POST":
+		var policy *database.RetentionPolicy
+		if r.ContentLength != 0 {
+			policy = &database.RetentionPolicy{}
+			if err := h.parseJSON(r, policy); err != nil {
+				h.sendError(w, r, http.StatusBadRequest, "invalid request body")
+				return
+			}
+			if err := policy.Validate(); err != nil {
+				h.sendError(w, r, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+		report, err := retention.Preview(policy)
+		if err != nil {
+			h.sendError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		h.sendSuccess(w, r, report)
+	case path == "/run" && r.Method == "POST":
+		result, err := retention.RunNow()
+		if err != nil {
+			h.sendError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		h.sendSuccess(w, r, result)
+	case path == "/pins" && r.Method == "GET":
+		pins, err := h.pinRepo.List()
+		if err != nil {
+			h.sendError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		h.sendSuccess(w, r, pins)
+	case path == "/pins" && r.Method == "POST":
+		var req struct {
+			IDs []string `json:"ids"`
+		}
+		if err := h.parseJSON(r, &req); err != nil || len(req.IDs) == 0 {
+			h.sendError(w, r, http.StatusBadRequest, "ids is required")
+			return
+		}
+		for _, id := range req.IDs {
+			if err := h.pinRepo.Pin(id); err != nil {
+				h.sendError(w, r, http.StatusInternalServerError, err.Error())
+				return
+			}
+		}
+		h.sendSuccessMessage(w, r, "records pinned")
+	case strings.HasPrefix(path, "/pins/") && r.Method == "DELETE":
+		id := strings.TrimPrefix(path, "/pins/")
+		if id == "" || strings.Contains(id, "/") {
+			h.sendError(w, r, http.StatusBadRequest, "invalid record id")
+			return
+		}
+		if err := h.pinRepo.Unpin(id); err != nil {
+			h.sendError(w, r, http.StatusInternalServerError, err.Error())
+			return
+		}
+		h.sendSuccessMessage(w, r, "record unpinned")
+	default:
+		h.sendError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
 // ============================================================================
 // 统计 API 处理器
 // Requirements: 7.1, 7.2 - 统计和图表数据端点
@@ -866,6 +1187,59 @@ func (h *ConsoleAPIHandler) HandleStatsChart(w http.ResponseWriter, r *http.Requ
 	h.sendSuccess(w, r, chartData)
 }
 
+// engagementDays 解析互动曲线的天数参数，默认 30 天，最多 365 天
+func engagementDays(r *http.Request) int {
+	days := 30
+	if d := r.URL.Query().Get("days"); d != "" {
+		if parsed, err := strconv.Atoi(d); err == nil && parsed > 0 && parsed <= 365 {
+			days = parsed
+		}
+	}
+	return days
+}
+
+// HandleStatsEngagementVideo 处理 GET /api/stats/engagement/video?videoId= - 获取视频互动增长曲线
+func (h *ConsoleAPIHandler) HandleStatsEngagementVideo(w http.ResponseWriter, r *http.Request) {
+	if h.HandleCORS(w, r) {
+		return
+	}
+
+	videoID := r.URL.Query().Get("videoId")
+	if videoID == "" {
+		h.sendError(w, r, http.StatusBadRequest, "videoId is required")
+		return
+	}
+
+	series, err := h.engagement.VideoSeries(videoID, engagementDays(r))
+	if err != nil {
+		h.sendError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.sendSuccess(w, r, series)
+}
+
+// HandleStatsEngagementAuthor 处理 GET /api/stats/engagement/author?author= - 获取作者按天汇总的互动增长曲线
+func (h *ConsoleAPIHandler) HandleStatsEngagementAuthor(w http.ResponseWriter, r *http.Request) {
+	if h.HandleCORS(w, r) {
+		return
+	}
+
+	author := r.URL.Query().Get("author")
+	if author == "" {
+		h.sendError(w, r, http.StatusBadRequest, "author is required")
+		return
+	}
+
+	series, err := h.engagement.AuthorSeries(author, engagementDays(r))
+	if err != nil {
+		h.sendError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.sendSuccess(w, r, series)
+}
+
 // HandleStatsAPI 路由统计 API 请求
 func (h *ConsoleAPIHandler) HandleStatsAPI(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
@@ -880,9 +1254,14 @@ func (h *ConsoleAPIHandler) HandleStatsAPI(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	if path == "/api/stats/chart" {
+	switch path {
+	case "/api/stats/chart":
 		h.HandleStatsChart(w, r)
-	} else {
+	case "/api/stats/engagement/video":
+		h.HandleStatsEngagementVideo(w, r)
+	case "/api/stats/engagement/author":
+		h.HandleStatsEngagementAuthor(w, r)
+	default:
 		h.HandleStatsGet(w, r)
 	}
 }
@@ -1026,6 +1405,108 @@ func (h *ConsoleAPIHandler) HandleSearch(w http.ResponseWriter, r *http.Request)
 	h.sendSuccess(w, r, result)
 }
 
+// ============================================================================
+// 评论 API 处理器
+// ============================================================================
+
+// getCommentFilter 从查询字符串中提取评论过滤参数
+func getCommentFilter(r *http.Request) *database.CommentFilter {
+	query := r.URL.Query()
+	filter := &database.CommentFilter{
+		PaginationParams: *getPaginationParams(r),
+		ObjectID:         query.Get("object_id"),
+		ParentID:         query.Get("parent_id"),
+		Username:         query.Get("username"),
+		Query:            query.Get("query"),
+	}
+	filter.SortBy = "comment_time"
+	if sortBy := query.Get("sortBy"); sortBy != "" {
+		filter.SortBy = sortBy
+	}
+
+	if level, err := strconv.Atoi(query.Get("level")); err == nil && (level == 1 || level == 2) {
+		filter.Level = level
+	}
+	if minLikes, err := strconv.Atoi(query.Get("minLikes")); err == nil && minLikes > 0 {
+		filter.MinLikes = minLikes
+	}
+	if startDate := query.Get("startDate"); startDate != "" {
+		if t, err := time.ParseInLocation("2006-01-02", startDate, time.Local); err == nil {
+			filter.StartDate = &t
+		}
+	}
+	if endDate := query.Get("endDate"); endDate != "" {
+		if t, err := time.ParseInLocation("2006-01-02", endDate, time.Local); err == nil {
+			// 设置为当天的结束时间
+			t = t.Add(24*time.Hour - time.Second)
+			filter.EndDate = &t
+		}
+	}
+
+	return filter
+}
+
+// HandleCommentsList 处理 GET /api/comments - 按视频、用户、内容等条件分页查询已导入的评论
+func (h *ConsoleAPIHandler) HandleCommentsList(w http.ResponseWriter, r *http.Request) {
+	result, err := h.commentRepo.List(getCommentFilter(r))
+	if err != nil {
+		h.sendError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.sendSuccess(w, r, result)
+}
+
+// HandleCommentVideos 处理 GET /api/comments/videos - 已导入评论的视频列表
+func (h *ConsoleAPIHandler) HandleCommentVideos(w http.ResponseWriter, r *http.Request) {
+	videos, err := h.commentRepo.ListVideos()
+	if err != nil {
+		h.sendError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.sendSuccess(w, r, videos)
+}
+
+// HandleCommentsDelete 处理 DELETE /api/comments?object_id= - 删除某个视频已导入的评论
+func (h *ConsoleAPIHandler) HandleCommentsDelete(w http.ResponseWriter, r *http.Request) {
+	objectID := r.URL.Query().Get("object_id")
+	if objectID == "" {
+		h.sendError(w, r, http.StatusBadRequest, "object_id is required")
+		return
+	}
+
+	deleted, err := h.commentRepo.DeleteByObjectID(objectID)
+	if err != nil {
+		h.sendError(w, r, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.sendSuccess(w, r, map[string]interface{}{
+		"deleted": deleted,
+	})
+}
+
+// HandleCommentsAPI 路由评论 API 请求
+func (h *ConsoleAPIHandler) HandleCommentsAPI(w http.ResponseWriter, r *http.Request) {
+	// 处理 CORS 预检请求
+	if h.HandleCORS(w, r) {
+		return
+	}
+
+	path := strings.Replace(r.URL.Path, "/api/v1/", "/api/", 1)
+	switch {
+	case path == "/api/comments/videos" && r.Method == "GET":
+		h.HandleCommentVideos(w, r)
+	case (path == "/api/comments" || path == "/api/comments/") && r.Method == "GET":
+		h.HandleCommentsList(w, r)
+	case (path == "/api/comments" || path == "/api/comments/") && r.Method == "DELETE":
+		h.HandleCommentsDelete(w, r)
+	default:
+		h.sendError(w, r, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
 // ============================================================================
 // 健康检查 API 处理器
 // Requirements: 14.7 - 返回服务状态和版本的健康检查端点
@@ -1106,6 +1587,8 @@ func (h *ConsoleAPIHandler) HandleAPIRequest(w http.ResponseWriter, r *http.Requ
 		h.HandleQueueAPI(w, r)
 	case strings.HasPrefix(path, "/api/files"):
 		h.HandleFilesAPI(w, r)
+	case strings.HasPrefix(path, "/api/comments"):
+		h.HandleCommentsAPI(w, r)
 	case path == "/api/video/stream":
 		h.HandleVideoStream(w, r)
 	case path == "/api/video/play":
@@ -30,15 +30,6 @@ func (h *APIHandler) HandleInjectHealth(Conn *SunnyNet.HttpConn) bool {
 		return true
 	}
 
-	cfg := h.getConfig()
-	if cfg != nil && cfg.SecretToken != "" && Conn.Request.Header.Get("X-Local-Auth") != cfg.SecretToken {
-		headers := http.Header{}
-		headers.Set("Content-Type", "application/json")
-		headers.Set("X-Content-Type-Options", "nosniff")
-		Conn.StopRequest(401, `{"success":false,"error":"unauthorized"}`, headers)
-		return true
-	}
-
 	var health struct {
 		PagePath  string `json:"pagePath"`
 		Href      string `json:"href"`
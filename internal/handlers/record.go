@@ -64,16 +64,6 @@ func (h *RecordHandler) HandleRecordDownload(Conn *SunnyNet.HttpConn) bool {
 		return false
 	}
 
-	if h.getConfig() != nil && h.getConfig().SecretToken != "" {
-		if Conn.Request.Header.Get("X-Local-Auth") != h.getConfig().SecretToken {
-			headers := http.Header{}
-			headers.Set("Content-Type", "application/json")
-			headers.Set("X-Content-Type-Options", "nosniff")
-			Conn.StopRequest(401, `{"success":false,"error":"unauthorized"}`, headers)
-			return true
-		}
-	}
-
 	var data map[string]interface{}
 	body, err := io.ReadAll(Conn.Request.Body)
 	if err != nil {
@@ -175,16 +165,6 @@ func (h *RecordHandler) HandleBatchDownloadStatus(Conn *SunnyNet.HttpConn) bool
 		return false
 	}
 
-	if h.getConfig() != nil && h.getConfig().SecretToken != "" {
-		if Conn.Request.Header.Get("X-Local-Auth") != h.getConfig().SecretToken {
-			headers := http.Header{}
-			headers.Set("Content-Type", "application/json")
-			headers.Set("X-Content-Type-Options", "nosniff")
-			Conn.StopRequest(401, `{"success":false,"error":"unauthorized"}`, headers)
-			return true
-		}
-	}
-
 	var statusData struct {
 		Current int    `json:"current"`
 		Total   int    `json:"total"`
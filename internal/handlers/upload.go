@@ -195,11 +195,17 @@ func (h *UploadHandler) downloadWithHeaders(ctx context.Context, url, targetPath
 	}
 	defer out.Close()
 
+	// 直连下载同样受全局和单任务带宽限制
+	bandwidth := services.GetBandwidthLimiter()
+	limiter := bandwidth.NewItemLimiter()
+	defer bandwidth.ReleaseItemLimiter(limiter)
+	body := bandwidth.Reader(ctx, resp.Body, limiter)
+
 	buf := make([]byte, 256*1024)
 	var downloaded int64
 	var lastReport time.Time
 	for {
-		n, readErr := resp.Body.Read(buf)
+		n, readErr := body.Read(buf)
 		if n > 0 {
 			if _, err := out.Write(buf[:n]); err != nil {
 				return fmt.Errorf("write file failed: %w", err)
@@ -364,35 +370,6 @@ func (h *UploadHandler) HandleInitUpload(Conn *SunnyNet.HttpConn) bool {
 		return false
 	}
 
-	if h.getConfig() != nil && h.getConfig().SecretToken != "" {
-		if Conn.Request.Header.Get("X-Local-Auth") != h.getConfig().SecretToken {
-			headers := http.Header{}
-			headers.Set("Content-Type", "application/json")
-			headers.Set("X-Content-Type-Options", "nosniff")
-			Conn.StopRequest(401, `{"success":false,"error":"unauthorized"}`, headers)
-			return true
-		}
-	}
-	if h.getConfig() != nil && len(h.getConfig().AllowedOrigins) > 0 {
-		origin := Conn.Request.Header.Get("Origin")
-		if origin != "" {
-			allowed := false
-			for _, o := range h.getConfig().AllowedOrigins {
-				if o == origin {
-					allowed = true
-					break
-				}
-			}
-			if !allowed {
-				headers := http.Header{}
-				headers.Set("Content-Type", "application/json")
-				headers.Set("X-Content-Type-Options", "nosniff")
-				Conn.StopRequest(403, `{"success":false,"error":"forbidden_origin"}`, headers)
-				return true
-			}
-		}
-	}
-
 	// 获取下载目录
 	downloadsDir, err := h.getDownloadsDir()
 	if err != nil {
@@ -451,35 +428,6 @@ func (h *UploadHandler) HandleUploadChunk(Conn *SunnyNet.HttpConn) bool {
 		defer func() { <-h.chunkSem }()
 	}
 
-	if h.getConfig() != nil && h.getConfig().SecretToken != "" {
-		if Conn.Request.Header.Get("X-Local-Auth") != h.getConfig().SecretToken {
-			headers := http.Header{}
-			headers.Set("Content-Type", "application/json")
-			headers.Set("X-Content-Type-Options", "nosniff")
-			Conn.StopRequest(401, `{"success":false,"error":"unauthorized"}`, headers)
-			return true
-		}
-	}
-	if h.getConfig() != nil && len(h.getConfig().AllowedOrigins) > 0 {
-		origin := Conn.Request.Header.Get("Origin")
-		if origin != "" {
-			allowed := false
-			for _, o := range h.getConfig().AllowedOrigins {
-				if o == origin {
-					allowed = true
-					break
-				}
-			}
-			if !allowed {
-				headers := http.Header{}
-				headers.Set("Content-Type", "application/json")
-				headers.Set("X-Content-Type-Options", "nosniff")
-				Conn.StopRequest(403, `{"success":false,"error":"forbidden_origin"}`, headers)
-				return true
-			}
-		}
-	}
-
 	// 解析multipart表单
 	err := Conn.Request.ParseMultipartForm(h.getConfig().MaxUploadSize)
 	if err != nil {
@@ -654,35 +602,6 @@ func (h *UploadHandler) HandleCompleteUpload(Conn *SunnyNet.HttpConn) bool {
 		defer func() { <-h.mergeSem }()
 	}
 
-	if h.getConfig() != nil && h.getConfig().SecretToken != "" {
-		if Conn.Request.Header.Get("X-Local-Auth") != h.getConfig().SecretToken {
-			headers := http.Header{}
-			headers.Set("Content-Type", "application/json")
-			headers.Set("X-Content-Type-Options", "nosniff")
-			Conn.StopRequest(401, `{"success":false,"error":"unauthorized"}`, headers)
-			return true
-		}
-	}
-	if h.getConfig() != nil && len(h.getConfig().AllowedOrigins) > 0 {
-		origin := Conn.Request.Header.Get("Origin")
-		if origin != "" {
-			allowed := false
-			for _, o := range h.getConfig().AllowedOrigins {
-				if o == origin {
-					allowed = true
-					break
-				}
-			}
-			if !allowed {
-				headers := http.Header{}
-				headers.Set("Content-Type", "application/json")
-				headers.Set("X-Content-Type-Options", "nosniff")
-				Conn.StopRequest(403, `{"success":false,"error":"forbidden_origin"}`, headers)
-				return true
-			}
-		}
-	}
-
 	body, err := io.ReadAll(Conn.Request.Body)
 	if err != nil {
 		utils.HandleError(err, "读取complete_upload请求体")
@@ -820,35 +739,6 @@ func (h *UploadHandler) HandleSaveVideo(Conn *SunnyNet.HttpConn) bool {
 		return false
 	}
 
-	if h.getConfig() != nil && h.getConfig().SecretToken != "" {
-		if Conn.Request.Header.Get("X-Local-Auth") != h.getConfig().SecretToken {
-			headers := http.Header{}
-			headers.Set("Content-Type", "application/json")
-			headers.Set("X-Content-Type-Options", "nosniff")
-			Conn.StopRequest(401, `{"success":false,"error":"unauthorized"}`, headers)
-			return true
-		}
-	}
-	if h.getConfig() != nil && len(h.getConfig().AllowedOrigins) > 0 {
-		origin := Conn.Request.Header.Get("Origin")
-		if origin != "" {
-			allowed := false
-			for _, o := range h.getConfig().AllowedOrigins {
-				if o == origin {
-					allowed = true
-					break
-				}
-			}
-			if !allowed {
-				headers := http.Header{}
-				headers.Set("Content-Type", "application/json")
-				headers.Set("X-Content-Type-Options", "nosniff")
-				Conn.StopRequest(403, `{"success":false,"error":"forbidden_origin"}`, headers)
-				return true
-			}
-		}
-	}
-
 	utils.Info("🔄 save_video: 开始处理请求")
 
 	// 解析multipart表单
@@ -991,36 +881,6 @@ func (h *UploadHandler) HandleSaveCover(Conn *SunnyNet.HttpConn) bool {
 		return false
 	}
 
-	// 授权校验
-	if h.getConfig() != nil && h.getConfig().SecretToken != "" {
-		if Conn.Request.Header.Get("X-Local-Auth") != h.getConfig().SecretToken {
-			headers := http.Header{}
-			headers.Set("Content-Type", "application/json")
-			headers.Set("X-Content-Type-Options", "nosniff")
-			Conn.StopRequest(401, `{"success":false,"error":"unauthorized"}`, headers)
-			return true
-		}
-	}
-	if h.getConfig() != nil && len(h.getConfig().AllowedOrigins) > 0 {
-		origin := Conn.Request.Header.Get("Origin")
-		if origin != "" {
-			allowed := false
-			for _, o := range h.getConfig().AllowedOrigins {
-				if o == origin {
-					allowed = true
-					break
-				}
-			}
-			if !allowed {
-				headers := http.Header{}
-				headers.Set("Content-Type", "application/json")
-				headers.Set("X-Content-Type-Options", "nosniff")
-				Conn.StopRequest(403, `{"success":false,"error":"forbidden_origin"}`, headers)
-				return true
-			}
-		}
-	}
-
 	// 只处理 POST 请求
 	if Conn.Request.Method != "POST" {
 		h.sendErrorResponse(Conn, fmt.Errorf("method not allowed: %s", Conn.Request.Method))
@@ -1166,36 +1026,6 @@ func (h *UploadHandler) HandleDownloadVideo(Conn *SunnyNet.HttpConn) bool {
 		return false
 	}
 
-	// 授权校验
-	if h.getConfig() != nil && h.getConfig().SecretToken != "" {
-		if Conn.Request.Header.Get("X-Local-Auth") != h.getConfig().SecretToken {
-			headers := http.Header{}
-			headers.Set("Content-Type", "application/json")
-			headers.Set("X-Content-Type-Options", "nosniff")
-			Conn.StopRequest(401, `{"success":false,"error":"unauthorized"}`, headers)
-			return true
-		}
-	}
-	if h.getConfig() != nil && len(h.getConfig().AllowedOrigins) > 0 {
-		origin := Conn.Request.Header.Get("Origin")
-		if origin != "" {
-			allowed := false
-			for _, o := range h.getConfig().AllowedOrigins {
-				if o == origin {
-					allowed = true
-					break
-				}
-			}
-			if !allowed {
-				headers := http.Header{}
-				headers.Set("Content-Type", "application/json")
-				headers.Set("X-Content-Type-Options", "nosniff")
-				Conn.StopRequest(403, `{"success":false,"error":"forbidden_origin"}`, headers)
-				return true
-			}
-		}
-	}
-
 	// 只处理 POST 请求
 	if Conn.Request.Method != "POST" {
 		h.sendErrorResponse(Conn, fmt.Errorf("method not allowed: %s", Conn.Request.Method))
@@ -1225,348 +1055,37 @@ func (h *UploadHandler) HandleDownloadVideo(Conn *SunnyNet.HttpConn) bool {
 		return true
 	}
 
-	if req.VideoURL == "" {
-		h.sendErrorResponse(Conn, fmt.Errorf("视频URL不能为空"))
-		return true
-	}
-
-	// 创建作者目录
-	authorFolder := utils.CleanFolderName(req.Author)
-	if authorFolder == "" {
-		authorFolder = "未知作者"
-	}
-
-	downloadsDir, err := h.getDownloadsDir()
+	start, err := h.StartVideoDownload(context.Background(), req, DownloadOptions{
+		Reporter: newWSDownloadReporter(h.wsHub, req),
+	})
 	if err != nil {
-		utils.HandleError(err, "获取下载目录")
 		h.sendErrorResponse(Conn, err)
 		return true
 	}
-	savePath := filepath.Join(downloadsDir, authorFolder)
-
-	if err := utils.EnsureDir(savePath); err != nil {
-		utils.HandleError(err, "创建作者目录")
-		h.sendErrorResponse(Conn, err)
-		return true
-	}
-
-	settings, err := h.settingsRepo.Load()
-	if err != nil {
-		utils.Warn("加载下载命名设置失败，继续使用默认命名策略: %v", err)
-	}
-	includeVideoID := true
-	if settings != nil {
-		includeVideoID = settings.DownloadFilenameWithVideoID
-	}
-	filenameTemplate := ""
-	if cfg := h.getConfig(); cfg != nil {
-		filenameTemplate = cfg.DownloadFilenameTemplate
-	}
-
-	// 生成文件名：默认仅使用标题；如配置模板，则优先按模板渲染。
-	filename := utils.BuildVideoFilename(utils.VideoFilenameMeta{
-		Title:   req.Title,
-		VideoID: req.VideoID,
-		Author:  req.Author,
-	}, includeVideoID, filenameTemplate)
-
-	// 检查文件名中是否已经包含分辨率信息（避免重复添加）
-	hasResolutionInFilename := false
-	if req.Width > 0 && req.Height > 0 {
-		resolutionPattern := fmt.Sprintf("_%dx%d", req.Width, req.Height)
-		hasResolutionInFilename = strings.Contains(filename, resolutionPattern)
-	} else if req.Resolution != "" {
-		cleanResolution := strings.ReplaceAll(req.Resolution, " ", "")
-		cleanResolution = strings.ReplaceAll(cleanResolution, "×", "x")
-		cleanResolution = strings.ReplaceAll(cleanResolution, "X", "x")
-		hasResolutionInFilename = strings.Contains(filename, "_"+cleanResolution) || strings.Contains(filename, cleanResolution)
-	}
-
-	// 如果有分辨率信息且文件名中还没有，添加到文件名中（与前端命名方式一致）
-	if !hasResolutionInFilename && (req.FileFormat != "" || req.Width > 0 || req.Height > 0 || req.Resolution != "") {
-		var qualityInfo string
-		if req.FileFormat != "" {
-			qualityInfo = req.FileFormat
-		} else {
-			qualityInfo = "quality"
-		}
-
-		// 优先使用 width 和 height，其次使用 resolution 字符串
-		if req.Width > 0 && req.Height > 0 {
-			qualityInfo += fmt.Sprintf("_%dx%d", req.Width, req.Height)
-		} else if req.Resolution != "" {
-			// 清理分辨率字符串，移除空格和特殊字符
-			cleanResolution := strings.ReplaceAll(req.Resolution, " ", "")
-			cleanResolution = strings.ReplaceAll(cleanResolution, "×", "x")
-			cleanResolution = strings.ReplaceAll(cleanResolution, "X", "x")
-			qualityInfo += "_" + cleanResolution
-		}
-
-		// 在添加分辨率信息前，需要先移除扩展名
-		base := strings.TrimSuffix(filename, filepath.Ext(filename))
-		ext := filepath.Ext(filename)
-		if ext == "" {
-			ext = ".mp4"
-		}
-		filename = base + "_" + qualityInfo + ext
-		utils.Info("📐 [视频下载] 添加分辨率信息到文件名: %s", qualityInfo)
-	} else if hasResolutionInFilename {
-		utils.Info("📐 [视频下载] 文件名中已包含分辨率信息，跳过添加")
-	}
-
-	// 确保文件扩展名
-	filename = utils.EnsureExtension(filename, ".mp4")
-	videoPath := filepath.Join(savePath, filename)
-
-	if !req.ForceSave {
-		if req.VideoID != "" && h.downloadService != nil {
-			if existing, err := h.downloadService.GetByID(req.VideoID); err == nil && existing != nil && existing.FilePath != "" {
-				if stat, statErr := os.Stat(existing.FilePath); statErr == nil {
-					fileSize := float64(stat.Size()) / (1024 * 1024)
-					relativePath, _ := filepath.Rel(downloadsDir, existing.FilePath)
-					utils.Info("⏭️ [视频下载] 视频已存在，跳过: %s", relativePath)
-
-					responseData := map[string]interface{}{
-						"success":      true,
-						"path":         existing.FilePath,
-						"relativePath": relativePath,
-						"size":         fileSize,
-						"skipped":      true,
-						"message":      "视频已存在，跳过下载",
-					}
-					responseBytes, _ := json.Marshal(responseData)
-					h.sendJSONResponse(Conn, 200, responseBytes)
-					return true
-				}
-			}
-		}
-		videoPath = utils.GenerateUniquePath(savePath, filename)
-	}
-
-	if req.VideoID != "" {
-		if _, exists := h.activeDownloads.Load(req.VideoID); exists {
-			h.sendJSONResponse(Conn, 200, map[string]interface{}{
-				"success": true,
-				"started": true,
-				"message": "下载任务已在后台进行中",
-			})
-			return true
-		}
-	}
-
-	// 判断是否需要解密
-	needDecrypt := req.Key != ""
-
-	// 临时文件路径
-	tmpHint := req.VideoID
-	if strings.TrimSpace(tmpHint) == "" {
-		tmpHint = utils.RandomString(8)
-	}
-	tmpPath := utils.BuildTempDownloadPath(videoPath, tmpHint)
-
-	// 进度回调
-	var lastLogTime time.Time
-	onProgress := func(progress float64, downloaded int64, total int64) {
-		// 每秒打印一次日志，避免刷屏
-		now := time.Now()
-		if now.Sub(lastLogTime) >= time.Second {
-			// 转换为MB
-			downloadedMB := float64(downloaded) / (1024 * 1024)
-			totalMB := float64(total) / (1024 * 1024)
-			percentage := progress * 100
-
-			utils.Info("📥 [视频下载] 进度: %.2f%% (%.2f/%.2f MB)", percentage, downloadedMB, totalMB)
-
-			// 发送 WebSocket 事件
-			if h.wsHub != nil {
-				// api_client.js 只识别 type='cmd'
-				h.wsHub.Broadcast(map[string]interface{}{
-					"type": "cmd",
-					"data": map[string]interface{}{
-						"action": "download_progress",
-						"payload": map[string]interface{}{
-							"videoId":    req.VideoID,
-							"percentage": percentage, // 前端 expect "percentage"
-							"downloaded": downloaded,
-							"total":      total,
-							"speed":      0,
-						},
-					},
-				})
-			}
-			lastLogTime = now
-		}
-	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	if req.VideoID != "" {
-		h.activeDownloads.Store(req.VideoID, cancel)
+	switch {
+	case start.Skipped:
+		h.sendJSONResponse(Conn, 200, map[string]interface{}{
+			"success":      true,
+			"path":         start.Result.Path,
+			"relativePath": start.Result.RelativePath,
+			"size":         float64(start.Result.Size) / (1024 * 1024),
+			"skipped":      true,
+			"message":      "视频已存在，跳过下载",
+		})
+	case start.InProgress:
+		h.sendJSONResponse(Conn, 200, map[string]interface{}{
+			"success": true,
+			"started": true,
+			"message": "下载任务已在后台进行中",
+		})
+	default:
+		h.sendJSONResponse(Conn, 200, map[string]interface{}{
+			"success": true,
+			"started": true,
+			"message": "下载任务已在后台启动",
+		})
 	}
-
-	go func(req DownloadVideoRequest, videoPath, tmpPath, downloadsDir string, needDecrypt bool, cancel context.CancelFunc) {
-		defer cancel()
-		if req.VideoID != "" {
-			defer h.activeDownloads.Delete(req.VideoID)
-		}
-
-		downloadCtx, downloadCancel := context.WithTimeout(ctx, 30*time.Minute)
-		defer downloadCancel()
-
-		utils.Info("🚀 [视频下载] 使用 Gopeed 引擎: %s", req.Title)
-
-		connections := 8
-		cfg := config.Get()
-		if cfg != nil && cfg.DownloadConnections > 0 {
-			connections = cfg.DownloadConnections
-		}
-		mode := downloadModeFromRequest(req)
-		normalizedURL := normalizeDownloadVideoURL(req)
-		if normalizedURL != req.VideoURL {
-			utils.Info("🩹 [视频下载] 原始视频链接已归一化为 encfilekey+token 直链")
-			req.VideoURL = normalizedURL
-		}
-		connections = downloadConnectionCountFromMode(connections, mode)
-		if mode == downloadVideoModeOriginal {
-			utils.Info("🎯 [视频下载] 原始视频使用单连接模式")
-		}
-
-		reqHeaders := map[string]string{
-			"Origin": "https://channels.weixin.qq.com",
-		}
-		if req.SourceURL != "" {
-			reqHeaders["Referer"] = req.SourceURL
-		}
-		if req.UserAgent != "" {
-			reqHeaders["User-Agent"] = req.UserAgent
-		}
-		for k, v := range req.Headers {
-			if strings.TrimSpace(k) == "" || strings.TrimSpace(v) == "" {
-				continue
-			}
-			reqHeaders[k] = v
-		}
-		utils.Info("🌐 [视频下载] 请求头: Referer=%s | UA=%s | 连接数=%d", reqHeaders["Referer"], reqHeaders["User-Agent"], connections)
-
-		_ = os.Remove(tmpPath)
-
-		actualPath, err := h.gopeedService.DownloadSync(downloadCtx, req.VideoURL, tmpPath, connections, reqHeaders, onProgress)
-		if err != nil {
-			utils.Error("❌ [视频下载] 下载失败: %v", err)
-			if actualPath != "" {
-				_ = os.Remove(actualPath)
-			}
-			if h.wsHub != nil {
-				h.wsHub.BroadcastCommand("download_failed", map[string]interface{}{
-					"videoId": req.VideoID,
-					"title":   req.Title,
-					"error":   err.Error(),
-				})
-			}
-			return
-		}
-		if actualPath == "" {
-			actualPath = tmpPath
-		}
-
-		stat, err := os.Stat(actualPath)
-		if err != nil || stat.Size() == 0 {
-			utils.Error("❌ [视频下载] 下载文件无效")
-			_ = os.Remove(actualPath)
-			if h.wsHub != nil {
-				h.wsHub.BroadcastCommand("download_failed", map[string]interface{}{
-					"videoId": req.VideoID,
-					"title":   req.Title,
-					"error":   "下载文件无效",
-				})
-			}
-			return
-		}
-
-		if needDecrypt {
-			utils.Info("🔐 [视频下载] 开始解密...")
-			if err := utils.DecryptFileInPlace(actualPath, req.Key, "", 0); err != nil {
-				utils.Error("❌ [视频下载] 解密失败: %v", err)
-				_ = os.Remove(actualPath)
-				if h.wsHub != nil {
-					h.wsHub.BroadcastCommand("download_failed", map[string]interface{}{
-						"videoId": req.VideoID,
-						"title":   req.Title,
-						"error":   fmt.Sprintf("解密失败: %v", err),
-					})
-				}
-				return
-			}
-			utils.Info("✓ [视频下载] 解密完成")
-		}
-
-		finalPath, err := utils.MoveFileToAvailablePath(actualPath, videoPath)
-		if err != nil {
-			_ = os.Remove(actualPath)
-			utils.Error("❌ [视频下载] 重命名文件失败: %v", err)
-			if h.wsHub != nil {
-				h.wsHub.BroadcastCommand("download_failed", map[string]interface{}{
-					"videoId": req.VideoID,
-					"title":   req.Title,
-					"error":   fmt.Sprintf("重命名文件失败: %v", err),
-				})
-			}
-			return
-		}
-		if finalPath != videoPath {
-			utils.Warn("📁 [视频下载] 目标文件已存在，已自动保存为: %s", filepath.Base(finalPath))
-		}
-
-		fileSize := float64(stat.Size()) / (1024 * 1024)
-		relativePath, _ := filepath.Rel(downloadsDir, finalPath)
-
-		statusMsg := ""
-		if needDecrypt {
-			statusMsg = " [已解密]"
-		}
-		utils.Info("✓ [视频下载] 视频已保存%s", statusMsg)
-
-		if h.downloadService != nil {
-			record := &database.DownloadRecord{
-				ID:           req.VideoID,
-				VideoID:      req.VideoID,
-				Title:        req.Title,
-				Author:       req.Author,
-				Duration:     0,
-				FileSize:     int64(stat.Size()),
-				FilePath:     finalPath,
-				Format:       "mp4",
-				Resolution:   req.Resolution,
-				Status:       database.DownloadStatusCompleted,
-				DownloadTime: time.Now(),
-				LikeCount:    req.LikeCount,
-				CommentCount: req.CommentCount,
-				ForwardCount: req.ForwardCount,
-				FavCount:     req.FavCount,
-			}
-			if err := h.downloadService.Create(record); err != nil {
-				utils.Error("保存下载记录失败: %v", err)
-			} else {
-				utils.Info("已保存下载记录: %s", record.Title)
-			}
-		}
-
-		if h.wsHub != nil {
-			h.wsHub.BroadcastCommand("download_complete", map[string]interface{}{
-				"videoId":      req.VideoID,
-				"title":        req.Title,
-				"path":         finalPath,
-				"relativePath": relativePath,
-				"size":         fileSize,
-				"decrypted":    needDecrypt,
-			})
-		}
-	}(req, videoPath, tmpPath, downloadsDir, needDecrypt, cancel)
-
-	h.sendJSONResponse(Conn, 200, map[string]interface{}{
-		"success": true,
-		"started": true,
-		"message": "下载任务已在后台启动",
-	})
 	return true
 }
 
@@ -1819,35 +1338,6 @@ func (h *UploadHandler) HandleUploadStatus(Conn *SunnyNet.HttpConn) bool {
 		return false
 	}
 
-	if h.getConfig() != nil && h.getConfig().SecretToken != "" {
-		if Conn.Request.Header.Get("X-Local-Auth") != h.getConfig().SecretToken {
-			headers := http.Header{}
-			headers.Set("Content-Type", "application/json")
-			headers.Set("X-Content-Type-Options", "nosniff")
-			Conn.StopRequest(401, `{"success":false,"error":"unauthorized"}`, headers)
-			return true
-		}
-	}
-	if h.getConfig() != nil && len(h.getConfig().AllowedOrigins) > 0 {
-		origin := Conn.Request.Header.Get("Origin")
-		if origin != "" {
-			allowed := false
-			for _, o := range h.getConfig().AllowedOrigins {
-				if o == origin {
-					allowed = true
-					break
-				}
-			}
-			if !allowed {
-				headers := http.Header{}
-				headers.Set("Content-Type", "application/json")
-				headers.Set("X-Content-Type-Options", "nosniff")
-				Conn.StopRequest(403, `{"success":false,"error":"forbidden_origin"}`, headers)
-				return true
-			}
-		}
-	}
-
 	body, err := io.ReadAll(Conn.Request.Body)
 	if err != nil {
 		h.sendErrorResponse(Conn, err)
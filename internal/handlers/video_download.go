@@ -0,0 +1,440 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"wx_channel/internal/config"
+	"wx_channel/internal/database"
+	"wx_channel/internal/utils"
+	"wx_channel/internal/websocket"
+)
+
+// 视频下载阶段
+const (
+	DownloadStageDownloading   = "downloading"    // 下载中（加密视频边下载边解密）
+	DownloadStageDecryptVerify = "decrypt_verify" // 校验解密结果
+	DownloadStageHashing       = "hashing"        // 计算文件哈希
+	DownloadStageSaving        = "saving"         // 移动到最终路径并保存下载记录
+)
+
+// DownloadProgress 视频下载进度
+type DownloadProgress struct {
+	VideoID    string  `json:"videoId"`
+	Percentage float64 `json:"percentage"`
+	Downloaded int64   `json:"downloaded"`
+	Total      int64   `json:"total"`
+	Speed      float64 `json:"speed"` // 字节/秒
+}
+
+// DownloadResult 视频下载结果
+type DownloadResult struct {
+	Path         string `json:"path"`
+	RelativePath string `json:"relativePath"`
+	Size         int64  `json:"size"` // 字节
+	Decrypted    bool   `json:"decrypted"`
+	SHA256       string `json:"sha256,omitempty"` // 仅在 DownloadOptions.ComputeHash 时计算
+}
+
+// DownloadStart StartVideoDownload 的返回值
+type DownloadStart struct {
+	Skipped    bool            // 视频已存在，Result 为已有文件
+	InProgress bool            // 同一视频已在下载中
+	Result     *DownloadResult // 仅 Skipped 时有值
+}
+
+// DownloadReporter 接收后台下载的进度和结果
+type DownloadReporter interface {
+	Progress(p DownloadProgress)
+	Stage(stage string)
+	Completed(result DownloadResult)
+	Failed(err error)
+}
+
+// DownloadOptions 后台下载选项
+type DownloadOptions struct {
+	Reporter    DownloadReporter
+	ComputeHash bool // 完成后计算 SHA-256
+}
+
+// StartVideoDownload 准备保存路径并在后台下载视频，进度和结果通过 opts.Reporter 报告
+// 视频已存在或同一视频正在下载时不会启动新的下载；ctx 取消时中止下载
+func (h *UploadHandler) StartVideoDownload(ctx context.Context, req DownloadVideoRequest, opts DownloadOptions) (*DownloadStart, error) {
+	if req.VideoURL == "" {
+		return nil, fmt.Errorf("视频URL不能为空")
+	}
+	reporter := opts.Reporter
+	if reporter == nil {
+		reporter = nopDownloadReporter{}
+	}
+
+	// 创建作者目录
+	authorFolder := utils.CleanFolderName(req.Author)
+	if authorFolder == "" {
+		authorFolder = "未知作者"
+	}
+
+	downloadsDir, err := h.getDownloadsDir()
+	if err != nil {
+		utils.HandleError(err, "获取下载目录")
+		return nil, err
+	}
+	savePath := filepath.Join(downloadsDir, authorFolder)
+
+	if err := utils.EnsureDir(savePath); err != nil {
+		utils.HandleError(err, "创建作者目录")
+		return nil, err
+	}
+
+	settings, err := h.settingsRepo.Load()
+	if err != nil {
+		utils.Warn("加载下载命名设置失败，继续使用默认命名策略: %v", err)
+	}
+	includeVideoID := true
+	if settings != nil {
+		includeVideoID = settings.DownloadFilenameWithVideoID
+	}
+	filenameTemplate := ""
+	if cfg := h.getConfig(); cfg != nil {
+		filenameTemplate = cfg.DownloadFilenameTemplate
+	}
+
+	// 生成文件名：默认仅使用标题；如配置模板，则优先按模板渲染。
+	filename := utils.BuildVideoFilename(utils.VideoFilenameMeta{
+		Title:   req.Title,
+		VideoID: req.VideoID,
+		Author:  req.Author,
+	}, includeVideoID, filenameTemplate)
+	filename = appendQualityInfo(filename, req)
+
+	// 确保文件扩展名
+	filename = utils.EnsureExtension(filename, ".mp4")
+	videoPath := filepath.Join(savePath, filename)
+
+	if !req.ForceSave {
+		if req.VideoID != "" && h.downloadService != nil {
+			if existing, err := h.downloadService.GetByID(req.VideoID); err == nil && existing != nil && existing.FilePath != "" {
+				if stat, statErr := os.Stat(existing.FilePath); statErr == nil {
+					relativePath, _ := filepath.Rel(downloadsDir, existing.FilePath)
+					utils.Info("⏭️ [视频下载] 视频已存在，跳过: %s", relativePath)
+					return &DownloadStart{Skipped: true, Result: &DownloadResult{
+						Path:         existing.FilePath,
+						RelativePath: relativePath,
+						Size:         stat.Size(),
+					}}, nil
+				}
+			}
+		}
+		videoPath = utils.GenerateUniquePath(savePath, filename)
+	}
+
+	if req.VideoID != "" {
+		if _, exists := h.activeDownloads.Load(req.VideoID); exists {
+			return &DownloadStart{InProgress: true}, nil
+		}
+	}
+
+	// 临时文件路径
+	tmpHint := req.VideoID
+	if strings.TrimSpace(tmpHint) == "" {
+		tmpHint = utils.RandomString(8)
+	}
+	tmpPath := utils.BuildTempDownloadPath(videoPath, tmpHint)
+
+	ctx, cancel := context.WithCancel(ctx)
+	if req.VideoID != "" {
+		h.activeDownloads.Store(req.VideoID, cancel)
+	}
+
+	go func() {
+		defer cancel()
+		if req.VideoID != "" {
+			defer h.activeDownloads.Delete(req.VideoID)
+		}
+		h.runVideoDownload(ctx, req, videoPath, tmpPath, downloadsDir, opts.ComputeHash, reporter)
+	}()
+	return &DownloadStart{}, nil
+}
+
+// appendQualityInfo 在文件名中追加清晰度和分辨率信息（与前端命名方式一致）
+func appendQualityInfo(filename string, req DownloadVideoRequest) string {
+	// 检查文件名中是否已经包含分辨率信息（避免重复添加）
+	hasResolutionInFilename := false
+	if req.Width > 0 && req.Height > 0 {
+		resolutionPattern := fmt.Sprintf("_%dx%d", req.Width, req.Height)
+		hasResolutionInFilename = strings.Contains(filename, resolutionPattern)
+	} else if req.Resolution != "" {
+		cleanResolution := strings.ReplaceAll(req.Resolution, " ", "")
+		cleanResolution = strings.ReplaceAll(cleanResolution, "×", "x")
+		cleanResolution = strings.ReplaceAll(cleanResolution, "X", "x")
+		hasResolutionInFilename = strings.Contains(filename, "_"+cleanResolution) || strings.Contains(filename, cleanResolution)
+	}
+
+	if hasResolutionInFilename {
+		utils.Info("📐 [视频下载] 文件名中已包含分辨率信息，跳过添加")
+		return filename
+	}
+	if req.FileFormat == "" && req.Width <= 0 && req.Height <= 0 && req.Resolution == "" {
+		return filename
+	}
+
+	var qualityInfo string
+	if req.FileFormat != "" {
+		qualityInfo = req.FileFormat
+	} else {
+		qualityInfo = "quality"
+	}
+
+	// 优先使用 width 和 height，其次使用 resolution 字符串
+	if req.Width > 0 && req.Height > 0 {
+		qualityInfo += fmt.Sprintf("_%dx%d", req.Width, req.Height)
+	} else if req.Resolution != "" {
+		// 清理分辨率字符串，移除空格和特殊字符
+		cleanResolution := strings.ReplaceAll(req.Resolution, " ", "")
+		cleanResolution = strings.ReplaceAll(cleanResolution, "×", "x")
+		cleanResolution = strings.ReplaceAll(cleanResolution, "X", "x")
+		qualityInfo += "_" + cleanResolution
+	}
+
+	// 在添加分辨率信息前，需要先移除扩展名
+	base := strings.TrimSuffix(filename, filepath.Ext(filename))
+	ext := filepath.Ext(filename)
+	if ext == "" {
+		ext = ".mp4"
+	}
+	utils.Info("📐 [视频下载] 添加分辨率信息到文件名: %s", qualityInfo)
+	return base + "_" + qualityInfo + ext
+}
+
+// runVideoDownload 执行下载、解密校验和保存，结果通过 reporter 报告
+func (h *UploadHandler) runVideoDownload(ctx context.Context, req DownloadVideoRequest, videoPath, tmpPath, downloadsDir string, computeHash bool, reporter DownloadReporter) {
+	downloadCtx, downloadCancel := context.WithTimeout(ctx, 30*time.Minute)
+	defer downloadCancel()
+
+	utils.Info("🚀 [视频下载] 使用 Gopeed 引擎: %s", req.Title)
+
+	connections := 8
+	cfg := config.Get()
+	if cfg != nil && cfg.DownloadConnections > 0 {
+		connections = cfg.DownloadConnections
+	}
+	mode := downloadModeFromRequest(req)
+	normalizedURL := normalizeDownloadVideoURL(req)
+	if normalizedURL != req.VideoURL {
+		utils.Info("🩹 [视频下载] 原始视频链接已归一化为 encfilekey+token 直链")
+		req.VideoURL = normalizedURL
+	}
+	connections = downloadConnectionCountFromMode(connections, mode)
+	if mode == downloadVideoModeOriginal {
+		utils.Info("🎯 [视频下载] 原始视频使用单连接模式")
+	}
+
+	reqHeaders := map[string]string{
+		"Origin": "https://channels.weixin.qq.com",
+	}
+	if req.SourceURL != "" {
+		reqHeaders["Referer"] = req.SourceURL
+	}
+	if req.UserAgent != "" {
+		reqHeaders["User-Agent"] = req.UserAgent
+	}
+	for k, v := range req.Headers {
+		if strings.TrimSpace(k) == "" || strings.TrimSpace(v) == "" {
+			continue
+		}
+		reqHeaders[k] = v
+	}
+	utils.Info("🌐 [视频下载] 请求头: Referer=%s | UA=%s | 连接数=%d", reqHeaders["Referer"], reqHeaders["User-Agent"], connections)
+
+	_ = os.Remove(tmpPath)
+
+	// 加密视频边下载边解密，完成后只校验文件头
+	needDecrypt := req.Key != ""
+	var decryptor []byte
+	if needDecrypt {
+		var err error
+		decryptor, err = utils.BuildDecryptorPrefix(req.Key, "", 0)
+		if err != nil {
+			utils.Error("❌ [视频下载] 解密失败: %v", err)
+			reporter.Failed(fmt.Errorf("解密失败: %v", err))
+			return
+		}
+		utils.Info("🔐 [视频下载] 边下载边解密")
+	}
+
+	// 进度回调，每秒报告一次，避免刷屏
+	var lastReport time.Time
+	var lastDownloaded int64
+	onProgress := func(progress float64, downloaded int64, total int64) {
+		now := time.Now()
+		if now.Sub(lastReport) < time.Second {
+			return
+		}
+		speed := 0.0
+		if !lastReport.IsZero() {
+			speed = float64(downloaded-lastDownloaded) / now.Sub(lastReport).Seconds()
+		}
+		percentage := progress * 100
+		utils.Info("📥 [视频下载] 进度: %.2f%% (%.2f/%.2f MB)", percentage,
+			float64(downloaded)/(1024*1024), float64(total)/(1024*1024))
+		reporter.Progress(DownloadProgress{
+			VideoID:    req.VideoID,
+			Percentage: percentage,
+			Downloaded: downloaded,
+			Total:      total,
+			Speed:      speed,
+		})
+		lastReport, lastDownloaded = now, downloaded
+	}
+
+	reporter.Stage(DownloadStageDownloading)
+	actualPath, err := h.gopeedService.DownloadSyncDecrypted(downloadCtx, req.VideoURL, tmpPath, connections, reqHeaders, decryptor, onProgress)
+	if err != nil {
+		utils.Error("❌ [视频下载] 下载失败: %v", err)
+		if actualPath != "" {
+			_ = os.Remove(actualPath)
+		}
+		reporter.Failed(err)
+		return
+	}
+	if actualPath == "" {
+		actualPath = tmpPath
+	}
+
+	stat, err := os.Stat(actualPath)
+	if err != nil || stat.Size() == 0 {
+		utils.Error("❌ [视频下载] 下载文件无效")
+		_ = os.Remove(actualPath)
+		reporter.Failed(fmt.Errorf("下载文件无效"))
+		return
+	}
+
+	if needDecrypt {
+		reporter.Stage(DownloadStageDecryptVerify)
+		if err := utils.ValidateDecryptedFile(actualPath); err != nil {
+			utils.Error("❌ [视频下载] 解密失败: %v", err)
+			_ = os.Remove(actualPath)
+			reporter.Failed(fmt.Errorf("解密失败: %v", err))
+			return
+		}
+		utils.Info("✓ [视频下载] 解密完成")
+	}
+
+	var hash string
+	if computeHash {
+		reporter.Stage(DownloadStageHashing)
+		if hash, err = utils.FileSHA256(actualPath); err != nil {
+			utils.Warn("计算文件哈希失败: %v", err)
+		}
+	}
+
+	reporter.Stage(DownloadStageSaving)
+	finalPath, err := utils.MoveFileToAvailablePath(actualPath, videoPath)
+	if err != nil {
+		_ = os.Remove(actualPath)
+		utils.Error("❌ [视频下载] 重命名文件失败: %v", err)
+		reporter.Failed(fmt.Errorf("重命名文件失败: %v", err))
+		return
+	}
+	if finalPath != videoPath {
+		utils.Warn("📁 [视频下载] 目标文件已存在，已自动保存为: %s", filepath.Base(finalPath))
+	}
+
+	relativePath, _ := filepath.Rel(downloadsDir, finalPath)
+
+	statusMsg := ""
+	if needDecrypt {
+		statusMsg = " [已解密]"
+	}
+	utils.Info("✓ [视频下载] 视频已保存%s", statusMsg)
+
+	if h.downloadService != nil {
+		record := &database.DownloadRecord{
+			ID:           req.VideoID,
+			VideoID:      req.VideoID,
+			Title:        req.Title,
+			Author:       req.Author,
+			Duration:     0,
+			FileSize:     stat.Size(),
+			FilePath:     finalPath,
+			Format:       "mp4",
+			Resolution:   req.Resolution,
+			Status:       database.DownloadStatusCompleted,
+			DownloadTime: time.Now(),
+			LikeCount:    req.LikeCount,
+			CommentCount: req.CommentCount,
+			ForwardCount: req.ForwardCount,
+			FavCount:     req.FavCount,
+		}
+		if err := h.downloadService.Create(record); err != nil {
+			utils.Error("保存下载记录失败: %v", err)
+		} else {
+			utils.Info("已保存下载记录: %s", record.Title)
+		}
+	}
+
+	reporter.Completed(DownloadResult{
+		Path:         finalPath,
+		RelativePath: relativePath,
+		Size:         stat.Size(),
+		Decrypted:    needDecrypt,
+		SHA256:       hash,
+	})
+}
+
+// nopDownloadReporter 忽略所有下载进度
+type nopDownloadReporter struct{}
+
+func (nopDownloadReporter) Progress(DownloadProgress) {}
+func (nopDownloadReporter) Stage(string)              {}
+func (nopDownloadReporter) Completed(DownloadResult)  {}
+func (nopDownloadReporter) Failed(error)              {}
+
+// wsDownloadReporter 通过本地 WebSocket 向页面广播下载进度
+type wsDownloadReporter struct {
+	hub *websocket.Hub
+	req DownloadVideoRequest
+}
+
+func newWSDownloadReporter(hub *websocket.Hub, req DownloadVideoRequest) DownloadReporter {
+	if hub == nil {
+		return nopDownloadReporter{}
+	}
+	return &wsDownloadReporter{hub: hub, req: req}
+}
+
+func (r *wsDownloadReporter) Progress(p DownloadProgress) {
+	// api_client.js 只识别 type='cmd'
+	r.hub.Broadcast(map[string]interface{}{
+		"type": "cmd",
+		"data": map[string]interface{}{
+			"action":  "download_progress",
+			"payload": p,
+		},
+	})
+}
+
+func (r *wsDownloadReporter) Stage(string) {}
+
+func (r *wsDownloadReporter) Completed(result DownloadResult) {
+	r.hub.BroadcastCommand("download_complete", map[string]interface{}{
+		"videoId":      r.req.VideoID,
+		"title":        r.req.Title,
+		"path":         result.Path,
+		"relativePath": result.RelativePath,
+		"size":         float64(result.Size) / (1024 * 1024),
+		"decrypted":    result.Decrypted,
+	})
+}
+
+func (r *wsDownloadReporter) Failed(err error) {
+	r.hub.BroadcastCommand("download_failed", map[string]interface{}{
+		"videoId": r.req.VideoID,
+		"title":   r.req.Title,
+		"error":   err.Error(),
+	})
+}
@@ -4,14 +4,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"net/http"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
 	"wx_channel/internal/database"
-	"wx_channel/internal/response"
 	"wx_channel/internal/utils"
 
 	"github.com/fatih/color"
@@ -26,36 +24,6 @@ func (h *APIHandler) HandleProfile(Conn *SunnyNet.HttpConn) bool {
 	}
 	utils.LogInfo("[Profile API] 收到视频信息请求")
 
-	// 授权与来源校验（可选）
-	if h.getConfig() != nil && h.getConfig().SecretToken != "" {
-		if Conn.Request.Header.Get("X-Local-Auth") != h.getConfig().SecretToken {
-			headers := http.Header{}
-			headers.Set("Content-Type", "application/json")
-			headers.Set("X-Content-Type-Options", "nosniff")
-			Conn.StopRequest(401, string(response.ErrorJSON(401, "unauthorized")), headers)
-			return true
-		}
-	}
-	if h.getConfig() != nil && len(h.getConfig().AllowedOrigins) > 0 {
-		origin := Conn.Request.Header.Get("Origin")
-		if origin != "" {
-			allowed := false
-			for _, o := range h.getConfig().AllowedOrigins {
-				if o == origin {
-					allowed = true
-					break
-				}
-			}
-			if !allowed {
-				headers := http.Header{}
-				headers.Set("Content-Type", "application/json")
-				headers.Set("X-Content-Type-Options", "nosniff")
-				Conn.StopRequest(403, string(response.ErrorJSON(403, "forbidden_origin")), headers)
-				return true
-			}
-		}
-	}
-
 	var data map[string]interface{}
 	body, err := io.ReadAll(Conn.Request.Body)
 	if err != nil {
@@ -378,35 +346,6 @@ func (h *APIHandler) HandleTip(Conn *SunnyNet.HttpConn) bool {
 		return false
 	}
 
-	if h.getConfig() != nil && h.getConfig().SecretToken != "" {
-		if Conn.Request.Header.Get("X-Local-Auth") != h.getConfig().SecretToken {
-			headers := http.Header{}
-			headers.Set("Content-Type", "application/json")
-			headers.Set("X-Content-Type-Options", "nosniff")
-			Conn.StopRequest(401, string(response.ErrorJSON(401, "unauthorized")), headers)
-			return true
-		}
-	}
-	if h.getConfig() != nil && len(h.getConfig().AllowedOrigins) > 0 {
-		origin := Conn.Request.Header.Get("Origin")
-		if origin != "" {
-			allowed := false
-			for _, o := range h.getConfig().AllowedOrigins {
-				if o == origin {
-					allowed = true
-					break
-				}
-			}
-			if !allowed {
-				headers := http.Header{}
-				headers.Set("Content-Type", "application/json")
-				headers.Set("X-Content-Type-Options", "nosniff")
-				Conn.StopRequest(403, string(response.ErrorJSON(403, "forbidden_origin")), headers)
-				return true
-			}
-		}
-	}
-
 	var data struct {
 		Msg string `json:"msg"`
 	}
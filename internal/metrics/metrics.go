@@ -77,4 +77,16 @@ var (
 		Name: "wx_channel_active_requests_per_client",
 		Help: "每个客户端的活跃请求数",
 	}, []string{"client_id"})
+
+	// 本地接口（/__wx_channels_api/）指标
+	LocalAPIRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "wx_channel_local_api_requests_total",
+		Help: "本地接口请求总数",
+	}, []string{"path", "status"})
+
+	LocalAPIRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "wx_channel_local_api_request_duration_seconds",
+		Help:    "本地接口请求耗时（秒）",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"path"})
 )
@@ -21,10 +21,12 @@ type APIRouter struct {
 	systemService      *api.SystemService
 	logsService        *api.LogsService
 	exportService      *api.ExportAPI
+	importService      *api.ImportAPI
 	proxyService       *api.ProxyService
 	certificateService *api.CertificateService
 	versionService     *api.VersionAPI
 	radarAPI           *api.RadarServiceAPI
+	savedSearchAPI     *api.SavedSearchAPI
 	allowedOrigins     []string
 	secretToken        string
 }
@@ -68,10 +70,12 @@ func NewAPIRouterWithRuntimeDiagnostics(cfg *config.Config, hub *websocket.Hub,
 		systemService:      api.NewSystemService(),
 		logsService:        api.NewLogsService(cfg),
 		exportService:      api.NewExportAPI(),
+		importService:      api.NewImportAPI(cfg),
 		proxyService:       api.NewProxyService(sunny, cfg.Port),
 		certificateService: api.NewCertificateService(sunny),
 		versionService:     api.NewVersionAPI(),
 		radarAPI:           api.NewRadarServiceAPI(),
+		savedSearchAPI:     api.NewSavedSearchAPI(hub),
 		allowedOrigins:     cfg.AllowedOrigins,
 		secretToken:        cfg.SecretToken,
 	}
@@ -106,10 +110,18 @@ func (r *APIRouter) registerRoutes() {
 	r.mux.HandleFunc("/api/queue", r.consoleHandler.HandleQueueAPI)
 	r.mux.HandleFunc("/api/queue/", r.consoleHandler.HandleQueueAPI)
 
+	// 控制台 API - 已导入的评论
+	r.mux.HandleFunc("/api/comments", r.consoleHandler.HandleCommentsAPI)
+	r.mux.HandleFunc("/api/comments/", r.consoleHandler.HandleCommentsAPI)
+
 	// Console API - Settings
 	// 设置管理
 	r.mux.HandleFunc("/api/settings", r.consoleHandler.HandleSettingsAPI)
 
+	// 下载保留策略
+	r.mux.HandleFunc("/api/retention", r.consoleHandler.HandleRetentionAPI)
+	r.mux.HandleFunc("/api/retention/", r.consoleHandler.HandleRetentionAPI)
+
 	// 健康检查
 	r.mux.HandleFunc("/api/health", r.consoleHandler.HandleHealth)
 
@@ -129,6 +141,10 @@ func (r *APIRouter) registerRoutes() {
 	r.mux.HandleFunc("/api/export/browse", r.exportService.HandleExportBrowseHistory)
 	r.mux.HandleFunc("/api/export/downloads", r.exportService.HandleExportDownloadRecords)
 
+	// 控制台 API - 导入功能
+	r.mux.HandleFunc("/api/import/browse", r.importService.HandleImportBrowseHistory)
+	r.mux.HandleFunc("/api/import/downloads", r.importService.HandleImportDownloadRecords)
+
 	// 控制台 API - 视频相关
 	r.mux.HandleFunc("/api/video/stream", r.consoleHandler.HandleVideoStream)
 	r.mux.HandleFunc("/api/video/play", r.consoleHandler.HandleVideoPlay)
@@ -143,9 +159,19 @@ func (r *APIRouter) registerRoutes() {
 	r.mux.HandleFunc("/api/v1/settings", r.consoleHandler.HandleSettingsAPI)
 	r.mux.HandleFunc("/api/v1/stats", r.consoleHandler.HandleStatsAPI)
 	r.mux.HandleFunc("/api/v1/stats/", r.consoleHandler.HandleStatsAPI)
+	r.mux.HandleFunc("/api/v1/comments", r.consoleHandler.HandleCommentsAPI)
+	r.mux.HandleFunc("/api/v1/comments/", r.consoleHandler.HandleCommentsAPI)
 
 	// Radar API
 	r.radarAPI.RegisterRoutes(r.mux)
+
+	// 保存的关键词搜索
+	r.savedSearchAPI.RegisterRoutes(r.mux)
+}
+
+// ResumeCommentExportJobs 恢复上次退出时未完成的评论导出任务
+func (r *APIRouter) ResumeCommentExportJobs() int {
+	return r.searchService.ResumeCommentExportJobs()
 }
 
 // Handler 返回带中间件的 HTTP Handler
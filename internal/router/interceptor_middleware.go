@@ -0,0 +1,314 @@
+package router
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"wx_channel/internal/config"
+	"wx_channel/internal/metrics"
+	"wx_channel/internal/response"
+	"wx_channel/internal/utils"
+
+	"github.com/qtgolang/SunnyNet/SunnyNet"
+)
+
+// localAPIPrefix 注入脚本调用的本地接口前缀
+const localAPIPrefix = "/__wx_channels_api/"
+
+// 本地接口默认限流：每个来源 IP 的每个接口每秒 50 次，突发 100 次
+const (
+	localAPIRateLimit = 50
+	localAPIRateBurst = 100
+)
+
+// publicLocalAPIPaths 页面加载时由注入脚本直接调用、不携带令牌的接口，不做令牌和来源校验
+var publicLocalAPIPaths = map[string]bool{
+	"/__wx_channels_api/page_url":          true,
+	"/__wx_channels_api/save_page_content": true,
+	"/__wx_channels_api/cancel_download":   true,
+}
+
+// InterceptorFunc 将普通函数适配为 Interceptor
+type InterceptorFunc func(conn *SunnyNet.HttpConn) bool
+
+// Handle implements Interceptor
+func (f InterceptorFunc) Handle(conn *SunnyNet.HttpConn) bool {
+	return f(conn)
+}
+
+// Interceptors 按顺序尝试的一组拦截器，任意一个处理后即停止
+type Interceptors []Interceptor
+
+// Handle implements Interceptor
+func (list Interceptors) Handle(conn *SunnyNet.HttpConn) bool {
+	for _, interceptor := range list {
+		if interceptor != nil && interceptor.Handle(conn) {
+			return true
+		}
+	}
+	return false
+}
+
+// InterceptorMiddleware 拦截器中间件
+type InterceptorMiddleware func(next Interceptor) Interceptor
+
+// ChainInterceptor 将多个中间件链接起来，第一个中间件在最外层
+func ChainInterceptor(interceptor Interceptor, middlewares ...InterceptorMiddleware) Interceptor {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		interceptor = middlewares[i](interceptor)
+	}
+	return interceptor
+}
+
+// NewLocalAPIInterceptor 组合 /__wx_channels_api/ 的处理器，并统一套上恢复、日志、指标、限流、来源和授权校验
+func NewLocalAPIInterceptor(cfg *config.Config, interceptors ...Interceptor) Interceptor {
+	getConfig := func() *config.Config {
+		if cfg != nil {
+			return cfg
+		}
+		return config.Get()
+	}
+
+	return ChainInterceptor(Interceptors(interceptors),
+		InterceptorRecovery,
+		InterceptorLogger,
+		InterceptorMetrics,
+		InterceptorRateLimit(localAPIRateLimit, localAPIRateBurst),
+		InterceptorOrigin(func() []string {
+			if c := getConfig(); c != nil {
+				return c.AllowedOrigins
+			}
+			return nil
+		}),
+		InterceptorAuth(func() string {
+			if c := getConfig(); c != nil {
+				return c.SecretToken
+			}
+			return ""
+		}),
+	)
+}
+
+// localAPIPath 返回本地接口的请求路径，不是本地接口时返回空字符串
+func localAPIPath(conn *SunnyNet.HttpConn) string {
+	if conn == nil || conn.Request == nil || conn.Request.URL == nil {
+		return ""
+	}
+	if !strings.HasPrefix(conn.Request.URL.Path, localAPIPrefix) {
+		return ""
+	}
+	return conn.Request.URL.Path
+}
+
+// responseStatus 返回已写入连接的响应状态码
+func responseStatus(conn *SunnyNet.HttpConn) int {
+	if conn.Response == nil {
+		return 0
+	}
+	return conn.Response.StatusCode
+}
+
+// stopWithError 直接向连接返回 JSON 错误
+func stopWithError(conn *SunnyNet.HttpConn, status int, message string) {
+	headers := http.Header{}
+	headers.Set("Content-Type", "application/json")
+	headers.Set("X-Content-Type-Options", "nosniff")
+	conn.StopRequest(status, string(response.ErrorJSON(status, message)), headers)
+}
+
+// InterceptorRecovery 异常恢复中间件，本地接口发生 panic 时返回 500
+func InterceptorRecovery(next Interceptor) Interceptor {
+	return InterceptorFunc(func(conn *SunnyNet.HttpConn) (handled bool) {
+		defer func() {
+			if err := recover(); err != nil {
+				path := localAPIPath(conn)
+				utils.GetLogger().Error("Interceptor panic recovered: %v, path: %s", err, path)
+				if path != "" {
+					stopWithError(conn, http.StatusInternalServerError, "Internal Server Error")
+					handled = true
+				}
+			}
+		}()
+		return next.Handle(conn)
+	})
+}
+
+// InterceptorLogger 日志中间件，记录已处理的本地接口请求
+func InterceptorLogger(next Interceptor) Interceptor {
+	return InterceptorFunc(func(conn *SunnyNet.HttpConn) bool {
+		path := localAPIPath(conn)
+		if path == "" {
+			return next.Handle(conn)
+		}
+
+		start := time.Now()
+		handled := next.Handle(conn)
+		if handled {
+			// 分片上传等接口调用频繁，使用 Debug 级别
+			utils.GetLogger().Debug(
+				"本地接口请求: %s %s [%d] %s from %s",
+				conn.Request.Method,
+				path,
+				responseStatus(conn),
+				time.Since(start).String(),
+				conn.ClientIP,
+			)
+		}
+		return handled
+	})
+}
+
+// InterceptorMetrics 指标中间件，按接口记录请求数和耗时
+// 只记录已处理的请求，避免未知路径产生大量标签
+func InterceptorMetrics(next Interceptor) Interceptor {
+	return InterceptorFunc(func(conn *SunnyNet.HttpConn) bool {
+		path := localAPIPath(conn)
+		if path == "" {
+			return next.Handle(conn)
+		}
+
+		start := time.Now()
+		handled := next.Handle(conn)
+		if handled {
+			metrics.LocalAPIRequestsTotal.WithLabelValues(path, strconv.Itoa(responseStatus(conn))).Inc()
+			metrics.LocalAPIRequestDuration.WithLabelValues(path).Observe(time.Since(start).Seconds())
+		}
+		return handled
+	})
+}
+
+// InterceptorRateLimit 限流中间件，按来源 IP 和接口分别限流，超出时返回 429
+func InterceptorRateLimit(perSecond float64, burst int) InterceptorMiddleware {
+	limiter := newRequestLimiter(perSecond, burst)
+	return func(next Interceptor) Interceptor {
+		return InterceptorFunc(func(conn *SunnyNet.HttpConn) bool {
+			path := localAPIPath(conn)
+			if path == "" {
+				return next.Handle(conn)
+			}
+			if !limiter.allow(conn.ClientIP + " " + path) {
+				stopWithError(conn, http.StatusTooManyRequests, "too_many_requests")
+				return true
+			}
+			return next.Handle(conn)
+		})
+	}
+}
+
+// InterceptorOrigin 来源校验中间件：处理 CORS 预检，配置了 AllowedOrigins 时拒绝其他来源
+func InterceptorOrigin(allowedOrigins func() []string) InterceptorMiddleware {
+	return func(next Interceptor) Interceptor {
+		return InterceptorFunc(func(conn *SunnyNet.HttpConn) bool {
+			path := localAPIPath(conn)
+			if path == "" {
+				return next.Handle(conn)
+			}
+
+			origins := allowedOrigins()
+			origin := conn.Request.Header.Get("Origin")
+			if conn.Request.Method == http.MethodOptions {
+				headers := http.Header{}
+				headers.Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+				headers.Set("Access-Control-Allow-Headers", "Content-Type, X-Local-Auth")
+				if origin != "" && originAllowed(origins, origin) {
+					headers.Set("Access-Control-Allow-Origin", origin)
+					headers.Set("Vary", "Origin")
+				}
+				conn.StopRequest(http.StatusNoContent, "", headers)
+				return true
+			}
+
+			if len(origins) > 0 && origin != "" && !publicLocalAPIPaths[path] && !originAllowed(origins, origin) {
+				stopWithError(conn, http.StatusForbidden, "forbidden_origin")
+				return true
+			}
+			return next.Handle(conn)
+		})
+	}
+}
+
+// InterceptorAuth 令牌校验中间件，令牌为空时不启用
+func InterceptorAuth(secretToken func() string) InterceptorMiddleware {
+	return func(next Interceptor) Interceptor {
+		return InterceptorFunc(func(conn *SunnyNet.HttpConn) bool {
+			path := localAPIPath(conn)
+			if path == "" || publicLocalAPIPaths[path] || conn.Request.Method == http.MethodOptions {
+				return next.Handle(conn)
+			}
+
+			token := secretToken()
+			if token != "" && requestToken(conn.Request) != token {
+				stopWithError(conn, http.StatusUnauthorized, "unauthorized")
+				return true
+			}
+			return next.Handle(conn)
+		})
+	}
+}
+
+// requestLimiter 按 key 分别计数的请求令牌桶
+type requestLimiter struct {
+	mu        sync.Mutex
+	perSecond float64
+	burst     float64
+	buckets   map[string]*requestBucket
+}
+
+type requestBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// maxRequestBuckets 超过后清理空闲的令牌桶
+const maxRequestBuckets = 1024
+
+func newRequestLimiter(perSecond float64, burst int) *requestLimiter {
+	return &requestLimiter{
+		perSecond: perSecond,
+		burst:     float64(burst),
+		buckets:   make(map[string]*requestBucket),
+	}
+}
+
+// allow 尝试取出一个令牌，perSecond <= 0 表示不限流
+func (l *requestLimiter) allow(key string) bool {
+	if l.perSecond <= 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	bucket, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= maxRequestBuckets {
+			l.pruneLocked(now)
+		}
+		bucket = &requestBucket{tokens: l.burst, last: now}
+		l.buckets[key] = bucket
+	}
+
+	bucket.tokens += now.Sub(bucket.last).Seconds() * l.perSecond
+	bucket.last = now
+	if bucket.tokens > l.burst {
+		bucket.tokens = l.burst
+	}
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// pruneLocked 删除已经回满的令牌桶，调用方需持有锁
+func (l *requestLimiter) pruneLocked(now time.Time) {
+	for key, bucket := range l.buckets {
+		if bucket.tokens+now.Sub(bucket.last).Seconds()*l.perSecond >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"wx_channel/internal/config"
+	"wx_channel/internal/metrics"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/qtgolang/SunnyNet/SunnyNet"
+	"github.com/qtgolang/SunnyNet/public"
+)
+
+func newTestConn(method, target string, headers map[string]string) *SunnyNet.HttpConn {
+	req := httptest.NewRequest(method, target, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return &SunnyNet.HttpConn{
+		Type:     public.HttpSendRequest,
+		ClientIP: "127.0.0.1",
+		Request:  req,
+	}
+}
+
+// okInterceptor 处理所有请求并返回 200，记录被调用的次数
+type okInterceptor struct {
+	calls int
+}
+
+func (i *okInterceptor) Handle(conn *SunnyNet.HttpConn) bool {
+	i.calls++
+	conn.StopRequest(http.StatusOK, `{"code":0}`, http.Header{})
+	return true
+}
+
+func TestInterceptorAuth(t *testing.T) {
+	inner := &okInterceptor{}
+	handler := ChainInterceptor(inner, InterceptorAuth(func() string { return "secret" }))
+
+	tests := []struct {
+		name    string
+		method  string
+		target  string
+		headers map[string]string
+		status  int
+	}{
+		{"missing token", http.MethodPost, "/__wx_channels_api/profile", nil, http.StatusUnauthorized},
+		{"wrong token", http.MethodPost, "/__wx_channels_api/profile", map[string]string{"X-Local-Auth": "bad"}, http.StatusUnauthorized},
+		{"header token", http.MethodPost, "/__wx_channels_api/profile", map[string]string{"X-Local-Auth": "secret"}, http.StatusOK},
+		{"bearer token", http.MethodPost, "/__wx_channels_api/profile", map[string]string{"Authorization": "Bearer secret"}, http.StatusOK},
+		{"public path", http.MethodPost, "/__wx_channels_api/page_url", nil, http.StatusOK},
+		{"preflight", http.MethodOptions, "/__wx_channels_api/profile", nil, http.StatusOK},
+		{"other path", http.MethodGet, "/js/app.js", nil, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := newTestConn(tt.method, tt.target, tt.headers)
+			if !handler.Handle(conn) {
+				t.Fatal("expected request to be handled")
+			}
+			if got := responseStatus(conn); got != tt.status {
+				t.Fatalf("status = %d, want %d", got, tt.status)
+			}
+		})
+	}
+
+	inner.calls = 0
+	handler = ChainInterceptor(inner, InterceptorAuth(func() string { return "" }))
+	if !handler.Handle(newTestConn(http.MethodPost, "/__wx_channels_api/profile", nil)) || inner.calls != 1 {
+		t.Fatal("expected request to pass when token is not configured")
+	}
+}
+
+func TestInterceptorOrigin(t *testing.T) {
+	inner := &okInterceptor{}
+	handler := ChainInterceptor(inner, InterceptorOrigin(func() []string {
+		return []string{"https://channels.weixin.qq.com"}
+	}))
+
+	conn := newTestConn(http.MethodPost, "/__wx_channels_api/init_upload", map[string]string{"Origin": "https://evil.example"})
+	handler.Handle(conn)
+	if responseStatus(conn) != http.StatusForbidden || inner.calls != 0 {
+		t.Fatalf("expected forbidden origin, got %d (calls=%d)", responseStatus(conn), inner.calls)
+	}
+
+	conn = newTestConn(http.MethodPost, "/__wx_channels_api/init_upload", map[string]string{"Origin": "https://channels.weixin.qq.com"})
+	handler.Handle(conn)
+	if responseStatus(conn) != http.StatusOK {
+		t.Fatalf("expected allowed origin to pass, got %d", responseStatus(conn))
+	}
+
+	conn = newTestConn(http.MethodPost, "/__wx_channels_api/init_upload", nil)
+	handler.Handle(conn)
+	if responseStatus(conn) != http.StatusOK {
+		t.Fatalf("expected request without origin to pass, got %d", responseStatus(conn))
+	}
+
+	conn = newTestConn(http.MethodOptions, "/__wx_channels_api/init_upload", map[string]string{"Origin": "https://channels.weixin.qq.com"})
+	handler.Handle(conn)
+	if responseStatus(conn) != http.StatusNoContent {
+		t.Fatalf("expected preflight 204, got %d", responseStatus(conn))
+	}
+	if got := conn.Response.Header.Get("Access-Control-Allow-Origin"); got != "https://channels.weixin.qq.com" {
+		t.Fatalf("Access-Control-Allow-Origin = %q", got)
+	}
+	if inner.calls != 2 {
+		t.Fatalf("inner calls = %d, want 2", inner.calls)
+	}
+}
+
+func TestInterceptorRecovery(t *testing.T) {
+	handler := ChainInterceptor(InterceptorFunc(func(conn *SunnyNet.HttpConn) bool {
+		panic("boom")
+	}), InterceptorRecovery)
+
+	conn := newTestConn(http.MethodPost, "/__wx_channels_api/profile", nil)
+	if !handler.Handle(conn) {
+		t.Fatal("expected panicking local API request to be handled")
+	}
+	if responseStatus(conn) != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", responseStatus(conn))
+	}
+
+	conn = newTestConn(http.MethodGet, "/other", nil)
+	if handler.Handle(conn) {
+		t.Fatal("expected panicking non-local request to fall through")
+	}
+}
+
+func TestInterceptorRateLimit(t *testing.T) {
+	inner := &okInterceptor{}
+	handler := ChainInterceptor(inner, InterceptorRateLimit(0.001, 2))
+
+	for i := 0; i < 2; i++ {
+		conn := newTestConn(http.MethodGet, "/__wx_channels_api/batch_progress", nil)
+		handler.Handle(conn)
+		if responseStatus(conn) != http.StatusOK {
+			t.Fatalf("request %d: status = %d, want 200", i, responseStatus(conn))
+		}
+	}
+	conn := newTestConn(http.MethodGet, "/__wx_channels_api/batch_progress", nil)
+	handler.Handle(conn)
+	if responseStatus(conn) != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", responseStatus(conn))
+	}
+
+	// 不同接口使用独立的令牌桶
+	conn = newTestConn(http.MethodPost, "/__wx_channels_api/profile", nil)
+	handler.Handle(conn)
+	if responseStatus(conn) != http.StatusOK {
+		t.Fatalf("status = %d, want 200", responseStatus(conn))
+	}
+}
+
+func TestInterceptorMetrics(t *testing.T) {
+	path := "/__wx_channels_api/metrics_test"
+	counter := metrics.LocalAPIRequestsTotal.WithLabelValues(path, "200")
+	before := testutil.ToFloat64(counter)
+
+	handler := ChainInterceptor(&okInterceptor{}, InterceptorMetrics)
+	handler.Handle(newTestConn(http.MethodPost, path, nil))
+
+	if got := testutil.ToFloat64(counter) - before; got != 1 {
+		t.Fatalf("counter delta = %v, want 1", got)
+	}
+}
+
+func TestNewLocalAPIInterceptor(t *testing.T) {
+	cfg := &config.Config{SecretToken: "secret"}
+	skip := InterceptorFunc(func(conn *SunnyNet.HttpConn) bool { return false })
+	inner := &okInterceptor{}
+	handler := NewLocalAPIInterceptor(cfg, skip, inner)
+
+	conn := newTestConn(http.MethodPost, "/__wx_channels_api/save_video", nil)
+	handler.Handle(conn)
+	if responseStatus(conn) != http.StatusUnauthorized || inner.calls != 0 {
+		t.Fatalf("expected unauthorized, got %d (calls=%d)", responseStatus(conn), inner.calls)
+	}
+
+	conn = newTestConn(http.MethodPost, "/__wx_channels_api/save_video", map[string]string{"X-Local-Auth": "secret"})
+	if !handler.Handle(conn) || inner.calls != 1 {
+		t.Fatalf("expected request to reach the second interceptor (calls=%d)", inner.calls)
+	}
+}
@@ -60,16 +60,7 @@ func CORSMiddleware(allowedOrigins []string) func(http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			origin := r.Header.Get("Origin")
 
-			// 检查 origin 是否在允许列表中
-			allowed := false
-			for _, o := range allowedOrigins {
-				if o == "*" || o == origin {
-					allowed = true
-					break
-				}
-			}
-
-			if allowed && origin != "" {
+			if origin != "" && originAllowed(allowedOrigins, origin) {
 				w.Header().Set("Access-Control-Allow-Origin", origin)
 				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
 				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Local-Auth")
@@ -111,18 +102,7 @@ func AuthMiddleware(secretToken string) func(http.Handler) http.Handler {
 				return
 			}
 
-			token := r.Header.Get("X-Local-Auth")
-			if token == "" {
-				auth := r.Header.Get("Authorization")
-				if strings.HasPrefix(strings.ToLower(auth), "bearer ") {
-					token = strings.TrimSpace(auth[len("Bearer "):])
-				}
-			}
-			if token == "" {
-				token = r.URL.Query().Get("token")
-			}
-
-			if token != secretToken {
+			if requestToken(r) != secretToken {
 				response.ErrorWithStatus(w, http.StatusUnauthorized, 401, "unauthorized")
 				return
 			}
@@ -132,6 +112,31 @@ func AuthMiddleware(secretToken string) func(http.Handler) http.Handler {
 	}
 }
 
+// originAllowed 检查 origin 是否在允许列表中，"*" 表示允许所有来源
+func originAllowed(allowedOrigins []string, origin string) bool {
+	for _, o := range allowedOrigins {
+		if o == "*" || o == origin {
+			return true
+		}
+	}
+	return false
+}
+
+// requestToken 依次从 X-Local-Auth、Bearer 和 token 查询参数中读取令牌
+func requestToken(r *http.Request) string {
+	token := r.Header.Get("X-Local-Auth")
+	if token == "" {
+		auth := r.Header.Get("Authorization")
+		if strings.HasPrefix(strings.ToLower(auth), "bearer ") {
+			token = strings.TrimSpace(auth[len("Bearer "):])
+		}
+	}
+	if token == "" {
+		token = r.URL.Query().Get("token")
+	}
+	return token
+}
+
 func isPublicAPIPath(path string) bool {
 	switch path {
 	case "/api/health", "/api/console/verify-token", "/api/system/health", "/api/v1/system/health":
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"wx_channel/internal/database"
+	"wx_channel/internal/utils"
+)
+
+// bandwidthRefreshInterval 按时间表重新计算限速的间隔
+const bandwidthRefreshInterval = 30 * time.Second
+
+// BandwidthLimiter 管理所有下载通道共享的全局限速和每个任务的单独限速
+// 限速值取自 Settings，并按 BandwidthSchedule 的时间段自动切换
+type BandwidthLimiter struct {
+	mu          sync.Mutex
+	settings    database.Settings
+	global      *utils.RateLimiter
+	items       map[*utils.RateLimiter]struct{}
+	itemLimit   int64
+	lastRefresh time.Time
+}
+
+var (
+	bandwidthLimiter     *BandwidthLimiter
+	bandwidthLimiterOnce sync.Once
+)
+
+// GetBandwidthLimiter 返回全局限速器，首次调用时从数据库加载设置
+func GetBandwidthLimiter() *BandwidthLimiter {
+	bandwidthLimiterOnce.Do(func() {
+		bandwidthLimiter = NewBandwidthLimiter()
+		if database.GetDB() != nil {
+			if settings, err := database.NewSettingsRepository().Load(); err == nil {
+				bandwidthLimiter.Apply(settings)
+			}
+		}
+	})
+	return bandwidthLimiter
+}
+
+// NewBandwidthLimiter 创建一个不限速的限速器
+func NewBandwidthLimiter() *BandwidthLimiter {
+	return &BandwidthLimiter{
+		global: utils.NewRateLimiter(0),
+		items:  make(map[*utils.RateLimiter]struct{}),
+	}
+}
+
+// Apply 应用新的限速设置
+func (b *BandwidthLimiter) Apply(settings *database.Settings) {
+	if settings == nil {
+		return
+	}
+	b.mu.Lock()
+	b.settings = *settings
+	b.settings.BandwidthSchedule = append([]database.BandwidthWindow(nil), settings.BandwidthSchedule...)
+	b.refreshLocked(time.Now())
+	b.mu.Unlock()
+}
+
+// Enabled 返回是否配置了任何限速（包括仅在部分时间段生效的限速）
+func (b *BandwidthLimiter) Enabled() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.settings.BandwidthLimit > 0 || b.settings.ItemBandwidthLimit > 0 {
+		return true
+	}
+	for _, window := range b.settings.BandwidthSchedule {
+		if window.Limit > 0 || window.ItemLimit > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Current 返回当前生效的全局和单任务限速（字节/秒）
+func (b *BandwidthLimiter) Current() (global int64, item int64) {
+	b.refresh()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.global.Limit(), b.itemLimit
+}
+
+// NewItemLimiter 为单个下载任务创建限速器，任务结束后需调用 ReleaseItemLimiter
+func (b *BandwidthLimiter) NewItemLimiter() *utils.RateLimiter {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	limiter := utils.NewRateLimiter(b.itemLimit)
+	b.items[limiter] = struct{}{}
+	return limiter
+}
+
+// ReleaseItemLimiter 注销任务限速器
+func (b *BandwidthLimiter) ReleaseItemLimiter(limiter *utils.RateLimiter) {
+	if limiter == nil {
+		return
+	}
+	b.mu.Lock()
+	delete(b.items, limiter)
+	b.mu.Unlock()
+}
+
+// Reader 为响应体套上全局限速和任务限速
+func (b *BandwidthLimiter) Reader(ctx context.Context, reader io.Reader, item *utils.RateLimiter) io.Reader {
+	b.refresh()
+	return utils.NewRateLimitedReader(ctx, reader, b.global, item)
+}
+
+// refresh 距上次计算超过刷新间隔时按时间表重新计算限速
+func (b *BandwidthLimiter) refresh() {
+	now := time.Now()
+	b.mu.Lock()
+	if now.Sub(b.lastRefresh) >= bandwidthRefreshInterval {
+		b.refreshLocked(now)
+	}
+	b.mu.Unlock()
+}
+
+func (b *BandwidthLimiter) refreshLocked(now time.Time) {
+	global, item := b.settings.BandwidthAt(now)
+	b.global.SetLimit(global)
+	b.itemLimit = item
+	for limiter := range b.items {
+		limiter.SetLimit(item)
+	}
+	b.lastRefresh = now
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"encoding/hex"
+	"sync"
+)
+
+// ChunkBitmap 记录每个分片是否已写入磁盘，可编码后持久化到队列表
+// 分片乱序完成时，恢复下载只需跳过已置位的分片
+type ChunkBitmap struct {
+	mu    sync.Mutex
+	bits  []byte
+	total int
+}
+
+// NewChunkBitmap 创建一个包含 total 个分片的空位图
+func NewChunkBitmap(total int) *ChunkBitmap {
+	if total < 0 {
+		total = 0
+	}
+	return &ChunkBitmap{
+		bits:  make([]byte, (total+7)/8),
+		total: total,
+	}
+}
+
+// ParseChunkBitmap 解析持久化的位图，格式或长度不匹配时返回空位图
+func ParseChunkBitmap(encoded string, total int) *ChunkBitmap {
+	bitmap := NewChunkBitmap(total)
+	if encoded == "" {
+		return bitmap
+	}
+	bits, err := hex.DecodeString(encoded)
+	if err != nil || len(bits) != len(bitmap.bits) {
+		return bitmap
+	}
+	bitmap.bits = bits
+	// 清除超出分片总数的多余位
+	if rem := total % 8; rem != 0 {
+		bitmap.bits[len(bitmap.bits)-1] &= byte(1<<rem) - 1
+	}
+	return bitmap
+}
+
+// Total 返回分片总数
+func (b *ChunkBitmap) Total() int {
+	return b.total
+}
+
+// Set 标记分片已完成
+func (b *ChunkBitmap) Set(index int) {
+	if index < 0 || index >= b.total {
+		return
+	}
+	b.mu.Lock()
+	b.bits[index/8] |= 1 << (index % 8)
+	b.mu.Unlock()
+}
+
+// SetRange 标记 [0, n) 范围内的分片已完成，用于兼容仅记录连续完成数的旧数据
+func (b *ChunkBitmap) SetRange(n int) {
+	for i := 0; i < n && i < b.total; i++ {
+		b.Set(i)
+	}
+}
+
+// Has 返回分片是否已完成
+func (b *ChunkBitmap) Has(index int) bool {
+	if index < 0 || index >= b.total {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.bits[index/8]&(1<<(index%8)) != 0
+}
+
+// Count 返回已完成的分片数
+func (b *ChunkBitmap) Count() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	count := 0
+	for i := 0; i < b.total; i++ {
+		if b.bits[i/8]&(1<<(i%8)) != 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// Missing 返回尚未完成的分片索引
+func (b *ChunkBitmap) Missing() []int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	missing := make([]int, 0, b.total)
+	for i := 0; i < b.total; i++ {
+		if b.bits[i/8]&(1<<(i%8)) == 0 {
+			missing = append(missing, i)
+		}
+	}
+	return missing
+}
+
+// Encode 返回位图的十六进制编码
+func (b *ChunkBitmap) Encode() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return hex.EncodeToString(b.bits)
+}
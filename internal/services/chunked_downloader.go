@@ -23,6 +23,7 @@ type ChunkedDownloader struct {
 	downloadDir  string
 
 	mu            sync.RWMutex
+	wg            sync.WaitGroup
 	activeItems   map[string]*DownloadState
 	progressChan  chan ProgressUpdate
 	ctx           context.Context
@@ -34,12 +35,16 @@ type ChunkedDownloader struct {
 // DownloadState 跟踪活动下载的状态
 type DownloadState struct {
 	QueueItem      *database.QueueItem
-	CurrentChunk   int
-	ChunkProgress  int64 // bytes downloaded in current chunk
+	CurrentChunk   int          // 已完成的分片数
+	Bitmap         *ChunkBitmap // 已完成分片位图
+	ChunkProgress  int64        // bytes downloaded in current chunk
 	LastUpdateTime time.Time
 	BytesPerSecond int64
 	IsPaused       bool
 	CancelFunc     context.CancelFunc
+
+	decryptor []byte             // 加密视频的前缀解密数组，分片写入时即时解密
+	limiter   *utils.RateLimiter // 单任务限速，由该任务的所有分片连接共享
 }
 
 // ProgressUpdate 表示下载进度更新
@@ -94,6 +99,12 @@ func (d *ChunkedDownloader) StartDownload(item *database.QueueItem) error {
 		return fmt.Errorf("download already in progress for item: %s", item.ID)
 	}
 
+	// 同步标记为正在下载，避免调度器在 goroutine 启动前重复领取同一项目
+	if err := d.queueService.StartDownload(item.ID); err != nil {
+		return fmt.Errorf("failed to start download: %w", err)
+	}
+	item.Status = database.QueueStatusDownloading
+
 	// 创建下载上下文
 	ctx, cancel := context.WithCancel(d.ctx)
 
@@ -107,7 +118,11 @@ func (d *ChunkedDownloader) StartDownload(item *database.QueueItem) error {
 	d.activeItems[item.ID] = state
 
 	// 在 goroutine 中开始下载
-	go d.downloadItem(ctx, state)
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.downloadItem(ctx, state)
+	}()
 
 	return nil
 }
@@ -116,12 +131,6 @@ func (d *ChunkedDownloader) StartDownload(item *database.QueueItem) error {
 func (d *ChunkedDownloader) downloadItem(ctx context.Context, state *DownloadState) {
 	item := state.QueueItem
 
-	// 标记为正在下载
-	if err := d.queueService.StartDownload(item.ID); err != nil {
-		d.handleError(item.ID, fmt.Errorf("failed to start download: %w", err))
-		return
-	}
-
 	// 准备下载目录
 	downloadPath, err := d.prepareDownloadPath(item)
 	if err != nil {
@@ -129,6 +138,20 @@ func (d *ChunkedDownloader) downloadItem(ctx context.Context, state *DownloadSta
 		return
 	}
 
+	// 加密视频在写入分片时直接解密，磁盘上不会出现半解密的文件
+	if item.DecryptKey != "" {
+		decryptor, err := utils.BuildDecryptorPrefix(item.DecryptKey, "", 0)
+		if err != nil {
+			d.handleError(item.ID, fmt.Errorf("failed to prepare decryptor: %w", err))
+			return
+		}
+		state.decryptor = decryptor
+	}
+
+	bandwidth := GetBandwidthLimiter()
+	state.limiter = bandwidth.NewItemLimiter()
+	defer bandwidth.ReleaseItemLimiter(state.limiter)
+
 	// 下载分片
 	err = d.downloadChunks(ctx, state, downloadPath)
 	if err != nil {
@@ -145,9 +168,17 @@ func (d *ChunkedDownloader) downloadItem(ctx context.Context, state *DownloadSta
 		d.handleError(item.ID, fmt.Errorf("file integrity check failed: %w", err))
 		return
 	}
+	if state.decryptor != nil {
+		if err := utils.ValidateDecryptedFile(downloadPath); err != nil {
+			// 密钥错误时文件不可用，删除后重试会从头下载
+			_ = os.Remove(downloadPath)
+			d.handleError(item.ID, err)
+			return
+		}
+	}
 
 	// 标记为完成
-	if err := d.queueService.CompleteDownload(item.ID); err != nil {
+	if err := d.queueService.CompleteDownloadAt(item.ID, downloadPath); err != nil {
 		d.handleError(item.ID, fmt.Errorf("failed to mark download as completed: %w", err))
 		return
 	}
@@ -169,12 +200,18 @@ func (d *ChunkedDownloader) downloadItem(ctx context.Context, state *DownloadSta
 	d.mu.Unlock()
 }
 
-// downloadChunks 下载项目的所有分片（并发下载）
+// downloadChunks 使用多个 Range 连接并发下载项目尚未完成的分片
+// 每完成一个分片即持久化位图，恢复时只下载缺失的分片
 func (d *ChunkedDownloader) downloadChunks(ctx context.Context, state *DownloadState, downloadPath string) error {
 	item := state.QueueItem
-	chunkSize := item.ChunkSize
 	totalChunks := item.ChunksTotal
 
+	bitmap := d.loadChunkBitmap(item, downloadPath)
+	d.mu.Lock()
+	state.Bitmap = bitmap
+	state.CurrentChunk = bitmap.Count()
+	d.mu.Unlock()
+
 	// 打开或创建文件
 	file, err := os.OpenFile(downloadPath, os.O_CREATE|os.O_RDWR, 0644)
 	if err != nil {
@@ -187,22 +224,22 @@ func (d *ChunkedDownloader) downloadChunks(ctx context.Context, state *DownloadS
 		return fmt.Errorf("failed to truncate file: %w", err)
 	}
 
-	startChunk := state.CurrentChunk
-	downloadedSize := int64(startChunk) * chunkSize
+	missing := bitmap.Missing()
+	downloadedSize := completedChunkBytes(item, bitmap)
 
-	// 获取最大并发数
-	concurrentLimit := d.maxConcurrent
-	if concurrentLimit <= 0 {
-		concurrentLimit = 3 // 默认并发 3
+	// 单文件连接数
+	connections := d.connectionsPerItem()
+	if connections > len(missing) {
+		connections = len(missing)
 	}
 
 	var wg sync.WaitGroup
-	errChan := make(chan error, totalChunks)
-	chunkChan := make(chan int, totalChunks)
+	errChan := make(chan error, len(missing))
+	chunkChan := make(chan int, len(missing))
 
 	// 填充任务通道
-	for i := startChunk; i < totalChunks; i++ {
-		chunkChan <- i
+	for _, chunkIndex := range missing {
+		chunkChan <- chunkIndex
 	}
 	close(chunkChan)
 
@@ -211,7 +248,7 @@ func (d *ChunkedDownloader) downloadChunks(ctx context.Context, state *DownloadS
 	lastDownloadedSize := downloadedSize
 
 	// 启动 worker
-	for i := 0; i < concurrentLimit; i++ {
+	for i := 0; i < connections; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
@@ -232,53 +269,47 @@ func (d *ChunkedDownloader) downloadChunks(ctx context.Context, state *DownloadS
 				}
 
 				// 计算分片范围
-				chunkStart := int64(chunkIndex) * chunkSize
-				chunkEnd := chunkStart + chunkSize - 1
-				if chunkEnd >= item.TotalSize {
-					chunkEnd = item.TotalSize - 1
-				}
+				chunkStart, chunkEnd := chunkRange(item, chunkIndex)
 
 				// 带重试下载并写入分片
-				written, err := d.downloadAndWriteChunkWithRetry(ctx, item.VideoURL, chunkStart, chunkEnd, file)
+				written, err := d.downloadAndWriteChunkWithRetry(ctx, state, chunkStart, chunkEnd, file)
 				if err != nil {
 					errChan <- fmt.Errorf("failed to download chunk %d: %w", chunkIndex, err)
 					return
 				}
 
-				// 更新状态
+				// 更新状态并持久化位图；持锁写库保证位图按完成顺序落盘
 				mu.Lock()
+				bitmap.Set(chunkIndex)
 				downloadedSize += written
-				// 这里简单地增加 CurrentChunk，实际上多线程下 Completed 计数才是准确的
-				// 但为了兼容，保持每次加 1
-				state.CurrentChunk++
+				completed := bitmap.Count()
 
 				// 计算速度
 				now := time.Now()
 				elapsed := now.Sub(lastSpeedCalcTime).Seconds()
+				d.mu.Lock()
+				state.CurrentChunk = completed
 				if elapsed >= 1.0 {
 					bytesDownloaded := downloadedSize - lastDownloadedSize
 					state.BytesPerSecond = int64(float64(bytesDownloaded) / elapsed)
 					lastSpeedCalcTime = now
 					lastDownloadedSize = downloadedSize
 				}
-
-				// 复制状态用于后续使用
-				currChunk := state.CurrentChunk
 				bytesPerSec := state.BytesPerSecond
+				d.mu.Unlock()
 
-				mu.Unlock()
-
-				// 更新数据库中的进度（只做一次 DB 调用）
-				if err := d.queueService.UpdateProgress(item.ID, downloadedSize, currChunk, bytesPerSec); err != nil {
+				if err := d.queueService.UpdateChunkProgress(item.ID, downloadedSize, completed, bitmap.Encode(), bytesPerSec); err != nil {
 					utils.Warn("[ChunkedDownloader] Failed to update progress: %v", err)
 				}
+				currSize := downloadedSize
+				mu.Unlock()
 
 				// 发送进度更新
 				d.sendProgress(ProgressUpdate{
 					QueueID:         item.ID,
-					DownloadedSize:  downloadedSize,
+					DownloadedSize:  currSize,
 					TotalSize:       item.TotalSize,
-					ChunksCompleted: currChunk,
+					ChunksCompleted: completed,
 					ChunksTotal:     totalChunks,
 					Speed:           bytesPerSec,
 					Status:          database.QueueStatusDownloading,
@@ -302,11 +333,68 @@ func (d *ChunkedDownloader) downloadChunks(ctx context.Context, state *DownloadS
 		return ctx.Err()
 	}
 
+	if completed := bitmap.Count(); completed < totalChunks {
+		return fmt.Errorf("incomplete download: %d/%d chunks", completed, totalChunks)
+	}
+
 	return nil
 }
 
+// loadChunkBitmap 读取持久化的分片位图
+// 磁盘文件缺失或大小不符时已记录的分片不可信，从头下载
+func (d *ChunkedDownloader) loadChunkBitmap(item *database.QueueItem, downloadPath string) *ChunkBitmap {
+	bitmap := ParseChunkBitmap(item.ChunksBitmap, item.ChunksTotal)
+	if item.ChunksBitmap == "" && item.ChunksCompleted > 0 {
+		// 兼容旧版本仅记录连续完成数的数据
+		bitmap.SetRange(item.ChunksCompleted)
+	}
+	if bitmap.Count() == 0 {
+		return bitmap
+	}
+
+	info, err := os.Stat(downloadPath)
+	if err != nil || info.Size() != item.TotalSize {
+		utils.Warn("[ChunkedDownloader] Partial file missing or resized, restarting: %s", downloadPath)
+		return NewChunkBitmap(item.ChunksTotal)
+	}
+	return bitmap
+}
+
+// connectionsPerItem 返回单个文件的并发连接数
+func (d *ChunkedDownloader) connectionsPerItem() int {
+	if cfg := config.Get(); cfg != nil && cfg.DownloadConnections > 0 {
+		return cfg.DownloadConnections
+	}
+	if d.maxConcurrent > 0 {
+		return d.maxConcurrent
+	}
+	return 3 // 默认并发 3
+}
+
+// chunkRange 返回分片的字节范围（闭区间）
+func chunkRange(item *database.QueueItem, chunkIndex int) (int64, int64) {
+	start := int64(chunkIndex) * item.ChunkSize
+	end := start + item.ChunkSize - 1
+	if end >= item.TotalSize {
+		end = item.TotalSize - 1
+	}
+	return start, end
+}
+
+// completedChunkBytes 计算位图中已完成分片的总字节数
+func completedChunkBytes(item *database.QueueItem, bitmap *ChunkBitmap) int64 {
+	var size int64
+	for i := 0; i < bitmap.Total(); i++ {
+		if bitmap.Has(i) {
+			start, end := chunkRange(item, i)
+			size += end - start + 1
+		}
+	}
+	return size
+}
+
 // downloadAndWriteChunkWithRetry 带重试逻辑下载并写入单个分片
-func (d *ChunkedDownloader) downloadAndWriteChunkWithRetry(ctx context.Context, url string, start, end int64, file *os.File) (int64, error) {
+func (d *ChunkedDownloader) downloadAndWriteChunkWithRetry(ctx context.Context, state *DownloadState, start, end int64, file *os.File) (int64, error) {
 	var lastErr error
 
 	for attempt := 0; attempt <= d.maxRetries; attempt++ {
@@ -326,7 +414,7 @@ func (d *ChunkedDownloader) downloadAndWriteChunkWithRetry(ctx context.Context,
 			}
 		}
 
-		written, err := d.downloadAndWriteChunk(ctx, url, start, end, file)
+		written, err := d.downloadAndWriteChunk(ctx, state, start, end, file)
 		if err == nil {
 			return written, nil
 		}
@@ -339,8 +427,9 @@ func (d *ChunkedDownloader) downloadAndWriteChunkWithRetry(ctx context.Context,
 }
 
 // downloadAndWriteChunk 使用 HTTP Range 请求下载单个分片并直接写入文件缓冲
-func (d *ChunkedDownloader) downloadAndWriteChunk(ctx context.Context, url string, start, end int64, file *os.File) (int64, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+// 读取时受全局和任务限速约束，加密视频按分片偏移即时解密加密前缀
+func (d *ChunkedDownloader) downloadAndWriteChunk(ctx context.Context, state *DownloadState, start, end int64, file *os.File) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, state.QueueItem.VideoURL, nil)
 	if err != nil {
 		return 0, fmt.Errorf("failed to create request: %w", err)
 	}
@@ -358,15 +447,28 @@ func (d *ChunkedDownloader) downloadAndWriteChunk(ctx context.Context, url strin
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
 		return 0, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
+	// 服务器忽略 Range 时返回完整内容，只有首个分片可以直接使用
+	if resp.StatusCode == http.StatusOK && start > 0 {
+		return 0, fmt.Errorf("server does not support range requests")
+	}
 
 	// 使用 io.Copy 代替 io.ReadAll，避免内存暴涨
 	// 创建一个专门用于此分片写入的 SectionWriter
+	expected := end - start + 1
 	writer := io.NewOffsetWriter(file, start)
 
-	written, err := io.Copy(writer, resp.Body)
+	body := GetBandwidthLimiter().Reader(ctx, io.LimitReader(resp.Body, expected), state.limiter)
+	if decryptor := state.decryptor; decryptor != nil && start < int64(len(decryptor)) {
+		body = utils.NewPrefixDecryptReader(body, decryptor, uint64(start))
+	}
+
+	written, err := io.Copy(writer, body)
 	if err != nil {
 		return written, fmt.Errorf("failed to write response to file: %w", err)
 	}
+	if written != expected {
+		return written, fmt.Errorf("short chunk: expected %d bytes, got %d", expected, written)
+	}
 
 	return written, nil
 }
@@ -434,47 +536,15 @@ func (d *ChunkedDownloader) downloadChunk(ctx context.Context, url string, start
 }
 
 // prepareDownloadPath 准备项目的下载路径
+// 与 CompleteDownload 写入下载记录的路径保持一致
 func (d *ChunkedDownloader) prepareDownloadPath(item *database.QueueItem) (string, error) {
-	baseDir, err := utils.GetBaseDir()
-	if err != nil {
-		return "", err
-	}
-
-	// 创建作者文件夹
-	authorFolder := utils.CleanFolderName(item.Author)
-	downloadDir := filepath.Join(baseDir, d.downloadDir, authorFolder)
+	downloadPath := calculateDownloadFilePath(item)
 
-	if err := utils.EnsureDir(downloadDir); err != nil {
+	if err := utils.EnsureDir(filepath.Dir(downloadPath)); err != nil {
 		return "", fmt.Errorf("failed to create download directory: %w", err)
 	}
 
-	settings := database.DefaultSettings()
-	if d.settings != nil && d.settings.GetDBUnsafe() != nil {
-		loaded, err := d.settings.Load()
-		if err == nil && loaded != nil {
-			settings = loaded
-		}
-	}
-	includeVideoID := true
-	if settings != nil {
-		includeVideoID = settings.DownloadFilenameWithVideoID
-	}
-	template := ""
-	if cfg := config.Get(); cfg != nil {
-		template = cfg.DownloadFilenameTemplate
-	}
-
-	filename := utils.BuildVideoFilename(utils.VideoFilenameMeta{
-		Title:      item.Title,
-		VideoID:    item.VideoID,
-		Author:     item.Author,
-		Duration:   time.Duration(item.Duration) * time.Millisecond,
-		CreateTime: item.AddedTime,
-		SizeBytes:  item.TotalSize,
-	}, includeVideoID, template)
-	filename = utils.EnsureExtension(filename, ".mp4")
-
-	return filepath.Join(downloadDir, filename), nil
+	return downloadPath, nil
 }
 
 // verifyFileIntegrity 验证下载的文件大小是否与预期大小匹配
@@ -607,18 +677,18 @@ func (d *ChunkedDownloader) GetDownloadState(itemID string) (*DownloadState, boo
 	return state, exists
 }
 
-// Stop 停止下载器并取消所有活动下载
+// Stop 停止下载器并取消所有活动下载，等待下载 goroutine 退出后关闭进度通道
 func (d *ChunkedDownloader) Stop() {
 	d.cancel()
 
 	d.mu.Lock()
-	defer d.mu.Unlock()
-
 	for _, state := range d.activeItems {
 		state.CancelFunc()
 	}
 	d.activeItems = make(map[string]*DownloadState)
+	d.mu.Unlock()
 
+	d.wg.Wait()
 	close(d.progressChan)
 }
 
@@ -637,6 +707,7 @@ type ResumeInfo struct {
 	TotalSize       int64  `json:"totalSize"`
 	ChunkSize       int64  `json:"chunkSize"`
 	ResumePosition  int64  `json:"resumePosition"`
+	ChunksBitmap    string `json:"chunksBitmap,omitempty"` // 分片乱序完成时以位图为准
 }
 
 // GetResumeInfo 获取暂停下载的恢复信息
@@ -664,6 +735,7 @@ func (d *ChunkedDownloader) GetResumeInfo(itemID string) (*ResumeInfo, error) {
 		TotalSize:       item.TotalSize,
 		ChunkSize:       item.ChunkSize,
 		ResumePosition:  resumePosition,
+		ChunksBitmap:    item.ChunksBitmap,
 	}, nil
 }
 
@@ -690,22 +762,28 @@ func (d *ChunkedDownloader) CanResume(itemID string) (bool, error) {
 func (d *ChunkedDownloader) SaveProgress(itemID string) error {
 	d.mu.RLock()
 	state, exists := d.activeItems[itemID]
+	var bitmap *ChunkBitmap
+	var speed int64
+	if exists {
+		bitmap = state.Bitmap
+		speed = state.BytesPerSecond
+	}
 	d.mu.RUnlock()
 
 	if !exists {
 		return fmt.Errorf("no active download for item: %s", itemID)
 	}
-
-	downloadedSize := int64(state.CurrentChunk) * state.QueueItem.ChunkSize
-	if state.ChunkProgress > 0 {
-		downloadedSize += state.ChunkProgress
+	if bitmap == nil {
+		// 尚未开始写入分片
+		return nil
 	}
 
-	return d.queueService.UpdateProgress(
+	return d.queueService.UpdateChunkProgress(
 		itemID,
-		downloadedSize,
-		state.CurrentChunk,
-		state.BytesPerSecond,
+		completedChunkBytes(state.QueueItem, bitmap),
+		bitmap.Count(),
+		bitmap.Encode(),
+		speed,
 	)
 }
 
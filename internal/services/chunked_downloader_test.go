@@ -0,0 +1,133 @@
+package services
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"wx_channel/internal/database"
+)
+
+func TestChunkedDownloader_ResumesOnlyMissingChunks(t *testing.T) {
+	setupQueueWorkerTest(t)
+
+	const chunkSize = 1024
+	payload := make([]byte, chunkSize*4-100)
+	for i := range payload {
+		payload[i] = byte(i % 251)
+	}
+
+	var mu sync.Mutex
+	ranges := make([]string, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		mu.Unlock()
+		http.ServeContent(w, r, "video.mp4", time.Now(), bytes.NewReader(payload))
+	}))
+	defer server.Close()
+
+	// 模拟崩溃前已乱序完成第 0 和第 2 个分片
+	bitmap := NewChunkBitmap(4)
+	bitmap.Set(0)
+	bitmap.Set(2)
+	item := &database.QueueItem{
+		ID:              uuid.New().String(),
+		VideoID:         "resume1",
+		Title:           "resume",
+		Author:          "作者",
+		VideoURL:        server.URL,
+		TotalSize:       int64(len(payload)),
+		Status:          database.QueueStatusPending,
+		AddedTime:       time.Now(),
+		ChunkSize:       chunkSize,
+		ChunksTotal:     4,
+		ChunksCompleted: 2,
+	}
+	repo := database.NewQueueRepository()
+	if err := repo.Add(item); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := repo.UpdateChunkProgress(item.ID, 2*chunkSize, 2, bitmap.Encode(), 0); err != nil {
+		t.Fatalf("UpdateChunkProgress: %v", err)
+	}
+
+	path := calculateDownloadFilePath(item)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	partial := make([]byte, len(payload))
+	copy(partial[0:chunkSize], payload[0:chunkSize])
+	copy(partial[2*chunkSize:3*chunkSize], payload[2*chunkSize:3*chunkSize])
+	if err := os.WriteFile(path, partial, 0644); err != nil {
+		t.Fatalf("write partial: %v", err)
+	}
+
+	queueService := NewQueueService()
+	downloader := NewChunkedDownloader(queueService)
+	defer downloader.Stop()
+
+	loaded, _ := queueService.GetByID(item.ID)
+	if err := downloader.StartDownload(loaded); err != nil {
+		t.Fatalf("StartDownload: %v", err)
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		current, _ := queueService.GetByID(item.ID)
+		if current.Status == database.QueueStatusCompleted {
+			break
+		}
+		if current.Status == database.QueueStatusFailed {
+			t.Fatalf("download failed: %s", current.ErrorMessage)
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("status = %s, want completed", current.Status)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if !bytes.Equal(data, payload) {
+		t.Fatalf("content mismatch after resume")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := map[string]bool{"bytes=1024-2047": true, "bytes=3072-3995": true}
+	if len(ranges) != len(want) {
+		t.Fatalf("ranges = %v, want only missing chunks", ranges)
+	}
+	for _, r := range ranges {
+		if !want[r] {
+			t.Fatalf("unexpected range request %q", r)
+		}
+	}
+}
+
+func TestParseChunkBitmap_RejectsMismatchedLength(t *testing.T) {
+	bitmap := NewChunkBitmap(10)
+	bitmap.Set(9)
+	bitmap.Set(3)
+
+	parsed := ParseChunkBitmap(bitmap.Encode(), 10)
+	if !parsed.Has(9) || !parsed.Has(3) || parsed.Count() != 2 {
+		t.Fatalf("round trip failed: %s", parsed.Encode())
+	}
+	if got := ParseChunkBitmap(bitmap.Encode(), 20).Count(); got != 0 {
+		t.Fatalf("mismatched length should reset, got %d", got)
+	}
+	if got := ParseChunkBitmap("zz", 10).Count(); got != 0 {
+		t.Fatalf("invalid encoding should reset, got %d", got)
+	}
+}
@@ -3,19 +3,24 @@ package services
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"wx_channel/internal/database"
+	"wx_channel/internal/utils"
 )
 
 // CleanupResult 包含清理操作的结果
 type CleanupResult struct {
-	BrowseRecordsDeleted   int64     `json:"browseRecordsDeleted"`
-	DownloadRecordsDeleted int64     `json:"downloadRecordsDeleted"`
-	FilesDeleted           int64     `json:"filesDeleted"`
-	SpaceFreed             int64     `json:"spaceFreed"`
-	CleanupTime            time.Time `json:"cleanupTime"`
-	Errors                 []string  `json:"errors,omitempty"`
+	BrowseRecordsDeleted       int64     `json:"browseRecordsDeleted"`
+	DownloadRecordsDeleted     int64     `json:"downloadRecordsDeleted"`
+	FilesDeleted               int64     `json:"filesDeleted"`
+	SpaceFreed                 int64     `json:"spaceFreed"`
+	EngagementSnapshotsDeleted int64     `json:"engagementSnapshotsDeleted,omitempty"`
+	CleanupTime                time.Time `json:"cleanupTime"`
+	Errors                     []string  `json:"errors,omitempty"`
 }
 
 // CleanupService 处理数据清理操作
@@ -24,6 +29,8 @@ type CleanupService struct {
 	browseRepo   *database.BrowseHistoryRepository
 	downloadRepo *database.DownloadRecordRepository
 	settingsRepo *database.SettingsRepository
+	pinRepo      *database.DownloadPinRepository
+	engagement   *database.EngagementRepository
 }
 
 // NewCleanupService 创建一个新的 CleanupService
@@ -32,6 +39,8 @@ func NewCleanupService() *CleanupService {
 		browseRepo:   database.NewBrowseHistoryRepository(),
 		downloadRepo: database.NewDownloadRecordRepository(),
 		settingsRepo: database.NewSettingsRepository(),
+		pinRepo:      database.NewDownloadPinRepository(),
+		engagement:   database.NewEngagementRepository(),
 	}
 }
 
@@ -55,6 +64,26 @@ func (s *CleanupService) ClearBrowseHistory() (*CleanupResult, error) {
 	}, nil
 }
 
+// removeDownloadFile 删除下载文件并累计结果，文件还有其他硬链接时不计入释放的空间
+func removeDownloadFile(path string, result *CleanupResult) {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	links, err := utils.HardlinkCount(path)
+	if err != nil {
+		links = 1
+	}
+	if err := os.Remove(path); err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("failed to delete file %s: %v", path, err))
+		return
+	}
+	result.FilesDeleted++
+	if links <= 1 {
+		result.SpaceFreed += fileInfo.Size()
+	}
+}
+
 // ClearDownloadRecords 清空所有下载记录（可选删除文件）
 // Requirements: 5.3 - 清空下载记录（可选择删除文件）
 func (s *CleanupService) ClearDownloadRecords(deleteFiles bool) (*CleanupResult, error) {
@@ -73,15 +102,7 @@ func (s *CleanupService) ClearDownloadRecords(deleteFiles bool) (*CleanupResult,
 	if deleteFiles {
 		for _, record := range records {
 			if record.FilePath != "" {
-				fileInfo, err := os.Stat(record.FilePath)
-				if err == nil {
-					result.SpaceFreed += fileInfo.Size()
-					if err := os.Remove(record.FilePath); err != nil {
-						result.Errors = append(result.Errors, fmt.Sprintf("failed to delete file %s: %v", record.FilePath, err))
-					} else {
-						result.FilesDeleted++
-					}
-				}
+				removeDownloadFile(record.FilePath, result)
 			}
 		}
 	}
@@ -127,15 +148,7 @@ func (s *CleanupService) DeleteDownloadRecordsBefore(date time.Time, deleteFiles
 
 		for _, record := range records {
 			if record.DownloadTime.Before(date) && record.FilePath != "" {
-				fileInfo, err := os.Stat(record.FilePath)
-				if err == nil {
-					result.SpaceFreed += fileInfo.Size()
-					if err := os.Remove(record.FilePath); err != nil {
-						result.Errors = append(result.Errors, fmt.Sprintf("failed to delete file %s: %v", record.FilePath, err))
-					} else {
-						result.FilesDeleted++
-					}
-				}
+				removeDownloadFile(record.FilePath, result)
 			}
 		}
 	}
@@ -151,34 +164,273 @@ func (s *CleanupService) DeleteDownloadRecordsBefore(date time.Time, deleteFiles
 }
 
 // RunAutoCleanup 根据设置运行自动清理
+// 启用自动清理时删除旧的浏览记录；启用保留策略时按策略删除下载目录中的视频；
+// 超过保留天数的互动数据快照始终删除
 // Requirements: 11.5 - 基于设置的自动清理
-func (s *CleanupService) RunAutoCleanup() (*CleanupResult, error) {
+func (s *CleanupService) RunAutoCleanup(downloadsDir string) (*CleanupResult, error) {
 	// 加载设置
 	settings, err := s.settingsRepo.Load()
 	if err != nil {
 		return nil, fmt.Errorf("failed to load settings: %w", err)
 	}
+	policy, err := s.settingsRepo.LoadRetentionPolicy()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load retention policy: %w", err)
+	}
 
-	// 检查自动清理是否启用
-	if !settings.AutoCleanupEnabled {
-		return &CleanupResult{
-			CleanupTime: time.Now(),
-		}, nil
+	result := &CleanupResult{
+		CleanupTime: time.Now(),
 	}
 
-	// 计算截止日期
-	cutoffDate := time.Now().AddDate(0, 0, -settings.AutoCleanupDays)
+	if settings.AutoCleanupEnabled {
+		// 计算截止日期
+		cutoffDate := time.Now().AddDate(0, 0, -settings.AutoCleanupDays)
 
-	// 删除旧的浏览记录
-	browseResult, err := s.DeleteBrowseRecordsBefore(cutoffDate)
+		// 删除旧的浏览记录
+		browseResult, err := s.DeleteBrowseRecordsBefore(cutoffDate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to cleanup browse records: %w", err)
+		}
+		result.BrowseRecordsDeleted = browseResult.BrowseRecordsDeleted
+	}
+
+	engagementCutoff := time.Now().AddDate(0, 0, -settings.EngagementRetention())
+	engagementDeleted, err := s.engagement.DeleteBefore(engagementCutoff)
 	if err != nil {
-		return nil, fmt.Errorf("failed to cleanup browse records: %w", err)
+		return nil, fmt.Errorf("failed to cleanup engagement snapshots: %w", err)
 	}
+	result.EngagementSnapshotsDeleted = engagementDeleted
 
-	return &CleanupResult{
-		BrowseRecordsDeleted: browseResult.BrowseRecordsDeleted,
-		CleanupTime:          time.Now(),
-	}, nil
+	if policy.Enabled && downloadsDir != "" {
+		report, err := s.ApplyRetention(downloadsDir, policy, false)
+		if err != nil {
+			return nil, err
+		}
+		result.DownloadRecordsDeleted = report.RecordsDeleted
+		result.FilesDeleted = report.FilesDeleted
+		result.SpaceFreed = report.SpaceFreed
+		result.Errors = report.Errors
+	}
+
+	return result, nil
+}
+
+// 保留策略选中视频的原因
+const (
+	RetentionReasonKeepLast     = "keep_last"
+	RetentionReasonMaxAge       = "max_age"
+	RetentionReasonMaxTotalSize = "max_total_size"
+)
+
+// RetentionCandidate 保留策略选中删除的视频
+type RetentionCandidate struct {
+	RecordID     string    `json:"recordId"`
+	VideoID      string    `json:"videoId"`
+	Title        string    `json:"title"`
+	Author       string    `json:"author"` // 作者文件夹名
+	FilePath     string    `json:"filePath"`
+	FileSize     int64     `json:"fileSize"`
+	DownloadTime time.Time `json:"downloadTime"`
+	Reason       string    `json:"reason"`
+}
+
+// RetentionReport 保留策略的评估和执行结果
+type RetentionReport struct {
+	DryRun         bool                     `json:"dryRun"`
+	Policy         database.RetentionPolicy `json:"policy"`
+	ScannedFiles   int                      `json:"scannedFiles"`
+	TotalBytes     int64                    `json:"totalBytes"`
+	RemainingBytes int64                    `json:"remainingBytes"`
+	PinnedKept     int                      `json:"pinnedKept"` // 符合删除条件但已置顶而保留的视频数
+	Candidates     []RetentionCandidate     `json:"candidates"`
+	RecordsDeleted int64                    `json:"recordsDeleted"`
+	FilesDeleted   int64                    `json:"filesDeleted"`
+	SpaceFreed     int64                    `json:"spaceFreed"`
+	Errors         []string                 `json:"errors,omitempty"`
+	EvaluatedAt    time.Time                `json:"evaluatedAt"`
+}
+
+// retentionFile 参与保留策略评估的视频
+type retentionFile struct {
+	record database.DownloadRecord
+	author string
+	size   int64
+	inode  int // 指向同一文件（硬链接）的视频相同，大小只计算一次
+}
+
+// ApplyRetention 按保留策略选出要删除的视频，dryRun 为 false 时删除文件和记录
+// 只处理下载目录中仍然存在的已完成下载，置顶的记录永不删除
+func (s *CleanupService) ApplyRetention(downloadsDir string, policy *database.RetentionPolicy, dryRun bool) (*RetentionReport, error) {
+	records, err := s.downloadRepo.ListLibraryRecords()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get download records: %w", err)
+	}
+	pinned, err := s.pinRepo.IDs()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []retentionFile
+	var infos []os.FileInfo
+	for _, record := range records {
+		if record.Status != database.DownloadStatusCompleted {
+			continue
+		}
+		rel, err := filepath.Rel(downloadsDir, record.FilePath)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		info, err := os.Stat(record.FilePath)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		files = append(files, retentionFile{record: record, author: authorFolder(downloadsDir, record.FilePath), size: info.Size()})
+		infos = append(infos, info)
+	}
+	groupHardlinks(files, infos)
+
+	report := planRetention(policy, files, pinned, time.Now())
+	report.DryRun = dryRun
+	if dryRun || len(report.Candidates) == 0 {
+		return report, nil
+	}
+
+	ids := make([]string, 0, len(report.Candidates))
+	for _, candidate := range report.Candidates {
+		ids = append(ids, candidate.RecordID)
+	}
+	result, err := s.DeleteSelectedDownloadRecords(ids, true)
+	if err != nil {
+		return nil, err
+	}
+	report.RecordsDeleted = result.DownloadRecordsDeleted
+	report.FilesDeleted = result.FilesDeleted
+	report.SpaceFreed = result.SpaceFreed
+	report.Errors = result.Errors
+	return report, nil
+}
+
+// groupHardlinks 为指向同一文件的视频分配相同的 inode 编号
+// 只比较大小相同的文件
+func groupHardlinks(files []retentionFile, infos []os.FileInfo) {
+	bySize := make(map[int64][]int)
+	for i := range files {
+		files[i].inode = i
+		for _, j := range bySize[files[i].size] {
+			if os.SameFile(infos[i], infos[j]) {
+				files[i].inode = files[j].inode
+				break
+			}
+		}
+		bySize[files[i].size] = append(bySize[files[i].size], i)
+	}
+}
+
+// planRetention 依次按每个作者保留最新 N 个、按作者的最长保留天数、按总大小上限选出要删除的视频
+// 硬链接的视频只占用一份空间，所有链接都被删除时才计入释放的空间
+func planRetention(policy *database.RetentionPolicy, files []retentionFile, pinned map[string]bool, now time.Time) *RetentionReport {
+	report := &RetentionReport{
+		Policy:       *policy,
+		ScannedFiles: len(files),
+		Candidates:   []RetentionCandidate{},
+		EvaluatedAt:  now,
+	}
+
+	// 从新到旧排列
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].record.DownloadTime.After(files[j].record.DownloadTime)
+	})
+
+	links := make(map[int][]int) // inode -> files 下标
+	for i, f := range files {
+		if len(links[f.inode]) == 0 {
+			report.TotalBytes += f.size
+		}
+		links[f.inode] = append(links[f.inode], i)
+	}
+	report.RemainingBytes = report.TotalBytes
+
+	selected := make(map[string]string)
+	pinnedKept := make(map[string]bool)
+	remainingLinks := make(map[int]int)
+	for inode, idx := range links {
+		remainingLinks[inode] = len(idx)
+	}
+	selectFile := func(f retentionFile, reason string) {
+		selected[f.record.ID] = reason
+		remainingLinks[f.inode]--
+		if remainingLinks[f.inode] == 0 {
+			report.RemainingBytes -= f.size
+		}
+	}
+
+	perAuthor := make(map[string]int)
+	for _, f := range files {
+		perAuthor[f.author]++
+
+		reason := ""
+		if policy.KeepLastPerAuthor > 0 && perAuthor[f.author] > policy.KeepLastPerAuthor {
+			reason = RetentionReasonKeepLast
+		} else if days := policy.MaxAgeFor(f.author); days > 0 && now.Sub(f.record.DownloadTime) > time.Duration(days)*24*time.Hour {
+			reason = RetentionReasonMaxAge
+		}
+		if reason == "" {
+			continue
+		}
+		if pinned[f.record.ID] {
+			pinnedKept[f.record.ID] = true
+			continue
+		}
+		selectFile(f, reason)
+	}
+
+	// 超出总大小上限时从最早的下载开始删除，同一文件的所有链接一起删除
+	if policy.MaxTotalBytes > 0 {
+		for i := len(files) - 1; i >= 0 && report.RemainingBytes > policy.MaxTotalBytes; i-- {
+			f := files[i]
+			if selected[f.record.ID] != "" {
+				continue
+			}
+			group := links[f.inode]
+			keep := false
+			for _, j := range group {
+				if pinned[files[j].record.ID] {
+					pinnedKept[files[j].record.ID] = true
+					keep = true
+				}
+			}
+			if keep {
+				continue
+			}
+			for _, j := range group {
+				if selected[files[j].record.ID] == "" {
+					selectFile(files[j], RetentionReasonMaxTotalSize)
+				}
+			}
+		}
+	}
+
+	report.PinnedKept = len(pinnedKept)
+
+	// 从旧到新列出
+	for i := len(files) - 1; i >= 0; i-- {
+		f := files[i]
+		reason := selected[f.record.ID]
+		if reason == "" {
+			continue
+		}
+		report.Candidates = append(report.Candidates, RetentionCandidate{
+			RecordID:     f.record.ID,
+			VideoID:      f.record.VideoID,
+			Title:        f.record.Title,
+			Author:       f.author,
+			FilePath:     f.record.FilePath,
+			FileSize:     f.size,
+			DownloadTime: f.record.DownloadTime,
+			Reason:       reason,
+		})
+	}
+	return report
 }
 
 // DeleteSelectedBrowseRecords 按 ID 删除特定的浏览记录
@@ -212,15 +464,7 @@ func (s *CleanupService) DeleteSelectedDownloadRecords(ids []string, deleteFiles
 
 		for _, record := range records {
 			if record.FilePath != "" {
-				fileInfo, err := os.Stat(record.FilePath)
-				if err == nil {
-					result.SpaceFreed += fileInfo.Size()
-					if err := os.Remove(record.FilePath); err != nil {
-						result.Errors = append(result.Errors, fmt.Sprintf("failed to delete file %s: %v", record.FilePath, err))
-					} else {
-						result.FilesDeleted++
-					}
-				}
+				removeDownloadFile(record.FilePath, result)
 			}
 		}
 	}
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"wx_channel/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// DecryptProxy 是仅监听本机回环地址的解密代理
+// Gopeed 的多连接 Range 请求经代理转发到上游，响应按各自偏移即时解密后再写入磁盘，
+// 分片乱序到达也能正确解密，下载完成后无需再对文件做解密处理
+// Gopeed 本身不支持限速，配置了带宽限制时普通任务也经代理转发以套用 BandwidthLimiter
+type DecryptProxy struct {
+	mu       sync.RWMutex
+	client   *http.Client
+	listener net.Listener
+	server   *http.Server
+	routes   map[string]*decryptRoute
+}
+
+type decryptRoute struct {
+	url       string
+	headers   map[string]string
+	decryptor []byte
+	limiter   *utils.RateLimiter // 任务限速，同一任务的所有连接共享
+}
+
+// NewDecryptProxy 创建一个解密代理，首次注册时才开始监听
+func NewDecryptProxy() *DecryptProxy {
+	return &DecryptProxy{
+		client: &http.Client{
+			Transport: &http.Transport{
+				Proxy:              http.ProxyFromEnvironment,
+				DisableCompression: true, // 压缩会破坏 Range 偏移
+			},
+		},
+		routes: make(map[string]*decryptRoute),
+	}
+}
+
+// Register 注册一个上游地址，返回供下载器使用的本地地址
+// decryptor 为空时只转发不解密，limiter 为空时只受全局限速约束
+func (p *DecryptProxy) Register(upstreamURL string, headers map[string]string, decryptor []byte, limiter *utils.RateLimiter) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.listener == nil {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return "", fmt.Errorf("failed to start decrypt proxy: %w", err)
+		}
+		server := &http.Server{Handler: p}
+		p.listener = listener
+		p.server = server
+		go func() {
+			if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+				utils.Warn("[DecryptProxy] 服务异常退出: %v", err)
+			}
+		}()
+	}
+
+	token := uuid.New().String()
+	p.routes[token] = &decryptRoute{
+		url:       upstreamURL,
+		headers:   headers,
+		decryptor: decryptor,
+		limiter:   limiter,
+	}
+	return fmt.Sprintf("http://%s/decrypt/%s", p.listener.Addr().String(), token), nil
+}
+
+// Release 注销本地地址
+func (p *DecryptProxy) Release(proxyURL string) {
+	p.mu.Lock()
+	delete(p.routes, proxyToken(proxyURL))
+	p.mu.Unlock()
+}
+
+// Registered 返回本地地址是否仍可用
+// 令牌只保存在内存中，代理关闭或程序重启后原地址失效，使用它的下载任务需要重新注册
+func (p *DecryptProxy) Registered(proxyURL string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.listener == nil || !strings.HasPrefix(proxyURL, "http://"+p.listener.Addr().String()+"/") {
+		return false
+	}
+	_, ok := p.routes[proxyToken(proxyURL)]
+	return ok
+}
+
+// Close 关闭代理并注销所有地址，重新注册时会监听新的端口
+func (p *DecryptProxy) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.routes = make(map[string]*decryptRoute)
+	if p.server == nil {
+		return nil
+	}
+	err := p.server.Close()
+	p.server = nil
+	p.listener = nil
+	return err
+}
+
+// isDecryptProxyURL 判断地址是否为解密代理注册的本地地址
+func isDecryptProxyURL(rawURL string) bool {
+	return strings.HasPrefix(rawURL, "http://127.0.0.1:") && strings.Contains(rawURL, "/decrypt/")
+}
+
+// proxyToken 从本地地址中取出路由令牌
+func proxyToken(proxyURL string) string {
+	return proxyURL[strings.LastIndex(proxyURL, "/")+1:]
+}
+
+// ServeHTTP 转发请求到上游并解密响应
+func (p *DecryptProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.URL.Path, "/decrypt/")
+	p.mu.RLock()
+	route := p.routes[token]
+	p.mu.RUnlock()
+	if route == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	upstreamReq, err := http.NewRequestWithContext(r.Context(), r.Method, route.url, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	for k, v := range r.Header {
+		upstreamReq.Header[k] = v
+	}
+	upstreamReq.Header.Del("Accept-Encoding")
+	for k, v := range route.headers {
+		upstreamReq.Header.Set(k, v)
+	}
+
+	resp, err := p.client.Do(upstreamReq)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	for k, v := range resp.Header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(resp.StatusCode)
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	body := GetBandwidthLimiter().Reader(r.Context(), resp.Body, route.limiter)
+	switch resp.StatusCode {
+	case http.StatusOK:
+		body = utils.NewPrefixDecryptReader(body, route.decryptor, 0)
+	case http.StatusPartialContent:
+		body = utils.NewPrefixDecryptReader(body, route.decryptor, contentRangeStart(resp.Header.Get("Content-Range")))
+	}
+	_, _ = io.Copy(w, body)
+}
+
+// contentRangeStart 解析 Content-Range 头的起始偏移
+// 格式: "bytes start-end/total"
+func contentRangeStart(contentRange string) uint64 {
+	parts := strings.SplitN(strings.TrimSpace(contentRange), " ", 2)
+	if len(parts) != 2 {
+		return 0
+	}
+	dashIdx := strings.Index(parts[1], "-")
+	if dashIdx <= 0 {
+		return 0
+	}
+	start, err := strconv.ParseUint(parts[1][:dashIdx], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return start
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestDecryptProxy_DecryptsRangesAtOffset(t *testing.T) {
+	plain := make([]byte, 4096)
+	for i := range plain {
+		plain[i] = byte(i)
+	}
+	decryptor := bytes.Repeat([]byte{0x5a, 0xa5, 0x3c}, 400) // 1200 字节加密前缀
+	encrypted := append([]byte(nil), plain...)
+	for i := range decryptor {
+		encrypted[i] ^= decryptor[i]
+	}
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Referer") != "https://channels.weixin.qq.com" {
+			http.Error(w, "missing header", http.StatusForbidden)
+			return
+		}
+		http.ServeContent(w, r, "video.mp4", time.Now(), bytes.NewReader(encrypted))
+	}))
+	defer upstream.Close()
+
+	proxy := NewDecryptProxy()
+	defer proxy.Close()
+	proxyURL, err := proxy.Register(upstream.URL, map[string]string{"Referer": "https://channels.weixin.qq.com"}, decryptor, nil)
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	for _, r := range [][2]int{{0, 4095}, {1000, 1999}, {3000, 4095}} {
+		req, _ := http.NewRequest(http.MethodGet, proxyURL, nil)
+		if r[0] > 0 {
+			req.Header.Set("Range", "bytes="+strconv.Itoa(r[0])+"-"+strconv.Itoa(r[1]))
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET %v: %v", r, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if !bytes.Equal(body, plain[r[0]:r[1]+1]) {
+			t.Fatalf("range %v: decrypted body mismatch (status %d)", r, resp.StatusCode)
+		}
+	}
+
+	if !proxy.Registered(proxyURL) {
+		t.Fatal("registered route should be reported")
+	}
+	proxy.Release(proxyURL)
+	if proxy.Registered(proxyURL) {
+		t.Fatal("released route should not be reported")
+	}
+	resp, err := http.Get(proxyURL)
+	if err != nil {
+		t.Fatalf("GET released: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("released route status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestGopeedService_ProxiedTaskNotResumableAfterProxyClose(t *testing.T) {
+	svc := &GopeedService{proxy: NewDecryptProxy()}
+	proxyURL, err := svc.proxy.Register("https://example.com/video.mp4", nil, []byte{0x5a}, nil)
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if !svc.resumable(proxyURL) || !svc.resumable("https://example.com/video.mp4") {
+		t.Fatal("live proxy route and direct URL should be resumable")
+	}
+
+	// 代理关闭后令牌失效（与程序重启相同），重新监听也不会恢复旧地址
+	svc.proxy.Close()
+	if _, err := svc.proxy.Register("https://example.com/other.mp4", nil, nil, nil); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	defer svc.proxy.Close()
+	if svc.resumable(proxyURL) {
+		t.Fatal("proxy route should not survive a proxy restart")
+	}
+	svc.proxy = nil
+	if svc.resumable(proxyURL) {
+		t.Fatal("proxy route should not be resumable without a proxy")
+	}
+}
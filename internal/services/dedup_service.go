@@ -0,0 +1,290 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"wx_channel/internal/database"
+	"wx_channel/internal/utils"
+)
+
+// DedupService 基于文件内容哈希识别重复下载
+// 同一视频以不同 ID 重新发布时文件名和 VideoID 都不同，只能通过内容判断
+type DedupService struct {
+	repo *database.DownloadRecordRepository
+}
+
+// DedupResult 表示单个文件的去重检查结果
+type DedupResult struct {
+	Duplicate *database.DownloadRecord `json:"duplicate,omitempty"` // 内容相同的已有记录
+	Linked    bool                     `json:"linked"`              // 新文件已替换为指向已有文件的硬链接
+}
+
+// DedupScanResult 表示一次哈希补算的结果
+type DedupScanResult struct {
+	Fingerprinted int `json:"fingerprinted"`
+	Hashed        int `json:"hashed"`
+	Missing       int `json:"missing"` // 文件已不存在的记录
+}
+
+// DedupLinkResult 表示硬链接替换的结果
+type DedupLinkResult struct {
+	Linked     int      `json:"linked"`
+	FreedBytes int64    `json:"freedBytes"`
+	Errors     []string `json:"errors,omitempty"`
+}
+
+// NewDedupService 创建一个新的 DedupService
+func NewDedupService() *DedupService {
+	return &DedupService{
+		repo: database.NewDownloadRecordRepository(),
+	}
+}
+
+// 后台去重任务，同一时间只计算一个文件的 SHA-256，避免与下载争抢磁盘
+var (
+	dedupJobs   sync.WaitGroup
+	dedupJobSem = make(chan struct{}, 1)
+)
+
+// ScheduleDedupCheck 在后台为已写入的下载记录计算 SHA-256 并与已有下载去重
+// 完整哈希在大文件上需要数秒，不在 HTTP 请求和下载完成流程中同步执行
+func ScheduleDedupCheck(recordID string) {
+	dedupJobs.Add(1)
+	go func() {
+		defer dedupJobs.Done()
+		dedupJobSem <- struct{}{}
+		defer func() { <-dedupJobSem }()
+
+		if database.GetDB() == nil {
+			return
+		}
+		if _, err := NewDedupService().CheckRecord(recordID); err != nil {
+			utils.Warn("[Dedup] 去重检查失败 %s: %v", recordID, err)
+		}
+	}()
+}
+
+// WaitDedupChecks 等待所有后台去重任务完成
+func WaitDedupChecks() {
+	dedupJobs.Wait()
+}
+
+// Fingerprint 计算文件的快速指纹并填充到 record，写入记录前调用
+func (s *DedupService) Fingerprint(record *database.DownloadRecord) error {
+	if record == nil || record.FilePath == "" {
+		return nil
+	}
+	fingerprint, err := utils.FileFingerprint(record.FilePath)
+	if err != nil {
+		return fmt.Errorf("failed to fingerprint file: %w", err)
+	}
+	record.Fingerprint = fingerprint
+	return nil
+}
+
+// CheckRecord 对已写入的已完成记录做去重检查，并保存计算出的指纹和 SHA-256
+func (s *DedupService) CheckRecord(recordID string) (*DedupResult, error) {
+	record, err := s.repo.GetByID(recordID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil || record.Status != database.DownloadStatusCompleted || record.ContentHash != "" {
+		return &DedupResult{}, nil
+	}
+	if _, err := os.Stat(record.FilePath); err != nil {
+		return &DedupResult{}, nil
+	}
+
+	result, err := s.Check(record)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateHashes(record.ID, record.Fingerprint, record.ContentHash); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Check 计算文件指纹和 SHA-256，并填充到 record
+// 若已有记录的文件内容完全相同，则用硬链接替换该文件，磁盘上只保留一份数据
+func (s *DedupService) Check(record *database.DownloadRecord) (*DedupResult, error) {
+	if record == nil || record.FilePath == "" {
+		return &DedupResult{}, nil
+	}
+
+	fingerprint, err := utils.FileFingerprint(record.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fingerprint file: %w", err)
+	}
+	contentHash, err := utils.FileSHA256(record.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	record.Fingerprint = fingerprint
+	record.ContentHash = contentHash
+
+	// 先用快速指纹缩小范围，旧记录缺少哈希时在此补算
+	candidates, err := s.repo.FindByFingerprint(fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	for i := range candidates {
+		candidate := &candidates[i]
+		if candidate.ID == record.ID || candidate.FilePath == "" || candidate.FilePath == record.FilePath {
+			continue
+		}
+		if candidate.ContentHash == "" {
+			hash, err := utils.FileSHA256(candidate.FilePath)
+			if err != nil {
+				continue
+			}
+			candidate.ContentHash = hash
+			_ = s.repo.UpdateHashes(candidate.ID, candidate.Fingerprint, hash)
+		}
+		if candidate.ContentHash != contentHash {
+			continue
+		}
+		if _, err := os.Stat(candidate.FilePath); err != nil {
+			continue
+		}
+
+		result := &DedupResult{Duplicate: candidate}
+		if err := utils.ReplaceWithHardlink(candidate.FilePath, record.FilePath); err != nil {
+			// 跨磁盘等情况无法建立硬链接，保留新文件
+			utils.Warn("[Dedup] 无法硬链接重复文件 %s: %v", record.FilePath, err)
+			return result, nil
+		}
+		result.Linked = true
+		utils.Info("[Dedup] %s 与已有下载 %s 内容相同，已替换为硬链接", record.FilePath, candidate.FilePath)
+		return result, nil
+	}
+
+	return &DedupResult{}, nil
+}
+
+// ScanMissingHashes 为旧记录补算指纹，仅对指纹冲突的记录计算完整 SHA-256
+func (s *DedupService) ScanMissingHashes() (*DedupScanResult, error) {
+	result := &DedupScanResult{}
+
+	records, err := s.repo.ListWithoutFingerprint()
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		fingerprint, err := utils.FileFingerprint(record.FilePath)
+		if err != nil {
+			result.Missing++
+			continue
+		}
+		if err := s.repo.UpdateHashes(record.ID, fingerprint, ""); err != nil {
+			return nil, err
+		}
+		result.Fingerprinted++
+	}
+
+	collisions, err := s.repo.ListUnhashedFingerprintCollisions()
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range collisions {
+		hash, err := utils.FileSHA256(record.FilePath)
+		if err != nil {
+			result.Missing++
+			continue
+		}
+		if err := s.repo.UpdateHashes(record.ID, record.Fingerprint, hash); err != nil {
+			return nil, err
+		}
+		result.Hashed++
+	}
+
+	return result, nil
+}
+
+// ListDuplicateGroups 列出内容相同的记录分组
+// WastedBytes 只统计尚未与保留文件硬链接的副本
+func (s *DedupService) ListDuplicateGroups() ([]database.DuplicateGroup, error) {
+	groups, err := s.repo.ListDuplicateGroups()
+	if err != nil {
+		return nil, err
+	}
+	for i := range groups {
+		keeper := duplicateKeeper(groups[i].Records)
+		var wasted int64
+		for _, record := range groups[i].Records {
+			if keeper == nil || record.ID == keeper.ID {
+				continue
+			}
+			if _, err := os.Stat(record.FilePath); err != nil {
+				continue
+			}
+			if !utils.SameFile(keeper.FilePath, record.FilePath) {
+				wasted += record.FileSize
+			}
+		}
+		groups[i].WastedBytes = wasted
+	}
+	return groups, nil
+}
+
+// LinkDuplicates 将分组中的重复文件替换为指向最早下载文件的硬链接
+// contentHash 为空时处理所有分组；替换前会重新校验文件内容
+func (s *DedupService) LinkDuplicates(contentHash string) (*DedupLinkResult, error) {
+	var groups []database.DuplicateGroup
+	if contentHash != "" {
+		records, err := s.repo.FindByContentHash(contentHash)
+		if err != nil {
+			return nil, err
+		}
+		groups = []database.DuplicateGroup{{ContentHash: contentHash, Records: records}}
+	} else {
+		var err error
+		groups, err = s.repo.ListDuplicateGroups()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	result := &DedupLinkResult{}
+	for _, group := range groups {
+		keeper := duplicateKeeper(group.Records)
+		if keeper == nil {
+			continue
+		}
+		if hash, err := utils.FileSHA256(keeper.FilePath); err != nil || hash != group.ContentHash {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: 文件内容已变化", keeper.FilePath))
+			continue
+		}
+		for _, record := range group.Records {
+			if record.ID == keeper.ID {
+				continue
+			}
+			if _, err := os.Stat(record.FilePath); err != nil || utils.SameFile(keeper.FilePath, record.FilePath) {
+				continue
+			}
+			if hash, err := utils.FileSHA256(record.FilePath); err != nil || hash != group.ContentHash {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: 文件内容已变化", record.FilePath))
+				continue
+			}
+			if err := utils.ReplaceWithHardlink(keeper.FilePath, record.FilePath); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", record.FilePath, err))
+				continue
+			}
+			result.Linked++
+			result.FreedBytes += record.FileSize
+		}
+	}
+	return result, nil
+}
+
+// duplicateKeeper 返回分组中最早下载且文件仍存在的记录
+func duplicateKeeper(records []database.DownloadRecord) *database.DownloadRecord {
+	for i := range records {
+		if _, err := os.Stat(records[i].FilePath); err == nil {
+			return &records[i]
+		}
+	}
+	return nil
+}
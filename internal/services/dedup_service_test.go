@@ -0,0 +1,125 @@
+package services
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"wx_channel/internal/database"
+	"wx_channel/internal/utils"
+)
+
+func writeDedupTestFile(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestDownloadRecordService_CreateLinksDuplicateContent(t *testing.T) {
+	dir := setupQueueWorkerTest(t)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	payload := bytes.Repeat([]byte("same clip "), 20000)
+	first := writeDedupTestFile(t, dir, "first.mp4", payload)
+	second := writeDedupTestFile(t, dir, "second.mp4", payload)
+
+	service := NewDownloadRecordService()
+	for i, path := range []string{first, second} {
+		record := &database.DownloadRecord{
+			ID:           []string{"v1", "v2"}[i],
+			VideoID:      []string{"v1", "v2"}[i],
+			Title:        filepath.Base(path),
+			FileSize:     int64(len(payload)),
+			FilePath:     path,
+			Status:       database.DownloadStatusCompleted,
+			DownloadTime: time.Now().Add(time.Duration(i) * time.Second),
+		}
+		if err := service.Create(record); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		// 写入时只计算快速指纹，完整哈希在后台计算
+		if record.Fingerprint == "" {
+			t.Fatalf("expected fingerprint to be computed before insert")
+		}
+	}
+	WaitDedupChecks()
+
+	if !utils.SameFile(first, second) {
+		t.Fatalf("expected duplicate to be replaced with a hardlink")
+	}
+	record, _ := service.GetByID("v2")
+	if record == nil || record.ContentHash == "" || record.Fingerprint == "" {
+		t.Fatalf("expected hashes to be stored, got %+v", record)
+	}
+
+	groups, err := NewDedupService().ListDuplicateGroups()
+	if err != nil {
+		t.Fatalf("ListDuplicateGroups: %v", err)
+	}
+	if len(groups) != 1 || len(groups[0].Records) != 2 {
+		t.Fatalf("groups = %+v, want one group of two", groups)
+	}
+	if groups[0].WastedBytes != 0 {
+		t.Fatalf("wasted = %d, want 0 after linking", groups[0].WastedBytes)
+	}
+}
+
+func TestDedupService_ScanAndLinkLegacyRecords(t *testing.T) {
+	dir := setupQueueWorkerTest(t)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+
+	payload := bytes.Repeat([]byte("legacy "), 30000)
+	other := bytes.Repeat([]byte("unique "), 30000)
+	repo := database.NewDownloadRecordRepository()
+	paths := map[string]string{
+		"a": writeDedupTestFile(t, dir, "a.mp4", payload),
+		"b": writeDedupTestFile(t, dir, "b.mp4", payload),
+		"c": writeDedupTestFile(t, dir, "c.mp4", other),
+	}
+	for i, id := range []string{"a", "b", "c"} {
+		// 直接写入仓库，模拟去重功能上线前的旧记录
+		if err := repo.Create(&database.DownloadRecord{
+			ID: id, VideoID: id, Title: id, FileSize: int64(len(payload)), FilePath: paths[id],
+			Status: database.DownloadStatusCompleted, DownloadTime: time.Now().Add(time.Duration(i) * time.Second),
+		}); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	service := NewDedupService()
+	scan, err := service.ScanMissingHashes()
+	if err != nil {
+		t.Fatalf("ScanMissingHashes: %v", err)
+	}
+	if scan.Fingerprinted != 3 || scan.Hashed != 2 {
+		t.Fatalf("scan = %+v, want 3 fingerprinted and 2 hashed", scan)
+	}
+
+	groups, err := service.ListDuplicateGroups()
+	if err != nil || len(groups) != 1 {
+		t.Fatalf("groups = %+v, err = %v", groups, err)
+	}
+	if groups[0].WastedBytes != int64(len(payload)) {
+		t.Fatalf("wasted = %d, want %d", groups[0].WastedBytes, len(payload))
+	}
+
+	result, err := service.LinkDuplicates("")
+	if err != nil {
+		t.Fatalf("LinkDuplicates: %v", err)
+	}
+	if result.Linked != 1 {
+		t.Fatalf("linked = %d, want 1 (errors: %v)", result.Linked, result.Errors)
+	}
+	if !utils.SameFile(paths["a"], paths["b"]) || utils.SameFile(paths["a"], paths["c"]) {
+		t.Fatalf("unexpected hardlink layout")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"wx_channel/internal/database"
+	"wx_channel/internal/utils"
 )
 
 // DownloadRecordService 处理下载记录业务逻辑
@@ -40,6 +41,11 @@ func (s *DownloadRecordService) GetByID(id string) (*database.DownloadRecord, er
 	return s.repo.GetByID(id)
 }
 
+// GetByVideoID 按视频 ID 获取下载记录
+func (s *DownloadRecordService) GetByVideoID(videoID string) (*database.DownloadRecord, error) {
+	return s.repo.GetByVideoID(videoID)
+}
+
 // Delete 按 ID 删除下载记录（可选删除文件）
 // Requirements: 5.3 - 删除记录（可选择保留或删除文件）
 func (s *DownloadRecordService) Delete(id string, deleteFile bool) error {
@@ -166,8 +172,21 @@ func (s *DownloadRecordService) GetTotalFileSize() (int64, error) {
 }
 
 // Create 添加新的下载记录
+// 已完成的记录写入前计算快速指纹，写入后在后台计算 SHA-256 并将重复文件替换为硬链接
 func (s *DownloadRecordService) Create(record *database.DownloadRecord) error {
-	return s.repo.Create(record)
+	dedup := record.Status == database.DownloadStatusCompleted && record.ContentHash == "" && record.FilePath != ""
+	if dedup && record.Fingerprint == "" {
+		if err := NewDedupService().Fingerprint(record); err != nil {
+			utils.Warn("[Dedup] 计算文件指纹失败 %s: %v", record.FilePath, err)
+		}
+	}
+	if err := s.repo.Create(record); err != nil {
+		return err
+	}
+	if dedup {
+		ScheduleDedupCheck(record.ID)
+	}
+	return nil
 }
 
 // Update 更新现有的下载记录
@@ -0,0 +1,241 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"wx_channel/internal/database"
+	"wx_channel/internal/utils"
+)
+
+const (
+	// engagementSnapshotInterval 同一视频两次快照的最短间隔
+	engagementSnapshotInterval = time.Hour
+	// engagementRefreshAfter 超过该时间未更新的视频通过 feed_profile 补拍快照
+	engagementRefreshAfter = 6 * time.Hour
+	// engagementTrackDays 视频首次出现后持续跟踪的天数
+	engagementTrackDays = 30
+	// engagementRefreshBatch 每轮最多补拍的视频数
+	engagementRefreshBatch = 10
+)
+
+// EngagementCounts 互动计数
+type EngagementCounts struct {
+	LikeCount    int64 `json:"likeCount"`
+	CommentCount int64 `json:"commentCount"`
+	FavCount     int64 `json:"favCount"`
+	ForwardCount int64 `json:"forwardCount"`
+}
+
+// EngagementPoint 增长曲线上的一个点
+type EngagementPoint struct {
+	Time time.Time `json:"time"`
+	EngagementCounts
+}
+
+// EngagementSeries 视频或作者的互动增长曲线
+type EngagementSeries struct {
+	VideoID string            `json:"videoId,omitempty"`
+	Author  string            `json:"author,omitempty"`
+	Title   string            `json:"title,omitempty"`
+	Videos  int               `json:"videos"` // 曲线包含的视频数
+	Points  []EngagementPoint `json:"points"`
+	Growth  EngagementCounts  `json:"growth"` // 区间内第一个点到最后一个点的增长
+}
+
+// EngagementService 记录雷达跟踪视频的互动数据快照，并生成增长曲线
+type EngagementService struct {
+	repo      *database.EngagementRepository
+	downloads *database.DownloadRecordRepository
+}
+
+// NewEngagementService 创建一个新的 EngagementService
+func NewEngagementService() *EngagementService {
+	return &EngagementService{
+		repo:      database.NewEngagementRepository(),
+		downloads: database.NewDownloadRecordRepository(),
+	}
+}
+
+// RecordFeedObject 从 feed_list 返回的视频记录一次快照
+// 距上次快照不足 engagementSnapshotInterval 时跳过
+func (s *EngagementService) RecordFeedObject(target database.RadarTarget, feed FeedObject) error {
+	snapshot := &database.EngagementSnapshot{
+		VideoID:  feed.ID,
+		NonceID:  feed.NonceID,
+		Title:    feed.Title,
+		Author:   target.AuthorName,
+		TargetID: target.ID,
+	}
+	applyEngagementCounts(snapshot, feed.Counts)
+	return s.record(snapshot, time.Now())
+}
+
+// record 写入快照并同步下载记录上的计数
+func (s *EngagementService) record(snapshot *database.EngagementSnapshot, now time.Time) error {
+	latest, err := s.repo.GetLatest(snapshot.VideoID)
+	if err != nil {
+		return err
+	}
+	if latest != nil && now.Sub(latest.CapturedAt) < engagementSnapshotInterval {
+		return nil
+	}
+
+	snapshot.CapturedAt = now
+	if err := s.repo.Add(snapshot); err != nil {
+		return err
+	}
+	return s.downloads.UpdateEngagement(snapshot.VideoID, snapshot.LikeCount, snapshot.CommentCount, snapshot.FavCount, snapshot.ForwardCount)
+}
+
+// RefreshStale 为跟踪期内、较长时间没有出现在 feed_list 中的视频补拍快照
+// fetch 通过 feed_profile 获取视频的最新计数；单个视频失败不影响其他视频
+// 返回补拍成功的视频数和各视频的错误
+func (s *EngagementService) RefreshStale(ctx context.Context, fetch func(objectID, nonceID string) (EngagementCounts, error)) (int, error) {
+	now := time.Now()
+	stale, err := s.repo.ListStale(now.AddDate(0, 0, -engagementTrackDays), now.Add(-engagementRefreshAfter), engagementRefreshBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	refreshed := 0
+	var errs []error
+	for _, latest := range stale {
+		if ctx.Err() != nil {
+			return refreshed, ctx.Err()
+		}
+		counts, err := fetch(latest.VideoID, latest.NonceID)
+		if err == nil {
+			snapshot := latest
+			snapshot.ID = 0
+			applyEngagementCounts(&snapshot, counts)
+			err = s.record(&snapshot, time.Now())
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("video %s: %w", latest.VideoID, err))
+			continue
+		}
+		refreshed++
+	}
+	return refreshed, errors.Join(errs...)
+}
+
+// VideoSeries 返回视频最近 days 天的互动增长曲线
+func (s *EngagementService) VideoSeries(videoID string, days int) (*EngagementSeries, error) {
+	snapshots, err := s.repo.ListByVideo(videoID, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		return nil, err
+	}
+
+	series := &EngagementSeries{VideoID: videoID, Points: []EngagementPoint{}}
+	for _, snapshot := range snapshots {
+		series.Title = snapshot.Title
+		series.Author = snapshot.Author
+		series.Points = append(series.Points, EngagementPoint{Time: snapshot.CapturedAt, EngagementCounts: snapshotCounts(snapshot)})
+	}
+	if len(snapshots) > 0 {
+		series.Videos = 1
+	}
+	series.Growth = seriesGrowth(series.Points)
+	return series, nil
+}
+
+// AuthorSeries 返回作者最近 days 天按天汇总的互动增长曲线
+// 每天取各视频截至当天的最新快照求和，当天没有快照的视频沿用之前的值
+func (s *EngagementService) AuthorSeries(author string, days int) (*EngagementSeries, error) {
+	snapshots, err := s.repo.ListByAuthor(author, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		return nil, err
+	}
+
+	series := &EngagementSeries{Author: author, Points: []EngagementPoint{}}
+	latest := make(map[string]EngagementCounts)
+	var day time.Time
+	flush := func() {
+		var total EngagementCounts
+		for _, c := range latest {
+			total.LikeCount += c.LikeCount
+			total.CommentCount += c.CommentCount
+			total.FavCount += c.FavCount
+			total.ForwardCount += c.ForwardCount
+		}
+		series.Points = append(series.Points, EngagementPoint{Time: day, EngagementCounts: total})
+	}
+
+	for _, snapshot := range snapshots {
+		y, m, d := snapshot.CapturedAt.In(time.Local).Date()
+		snapshotDay := time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+		if !day.IsZero() && !snapshotDay.Equal(day) {
+			flush()
+		}
+		day = snapshotDay
+		latest[snapshot.VideoID] = snapshotCounts(snapshot)
+	}
+	if !day.IsZero() {
+		flush()
+	}
+
+	series.Videos = len(latest)
+	series.Growth = seriesGrowth(series.Points)
+	return series, nil
+}
+
+// parseEngagementCounts 解析视频对象中的计数字段，兼容数字和字符串
+func parseEngagementCounts(objMap map[string]interface{}) EngagementCounts {
+	return EngagementCounts{
+		LikeCount:    jsonInt64(objMap["likeCount"]),
+		CommentCount: jsonInt64(objMap["commentCount"]),
+		FavCount:     jsonInt64(objMap["favCount"]),
+		ForwardCount: jsonInt64(objMap["forwardCount"]),
+	}
+}
+
+func jsonInt64(v interface{}) int64 {
+	switch n := v.(type) {
+	case float64:
+		return int64(n)
+	case string:
+		i, _ := strconv.ParseInt(n, 10, 64)
+		return i
+	}
+	return 0
+}
+
+func applyEngagementCounts(snapshot *database.EngagementSnapshot, counts EngagementCounts) {
+	snapshot.LikeCount = counts.LikeCount
+	snapshot.CommentCount = counts.CommentCount
+	snapshot.FavCount = counts.FavCount
+	snapshot.ForwardCount = counts.ForwardCount
+}
+
+func snapshotCounts(snapshot database.EngagementSnapshot) EngagementCounts {
+	return EngagementCounts{
+		LikeCount:    snapshot.LikeCount,
+		CommentCount: snapshot.CommentCount,
+		FavCount:     snapshot.FavCount,
+		ForwardCount: snapshot.ForwardCount,
+	}
+}
+
+func seriesGrowth(points []EngagementPoint) EngagementCounts {
+	if len(points) < 2 {
+		return EngagementCounts{}
+	}
+	first, last := points[0], points[len(points)-1]
+	return EngagementCounts{
+		LikeCount:    last.LikeCount - first.LikeCount,
+		CommentCount: last.CommentCount - first.CommentCount,
+		FavCount:     last.FavCount - first.FavCount,
+		ForwardCount: last.ForwardCount - first.ForwardCount,
+	}
+}
+
+// logEngagementError 记录快照失败，不影响雷达检测
+func logEngagementError(videoID string, err error) {
+	if err != nil {
+		utils.LogWarn("[Engagement] 记录互动数据失败 [%s]: %v", videoID, err)
+	}
+}
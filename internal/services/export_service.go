@@ -5,6 +5,8 @@ import (
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"wx_channel/internal/database"
@@ -303,3 +305,124 @@ func ParseDownloadRecordsFromJSON(data []byte) ([]database.DownloadRecord, error
 	}
 	return records, nil
 }
+
+// ParseBrowseRecordsFromCSV 从 exportBrowseRecordsToCSV 导出的 CSV 解析浏览记录
+// 按表头名称定位列，缺少的列保持零值
+func ParseBrowseRecordsFromCSV(data []byte) ([]database.BrowseRecord, error) {
+	columns, rows, err := readExportCSV(data, "ID")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse browse records from CSV: %w", err)
+	}
+
+	records := make([]database.BrowseRecord, 0, len(rows))
+	for _, row := range rows {
+		get := func(name string) string { return columns.get(row, name) }
+		records = append(records, database.BrowseRecord{
+			ID:           get("ID"),
+			Title:        get("Title"),
+			Author:       get("Author"),
+			AuthorID:     get("AuthorID"),
+			Duration:     parseCSVInt(get("Duration")),
+			Size:         parseCSVInt(get("Size")),
+			Resolution:   get("Resolution"),
+			CoverURL:     get("CoverURL"),
+			VideoURL:     get("VideoURL"),
+			DecryptKey:   get("DecryptKey"),
+			BrowseTime:   parseCSVTime(get("BrowseTime")),
+			LikeCount:    parseCSVInt(get("LikeCount")),
+			CommentCount: parseCSVInt(get("CommentCount")),
+			FavCount:     parseCSVInt(get("FavCount")),
+			ForwardCount: parseCSVInt(get("ForwardCount")),
+			PageURL:      get("PageURL"),
+			CreatedAt:    parseCSVTime(get("CreatedAt")),
+			UpdatedAt:    parseCSVTime(get("UpdatedAt")),
+		})
+	}
+	return records, nil
+}
+
+// ParseDownloadRecordsFromCSV 从 exportDownloadRecordsToCSV 导出的 CSV 解析下载记录
+// 时长和文件大小是格式化后的文本，解析结果精确到秒和两位小数
+func ParseDownloadRecordsFromCSV(data []byte) ([]database.DownloadRecord, error) {
+	columns, rows, err := readExportCSV(data, "VideoID")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse download records from CSV: %w", err)
+	}
+
+	records := make([]database.DownloadRecord, 0, len(rows))
+	for _, row := range rows {
+		get := func(name string) string { return columns.get(row, name) }
+		records = append(records, database.DownloadRecord{
+			ID:           get("ID"),
+			VideoID:      get("VideoID"),
+			Title:        get("Title"),
+			Author:       get("Author"),
+			Duration:     parseDurationText(get("Duration")),
+			FileSize:     parseSizeText(get("FileSize")),
+			FilePath:     get("FilePath"),
+			Format:       get("Format"),
+			Resolution:   get("Resolution"),
+			Status:       get("Status"),
+			DownloadTime: parseCSVTime(get("DownloadTime")),
+			LikeCount:    parseCSVInt(get("LikeCount")),
+			CommentCount: parseCSVInt(get("CommentCount")),
+			ForwardCount: parseCSVInt(get("ForwardCount")),
+			FavCount:     parseCSVInt(get("FavCount")),
+			ErrorMessage: get("ErrorMessage"),
+			CreatedAt:    parseCSVTime(get("CreatedAt")),
+			UpdatedAt:    parseCSVTime(get("UpdatedAt")),
+		})
+	}
+	return records, nil
+}
+
+// csvColumns 表头名称到列下标的映射
+type csvColumns map[string]int
+
+func (c csvColumns) get(row []string, name string) string {
+	if i, ok := c[name]; ok && i < len(row) {
+		return strings.TrimSpace(row[i])
+	}
+	return ""
+}
+
+// readExportCSV 读取导出的 CSV，返回表头映射和数据行；表头必须包含 required 列
+func readExportCSV(data []byte, required string) (csvColumns, [][]string, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, utf8BOM)))
+	reader.FieldsPerRecord = -1
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rows) == 0 {
+		return csvColumns{}, nil, nil
+	}
+
+	columns := make(csvColumns, len(rows[0]))
+	for i, name := range rows[0] {
+		columns[strings.TrimSpace(name)] = i
+	}
+	if _, ok := columns[required]; !ok {
+		return nil, nil, fmt.Errorf("missing column %q", required)
+	}
+
+	filtered := make([][]string, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		if len(row) == 1 && strings.TrimSpace(row[0]) == "" {
+			continue
+		}
+		filtered = append(filtered, row)
+	}
+	return columns, filtered, nil
+}
+
+func parseCSVInt(text string) int64 {
+	value, _ := strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+	return value
+}
+
+func parseCSVTime(text string) time.Time {
+	t, _ := time.Parse(time.RFC3339, strings.TrimSpace(text))
+	return t
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"wx_channel/internal/websocket"
+)
+
+// ErrFeedListRejected 微信接口返回 Ret != 0，通常是请求过于频繁或账号异常
+var ErrFeedListRejected = errors.New("微信接口返回失败")
+
+// FeedListCaller 通过注入脚本发送一次 feed_list 请求，返回原始数据
+type FeedListCaller func(body websocket.FeedListBody) ([]byte, error)
+
+// FeedObject feed_list 作品对象中下载和展示需要的字段
+type FeedObject struct {
+	ID         string
+	NonceID    string
+	Title      string
+	Author     string
+	CreateTime int64 // Unix 秒
+	Duration   int64 // 毫秒
+	FileSize   int64
+	Resolution string
+	VideoURL   string
+	CoverURL   string
+	DecryptKey string
+	Counts     EngagementCounts
+}
+
+// EncodeNextMarker 转义分页游标
+// 注入脚本会对 next_marker 做 decodeURIComponent，游标中的 +、/、= 等字符需要先转义
+func EncodeNextMarker(marker string) string {
+	return url.QueryEscape(marker)
+}
+
+// FetchFeedListPage 拉取账号的一页 feed_list，返回作品列表和下一页游标
+// call 返回的错误原样返回，由调用方处理客户端未连接等情况
+func FetchFeedListPage(call FeedListCaller, username, marker string) ([]interface{}, string, error) {
+	data, err := call(websocket.FeedListBody{
+		Username:   username,
+		NextMarker: EncodeNextMarker(marker),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	var rawResp struct {
+		Data struct {
+			BaseResponse struct {
+				Ret int `json:"Ret"`
+			} `json:"BaseResponse"`
+			ObjectList []interface{} `json:"objectList"`
+			Object     []interface{} `json:"object"`
+			LastBuffer string        `json:"lastBuffer"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &rawResp); err != nil {
+		return nil, "", fmt.Errorf("解析返回数据失败: %w", err)
+	}
+	if rawResp.Data.BaseResponse.Ret != 0 {
+		return nil, "", fmt.Errorf("%w，状态码: %d (可能是请求过于频繁或账号异常)", ErrFeedListRejected, rawResp.Data.BaseResponse.Ret)
+	}
+
+	// 兼容不同版本返回的字段
+	objects := rawResp.Data.ObjectList
+	if len(objects) == 0 {
+		objects = rawResp.Data.Object
+	}
+	return objects, rawResp.Data.LastBuffer, nil
+}
+
+// ParseFeedObject 从 feed_list 作品对象提取字段，媒体信息取 objectDesc 中的第一条
+// 对象不是作品或缺少 id 时返回 false
+func ParseFeedObject(obj interface{}) (FeedObject, bool) {
+	objMap, ok := obj.(map[string]interface{})
+	if !ok {
+		return FeedObject{}, false
+	}
+	feed := FeedObject{
+		ID:         jsonString(objMap["id"]),
+		NonceID:    jsonString(objMap["objectNonceId"]),
+		Author:     jsonString(objMap["nickname"]),
+		CreateTime: jsonInt64(objMap["createtime"]),
+		Counts:     parseEngagementCounts(objMap),
+	}
+	if feed.ID == "" {
+		return FeedObject{}, false
+	}
+	if feed.Author == "" {
+		if contact, ok := objMap["contact"].(map[string]interface{}); ok {
+			feed.Author = jsonString(contact["nickname"])
+		}
+	}
+
+	if desc, ok := objMap["objectDesc"].(map[string]interface{}); ok {
+		feed.Title = jsonString(desc["description"])
+		if mediaList, ok := desc["media"].([]interface{}); ok && len(mediaList) > 0 {
+			if media, ok := mediaList[0].(map[string]interface{}); ok {
+				if rawURL := jsonString(media["url"]); rawURL != "" {
+					feed.VideoURL = rawURL + jsonString(media["urlToken"])
+				}
+				feed.CoverURL = jsonString(media["thumbUrl"])
+				feed.DecryptKey = jsonString(media["decodeKey"])
+				feed.FileSize = jsonInt64(media["fileSize"])
+				feed.Duration = jsonInt64(media["videoDuration"])
+				feed.Resolution = jsonString(media["videoResolution"])
+			}
+		}
+	}
+	return feed, true
+}
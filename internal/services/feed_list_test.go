@@ -0,0 +1,56 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"wx_channel/internal/websocket"
+)
+
+func TestFetchFeedListPage(t *testing.T) {
+	var sent websocket.FeedListBody
+	call := func(data string) FeedListCaller {
+		return func(body websocket.FeedListBody) ([]byte, error) {
+			sent = body
+			return []byte(data), nil
+		}
+	}
+
+	// 旧版本只返回 object 字段
+	objects, next, err := FetchFeedListPage(call(`{"data":{"object":[{"id":"v1"}],"lastBuffer":"b+2"}}`), "user", "a+/=")
+	if err != nil || len(objects) != 1 || next != "b+2" {
+		t.Fatalf("FetchFeedListPage = %v, %q, %v", objects, next, err)
+	}
+	if sent.Username != "user" || sent.NextMarker != "a%2B%2F%3D" {
+		t.Fatalf("unexpected body: %+v", sent)
+	}
+
+	_, _, err = FetchFeedListPage(call(`{"data":{"BaseResponse":{"Ret":-1}}}`), "user", "")
+	if !errors.Is(err, ErrFeedListRejected) {
+		t.Fatalf("expected ErrFeedListRejected, got %v", err)
+	}
+}
+
+func TestParseFeedObject(t *testing.T) {
+	feed, ok := ParseFeedObject(map[string]interface{}{
+		"id":            "v1",
+		"objectNonceId": "n1",
+		"createtime":    float64(1700000000),
+		"likeCount":     "12",
+		"contact":       map[string]interface{}{"nickname": "作者"},
+		"objectDesc": map[string]interface{}{
+			"description": "标题",
+			"media": []interface{}{map[string]interface{}{
+				"url": "https://example.com/v", "urlToken": "?t=1", "videoDuration": float64(125000),
+			}},
+		},
+	})
+	if !ok || feed.Author != "作者" || feed.Title != "标题" || feed.VideoURL != "https://example.com/v?t=1" ||
+		feed.Duration != 125000 || feed.CreateTime != 1700000000 || feed.Counts.LikeCount != 12 {
+		t.Fatalf("unexpected feed object: %+v", feed)
+	}
+
+	if _, ok := ParseFeedObject(map[string]interface{}{"objectDesc": map[string]interface{}{}}); ok {
+		t.Fatal("object without id should be skipped")
+	}
+}
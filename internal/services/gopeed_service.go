@@ -19,6 +19,9 @@ import (
 
 var ErrTaskPaused = errors.New("gopeed task paused")
 
+// ErrTaskNotResumable 任务经本地代理下载，代理地址已失效，需要重新创建任务
+var ErrTaskNotResumable = errors.New("gopeed task cannot be resumed")
+
 type GopeedTaskSnapshot struct {
 	ID         string
 	Status     base.Status
@@ -32,14 +35,25 @@ type GopeedService struct {
 	Downloader *download.Downloader
 	mu         sync.RWMutex
 	tasks      map[string]string // Maps internal ID to Gopeed Task ID
+
+	proxy       *DecryptProxy
+	proxyRoutes map[string]gopeedProxyRoute // Gopeed Task ID -> proxy route
+}
+
+// gopeedProxyRoute 记录经本地代理下载的任务
+type gopeedProxyRoute struct {
+	url     string
+	limiter *utils.RateLimiter
 }
 
 // NewGopeedService creates a new GopeedService
 // Note: We bypass store for now due to dependency issues or signature changes
 func NewGopeedService(storageDir string) *GopeedService {
 	// Create downloader config
+	// 任务只保存在内存中：经解密代理的任务地址带有进程内的令牌和端口，
+	// 持久化后在重启时恢复只会请求到失效的地址
 	dlCfg := &download.DownloaderConfig{
-		// Default config is acceptable
+		Storage: download.NewMemStorage(),
 	}
 
 	// Create a downloader instance
@@ -51,8 +65,9 @@ func NewGopeedService(storageDir string) *GopeedService {
 	}
 
 	return &GopeedService{
-		Downloader: d,
-		tasks:      make(map[string]string),
+		Downloader:  d,
+		tasks:       make(map[string]string),
+		proxyRoutes: make(map[string]gopeedProxyRoute),
 	}
 }
 
@@ -97,11 +112,64 @@ func buildRequest(url string, headers map[string]string) *base.Request {
 }
 
 // CreateTask creates a download task and starts it immediately.
+// When bandwidth limits are configured the task is routed through the loopback proxy.
 func (s *GopeedService) CreateTask(url string, path string, connections int, headers map[string]string) (string, error) {
+	return s.CreateDecryptedTask(url, path, connections, headers, nil)
+}
+
+// CreateDecryptedTask creates a task whose encrypted prefix is decrypted while downloading.
+// Requests are routed through a loopback DecryptProxy so each range is decrypted at its own
+// offset; the file on disk is never left half-decrypted. The proxy also applies the global
+// and per-task bandwidth limits. Without decryptor or limits the task connects directly.
+func (s *GopeedService) CreateDecryptedTask(url string, path string, connections int, headers map[string]string, decryptor []byte) (string, error) {
 	if s.Downloader == nil {
 		return "", fmt.Errorf("downloader not initialized")
 	}
-	return s.Downloader.CreateDirect(buildRequest(url, headers), buildOptions(path, connections))
+	bandwidth := GetBandwidthLimiter()
+	if len(decryptor) == 0 && !bandwidth.Enabled() {
+		return s.Downloader.CreateDirect(buildRequest(url, headers), buildOptions(path, connections))
+	}
+
+	s.mu.Lock()
+	if s.proxy == nil {
+		s.proxy = NewDecryptProxy()
+	}
+	proxy := s.proxy
+	s.mu.Unlock()
+
+	limiter := bandwidth.NewItemLimiter()
+	proxyURL, err := proxy.Register(url, headers, decryptor, limiter)
+	if err != nil {
+		bandwidth.ReleaseItemLimiter(limiter)
+		return "", err
+	}
+	taskID, err := s.Downloader.CreateDirect(buildRequest(proxyURL, nil), buildOptions(path, connections))
+	if err != nil {
+		proxy.Release(proxyURL)
+		bandwidth.ReleaseItemLimiter(limiter)
+		return "", err
+	}
+
+	s.mu.Lock()
+	s.proxyRoutes[taskID] = gopeedProxyRoute{url: proxyURL, limiter: limiter}
+	s.mu.Unlock()
+	return taskID, nil
+}
+
+// releaseProxy drops the proxy route and bandwidth limiter of a task
+func (s *GopeedService) releaseProxy(taskID string) {
+	s.mu.Lock()
+	route, ok := s.proxyRoutes[taskID]
+	delete(s.proxyRoutes, taskID)
+	proxy := s.proxy
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+	if proxy != nil {
+		proxy.Release(route.url)
+	}
+	GetBandwidthLimiter().ReleaseItemLimiter(route.limiter)
 }
 
 func (s *GopeedService) PauseTask(taskID string) error {
@@ -114,6 +182,8 @@ func (s *GopeedService) PauseTask(taskID string) error {
 	return s.Downloader.Pause(&download.TaskFilter{IDs: []string{taskID}})
 }
 
+// ContinueTask resumes a paused task.
+// Proxied tasks whose loopback route is gone return ErrTaskNotResumable and must be recreated.
 func (s *GopeedService) ContinueTask(taskID string) error {
 	if s.Downloader == nil {
 		return fmt.Errorf("downloader not initialized")
@@ -121,9 +191,24 @@ func (s *GopeedService) ContinueTask(taskID string) error {
 	if strings.TrimSpace(taskID) == "" {
 		return fmt.Errorf("task id is empty")
 	}
+	if task := s.Downloader.GetTask(taskID); task != nil && task.Meta != nil && task.Meta.Req != nil && !s.resumable(task.Meta.Req.URL) {
+		return fmt.Errorf("%w: %s", ErrTaskNotResumable, taskID)
+	}
 	return s.Downloader.Continue(&download.TaskFilter{IDs: []string{taskID}})
 }
 
+// resumable reports whether a task requesting reqURL can continue:
+// direct URLs always can, proxy URLs only while still registered with the current proxy.
+func (s *GopeedService) resumable(reqURL string) bool {
+	if !isDecryptProxyURL(reqURL) {
+		return true
+	}
+	s.mu.RLock()
+	proxy := s.proxy
+	s.mu.RUnlock()
+	return proxy != nil && proxy.Registered(reqURL)
+}
+
 // DeleteTask removes a download task
 func (s *GopeedService) DeleteTask(taskID string, removeFiles bool) error {
 	if s.Downloader == nil {
@@ -132,6 +217,7 @@ func (s *GopeedService) DeleteTask(taskID string, removeFiles bool) error {
 	if strings.TrimSpace(taskID) == "" {
 		return nil
 	}
+	defer s.releaseProxy(taskID)
 	filter := &download.TaskFilter{IDs: []string{taskID}}
 	return s.Downloader.Delete(filter, removeFiles)
 }
@@ -230,7 +316,12 @@ func (s *GopeedService) WaitTask(ctx context.Context, taskID string, onProgress
 // DownloadSync downloads a file synchronously (blocking until done)
 // and returns the actual output path used by Gopeed.
 func (s *GopeedService) DownloadSync(ctx context.Context, url string, path string, connections int, headers map[string]string, onProgress func(progress float64, downloaded int64, total int64)) (string, error) {
-	id, err := s.CreateTask(url, path, connections, headers)
+	return s.DownloadSyncDecrypted(ctx, url, path, connections, headers, nil, onProgress)
+}
+
+// DownloadSyncDecrypted is DownloadSync with streaming prefix decryption (see CreateDecryptedTask).
+func (s *GopeedService) DownloadSyncDecrypted(ctx context.Context, url string, path string, connections int, headers map[string]string, decryptor []byte, onProgress func(progress float64, downloaded int64, total int64)) (string, error) {
+	id, err := s.CreateDecryptedTask(url, path, connections, headers, decryptor)
 	if err != nil {
 		return "", fmt.Errorf("failed to create task: %v", err)
 	}
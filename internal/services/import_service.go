@@ -0,0 +1,280 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"wx_channel/internal/database"
+)
+
+// ImportStrategy 导入记录与本地记录冲突时的合并策略
+type ImportStrategy string
+
+const (
+	// ImportStrategySkip 保留本地记录
+	ImportStrategySkip ImportStrategy = "skip"
+	// ImportStrategyOverwrite 用导入的记录覆盖本地记录
+	ImportStrategyOverwrite ImportStrategy = "overwrite"
+	// ImportStrategyNewest 保留更新时间较新的一方
+	ImportStrategyNewest ImportStrategy = "newest"
+)
+
+// ParseImportStrategy 解析合并策略，空字符串视为 skip
+func ParseImportStrategy(value string) (ImportStrategy, error) {
+	switch strategy := ImportStrategy(strings.ToLower(strings.TrimSpace(value))); strategy {
+	case "":
+		return ImportStrategySkip, nil
+	case ImportStrategySkip, ImportStrategyOverwrite, ImportStrategyNewest:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("unsupported import strategy: %s", value)
+	}
+}
+
+// 单条记录的导入动作
+const (
+	ImportActionCreate    = "create"
+	ImportActionOverwrite = "overwrite"
+	ImportActionSkip      = "skip"
+)
+
+// ImportOptions 导入选项
+type ImportOptions struct {
+	Format   ExportFormat
+	Strategy ImportStrategy
+	DryRun   bool
+	// SourceDir 导出机器上的下载目录，FilePath 位于其中时改写到 TargetDir 下
+	SourceDir string
+	TargetDir string
+}
+
+// ImportItem 预览模式下单条记录的处理结果
+type ImportItem struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Action   string `json:"action"`
+	FilePath string `json:"filePath,omitempty"`
+}
+
+// ImportResult 导入报告
+type ImportResult struct {
+	Format   ExportFormat   `json:"format"`
+	Strategy ImportStrategy `json:"strategy"`
+	DryRun   bool           `json:"dryRun"`
+	Total    int            `json:"total"`
+	Created  int            `json:"created"`
+	Updated  int            `json:"updated"`
+	Skipped  int            `json:"skipped"`
+	Invalid  int            `json:"invalid"`
+	Failed   int            `json:"failed"`
+	Rebased  int            `json:"rebased"`
+	Items    []ImportItem   `json:"items,omitempty"`
+	Errors   []string       `json:"errors,omitempty"`
+}
+
+func (r *ImportResult) addError(format string, args ...interface{}) {
+	if len(r.Errors) < maxRecordImportErrors {
+		r.Errors = append(r.Errors, fmt.Sprintf(format, args...))
+	}
+}
+
+// record 记录单条导入的动作，预览模式下同时保留明细
+func (r *ImportResult) record(id, title, action, filePath string) {
+	switch action {
+	case ImportActionCreate:
+		r.Created++
+	case ImportActionOverwrite:
+		r.Updated++
+	default:
+		r.Skipped++
+	}
+	if r.DryRun {
+		r.Items = append(r.Items, ImportItem{ID: id, Title: title, Action: action, FilePath: filePath})
+	}
+}
+
+// ImportService 导入 /api/export/* 导出的浏览历史和下载记录
+type ImportService struct {
+	browseRepo   *database.BrowseHistoryRepository
+	downloadRepo *database.DownloadRecordRepository
+}
+
+// NewImportService 创建一个新的 ImportService
+func NewImportService() *ImportService {
+	return &ImportService{
+		browseRepo:   database.NewBrowseHistoryRepository(),
+		downloadRepo: database.NewDownloadRecordRepository(),
+	}
+}
+
+// DetectImportFormat 根据内容判断导出文件格式
+func DetectImportFormat(data []byte) ExportFormat {
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(data, utf8BOM))
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		return ExportFormatJSON
+	}
+	return ExportFormatCSV
+}
+
+func newImportResult(data []byte, opts *ImportOptions) (*ImportResult, error) {
+	if opts.Format == "" {
+		opts.Format = DetectImportFormat(data)
+	}
+	if opts.Format != ExportFormatJSON && opts.Format != ExportFormatCSV {
+		return nil, fmt.Errorf("unsupported import format: %s", opts.Format)
+	}
+	if opts.Strategy == "" {
+		opts.Strategy = ImportStrategySkip
+	}
+	return &ImportResult{Format: opts.Format, Strategy: opts.Strategy, DryRun: opts.DryRun}, nil
+}
+
+// ImportBrowseHistory 导入浏览历史，按 ID 与本地记录匹配
+func (s *ImportService) ImportBrowseHistory(data []byte, opts ImportOptions) (*ImportResult, error) {
+	result, err := newImportResult(data, &opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []database.BrowseRecord
+	if opts.Format == ExportFormatJSON {
+		records, err = ParseBrowseRecordsFromJSON(bytes.TrimPrefix(data, utf8BOM))
+	} else {
+		records, err = ParseBrowseRecordsFromCSV(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range records {
+		record := &records[i]
+		result.Total++
+		record.ID = strings.TrimSpace(record.ID)
+		if record.ID == "" {
+			result.Invalid++
+			result.addError("record %d: missing id", i+1)
+			continue
+		}
+
+		existing, err := s.browseRepo.GetByID(record.ID)
+		if err != nil {
+			result.Failed++
+			result.addError("record %s: %v", record.ID, err)
+			continue
+		}
+
+		action := ImportActionCreate
+		if existing != nil {
+			action = resolveImportAction(opts.Strategy,
+				importTime(record.UpdatedAt, record.BrowseTime), importTime(existing.UpdatedAt, existing.BrowseTime))
+		}
+		if action != ImportActionSkip && !opts.DryRun {
+			if err := s.browseRepo.Import(record); err != nil {
+				result.Failed++
+				result.addError("record %s: %v", record.ID, err)
+				continue
+			}
+		}
+		result.record(record.ID, record.Title, action, "")
+	}
+	return result, nil
+}
+
+// ImportDownloadRecords 导入下载记录，按 ID 或视频 ID 与本地记录匹配
+func (s *ImportService) ImportDownloadRecords(data []byte, opts ImportOptions) (*ImportResult, error) {
+	result, err := newImportResult(data, &opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []database.DownloadRecord
+	if opts.Format == ExportFormatJSON {
+		records, err = ParseDownloadRecordsFromJSON(bytes.TrimPrefix(data, utf8BOM))
+	} else {
+		records, err = ParseDownloadRecordsFromCSV(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range records {
+		record := &records[i]
+		result.Total++
+		if err := normalizeImportedRecord(record); err != nil {
+			result.Invalid++
+			result.addError("record %d: %v", i+1, err)
+			continue
+		}
+		if path, ok := rebaseFilePath(record.FilePath, opts.SourceDir, opts.TargetDir); ok {
+			record.FilePath = path
+			result.Rebased++
+		}
+
+		existing, err := s.downloadRepo.GetByID(record.ID)
+		if err == nil && existing == nil {
+			existing, err = s.downloadRepo.GetByVideoID(record.VideoID)
+		}
+		if err != nil {
+			result.Failed++
+			result.addError("record %s: %v", record.ID, err)
+			continue
+		}
+
+		action := ImportActionCreate
+		if existing != nil {
+			action = resolveImportAction(opts.Strategy,
+				importTime(record.UpdatedAt, record.DownloadTime), importTime(existing.UpdatedAt, existing.DownloadTime))
+			// 按视频 ID 匹配到的记录沿用本地 ID，避免同一视频出现两条记录
+			record.ID = existing.ID
+		}
+		if action != ImportActionSkip && !opts.DryRun {
+			if err := s.downloadRepo.Import(record); err != nil {
+				result.Failed++
+				result.addError("record %s: %v", record.ID, err)
+				continue
+			}
+		}
+		result.record(record.ID, record.Title, action, record.FilePath)
+	}
+	return result, nil
+}
+
+// resolveImportAction 根据合并策略决定如何处理已存在的记录
+func resolveImportAction(strategy ImportStrategy, imported, local time.Time) string {
+	switch strategy {
+	case ImportStrategyOverwrite:
+		return ImportActionOverwrite
+	case ImportStrategyNewest:
+		if imported.After(local) {
+			return ImportActionOverwrite
+		}
+	}
+	return ImportActionSkip
+}
+
+// importTime 返回记录的更新时间，缺失时（如旧版导出）使用 fallback
+func importTime(updatedAt, fallback time.Time) time.Time {
+	if updatedAt.IsZero() {
+		return fallback
+	}
+	return updatedAt
+}
+
+// rebaseFilePath 将位于 sourceDir 下的路径改写到 targetDir 下
+// 导出机器可能是 Windows，比较时统一分隔符并忽略大小写
+func rebaseFilePath(path, sourceDir, targetDir string) (string, bool) {
+	if path == "" || sourceDir == "" || targetDir == "" {
+		return "", false
+	}
+	normalized := strings.ReplaceAll(path, `\`, "/")
+	prefix := strings.TrimRight(strings.ReplaceAll(sourceDir, `\`, "/"), "/")
+	if len(normalized) <= len(prefix) || normalized[len(prefix)] != '/' ||
+		!strings.EqualFold(normalized[:len(prefix)], prefix) {
+		return "", false
+	}
+	rel := strings.TrimLeft(normalized[len(prefix):], "/")
+	return filepath.Join(targetDir, filepath.FromSlash(rel)), true
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"wx_channel/internal/database"
+)
+
+func setupImportTest(t *testing.T) *ImportService {
+	t.Helper()
+	if err := database.Initialize(&database.Config{DBPath: filepath.Join(t.TempDir(), "records.db")}); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	return NewImportService()
+}
+
+func TestImportService_DownloadRecordsRoundTrip(t *testing.T) {
+	for _, format := range []ExportFormat{ExportFormatJSON, ExportFormatCSV} {
+		t.Run(string(format), func(t *testing.T) {
+			svc := setupImportTest(t)
+			repo := database.NewDownloadRecordRepository()
+
+			downloadTime := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+			if err := repo.Create(&database.DownloadRecord{
+				ID: "d1", VideoID: "v1", Title: "标题, 带逗号", Author: "作者", Duration: 150000, FileSize: 10 << 20,
+				FilePath: `D:\wx_channel\downloads\作者\v1.mp4`, Format: "mp4", Status: database.DownloadStatusCompleted,
+				DownloadTime: downloadTime, LikeCount: 7,
+			}); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			exported, err := NewExportService().ExportDownloadRecords(format, nil)
+			if err != nil {
+				t.Fatalf("Export: %v", err)
+			}
+			if err := repo.Clear(); err != nil {
+				t.Fatalf("Clear: %v", err)
+			}
+
+			targetDir := t.TempDir()
+			result, err := svc.ImportDownloadRecords(exported.Data, ImportOptions{
+				SourceDir: `d:/wx_channel/downloads/`,
+				TargetDir: targetDir,
+			})
+			if err != nil {
+				t.Fatalf("Import: %v", err)
+			}
+			if result.Format != format || result.Total != 1 || result.Created != 1 || result.Rebased != 1 {
+				t.Fatalf("unexpected result: %+v", result)
+			}
+
+			got, _ := repo.GetByID("d1")
+			if got == nil {
+				t.Fatal("expected record to be imported")
+			}
+			if got.VideoID != "v1" || got.Title != "标题, 带逗号" || got.Duration != 150000 || got.FileSize != 10<<20 ||
+				got.LikeCount != 7 || !got.DownloadTime.Equal(downloadTime) {
+				t.Fatalf("unexpected record: %+v", got)
+			}
+			if want := filepath.Join(targetDir, "作者", "v1.mp4"); got.FilePath != want {
+				t.Fatalf("FilePath = %q, want %q", got.FilePath, want)
+			}
+		})
+	}
+}
+
+func TestImportService_Strategies(t *testing.T) {
+	svc := setupImportTest(t)
+	repo := database.NewBrowseHistoryRepository()
+
+	older := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+	local := &database.BrowseRecord{ID: "b1", Title: "本地", BrowseTime: older, CreatedAt: older, UpdatedAt: newer}
+	if err := repo.Import(local); err != nil {
+		t.Fatalf("Import local: %v", err)
+	}
+
+	data := []byte(`[
+		{"id": "b1", "title": "导入", "browseTime": "2024-01-01T00:00:00Z", "updatedAt": "2024-01-01T00:30:00Z"},
+		{"id": "b2", "title": "新记录", "browseTime": "2024-01-01T00:00:00Z"},
+		{"id": "", "title": "无效"}
+	]`)
+
+	tests := []struct {
+		strategy ImportStrategy
+		title    string
+		updated  int
+		skipped  int
+	}{
+		{ImportStrategySkip, "本地", 0, 1},
+		{ImportStrategyNewest, "本地", 0, 1},
+		{ImportStrategyOverwrite, "导入", 1, 0},
+	}
+	for _, tt := range tests {
+		// 预览模式不写入数据库
+		preview, err := svc.ImportBrowseHistory(data, ImportOptions{Strategy: tt.strategy, DryRun: true})
+		if err != nil {
+			t.Fatalf("%s dry run: %v", tt.strategy, err)
+		}
+		if len(preview.Items) != 2 || preview.Updated != tt.updated || preview.Skipped != tt.skipped || preview.Invalid != 1 {
+			t.Fatalf("%s dry run: unexpected result: %+v", tt.strategy, preview)
+		}
+		if got, _ := repo.GetByID("b2"); got != nil {
+			t.Fatalf("%s dry run wrote record b2", tt.strategy)
+		}
+
+		result, err := svc.ImportBrowseHistory(data, ImportOptions{Strategy: tt.strategy})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.strategy, err)
+		}
+		if result.Created != 1 || result.Updated != tt.updated || len(result.Items) != 0 {
+			t.Fatalf("%s: unexpected result: %+v", tt.strategy, result)
+		}
+		if got, _ := repo.GetByID("b1"); got == nil || got.Title != tt.title {
+			t.Fatalf("%s: unexpected record: %+v", tt.strategy, got)
+		}
+		if err := repo.Delete("b2"); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+	}
+
+	// 导入的记录更新时间较新时覆盖本地记录
+	result, err := svc.ImportBrowseHistory([]byte(`[{"id": "b1", "title": "更新", "updatedAt": "2030-01-01T00:00:00Z"}]`),
+		ImportOptions{Strategy: ImportStrategyNewest})
+	if err != nil || result.Updated != 1 {
+		t.Fatalf("newest: %+v, %v", result, err)
+	}
+	if got, _ := repo.GetByID("b1"); got == nil || got.Title != "更新" {
+		t.Fatalf("newest: unexpected record: %+v", got)
+	}
+}
+
+func TestImportService_MatchesDownloadByVideoID(t *testing.T) {
+	svc := setupImportTest(t)
+	repo := database.NewDownloadRecordRepository()
+	if err := repo.Create(&database.DownloadRecord{ID: "local", VideoID: "v1", Title: "本地", DownloadTime: time.Now()}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	result, err := svc.ImportDownloadRecords([]byte(`[{"id": "remote", "videoId": "v1", "title": "远端"}]`),
+		ImportOptions{Strategy: ImportStrategyOverwrite})
+	if err != nil || result.Updated != 1 {
+		t.Fatalf("Import: %+v, %v", result, err)
+	}
+	if count, _ := repo.Count(); count != 1 {
+		t.Fatalf("count = %d, want 1", count)
+	}
+	if got, _ := repo.GetByID("local"); got == nil || got.Title != "远端" {
+		t.Fatalf("unexpected record: %+v", got)
+	}
+}
+
+func TestRebaseFilePath(t *testing.T) {
+	target := filepath.Join("data", "downloads")
+	tests := []struct {
+		path, source string
+		want         string
+		ok           bool
+	}{
+		{`C:\Users\a\downloads\作者\v.mp4`, `C:\Users\a\downloads`, filepath.Join(target, "作者", "v.mp4"), true},
+		{"/home/a/downloads/v.mp4", "/home/a/downloads/", filepath.Join(target, "v.mp4"), true},
+		{"/home/a/downloads2/v.mp4", "/home/a/downloads", "", false},
+		{"/other/v.mp4", "/home/a/downloads", "", false},
+		{"/home/a/downloads/v.mp4", "", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := rebaseFilePath(tt.path, tt.source, target)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("rebaseFilePath(%q, %q) = %q, %v; want %q, %v", tt.path, tt.source, got, ok, tt.want, tt.ok)
+		}
+	}
+}
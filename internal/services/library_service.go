@@ -0,0 +1,442 @@
+package services
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+	"unicode"
+
+	"wx_channel/internal/database"
+	"wx_channel/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// libraryVideoExts 视为视频文件的扩展名，其余文件（临时文件、封面等）不参与同步
+var libraryVideoExts = map[string]bool{
+	".mp4": true, ".m4v": true, ".mov": true, ".webm": true, ".mkv": true, ".flv": true,
+}
+
+// librarySkipDirs 下载目录中不保存视频的子目录，以 . 开头的目录同样跳过
+var librarySkipDirs = map[string]bool{
+	"cached_js":      true,
+	"page_snapshots": true,
+}
+
+// 记录与文件的匹配方式
+const (
+	LibraryMatchPath    = "path"
+	LibraryMatchHash    = "hash"
+	LibraryMatchVideoID = "video_id"
+)
+
+// minGuessedVideoIDLength 从文件名推测视频 ID 时要求的最少数字位数，避免把日期当作 ID
+const minGuessedVideoIDLength = 15
+
+// LibraryRelink 文件已移动（或重新出现）的记录
+type LibraryRelink struct {
+	RecordID  string `json:"recordId"`
+	VideoID   string `json:"videoId"`
+	Title     string `json:"title"`
+	OldPath   string `json:"oldPath"`
+	NewPath   string `json:"newPath"`
+	MatchedBy string `json:"matchedBy"`
+
+	fingerprint string
+}
+
+// LibraryMissing 找不到文件的记录
+type LibraryMissing struct {
+	RecordID string `json:"recordId"`
+	VideoID  string `json:"videoId"`
+	Title    string `json:"title"`
+	FilePath string `json:"filePath"`
+	Status   string `json:"status"` // missing 表示之前已标记过
+}
+
+// LibraryOrphan 没有对应记录的视频文件
+type LibraryOrphan struct {
+	FilePath string    `json:"filePath"`
+	FileSize int64     `json:"fileSize"`
+	ModTime  time.Time `json:"modTime"`
+	VideoID  string    `json:"videoId,omitempty"` // 从文件名推测的视频 ID
+	Author   string    `json:"author,omitempty"`  // 作者文件夹名
+
+	fingerprint string
+}
+
+// LibraryReport 下载目录与下载记录的同步报告
+type LibraryReport struct {
+	Dir          string           `json:"dir"`
+	ScannedFiles int              `json:"scannedFiles"`
+	Matched      int              `json:"matched"`
+	Relinks      []LibraryRelink  `json:"relinks"`
+	Missing      []LibraryMissing `json:"missing"`
+	Orphans      []LibraryOrphan  `json:"orphans"`
+	Errors       []string         `json:"errors,omitempty"`
+	ScannedAt    time.Time        `json:"scannedAt"`
+}
+
+// LibraryFixOptions 要执行的修复动作
+type LibraryFixOptions struct {
+	Relink       bool `json:"relink"`       // 更新已移动文件的路径，恢复已标记丢失的记录
+	MarkMissing  bool `json:"markMissing"`  // 将找不到文件的记录标记为 missing
+	AdoptOrphans bool `json:"adoptOrphans"` // 为没有记录的文件创建下载记录
+}
+
+// LibraryFixResult 修复结果，Report 为修复前的扫描报告
+type LibraryFixResult struct {
+	Report        *LibraryReport `json:"report"`
+	Relinked      int            `json:"relinked"`
+	MarkedMissing int            `json:"markedMissing"`
+	Adopted       int            `json:"adopted"`
+	AdoptedIDs    []string       `json:"adoptedIds"`
+	Errors        []string       `json:"errors,omitempty"`
+}
+
+// LibraryService 扫描下载目录，使下载记录与磁盘上的文件保持一致
+// 记录依次按路径、内容指纹、文件名中的视频 ID 与文件匹配
+type LibraryService struct {
+	repo *database.DownloadRecordRepository
+}
+
+// NewLibraryService 创建一个新的 LibraryService
+func NewLibraryService() *LibraryService {
+	return &LibraryService{
+		repo: database.NewDownloadRecordRepository(),
+	}
+}
+
+// libraryFile 扫描到的视频文件
+type libraryFile struct {
+	path        string
+	size        int64
+	modTime     time.Time
+	matched     bool
+	fingerprint string
+	hashed      bool
+}
+
+// getFingerprint 按需计算文件指纹，失败时返回空字符串
+func (f *libraryFile) getFingerprint() string {
+	if !f.hashed {
+		f.hashed = true
+		f.fingerprint, _ = utils.FileFingerprint(f.path)
+	}
+	return f.fingerprint
+}
+
+// Scan 扫描下载目录并生成同步报告，不修改任何记录
+func (s *LibraryService) Scan(dir string) (*LibraryReport, error) {
+	report := &LibraryReport{
+		Dir:       dir,
+		Relinks:   []LibraryRelink{},
+		Missing:   []LibraryMissing{},
+		Orphans:   []LibraryOrphan{},
+		ScannedAt: time.Now(),
+	}
+
+	files, err := s.walk(dir, report)
+	if err != nil {
+		return nil, err
+	}
+	report.ScannedFiles = len(files)
+
+	records, err := s.repo.ListLibraryRecords()
+	if err != nil {
+		return nil, err
+	}
+
+	// 第一轮：按路径匹配，路径仍然有效的记录无需处理
+	byPath := make(map[string]*libraryFile, len(files))
+	for _, f := range files {
+		byPath[libraryPathKey(f.path)] = f
+	}
+	var stale []database.DownloadRecord
+	for _, record := range records {
+		present := false
+		if f := byPath[libraryPathKey(record.FilePath)]; f != nil {
+			f.matched = true
+			present = true
+		} else if _, err := os.Stat(record.FilePath); err == nil {
+			// 目录外或非视频扩展名的文件
+			present = true
+		}
+		switch {
+		case !present:
+			stale = append(stale, record)
+		case record.Status == database.DownloadStatusMissing:
+			report.Relinks = append(report.Relinks, newLibraryRelink(record, record.FilePath, LibraryMatchPath, ""))
+		default:
+			report.Matched++
+		}
+	}
+
+	// 第二轮：按内容指纹匹配，记录有完整哈希时再校验一次
+	if len(stale) > 0 {
+		byFingerprint := make(map[string][]*libraryFile)
+		for _, f := range files {
+			if !f.matched && f.getFingerprint() != "" {
+				byFingerprint[f.fingerprint] = append(byFingerprint[f.fingerprint], f)
+			}
+		}
+
+		remaining := stale[:0]
+		for _, record := range stale {
+			if record.Fingerprint == "" {
+				remaining = append(remaining, record)
+				continue
+			}
+			if f := matchByFingerprint(record, byFingerprint[record.Fingerprint]); f != nil {
+				f.matched = true
+				report.Relinks = append(report.Relinks, newLibraryRelink(record, f.path, LibraryMatchHash, f.fingerprint))
+				continue
+			}
+			remaining = append(remaining, record)
+		}
+		stale = remaining
+	}
+
+	// 第三轮：按文件名中的视频 ID 匹配
+	if len(stale) > 0 {
+		byToken := make(map[string][]*libraryFile)
+		for _, f := range files {
+			if f.matched {
+				continue
+			}
+			for _, token := range filenameTokens(f.path) {
+				byToken[token] = append(byToken[token], f)
+			}
+		}
+
+		for _, record := range stale {
+			var match *libraryFile
+			if record.VideoID != "" {
+				for _, f := range byToken[record.VideoID] {
+					if !f.matched {
+						match = f
+						break
+					}
+				}
+			}
+			if match != nil {
+				match.matched = true
+				report.Relinks = append(report.Relinks, newLibraryRelink(record, match.path, LibraryMatchVideoID, match.getFingerprint()))
+				continue
+			}
+			report.Missing = append(report.Missing, LibraryMissing{
+				RecordID: record.ID,
+				VideoID:  record.VideoID,
+				Title:    record.Title,
+				FilePath: record.FilePath,
+				Status:   record.Status,
+			})
+		}
+	}
+
+	for _, f := range files {
+		if f.matched {
+			continue
+		}
+		report.Orphans = append(report.Orphans, LibraryOrphan{
+			FilePath:    f.path,
+			FileSize:    f.size,
+			ModTime:     f.modTime,
+			VideoID:     guessVideoID(f.path),
+			Author:      authorFolder(dir, f.path),
+			fingerprint: f.getFingerprint(),
+		})
+	}
+
+	return report, nil
+}
+
+// Fix 重新扫描下载目录并执行选中的修复动作
+func (s *LibraryService) Fix(dir string, opts LibraryFixOptions) (*LibraryFixResult, error) {
+	report, err := s.Scan(dir)
+	if err != nil {
+		return nil, err
+	}
+	result := &LibraryFixResult{Report: report, AdoptedIDs: []string{}}
+
+	if opts.Relink {
+		for _, relink := range report.Relinks {
+			if err := s.repo.UpdateLocation(relink.RecordID, relink.NewPath, database.DownloadStatusCompleted); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", relink.RecordID, err))
+				continue
+			}
+			// 按视频 ID 匹配到的可能是重新下载的文件，刷新指纹并清空旧哈希
+			if relink.MatchedBy == LibraryMatchVideoID {
+				_ = s.repo.UpdateHashes(relink.RecordID, relink.fingerprint, "")
+			}
+			result.Relinked++
+		}
+	}
+
+	if opts.MarkMissing {
+		for _, missing := range report.Missing {
+			if missing.Status == database.DownloadStatusMissing {
+				continue
+			}
+			if err := s.repo.UpdateLocation(missing.RecordID, missing.FilePath, database.DownloadStatusMissing); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", missing.RecordID, err))
+				continue
+			}
+			result.MarkedMissing++
+		}
+	}
+
+	if opts.AdoptOrphans {
+		for _, orphan := range report.Orphans {
+			record := orphanToRecord(orphan)
+			if err := s.repo.Create(record); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", orphan.FilePath, err))
+				continue
+			}
+			result.Adopted++
+			result.AdoptedIDs = append(result.AdoptedIDs, record.ID)
+		}
+	}
+
+	utils.Info("[Library] 同步下载目录完成: 更新路径 %d, 标记丢失 %d, 新增记录 %d",
+		result.Relinked, result.MarkedMissing, result.Adopted)
+	return result, nil
+}
+
+// walk 收集下载目录中的视频文件，无法读取的子目录记录到报告中并跳过
+func (s *LibraryService) walk(dir string, report *LibraryReport) ([]*libraryFile, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("failed to stat downloads dir: %w", err)
+	}
+
+	var files []*libraryFile
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir {
+				return err
+			}
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", path, err))
+			return nil
+		}
+		if d.IsDir() {
+			if path != dir && (strings.HasPrefix(d.Name(), ".") || librarySkipDirs[d.Name()]) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !libraryVideoExts[strings.ToLower(filepath.Ext(d.Name()))] {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", path, err))
+			return nil
+		}
+		files = append(files, &libraryFile{path: path, size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk downloads dir: %w", err)
+	}
+	return files, nil
+}
+
+func newLibraryRelink(record database.DownloadRecord, newPath, matchedBy, fingerprint string) LibraryRelink {
+	return LibraryRelink{
+		RecordID:    record.ID,
+		VideoID:     record.VideoID,
+		Title:       record.Title,
+		OldPath:     record.FilePath,
+		NewPath:     newPath,
+		MatchedBy:   matchedBy,
+		fingerprint: fingerprint,
+	}
+}
+
+// matchByFingerprint 在指纹相同的文件中找到与记录内容一致的第一个
+func matchByFingerprint(record database.DownloadRecord, candidates []*libraryFile) *libraryFile {
+	for _, f := range candidates {
+		if f.matched {
+			continue
+		}
+		if record.ContentHash != "" {
+			if hash, err := utils.FileSHA256(f.path); err != nil || hash != record.ContentHash {
+				continue
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+// libraryPathKey 返回用于比较的路径，Windows 下忽略大小写
+func libraryPathKey(path string) string {
+	path = filepath.Clean(path)
+	if runtime.GOOS == "windows" {
+		path = strings.ToLower(path)
+	}
+	return path
+}
+
+// filenameTokens 将文件名（不含扩展名）按非字母数字字符拆分
+func filenameTokens(path string) []string {
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return strings.FieldsFunc(base, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// guessVideoID 从文件名中取最后一个足够长的纯数字片段作为视频 ID
+func guessVideoID(path string) string {
+	tokens := filenameTokens(path)
+	for i := len(tokens) - 1; i >= 0; i-- {
+		token := tokens[i]
+		if len(token) >= minGuessedVideoIDLength && strings.IndexFunc(token, func(r rune) bool { return r < '0' || r > '9' }) < 0 {
+			return token
+		}
+	}
+	return ""
+}
+
+// authorFolder 返回文件所在的作者文件夹名，直接位于下载目录下时返回空字符串
+func authorFolder(dir, path string) string {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	if len(parts) < 2 || parts[0] == ".." {
+		return ""
+	}
+	return parts[0]
+}
+
+// orphanToRecord 为孤立文件生成下载记录
+func orphanToRecord(orphan LibraryOrphan) *database.DownloadRecord {
+	id := uuid.New().String()
+	videoID := orphan.VideoID
+	if videoID == "" {
+		videoID = id
+	}
+	ext := filepath.Ext(orphan.FilePath)
+	title := strings.TrimSuffix(filepath.Base(orphan.FilePath), ext)
+	if orphan.VideoID != "" {
+		title = strings.TrimSpace(strings.TrimSuffix(title, "_"+orphan.VideoID))
+	}
+	return &database.DownloadRecord{
+		ID:           id,
+		VideoID:      videoID,
+		Title:        title,
+		Author:       orphan.Author,
+		FileSize:     orphan.FileSize,
+		FilePath:     orphan.FilePath,
+		Format:       strings.TrimPrefix(strings.ToLower(ext), "."),
+		Status:       database.DownloadStatusCompleted,
+		DownloadTime: orphan.ModTime,
+		Fingerprint:  orphan.fingerprint,
+	}
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"wx_channel/internal/database"
+	"wx_channel/internal/utils"
+)
+
+func setupLibraryTest(t *testing.T) (*LibraryService, string) {
+	t.Helper()
+	if err := database.Initialize(&database.Config{DBPath: filepath.Join(t.TempDir(), "records.db")}); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	return NewLibraryService(), t.TempDir()
+}
+
+func writeLibraryFile(t *testing.T, path string, data []byte) string {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+	return path
+}
+
+func TestLibraryService_ScanAndFix(t *testing.T) {
+	svc, dir := setupLibraryTest(t)
+	repo := database.NewDownloadRecordRepository()
+
+	// 路径有效的记录
+	present := writeLibraryFile(t, filepath.Join(dir, "作者A", "present.mp4"), []byte("present"))
+	// 已被移动、内容不变的记录
+	movedData := bytes.Repeat([]byte("moved clip "), 10000)
+	moved := writeLibraryFile(t, filepath.Join(dir, "作者B", "renamed.mp4"), movedData)
+	movedFingerprint, _ := utils.FileFingerprint(moved)
+	movedHash, _ := utils.FileSHA256(moved)
+	// 文件名中带视频 ID 的记录
+	byID := writeLibraryFile(t, filepath.Join(dir, "作者C", "新标题_14123456789012345678.mp4"), []byte("by id"))
+	// 没有记录的文件，以及不参与同步的文件
+	orphan := writeLibraryFile(t, filepath.Join(dir, "作者D", "孤立视频_14999999999999999999.mp4"), []byte("orphan"))
+	writeLibraryFile(t, filepath.Join(dir, ".uploads", "u1", "000001.part"), []byte("part"))
+	writeLibraryFile(t, filepath.Join(dir, "作者A", "cover.jpg"), []byte("jpg"))
+
+	records := []database.DownloadRecord{
+		{ID: "present", VideoID: "v-present", FilePath: present},
+		{ID: "moved", VideoID: "v-moved", FilePath: filepath.Join(dir, "作者B", "old.mp4"), Fingerprint: movedFingerprint, ContentHash: movedHash},
+		{ID: "by-id", VideoID: "14123456789012345678", FilePath: filepath.Join(dir, "old", "旧标题.mp4")},
+		{ID: "missing", VideoID: "v-missing", FilePath: filepath.Join(dir, "gone.mp4")},
+		{ID: "failed", VideoID: "v-failed", FilePath: filepath.Join(dir, "failed.mp4"), Status: database.DownloadStatusFailed},
+	}
+	for i := range records {
+		if records[i].Status == "" {
+			records[i].Status = database.DownloadStatusCompleted
+		}
+		records[i].DownloadTime = time.Now()
+		if err := repo.Create(&records[i]); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	report, err := svc.Scan(dir)
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if report.ScannedFiles != 4 || report.Matched != 1 || len(report.Relinks) != 2 ||
+		len(report.Missing) != 1 || len(report.Orphans) != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	relinks := map[string]LibraryRelink{}
+	for _, relink := range report.Relinks {
+		relinks[relink.RecordID] = relink
+	}
+	if relinks["moved"].NewPath != moved || relinks["moved"].MatchedBy != LibraryMatchHash {
+		t.Fatalf("unexpected moved relink: %+v", relinks["moved"])
+	}
+	if relinks["by-id"].NewPath != byID || relinks["by-id"].MatchedBy != LibraryMatchVideoID {
+		t.Fatalf("unexpected video id relink: %+v", relinks["by-id"])
+	}
+	if report.Missing[0].RecordID != "missing" {
+		t.Fatalf("unexpected missing: %+v", report.Missing)
+	}
+	if got := report.Orphans[0]; got.FilePath != orphan || got.VideoID != "14999999999999999999" || got.Author != "作者D" {
+		t.Fatalf("unexpected orphan: %+v", got)
+	}
+
+	result, err := svc.Fix(dir, LibraryFixOptions{Relink: true, MarkMissing: true, AdoptOrphans: true})
+	if err != nil {
+		t.Fatalf("Fix: %v", err)
+	}
+	if result.Relinked != 2 || result.MarkedMissing != 1 || result.Adopted != 1 || len(result.Errors) != 0 {
+		t.Fatalf("unexpected fix result: %+v", result)
+	}
+	if got, _ := repo.GetByID("moved"); got == nil || got.FilePath != moved {
+		t.Fatalf("unexpected moved record: %+v", got)
+	}
+	if got, _ := repo.GetByID("missing"); got == nil || got.Status != database.DownloadStatusMissing {
+		t.Fatalf("unexpected missing record: %+v", got)
+	}
+	adopted, _ := repo.GetByID(result.AdoptedIDs[0])
+	if adopted == nil || adopted.VideoID != "14999999999999999999" || adopted.Title != "孤立视频" ||
+		adopted.Author != "作者D" || adopted.Format != "mp4" || adopted.FileSize != int64(len("orphan")) {
+		t.Fatalf("unexpected adopted record: %+v", adopted)
+	}
+
+	// 修复后再次扫描没有需要处理的项目，已标记丢失的记录仍然报告
+	report, err = svc.Scan(dir)
+	if err != nil {
+		t.Fatalf("Scan again: %v", err)
+	}
+	if report.Matched != 4 || len(report.Relinks) != 0 || len(report.Orphans) != 0 ||
+		len(report.Missing) != 1 || report.Missing[0].Status != database.DownloadStatusMissing {
+		t.Fatalf("unexpected report after fix: %+v", report)
+	}
+
+	// 丢失的文件重新出现后可以恢复
+	writeLibraryFile(t, filepath.Join(dir, "gone.mp4"), []byte("back"))
+	result, err = svc.Fix(dir, LibraryFixOptions{Relink: true})
+	if err != nil || result.Relinked != 1 {
+		t.Fatalf("Fix restore: %+v, %v", result, err)
+	}
+	if got, _ := repo.GetByID("missing"); got == nil || got.Status != database.DownloadStatusCompleted {
+		t.Fatalf("unexpected restored record: %+v", got)
+	}
+}
+
+func TestGuessVideoID(t *testing.T) {
+	tests := map[string]string{
+		"标题_14123456789012345678.mp4": "14123456789012345678",
+		"2024-05-01_标题.mp4":           "",
+		"video_14123456789012345678":  "14123456789012345678",
+		"plain.mp4":                   "",
+	}
+	for name, want := range tests {
+		if got := guessVideoID(name); got != want {
+			t.Errorf("guessVideoID(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
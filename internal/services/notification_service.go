@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"wx_channel/internal/database"
+	"wx_channel/internal/utils"
+)
+
+// notificationTimeout 单次通知（所有渠道）的超时时间
+const notificationTimeout = 30 * time.Second
+
+// NotificationService 按监控目标的通知配置，将雷达和下载事件发送到各通知渠道
+type NotificationService struct {
+	radar  *database.RadarRepository
+	client *http.Client
+	wg     sync.WaitGroup
+}
+
+var (
+	notificationService     *NotificationService
+	notificationServiceOnce sync.Once
+)
+
+// GetNotificationService 返回全局通知服务
+func GetNotificationService() *NotificationService {
+	notificationServiceOnce.Do(func() {
+		notificationService = NewNotificationService()
+	})
+	return notificationService
+}
+
+// NewNotificationService 创建一个新的通知服务
+func NewNotificationService() *NotificationService {
+	return &NotificationService{
+		radar:  database.NewRadarRepository(),
+		client: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// NotifyNewVideos 通知监控目标发布了新视频
+func (s *NotificationService) NotifyNewVideos(target database.RadarTarget, videos []database.RadarVideoSummary) {
+	if len(videos) == 0 || !target.Notify.Wants(database.NotifyEventNewVideo) {
+		return
+	}
+	s.dispatch(target.Notify, NotificationEvent{
+		Type:       database.NotifyEventNewVideo,
+		Time:       time.Now(),
+		TargetID:   target.ID,
+		AuthorName: target.AuthorName,
+		Username:   target.Username,
+		Videos:     videos,
+	})
+}
+
+// NotifyDownloadComplete 通知队列项目下载完成
+func (s *NotificationService) NotifyDownloadComplete(item *database.QueueItem, filePath string) {
+	s.notifyQueueItem(item, NotificationEvent{
+		Type:     database.NotifyEventDownloadComplete,
+		FilePath: filePath,
+	})
+}
+
+// NotifyDownloadFailed 通知队列项目在重试次数用尽后仍然失败
+func (s *NotificationService) NotifyDownloadFailed(item *database.QueueItem, errorMessage string) {
+	s.notifyQueueItem(item, NotificationEvent{
+		Type:  database.NotifyEventDownloadFailed,
+		Error: errorMessage,
+	})
+}
+
+// notifyQueueItem 按作者找到对应的监控目标，目标订阅了该事件时发送通知
+func (s *NotificationService) notifyQueueItem(item *database.QueueItem, event NotificationEvent) {
+	if item == nil || item.Author == "" || database.GetDB() == nil {
+		return
+	}
+	target, err := s.radar.GetByAuthorName(item.Author)
+	if err != nil || target == nil || !target.Notify.Wants(event.Type) {
+		return
+	}
+
+	event.Time = time.Now()
+	event.TargetID = target.ID
+	event.AuthorName = target.AuthorName
+	event.Username = target.Username
+	event.VideoID = item.VideoID
+	event.Title = item.Title
+	event.RetryCount = item.RetryCount
+	s.dispatch(target.Notify, event)
+}
+
+// dispatch 在后台发送通知，不阻塞雷达和下载流程
+func (s *NotificationService) dispatch(config database.RadarNotifyConfig, event NotificationEvent) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), notificationTimeout)
+		defer cancel()
+		if err := s.Deliver(ctx, config, event); err != nil {
+			utils.LogWarn("[Notify] 发送 %s 通知失败: %v", event.Type, err)
+		}
+	}()
+}
+
+// Deliver 同步发送通知到所有渠道，返回各渠道的错误
+func (s *NotificationService) Deliver(ctx context.Context, config database.RadarNotifyConfig, event NotificationEvent) error {
+	var errs []error
+	for i, channel := range config.Channels {
+		sink, err := newNotificationSink(channel, s.client)
+		if err == nil {
+			err = sink.Send(ctx, event)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("channel %d (%s): %w", i+1, channel.Type, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Wait 等待所有后台通知发送完成
+func (s *NotificationService) Wait() {
+	s.wg.Wait()
+}
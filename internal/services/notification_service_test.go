@@ -0,0 +1,433 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"wx_channel/internal/database"
+)
+
+// recordingServer 记录收到的请求
+type recordingServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	bodies   []string
+	queries  []string
+	response string
+}
+
+func newRecordingServer(t *testing.T, response string) *recordingServer {
+	t.Helper()
+	rs := &recordingServer{response: response}
+	rs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rs.mu.Lock()
+		rs.bodies = append(rs.bodies, string(body))
+		rs.queries = append(rs.queries, r.URL.RawQuery)
+		rs.mu.Unlock()
+		_, _ = io.WriteString(w, rs.response)
+	}))
+	t.Cleanup(rs.Close)
+	return rs
+}
+
+func (rs *recordingServer) received() []string {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return append([]string(nil), rs.bodies...)
+}
+
+// fakeSMTPServer 最简 SMTP 服务，记录收到的邮件内容
+type fakeSMTPServer struct {
+	listener net.Listener
+	mu       sync.Mutex
+	rcpts    []string
+	data     string
+}
+
+func newFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &fakeSMTPServer{listener: listener}
+	t.Cleanup(func() { listener.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *fakeSMTPServer) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = io.WriteString(conn, line+"\r\n") }
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL FROM"):
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO"):
+			s.mu.Lock()
+			s.rcpts = append(s.rcpts, strings.TrimSpace(line[len("RCPT TO:"):]))
+			s.mu.Unlock()
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			s.mu.Lock()
+			s.data = data.String()
+			s.mu.Unlock()
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func (s *fakeSMTPServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestNotificationService_DeliverToAllChannels(t *testing.T) {
+	webhook := newRecordingServer(t, "ok")
+	wecom := newRecordingServer(t, `{"errcode":0,"errmsg":"ok"}`)
+	dingtalk := newRecordingServer(t, `{"errcode":0,"errmsg":"ok"}`)
+	mail := newFakeSMTPServer(t)
+
+	config := database.RadarNotifyConfig{
+		Events: []string{database.NotifyEventNewVideo},
+		Channels: []database.NotifyChannel{
+			{Type: database.NotifyChannelWebhook, URL: webhook.URL, Body: `{"author": {{json .AuthorName}}, "count": {{len .Videos}}, "text": {{json .Text}}}`},
+			{Type: database.NotifyChannelWeCom, URL: wecom.URL},
+			{Type: database.NotifyChannelDingTalk, URL: dingtalk.URL + "?access_token=t", Secret: "SEC"},
+			{Type: database.NotifyChannelSMTP, Host: "127.0.0.1", Port: mail.port(), From: "bot@example.com", To: []string{"me@example.com"}},
+		},
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	event := NotificationEvent{
+		Type:       database.NotifyEventNewVideo,
+		Time:       time.Now(),
+		AuthorName: "作者\"A\"",
+		Videos:     []database.RadarVideoSummary{{VideoID: "v1", Title: "第一个视频"}, {VideoID: "v2", Title: "第二个视频"}},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := NewNotificationService().Deliver(ctx, config, event); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+
+	// 通用 webhook 按模板渲染，且 json 函数正确转义
+	var payload struct {
+		Author string `json:"author"`
+		Count  int    `json:"count"`
+		Text   string `json:"text"`
+	}
+	bodies := webhook.received()
+	if len(bodies) != 1 || json.Unmarshal([]byte(bodies[0]), &payload) != nil {
+		t.Fatalf("unexpected webhook body: %v", bodies)
+	}
+	if payload.Author != "作者\"A\"" || payload.Count != 2 || !strings.Contains(payload.Text, "第二个视频") {
+		t.Fatalf("unexpected webhook payload: %+v", payload)
+	}
+
+	// 机器人使用文本消息格式
+	var robot struct {
+		MsgType string `json:"msgtype"`
+		Text    struct {
+			Content string `json:"content"`
+		} `json:"text"`
+	}
+	if bodies := wecom.received(); len(bodies) != 1 || json.Unmarshal([]byte(bodies[0]), &robot) != nil || robot.MsgType != "text" || !strings.Contains(robot.Text.Content, "第一个视频") {
+		t.Fatalf("unexpected wecom body: %v", bodies)
+	}
+	if q := dingtalk.queries; len(q) != 1 || !strings.Contains(q[0], "access_token=t&timestamp=") || !strings.Contains(q[0], "&sign=") {
+		t.Fatalf("expected signed dingtalk url, got %v", q)
+	}
+
+	mail.mu.Lock()
+	defer mail.mu.Unlock()
+	if len(mail.rcpts) != 1 || !strings.Contains(mail.rcpts[0], "me@example.com") {
+		t.Fatalf("unexpected recipients: %v", mail.rcpts)
+	}
+	parts := strings.SplitN(mail.data, "\r\n\r\n", 2)
+	if len(parts) != 2 || !strings.Contains(parts[0], "Subject: =?UTF-8?b?") {
+		t.Fatalf("unexpected mail: %q", mail.data)
+	}
+	text, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(parts[1], "\r\n", ""))
+	if err != nil || !strings.Contains(string(text), "第二个视频") {
+		t.Fatalf("unexpected mail body: %q (%v)", text, err)
+	}
+}
+
+func TestNotificationService_RobotErrorCode(t *testing.T) {
+	robot := newRecordingServer(t, `{"errcode":93000,"errmsg":"invalid webhook url"}`)
+	config := database.RadarNotifyConfig{Channels: []database.NotifyChannel{{Type: database.NotifyChannelWeCom, URL: robot.URL}}}
+
+	err := NewNotificationService().Deliver(context.Background(), config, NotificationEvent{Type: database.NotifyEventNewVideo})
+	if err == nil || !strings.Contains(err.Error(), "93000") {
+		t.Fatalf("expected robot error, got %v", err)
+	}
+}
+
+func TestNotificationService_DownloadFailedAfterRetries(t *testing.T) {
+	setupQueueWorkerTest(t)
+	webhook := newRecordingServer(t, "ok")
+
+	target := &database.RadarTarget{
+		Username:        "u1",
+		AuthorName:      "作者",
+		IntervalMinutes: 5,
+		Status:          database.RadarStatusActive,
+		Notify: database.RadarNotifyConfig{
+			Events:   []string{database.NotifyEventDownloadFailed},
+			Channels: []database.NotifyChannel{{Type: database.NotifyChannelWebhook, URL: webhook.URL}},
+		},
+	}
+	if err := database.NewRadarRepository().Add(target); err != nil {
+		t.Fatalf("Add target: %v", err)
+	}
+
+	queueService := NewQueueService()
+	added, err := queueService.AddToQueue([]VideoInfo{{VideoID: "v1", Title: "视频", Author: "作者", VideoURL: "http://example.invalid/v1"}})
+	if err != nil {
+		t.Fatalf("AddToQueue: %v", err)
+	}
+	id := added[0].ID
+	maxRetries := database.DefaultSettings().MaxRetries
+
+	// 仍有重试次数时不通知
+	if err := queueService.FailDownload(id, "boom"); err != nil {
+		t.Fatalf("FailDownload: %v", err)
+	}
+	GetNotificationService().Wait()
+	if got := webhook.received(); len(got) != 0 {
+		t.Fatalf("expected no notification before retries are exhausted, got %v", got)
+	}
+
+	for i := 0; i < maxRetries; i++ {
+		if err := queueService.RequeueFailed(id); err != nil {
+			t.Fatalf("RequeueFailed: %v", err)
+		}
+	}
+	if err := queueService.FailDownload(id, "boom"); err != nil {
+		t.Fatalf("FailDownload: %v", err)
+	}
+	GetNotificationService().Wait()
+
+	bodies := webhook.received()
+	if len(bodies) != 1 {
+		t.Fatalf("expected 1 notification, got %d", len(bodies))
+	}
+	var event NotificationEvent
+	if err := json.Unmarshal([]byte(bodies[0]), &event); err != nil {
+		t.Fatalf("unmarshal event: %v", err)
+	}
+	if event.Type != database.NotifyEventDownloadFailed || event.TargetID != target.ID || event.Error != "boom" || event.RetryCount != maxRetries {
+		t.Fatalf("unexpected event: %+v (max retries %d)", event, maxRetries)
+	}
+}
+
+func TestNotificationService_DownloadCompleteUsesRecordedPath(t *testing.T) {
+	dir := setupQueueWorkerTest(t)
+	webhook := newRecordingServer(t, "ok")
+
+	target := &database.RadarTarget{
+		Username:        "u1",
+		AuthorName:      "作者",
+		IntervalMinutes: 5,
+		Status:          database.RadarStatusActive,
+		Notify: database.RadarNotifyConfig{
+			Events:   []string{database.NotifyEventDownloadComplete},
+			Channels: []database.NotifyChannel{{Type: database.NotifyChannelWebhook, URL: webhook.URL}},
+		},
+	}
+	if err := database.NewRadarRepository().Add(target); err != nil {
+		t.Fatalf("Add target: %v", err)
+	}
+
+	queueService := NewQueueService()
+	added, err := queueService.AddToQueue([]VideoInfo{{VideoID: "v1", Title: "视频", Author: "作者", VideoURL: "http://example.invalid/v1"}})
+	if err != nil {
+		t.Fatalf("AddToQueue: %v", err)
+	}
+
+	// 下载器写入的路径与按当前设置计算的路径不同
+	filePath := filepath.Join(dir, "作者", "实际文件名.mp4")
+	if err := queueService.CompleteDownloadAt(added[0].ID, filePath); err != nil {
+		t.Fatalf("CompleteDownloadAt: %v", err)
+	}
+	GetNotificationService().Wait()
+
+	bodies := webhook.received()
+	if len(bodies) != 1 {
+		t.Fatalf("expected 1 notification, got %d", len(bodies))
+	}
+	var event NotificationEvent
+	if err := json.Unmarshal([]byte(bodies[0]), &event); err != nil {
+		t.Fatalf("unmarshal event: %v", err)
+	}
+	if event.Type != database.NotifyEventDownloadComplete || event.FilePath != filePath {
+		t.Fatalf("unexpected event: %+v", event)
+	}
+	if record, _ := NewDownloadRecordService().GetByVideoID("v1"); record == nil || record.FilePath != filePath {
+		t.Fatalf("unexpected download record: %+v", record)
+	}
+}
+
+func TestRadarTargetService_KeepsMaskedNotifySecrets(t *testing.T) {
+	setupQueueWorkerTest(t)
+	svc := NewRadarTargetService()
+
+	target := &database.RadarTarget{
+		Username:   "u1",
+		AuthorName: "作者",
+		Notify: database.RadarNotifyConfig{
+			Events: []string{database.NotifyEventNewVideo},
+			Channels: []database.NotifyChannel{
+				{Type: database.NotifyChannelDingTalk, URL: "https://oapi.dingtalk.com/robot/send", Secret: "SEC123"},
+				{Type: database.NotifyChannelSMTP, Host: "smtp.example.com", User: "bot", Pass: "p@ss", From: "bot@example.com", To: []string{"a@example.com"}},
+			},
+		},
+	}
+	if err := svc.Add(target); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	// 响应中不包含密钥和密码
+	redacted := target.Redacted()
+	data, _ := json.Marshal(redacted)
+	if strings.Contains(string(data), "SEC123") || strings.Contains(string(data), "p@ss") {
+		t.Fatalf("secrets leaked: %s", data)
+	}
+	if target.Notify.Channels[0].Secret != "SEC123" {
+		t.Fatal("Redacted should not modify the original target")
+	}
+
+	// 原样提交隐藏后的配置时保留已保存的值，修改过的值正常更新
+	redacted.Notify.Channels[1].Pass = "new-pass"
+	if err := svc.Update(target.ID, &redacted); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	saved, err := svc.Get(target.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if saved.Notify.Channels[0].Secret != "SEC123" || saved.Notify.Channels[1].Pass != "new-pass" {
+		t.Fatalf("unexpected saved channels: %+v", saved.Notify.Channels)
+	}
+
+	// 更换机器人地址后不沿用旧密钥
+	redacted = saved.Redacted()
+	redacted.Notify.Channels[0].URL = "https://oapi.dingtalk.com/robot/send?access_token=other"
+	if err := svc.Update(target.ID, &redacted); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if saved, _ = svc.Get(target.ID); saved.Notify.Channels[0].Secret != "" {
+		t.Fatalf("expected secret to be cleared, got %q", saved.Notify.Channels[0].Secret)
+	}
+}
+
+func TestRadarTargetService_KeepsMaskedNotifyHeadersAndURLs(t *testing.T) {
+	setupQueueWorkerTest(t)
+	svc := NewRadarTargetService()
+
+	wecomURL := "https://qyapi.weixin.qq.com/cgi-bin/webhook/send?key=WECOMKEY"
+	dingURL := "https://oapi.dingtalk.com/robot/send?access_token=DINGTOKEN"
+	target := &database.RadarTarget{
+		Username:   "u1",
+		AuthorName: "作者",
+		Notify: database.RadarNotifyConfig{
+			Events: []string{database.NotifyEventNewVideo},
+			Channels: []database.NotifyChannel{
+				{Type: database.NotifyChannelWeCom, URL: wecomURL},
+				{Type: database.NotifyChannelDingTalk, URL: dingURL},
+				{Type: database.NotifyChannelWebhook, URL: "https://hook.example.com/notify", Headers: map[string]string{"Authorization": "Bearer HOOKTOKEN"}},
+			},
+		},
+	}
+	if err := svc.Add(target); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	redacted := target.Redacted()
+	data, _ := json.Marshal(redacted)
+	for _, secret := range []string{"WECOMKEY", "DINGTOKEN", "HOOKTOKEN"} {
+		if strings.Contains(string(data), secret) {
+			t.Fatalf("%s leaked: %s", secret, data)
+		}
+	}
+	if redacted.Notify.Channels[0].URL != "https://qyapi.weixin.qq.com/cgi-bin/webhook/send?"+database.NotifySecretMask {
+		t.Fatalf("unexpected masked url: %s", redacted.Notify.Channels[0].URL)
+	}
+
+	// 原样提交时恢复地址和请求头，新增的请求头正常保存
+	redacted.Notify.Channels[2].Headers["X-Trace"] = "1"
+	if err := svc.Update(target.ID, &redacted); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	saved, err := svc.Get(target.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	channels := saved.Notify.Channels
+	if channels[0].URL != wecomURL || channels[1].URL != dingURL ||
+		channels[2].Headers["Authorization"] != "Bearer HOOKTOKEN" || channels[2].Headers["X-Trace"] != "1" {
+		t.Fatalf("unexpected saved channels: %+v", channels)
+	}
+
+	// 提交新的完整地址时不沿用旧地址；与已保存渠道对不上的隐藏地址被拒绝
+	redacted = saved.Redacted()
+	redacted.Notify.Channels[0].URL = "https://qyapi.weixin.qq.com/cgi-bin/webhook/send?key=NEWKEY"
+	if err := svc.Update(target.ID, &redacted); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if saved, _ = svc.Get(target.ID); saved.Notify.Channels[0].URL != "https://qyapi.weixin.qq.com/cgi-bin/webhook/send?key=NEWKEY" {
+		t.Fatalf("unexpected url: %s", saved.Notify.Channels[0].URL)
+	}
+	redacted = saved.Redacted()
+	redacted.Notify.Channels[1].URL = "https://oapi.dingtalk.com/other/send?" + database.NotifySecretMask
+	var validationErr *ValidationError
+	if err := svc.Update(target.ID, &redacted); !errors.As(err, &validationErr) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+}
@@ -0,0 +1,300 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"wx_channel/internal/database"
+)
+
+// NotificationEvent 发送给各通知渠道的事件
+type NotificationEvent struct {
+	Type       string                       `json:"type"`
+	Time       time.Time                    `json:"time"`
+	TargetID   string                       `json:"targetId,omitempty"`
+	AuthorName string                       `json:"authorName,omitempty"`
+	Username   string                       `json:"username,omitempty"`
+	Videos     []database.RadarVideoSummary `json:"videos,omitempty"` // new_video 事件的新视频
+	VideoID    string                       `json:"videoId,omitempty"`
+	Title      string                       `json:"title,omitempty"`
+	FilePath   string                       `json:"filePath,omitempty"`
+	Error      string                       `json:"error,omitempty"`
+	RetryCount int                          `json:"retryCount,omitempty"`
+}
+
+// Subject 返回通知标题
+func (e NotificationEvent) Subject() string {
+	switch e.Type {
+	case database.NotifyEventNewVideo:
+		return fmt.Sprintf("[视频号雷达] %s 发布了 %d 个新视频", e.AuthorName, len(e.Videos))
+	case database.NotifyEventDownloadComplete:
+		return fmt.Sprintf("[视频号下载] 下载完成: %s", e.Title)
+	case database.NotifyEventDownloadFailed:
+		return fmt.Sprintf("[视频号下载] 下载失败: %s", e.Title)
+	default:
+		return "[视频号] " + e.Type
+	}
+}
+
+// Text 返回通知正文
+func (e NotificationEvent) Text() string {
+	var b strings.Builder
+	b.WriteString(e.Subject())
+	switch e.Type {
+	case database.NotifyEventNewVideo:
+		for _, v := range e.Videos {
+			b.WriteString("\n- ")
+			b.WriteString(v.Title)
+		}
+	case database.NotifyEventDownloadComplete:
+		fmt.Fprintf(&b, "\n作者: %s\n文件: %s", e.AuthorName, e.FilePath)
+	case database.NotifyEventDownloadFailed:
+		fmt.Fprintf(&b, "\n作者: %s\n已重试 %d 次，错误: %s", e.AuthorName, e.RetryCount, e.Error)
+	}
+	return b.String()
+}
+
+// NotificationSink 通知渠道
+type NotificationSink interface {
+	Send(ctx context.Context, event NotificationEvent) error
+}
+
+// newNotificationSink 根据渠道配置创建通知渠道
+func newNotificationSink(channel database.NotifyChannel, client *http.Client) (NotificationSink, error) {
+	switch channel.Type {
+	case database.NotifyChannelWebhook:
+		return newWebhookSink(channel, client)
+	case database.NotifyChannelWeCom, database.NotifyChannelDingTalk:
+		return &robotSink{channel: channel, client: client}, nil
+	case database.NotifyChannelSMTP:
+		return &smtpSink{channel: channel}, nil
+	default:
+		return nil, fmt.Errorf("unknown notification channel: %s", channel.Type)
+	}
+}
+
+// webhookTemplateFuncs 请求体模板可用的函数，json 将值编码为 JSON 字面量
+var webhookTemplateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// webhookSink 通用 HTTP Webhook
+// 请求体模板示例: {"title": {{json .Subject}}, "content": {{json .Text}}}
+type webhookSink struct {
+	channel database.NotifyChannel
+	body    *template.Template
+	client  *http.Client
+}
+
+func newWebhookSink(channel database.NotifyChannel, client *http.Client) (*webhookSink, error) {
+	sink := &webhookSink{channel: channel, client: client}
+	if channel.Body != "" {
+		tmpl, err := template.New("webhook").Funcs(webhookTemplateFuncs).Parse(channel.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse webhook template: %w", err)
+		}
+		sink.body = tmpl
+	}
+	return sink, nil
+}
+
+func (s *webhookSink) Send(ctx context.Context, event NotificationEvent) error {
+	var body []byte
+	if s.body != nil {
+		var buf bytes.Buffer
+		if err := s.body.Execute(&buf, event); err != nil {
+			return fmt.Errorf("failed to render webhook template: %w", err)
+		}
+		body = buf.Bytes()
+	} else {
+		var err error
+		if body, err = json.Marshal(event); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.channel.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.channel.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// robotSink 企业微信 / 钉钉群机器人，两者的文本消息格式相同
+type robotSink struct {
+	channel database.NotifyChannel
+	client  *http.Client
+}
+
+func (s *robotSink) Send(ctx context.Context, event NotificationEvent) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"msgtype": "text",
+		"text":    map[string]string{"content": event.Text()},
+	})
+	if err != nil {
+		return err
+	}
+
+	endpoint := s.channel.URL
+	if s.channel.Type == database.NotifyChannelDingTalk && s.channel.Secret != "" {
+		endpoint = dingTalkSignedURL(endpoint, s.channel.Secret, time.Now())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send robot message: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("robot webhook returned status %d", resp.StatusCode)
+	}
+
+	// 机器人接口以 errcode 表示业务错误
+	var result struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err == nil && result.ErrCode != 0 {
+		return fmt.Errorf("robot webhook error %d: %s", result.ErrCode, result.ErrMsg)
+	}
+	return nil
+}
+
+// dingTalkSignedURL 为钉钉机器人地址附加加签参数
+func dingTalkSignedURL(endpoint, secret string, now time.Time) string {
+	timestamp := strconv.FormatInt(now.UnixMilli(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + secret))
+	sign := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	sep := "?"
+	if strings.Contains(endpoint, "?") {
+		sep = "&"
+	}
+	return endpoint + sep + "timestamp=" + timestamp + "&sign=" + url.QueryEscape(sign)
+}
+
+// smtpSink 邮件通知，465 端口使用 TLS 直连，其他端口在服务器支持时升级 STARTTLS
+type smtpSink struct {
+	channel database.NotifyChannel
+}
+
+func (s *smtpSink) Send(ctx context.Context, event NotificationEvent) error {
+	port := s.channel.Port
+	if port == 0 {
+		port = 25
+	}
+	addr := net.JoinHostPort(s.channel.Host, strconv.Itoa(port))
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	var conn net.Conn
+	var err error
+	if port == 465 {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: s.channel.Host})
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect smtp server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, s.channel.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to create smtp client: %w", err)
+	}
+	defer client.Close()
+
+	if port != 465 {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: s.channel.Host}); err != nil {
+				return fmt.Errorf("failed to start tls: %w", err)
+			}
+		}
+	}
+	if s.channel.User != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.channel.User, s.channel.Pass, s.channel.Host)); err != nil {
+			return fmt.Errorf("smtp auth failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(s.channel.From); err != nil {
+		return fmt.Errorf("smtp MAIL FROM failed: %w", err)
+	}
+	for _, to := range s.channel.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("smtp RCPT TO %s failed: %w", to, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp DATA failed: %w", err)
+	}
+	if _, err := w.Write(buildNotificationMail(s.channel.From, s.channel.To, event)); err != nil {
+		return fmt.Errorf("failed to write mail: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+	return client.Quit()
+}
+
+// buildNotificationMail 构造 UTF-8 纯文本邮件
+func buildNotificationMail(from string, to []string, event NotificationEvent) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", event.Subject()))
+	fmt.Fprintf(&b, "Date: %s\r\n", event.Time.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+
+	encoded := base64.StdEncoding.EncodeToString([]byte(event.Text()))
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded + "\r\n")
+	return b.Bytes()
+}
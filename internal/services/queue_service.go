@@ -1,6 +1,7 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
 	"time"
@@ -12,6 +13,13 @@ import (
 	"github.com/google/uuid"
 )
 
+var (
+	// ErrQueueItemNotFound 队列项目不存在
+	ErrQueueItemNotFound = errors.New("queue item not found")
+	// ErrQueueItemStatus 队列项目当前状态不允许该操作
+	ErrQueueItemStatus = errors.New("queue item status does not allow this operation")
+)
+
 // QueueService 处理下载队列管理操作
 type QueueService struct {
 	repo     *database.QueueRepository
@@ -121,12 +129,12 @@ func (s *QueueService) Pause(id string) error {
 		return err
 	}
 	if item == nil {
-		return fmt.Errorf("queue item not found: %s", id)
+		return fmt.Errorf("%w: %s", ErrQueueItemNotFound, id)
 	}
 
 	// 只能暂停正在下载的项目
 	if item.Status != database.QueueStatusDownloading {
-		return fmt.Errorf("can only pause downloading items, current status: %s", item.Status)
+		return fmt.Errorf("%w: can only pause downloading items, current status: %s", ErrQueueItemStatus, item.Status)
 	}
 
 	return s.repo.UpdateStatus(id, database.QueueStatusPaused)
@@ -140,17 +148,40 @@ func (s *QueueService) Resume(id string) error {
 		return err
 	}
 	if item == nil {
-		return fmt.Errorf("queue item not found: %s", id)
+		return fmt.Errorf("%w: %s", ErrQueueItemNotFound, id)
 	}
 
 	// 只能恢复暂停的项目
 	if item.Status != database.QueueStatusPaused {
-		return fmt.Errorf("can only resume paused items, current status: %s", item.Status)
+		return fmt.Errorf("%w: can only resume paused items, current status: %s", ErrQueueItemStatus, item.Status)
 	}
 
 	return s.repo.UpdateStatus(id, database.QueueStatusPending)
 }
 
+// StartNow 将项目移到队列最前并唤醒 QueueWorker，由调度器优先下载
+// 暂停或失败的项目同时放回待下载状态；正在下载或已完成的项目返回 ErrQueueItemStatus
+func (s *QueueService) StartNow(id string) error {
+
+	item, err := s.repo.GetByID(id)
+	if err != nil {
+		return err
+	}
+	if item == nil {
+		return fmt.Errorf("%w: %s", ErrQueueItemNotFound, id)
+	}
+
+	moved, err := s.repo.MoveToFront(id)
+	if err != nil {
+		return err
+	}
+	if !moved {
+		return fmt.Errorf("%w: can only start pending, paused or failed items, current status: %s", ErrQueueItemStatus, item.Status)
+	}
+	WakeQueueWorker()
+	return nil
+}
+
 // Reorder 根据提供的 ID 顺序重新排序队列
 func (s *QueueService) Reorder(ids []string) error {
 
@@ -165,7 +196,7 @@ func (s *QueueService) SetPriority(id string, priority int) error {
 		return err
 	}
 	if item == nil {
-		return fmt.Errorf("queue item not found: %s", id)
+		return fmt.Errorf("%w: %s", ErrQueueItemNotFound, id)
 	}
 
 	item.Priority = priority
@@ -207,6 +238,12 @@ func (s *QueueService) UpdateProgress(id string, downloadedSize int64, chunksCom
 	return s.repo.UpdateProgress(id, downloadedSize, chunksCompleted, speed)
 }
 
+// UpdateChunkProgress 更新下载进度并持久化分片位图
+func (s *QueueService) UpdateChunkProgress(id string, downloadedSize int64, chunksCompleted int, chunksBitmap string, speed int64) error {
+
+	return s.repo.UpdateChunkProgress(id, downloadedSize, chunksCompleted, chunksBitmap, speed)
+}
+
 // UpdateStatus 更新队列项目的状态
 func (s *QueueService) UpdateStatus(id string, status string) error {
 
@@ -222,15 +259,21 @@ func (s *QueueService) StartDownload(id string) error {
 	return s.repo.SetStartTime(id, time.Now())
 }
 
-// CompleteDownload 标记项目为完成并创建下载记录
+// CompleteDownload 标记项目为完成并创建下载记录，文件路径按下载约定计算
 func (s *QueueService) CompleteDownload(id string) error {
+	return s.CompleteDownloadAt(id, "")
+}
+
+// CompleteDownloadAt 标记项目为完成，并以下载器实际写入的文件路径创建下载记录
+// 下载记录写入后才发送下载完成通知；filePath 为空时按下载约定计算
+func (s *QueueService) CompleteDownloadAt(id, filePath string) error {
 
 	item, err := s.repo.GetByID(id)
 	if err != nil {
 		return err
 	}
 	if item == nil {
-		return fmt.Errorf("queue item not found: %s", id)
+		return fmt.Errorf("%w: %s", ErrQueueItemNotFound, id)
 	}
 
 	// 检查是否已完成以避免重复记录
@@ -248,20 +291,23 @@ func (s *QueueService) CompleteDownload(id string) error {
 		return err
 	}
 
-	// 根据批量下载约定计算文件路径
-	// 路径格式: {baseDir}/downloads/{authorFolder}/{cleanFilename}.mp4
-	filePath := calculateDownloadFilePath(item)
+	if filePath == "" {
+		// 根据批量下载约定计算文件路径
+		// 路径格式: {baseDir}/downloads/{authorFolder}/{cleanFilename}.mp4
+		filePath = calculateDownloadFilePath(item)
+	}
 
-	downloadRepo := database.NewDownloadRecordRepository()
+	downloads := NewDownloadRecordService()
 
 	// 检查是否已经存在该视频的下载记录 (由 batch.go 等其他流程创建)
-	if existingRecord, _ := downloadRepo.GetByVideoID(item.VideoID); existingRecord != nil {
+	if existingRecord, _ := downloads.GetByVideoID(item.VideoID); existingRecord != nil {
 		// 已存在记录，仅需确保状态为完成，不需要新建
 		if existingRecord.Status != database.DownloadStatusCompleted {
 			existingRecord.Status = database.DownloadStatusCompleted
 			existingRecord.FilePath = filePath
-			_ = downloadRepo.Update(existingRecord)
+			_ = downloads.Update(existingRecord)
 		}
+		GetNotificationService().NotifyDownloadComplete(item, existingRecord.FilePath)
 		return nil
 	}
 
@@ -287,10 +333,12 @@ func (s *QueueService) CompleteDownload(id string) error {
 		DownloadTime: time.Now(),
 	}
 
-	if err := downloadRepo.Create(downloadRecord); err != nil {
+	// 通过下载记录服务写入，计算指纹并在后台去重
+	if err := downloads.Create(downloadRecord); err != nil {
 		// 记录错误但不失败完成
 		fmt.Printf("Warning: failed to create download record: %v\n", err)
 	}
+	GetNotificationService().NotifyDownloadComplete(item, filePath)
 
 	return nil
 }
@@ -363,7 +411,19 @@ func loadQueueSettings() *database.Settings {
 // FailDownload 标记项目为失败并附带错误消息
 func (s *QueueService) FailDownload(id string, errorMessage string) error {
 
-	return s.repo.SetError(id, errorMessage)
+	if err := s.repo.SetError(id, errorMessage); err != nil {
+		return err
+	}
+
+	// 重试次数用尽后不会再被放回队列，此时发送失败通知
+	settings, err := s.settings.Load()
+	if err != nil || settings == nil {
+		settings = database.DefaultSettings()
+	}
+	if item, err := s.repo.GetByID(id); err == nil && item != nil && item.RetryCount >= settings.MaxRetries {
+		GetNotificationService().NotifyDownloadFailed(item, errorMessage)
+	}
+	return nil
 }
 
 // IncrementRetryCount 增加项目的重试计数
@@ -430,7 +490,7 @@ func (s *QueueService) ResetRetryCount(id string) error {
 		return err
 	}
 	if item == nil {
-		return fmt.Errorf("queue item not found: %s", id)
+		return fmt.Errorf("%w: %s", ErrQueueItemNotFound, id)
 	}
 
 	item.RetryCount = 0
@@ -442,3 +502,31 @@ func (s *QueueService) UpdateItem(item *database.QueueItem) error {
 
 	return s.repo.Update(item)
 }
+
+// RecoverInterrupted 将异常退出时遗留在 downloading 状态的项目重置为 pending
+// 已完成的分片进度保留，重新调度后从检查点继续
+func (s *QueueService) RecoverInterrupted() (int, error) {
+
+	items, err := s.repo.ListByStatus(database.QueueStatusDownloading)
+	if err != nil {
+		return 0, err
+	}
+
+	recovered := 0
+	for _, item := range items {
+		if err := s.repo.UpdateStatus(item.ID, database.QueueStatusPending); err != nil {
+			return recovered, err
+		}
+		recovered++
+	}
+	return recovered, nil
+}
+
+// RequeueFailed 将失败项目重新放回待下载状态并增加重试计数
+func (s *QueueService) RequeueFailed(id string) error {
+
+	if err := s.repo.IncrementRetryCount(id); err != nil {
+		return err
+	}
+	return s.repo.UpdateStatus(id, database.QueueStatusPending)
+}
@@ -0,0 +1,315 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"wx_channel/internal/database"
+	"wx_channel/internal/utils"
+)
+
+const (
+	// queueWorkerPollInterval 队列轮询间隔
+	queueWorkerPollInterval = 5 * time.Second
+	// queueRetryBaseDelay 失败重试的基础退避时间，按重试次数指数增长
+	queueRetryBaseDelay = 30 * time.Second
+	// queueRetryMaxDelay 失败重试的最大退避时间
+	queueRetryMaxDelay = 30 * time.Minute
+)
+
+// QueueWorker 常驻后台，按优先级持续消费 SQLite 下载队列
+// 并发数取自 Settings.ConcurrentLimit，失败项目按 Settings.MaxRetries 退避重试
+type QueueWorker struct {
+	queueService *QueueService
+	downloader   *ChunkedDownloader
+	settings     *database.SettingsRepository
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	mu     sync.Mutex
+	wg     sync.WaitGroup
+
+	running  bool
+	paused   bool
+	wake     chan struct{}
+	progress chan ProgressUpdate
+}
+
+var (
+	// runningWorker 当前运行的调度器，供 WakeQueueWorker 唤醒
+	runningWorker   *QueueWorker
+	runningWorkerMu sync.Mutex
+)
+
+// WakeQueueWorker 唤醒正在运行的调度器，立即检查队列；调度器未启动时不做任何事
+func WakeQueueWorker() {
+	runningWorkerMu.Lock()
+	w := runningWorker
+	runningWorkerMu.Unlock()
+	if w != nil {
+		w.Notify()
+	}
+}
+
+// NewQueueWorker 创建一个新的队列调度器
+func NewQueueWorker(queueService *QueueService) *QueueWorker {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &QueueWorker{
+		queueService: queueService,
+		downloader:   NewChunkedDownloader(queueService),
+		settings:     database.NewSettingsRepository(),
+		ctx:          ctx,
+		cancel:       cancel,
+		wake:         make(chan struct{}, 1),
+		progress:     make(chan ProgressUpdate, 100),
+	}
+}
+
+// Downloader 返回调度器使用的分片下载器
+func (w *QueueWorker) Downloader() *ChunkedDownloader {
+	return w.downloader
+}
+
+// ProgressChannel 返回转发的下载进度通道，调度器停止后关闭
+func (w *QueueWorker) ProgressChannel() <-chan ProgressUpdate {
+	return w.progress
+}
+
+// Start 恢复崩溃遗留的项目并启动调度循环
+func (w *QueueWorker) Start() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.running {
+		return // 已启动
+	}
+	w.running = true
+
+	if recovered, err := w.queueService.RecoverInterrupted(); err != nil {
+		utils.LogError("[QueueWorker] 恢复中断的下载失败: %v", err)
+	} else if recovered > 0 {
+		utils.LogInfo("[QueueWorker] 已将 %d 个中断的下载重新放回队列", recovered)
+	}
+
+	w.wg.Add(1)
+	go w.run()
+
+	runningWorkerMu.Lock()
+	runningWorker = w
+	runningWorkerMu.Unlock()
+	utils.LogInfo("Queue Worker 已启动")
+}
+
+// Stop 停止调度并取消活动下载，已完成的分片进度保留以便下次恢复
+func (w *QueueWorker) Stop() {
+	w.mu.Lock()
+	if !w.running {
+		w.mu.Unlock()
+		return
+	}
+	w.running = false
+	w.mu.Unlock()
+
+	runningWorkerMu.Lock()
+	if runningWorker == w {
+		runningWorker = nil
+	}
+	runningWorkerMu.Unlock()
+
+	w.cancel()
+	w.wg.Wait()
+
+	active := w.downloader.GetActiveDownloads()
+	for _, id := range active {
+		if err := w.downloader.SaveProgress(id); err != nil {
+			utils.Warn("[QueueWorker] 保存下载进度失败 %s: %v", id, err)
+		}
+	}
+	w.downloader.Stop()
+	if _, err := w.queueService.RecoverInterrupted(); err != nil {
+		utils.Warn("[QueueWorker] 重置下载状态失败: %v", err)
+	}
+
+	utils.LogInfo("Queue Worker 已停止")
+}
+
+// Pause 暂停调度新的下载，正在进行的下载不受影响
+func (w *QueueWorker) Pause() {
+	w.mu.Lock()
+	w.paused = true
+	w.mu.Unlock()
+}
+
+// Resume 恢复调度
+func (w *QueueWorker) Resume() {
+	w.mu.Lock()
+	w.paused = false
+	w.mu.Unlock()
+	w.Notify()
+}
+
+// IsPaused 返回调度是否已暂停
+func (w *QueueWorker) IsPaused() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.paused
+}
+
+// Notify 唤醒调度循环，立即检查队列
+func (w *QueueWorker) Notify() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run 调度主循环
+func (w *QueueWorker) run() {
+	defer w.wg.Done()
+	defer close(w.progress)
+
+	ticker := time.NewTicker(queueWorkerPollInterval)
+	defer ticker.Stop()
+
+	updates := w.downloader.ProgressChannel()
+	w.tick()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case <-ticker.C:
+			w.tick()
+		case <-w.wake:
+			w.tick()
+		case update, ok := <-updates:
+			if !ok {
+				updates = nil
+				continue
+			}
+			w.forward(update)
+			// 有下载结束时立即补位
+			if update.Status == database.QueueStatusCompleted || update.Status == database.QueueStatusFailed {
+				w.tick()
+			}
+		}
+	}
+}
+
+// tick 执行一轮调度：同步暂停状态、重排失败项目、填满并发槽位
+func (w *QueueWorker) tick() {
+	if w.ctx.Err() != nil {
+		return
+	}
+
+	settings, err := w.settings.Load()
+	if err != nil || settings == nil {
+		settings = database.DefaultSettings()
+	}
+	GetBandwidthLimiter().Apply(settings)
+
+	w.reconcileActive()
+	w.requeueFailed(settings.MaxRetries)
+
+	if w.IsPaused() {
+		return
+	}
+	w.dispatch(settings.ConcurrentLimit)
+}
+
+// reconcileActive 取消在控制台被暂停或移除的活动下载
+func (w *QueueWorker) reconcileActive() {
+	for _, id := range w.downloader.GetActiveDownloads() {
+		item, err := w.queueService.GetByID(id)
+		if err != nil {
+			continue
+		}
+		if item != nil && item.Status == database.QueueStatusDownloading {
+			continue
+		}
+		if err := w.downloader.CancelDownload(id); err == nil {
+			utils.Info("[QueueWorker] 已停止下载: %s", id)
+		}
+	}
+}
+
+// requeueFailed 将仍有重试次数的失败项目在退避时间后放回队列
+func (w *QueueWorker) requeueFailed(maxRetries int) {
+	failed, err := w.queueService.GetByStatus(database.QueueStatusFailed)
+	if err != nil {
+		utils.Warn("[QueueWorker] 获取失败项目出错: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for _, item := range failed {
+		if !shouldRetryQueueItem(&item, maxRetries, now) {
+			continue
+		}
+		if err := w.queueService.RequeueFailed(item.ID); err != nil {
+			utils.Warn("[QueueWorker] 重试失败项目出错 %s: %v", item.ID, err)
+			continue
+		}
+		utils.Info("[QueueWorker] 重试下载 (%d/%d): %s", item.RetryCount+1, maxRetries, item.Title)
+	}
+}
+
+// dispatch 按优先级领取待下载项目直到并发上限
+func (w *QueueWorker) dispatch(limit int) {
+	if limit <= 0 {
+		limit = database.DefaultSettings().ConcurrentLimit
+	}
+
+	for len(w.downloader.GetActiveDownloads()) < limit {
+		if w.ctx.Err() != nil {
+			return
+		}
+
+		item, err := w.queueService.GetNextPending()
+		if err != nil {
+			utils.Warn("[QueueWorker] 获取待下载项目失败: %v", err)
+			return
+		}
+		if item == nil {
+			return
+		}
+
+		if err := w.downloader.StartDownload(item); err != nil {
+			utils.Warn("[QueueWorker] 启动下载失败 %s: %v", item.ID, err)
+			if failErr := w.queueService.FailDownload(item.ID, err.Error()); failErr != nil {
+				return
+			}
+			continue
+		}
+		utils.Info("[QueueWorker] 开始下载: %s", item.Title)
+	}
+}
+
+// forward 转发进度更新，通道已满时丢弃
+func (w *QueueWorker) forward(update ProgressUpdate) {
+	select {
+	case w.progress <- update:
+	default:
+	}
+}
+
+// shouldRetryQueueItem 判断失败项目是否已过退避时间且仍有重试次数
+func shouldRetryQueueItem(item *database.QueueItem, maxRetries int, now time.Time) bool {
+	if item.RetryCount >= maxRetries {
+		return false
+	}
+	return !now.Before(item.UpdatedAt.Add(queueRetryDelay(item.RetryCount)))
+}
+
+// queueRetryDelay 计算第 n 次重试前的退避时间
+func queueRetryDelay(retryCount int) time.Duration {
+	delay := queueRetryBaseDelay
+	for i := 0; i < retryCount; i++ {
+		delay *= 2
+		if delay >= queueRetryMaxDelay {
+			return queueRetryMaxDelay
+		}
+	}
+	return delay
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"wx_channel/internal/config"
+	"wx_channel/internal/database"
+)
+
+func setupQueueWorkerTest(t *testing.T) string {
+	t.Helper()
+	tmpDir := t.TempDir()
+	if err := database.Initialize(&database.Config{DBPath: filepath.Join(tmpDir, "records.db")}); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	// 先于关闭数据库执行，等待下载完成后的后台去重
+	t.Cleanup(WaitDedupChecks)
+
+	config.Reload()
+	cfg := config.Get()
+	cfg.DownloadsDir = filepath.Join(tmpDir, "downloads")
+	cfg.DownloadFilenameTemplate = ""
+	return cfg.DownloadsDir
+}
+
+func TestQueueWorker_DrainsPendingItems(t *testing.T) {
+	downloadsDir := setupQueueWorkerTest(t)
+
+	payload := bytes.Repeat([]byte("wx"), 4096)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "video.mp4", time.Now(), bytes.NewReader(payload))
+	}))
+	defer server.Close()
+
+	queueService := NewQueueService()
+	added, err := queueService.AddToQueue([]VideoInfo{
+		{VideoID: "v1", Title: "first", Author: "作者", VideoURL: server.URL + "/1", Size: int64(len(payload))},
+		{VideoID: "v2", Title: "second", Author: "作者", VideoURL: server.URL + "/2", Size: int64(len(payload))},
+	})
+	if err != nil {
+		t.Fatalf("AddToQueue: %v", err)
+	}
+
+	worker := NewQueueWorker(queueService)
+	worker.Start()
+	defer worker.Stop()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		completed, _ := queueService.GetByStatus(database.QueueStatusCompleted)
+		if len(completed) == len(added) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("completed = %d, want %d", len(completed), len(added))
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	for _, item := range added {
+		path := calculateDownloadFilePath(&item)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read %s: %v", path, err)
+		}
+		if !bytes.Equal(data, payload) {
+			t.Fatalf("content mismatch for %s", item.VideoID)
+		}
+		if !strings.HasPrefix(path, downloadsDir) {
+			t.Fatalf("file %s not under %s", path, downloadsDir)
+		}
+	}
+}
+
+func TestQueueService_RecoverInterrupted(t *testing.T) {
+	setupQueueWorkerTest(t)
+
+	queueService := NewQueueService()
+	added, err := queueService.AddToQueue([]VideoInfo{{VideoID: "v1", Title: "stuck", VideoURL: "http://127.0.0.1/", Size: 1}})
+	if err != nil {
+		t.Fatalf("AddToQueue: %v", err)
+	}
+	if err := queueService.StartDownload(added[0].ID); err != nil {
+		t.Fatalf("StartDownload: %v", err)
+	}
+
+	recovered, err := queueService.RecoverInterrupted()
+	if err != nil {
+		t.Fatalf("RecoverInterrupted: %v", err)
+	}
+	if recovered != 1 {
+		t.Fatalf("recovered = %d, want 1", recovered)
+	}
+	item, _ := queueService.GetByID(added[0].ID)
+	if item.Status != database.QueueStatusPending {
+		t.Fatalf("status = %s, want pending", item.Status)
+	}
+}
+
+func TestQueueService_StartNow(t *testing.T) {
+	setupQueueWorkerTest(t)
+	queueService := NewQueueService()
+	added, err := queueService.AddToQueue([]VideoInfo{
+		{VideoID: "v1", Title: "first", VideoURL: "https://example.com/1"},
+		{VideoID: "v2", Title: "second", VideoURL: "https://example.com/2"},
+		{VideoID: "v3", Title: "third", VideoURL: "https://example.com/3"},
+	})
+	if err != nil {
+		t.Fatalf("AddToQueue: %v", err)
+	}
+	if err := queueService.UpdateStatus(added[2].ID, database.QueueStatusPaused); err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+
+	// 暂停的项目放回待下载并排到最前
+	if err := queueService.StartNow(added[2].ID); err != nil {
+		t.Fatalf("StartNow: %v", err)
+	}
+	next, err := queueService.GetNextPending()
+	if err != nil || next == nil || next.ID != added[2].ID {
+		t.Fatalf("GetNextPending = %+v, %v", next, err)
+	}
+
+	// 已被调度器领取的项目不能再次启动
+	if err := queueService.StartDownload(added[1].ID); err != nil {
+		t.Fatalf("StartDownload: %v", err)
+	}
+	if err := queueService.StartNow(added[1].ID); !errors.Is(err, ErrQueueItemStatus) {
+		t.Fatalf("StartNow downloading item = %v, want ErrQueueItemStatus", err)
+	}
+	if err := queueService.StartNow("missing"); !errors.Is(err, ErrQueueItemNotFound) {
+		t.Fatalf("StartNow missing item = %v, want ErrQueueItemNotFound", err)
+	}
+}
+
+func TestShouldRetryQueueItem(t *testing.T) {
+	now := time.Now()
+	item := &database.QueueItem{RetryCount: 1, UpdatedAt: now.Add(-time.Minute)}
+
+	if !shouldRetryQueueItem(item, 3, now) {
+		t.Fatalf("expected retry after backoff elapsed")
+	}
+	if shouldRetryQueueItem(item, 1, now) {
+		t.Fatalf("expected no retry when retries exhausted")
+	}
+	item.UpdatedAt = now
+	if shouldRetryQueueItem(item, 3, now) {
+		t.Fatalf("expected no retry before backoff elapsed")
+	}
+	if queueRetryDelay(20) != queueRetryMaxDelay {
+		t.Fatalf("delay should be capped at %v", queueRetryMaxDelay)
+	}
+}
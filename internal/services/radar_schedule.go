@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"wx_channel/internal/database"
+)
+
+const (
+	// radarMaxAdaptiveInterval 自适应检测间隔的上限（IntervalMinutes 更大时以其为准）
+	radarMaxAdaptiveInterval = 6 * time.Hour
+	// radarMaxBackoff 被微信拒绝后单个目标的最大退避间隔
+	radarMaxBackoff = 24 * time.Hour
+	// radarJitterRatio 检测时间的随机抖动比例
+	radarJitterRatio = 0.1
+	// radarCooldownBase 被微信拒绝后全局暂停调用的基础时长，连续被拒时指数增长
+	radarCooldownBase = time.Minute
+	// radarCooldownMax 全局暂停调用的最大时长
+	radarCooldownMax = 30 * time.Minute
+	// radarPostSamples 估算发布间隔时使用的最近视频数
+	radarPostSamples = 10
+)
+
+// radarCallBudget 所有监控目标共享的调用预算
+// 按每分钟调用次数均匀间隔每次调用，被微信拒绝后全局暂停一段时间
+type radarCallBudget struct {
+	mu            sync.Mutex
+	perMinute     int
+	next          time.Time // 下一次允许调用的时间
+	cooldownUntil time.Time
+	strikes       int // 连续被拒绝的次数
+}
+
+func newRadarCallBudget() *radarCallBudget {
+	return &radarCallBudget{perMinute: database.DefaultRadarCallsPerMinute}
+}
+
+// SetRate 设置每分钟最多调用次数，<=0 时使用默认值
+func (b *radarCallBudget) SetRate(perMinute int) {
+	if perMinute <= 0 {
+		perMinute = database.DefaultRadarCallsPerMinute
+	}
+	b.mu.Lock()
+	b.perMinute = perMinute
+	b.mu.Unlock()
+}
+
+// Wait 阻塞直到可以发起下一次调用，或 ctx 被取消
+func (b *radarCallBudget) Wait(ctx context.Context) error {
+	b.mu.Lock()
+	now := time.Now()
+	at := b.next
+	if b.cooldownUntil.After(at) {
+		at = b.cooldownUntil
+	}
+	if at.Before(now) {
+		at = now
+	}
+	b.next = at.Add(time.Minute / time.Duration(b.perMinute))
+	b.mu.Unlock()
+
+	wait := time.Until(at)
+	if wait <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Reject 记录一次被微信拒绝，全局暂停调用并返回暂停时长
+func (b *radarCallBudget) Reject(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	cooldown := exponentialDelay(radarCooldownBase, b.strikes, radarCooldownMax)
+	b.strikes++
+	b.cooldownUntil = now.Add(cooldown)
+	return cooldown
+}
+
+// Succeed 调用成功后清零连续被拒次数
+func (b *radarCallBudget) Succeed() {
+	b.mu.Lock()
+	b.strikes = 0
+	b.mu.Unlock()
+}
+
+// CoolingDown 返回当前是否处于全局暂停中
+func (b *radarCallBudget) CoolingDown(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return now.Before(b.cooldownUntil)
+}
+
+// radarDue 判断目标是否到了检测时间
+func radarDue(target database.RadarTarget, now time.Time) bool {
+	if target.NextCheckTime != nil {
+		return !now.Before(*target.NextCheckTime)
+	}
+	if target.LastCheckTime == nil {
+		return true
+	}
+	return now.Sub(*target.LastCheckTime) >= time.Duration(target.IntervalMinutes)*time.Minute
+}
+
+// radarCheckInterval 计算目标的检测间隔
+// 自适应模式下按观测到的发布间隔的 1/4 检测，不低于 IntervalMinutes
+func radarCheckInterval(target database.RadarTarget) time.Duration {
+	base := time.Duration(target.IntervalMinutes) * time.Minute
+	if target.FixedInterval || target.PostIntervalMinutes <= 0 {
+		return base
+	}
+	interval := time.Duration(target.PostIntervalMinutes) * time.Minute / 4
+	limit := radarMaxAdaptiveInterval
+	if base > limit {
+		limit = base
+	}
+	if interval > limit {
+		interval = limit
+	}
+	if interval < base {
+		interval = base
+	}
+	return interval
+}
+
+// radarJitter 为间隔加上 ±radarJitterRatio 的随机抖动
+func radarJitter(d time.Duration) time.Duration {
+	spread := float64(d) * radarJitterRatio
+	return d + time.Duration((rand.Float64()*2-1)*spread)
+}
+
+// exponentialDelay 计算 base * 2^n，不超过 max
+func exponentialDelay(base time.Duration, n int, max time.Duration) time.Duration {
+	delay := base
+	for i := 0; i < n; i++ {
+		delay *= 2
+		if delay >= max {
+			return max
+		}
+	}
+	if delay > max {
+		return max
+	}
+	return delay
+}
+
+// estimatePostInterval 根据视频发布时间（Unix 秒）估算平均发布间隔（分钟）
+// 样本不足时返回 0
+func estimatePostInterval(createTimes []int64) int {
+	times := make([]int64, 0, len(createTimes))
+	for _, t := range createTimes {
+		if t > 0 {
+			times = append(times, t)
+		}
+	}
+	if len(times) < 2 {
+		return 0
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] > times[j] })
+	if len(times) > radarPostSamples {
+		times = times[:radarPostSamples]
+	}
+	span := times[0] - times[len(times)-1]
+	minutes := int(span / 60 / int64(len(times)-1))
+	if minutes < 1 {
+		minutes = 1
+	}
+	return minutes
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -20,6 +21,12 @@ type RadarService struct {
 	queueService *QueueService
 	hub          *websocket.Hub
 	settings     *database.SettingsRepository
+	downloads    *database.DownloadRecordRepository
+	engagement   *EngagementService
+	budget       *radarCallBudget
+
+	// callAPI 调用注入脚本的 API，默认转发到 hub，测试中可替换
+	callAPI func(key string, body interface{}, timeout time.Duration) (json.RawMessage, error)
 
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -32,14 +39,24 @@ type RadarService struct {
 // NewRadarService 创建一个新的雷达服务
 func NewRadarService(repo *database.RadarRepository, queueService *QueueService, hub *websocket.Hub) *RadarService {
 	ctx, cancel := context.WithCancel(context.Background())
-	return &RadarService{
+	s := &RadarService{
 		repo:         repo,
 		queueService: queueService,
 		hub:          hub,
 		settings:     database.NewSettingsRepository(),
+		downloads:    database.NewDownloadRecordRepository(),
+		engagement:   NewEngagementService(),
+		budget:       newRadarCallBudget(),
 		ctx:          ctx,
 		cancel:       cancel,
 	}
+	if hub != nil {
+		s.callAPI = func(key string, body interface{}, timeout time.Duration) (json.RawMessage, error) {
+			data, err := hub.CallAPI(key, body, timeout)
+			return json.RawMessage(data), err
+		}
+	}
+	return s
 }
 
 // Start 启动雷达服务轮询器
@@ -54,7 +71,7 @@ func (s *RadarService) Start() {
 		s.ctx, s.cancel = context.WithCancel(context.Background())
 	}
 
-	// 默认每分钟检查一次，但实际是否触发取决于每个 target 的 interval_minutes
+	// 默认每分钟检查一次，但实际是否触发取决于每个 target 的下次检测时间
 	s.ticker = time.NewTicker(time.Minute)
 	s.wg.Add(1)
 
@@ -113,21 +130,29 @@ func (s *RadarService) checkTargets() {
 		return
 	}
 
+	if settings, err := s.settings.Load(); err == nil && settings != nil {
+		s.budget.SetRate(settings.RadarCallsPerMinute)
+	}
+
 	now := time.Now()
+	if s.budget.CoolingDown(now) {
+		return // 刚被微信拒绝，暂停所有检测
+	}
 	hasClient := s.hub.ClientCount() > 0
 
 	for _, target := range targets {
+		if s.ctx.Err() != nil {
+			return
+		}
 		// 检查是否到了该检测的时间
-		if target.LastCheckTime != nil {
-			elapsed := now.Sub(*target.LastCheckTime)
-			if elapsed < time.Duration(target.IntervalMinutes)*time.Minute {
-				continue // 还没到时间
-			}
+		if !radarDue(target, now) {
+			continue // 还没到时间
 		}
 
 		if !hasClient {
 			// 更新最后检测时间
 			_ = s.repo.UpdateLastCheckTime(target.ID, now)
+			_ = s.repo.UpdateSchedule(target.ID, now.Add(radarJitter(radarCheckInterval(target))), target.PostIntervalMinutes, target.FailCount)
 			// 插入错误日志
 			_ = s.repo.AddLog(&database.RadarLog{
 				TargetID:     target.ID,
@@ -141,11 +166,32 @@ func (s *RadarService) checkTargets() {
 		// 执行检测
 		s.processTarget(target)
 	}
+
+	if hasClient {
+		s.refreshEngagement()
+	}
+}
+
+// refreshEngagement 为较长时间未出现在 feed_list 中的跟踪视频补拍互动快照
+func (s *RadarService) refreshEngagement() {
+	if s.ctx.Err() != nil || s.budget.CoolingDown(time.Now()) {
+		return
+	}
+	refreshed, err := s.engagement.RefreshStale(s.ctx, s.fetchFeedProfileCounts)
+	if err != nil && s.ctx.Err() == nil {
+		utils.LogWarn("[Radar] 刷新互动数据失败: %v", err)
+	}
+	if refreshed > 0 {
+		utils.LogInfo("[Radar] 已刷新 %d 个视频的互动数据", refreshed)
+	}
 }
 
 // processTarget 处理单个雷达监控目标的拉取与对比逻辑
+// 从最新一页开始沿 NextMarker 翻页，遇到已知或被过滤的视频、或达到翻页深度时停止；
+// 开启历史回填的目标随后再从保存的游标继续向前翻页
 func (s *RadarService) processTarget(target database.RadarTarget) {
 	utils.LogInfo("[Radar] 开始检测账号: %s (%s)", target.AuthorName, target.Username)
+	target.NormalizePageDepth()
 
 	// 更新最后检测时间
 	now := time.Now()
@@ -153,205 +199,331 @@ func (s *RadarService) processTarget(target database.RadarTarget) {
 		utils.LogError("[Radar] 更新检测时间失败 [%s]: %v", target.ID, err)
 	}
 
-	// 初始化日志记录
-	radarLog := &database.RadarLog{
-		TargetID:  target.ID,
-		CheckTime: now,
-		Status:    "success",
+	scan := s.scanFeedPages(target, "", target.PageDepth, true)
+	s.saveScanLog(target, now, database.RadarLogModeLatest, scan)
+	scans := []*radarScan{scan}
+	defer func() { s.scheduleNext(target, now, scans) }()
+	if scan.newVideos > 0 {
+		utils.LogInfo("[Radar] 账号 [%s] 检测完毕，翻页 %d 次，新增 %d 个视频并加入下载队列", target.AuthorName, scan.pages, scan.newVideos)
+		// 历史回填找到的是旧视频，只对最新检测发送新视频通知
+		GetNotificationService().NotifyNewVideos(target, scan.queued)
 	}
-
-	// 1. 调用 WebSocket 获取用户视频列表 (feed_list)
-	body := websocket.FeedListBody{
-		Username:   target.Username,
-		NextMarker: "", // 只需要第一页最新数据
+	if scan.err != nil || !target.Backfill {
+		return
 	}
 
-	// 限制 30 秒超时
-	data, err := s.hub.CallAPI("key:channels:feed_list", body, 30*time.Second)
-	if err != nil {
-		radarLog.Status = "error"
-		if strings.Contains(err.Error(), "no available client") {
-			radarLog.ErrorMessage = "微信客户端未连接或已退出"
-			utils.LogWarn("[Radar] 检测失败 [%s]: %s", target.AuthorName, radarLog.ErrorMessage)
-		} else {
-			radarLog.ErrorMessage = err.Error()
-			utils.LogError("[Radar] 获取视频列表失败 [%s]: %v", target.AuthorName, err)
+	// 历史回填：首次回填从最新检测停下的位置开始，之后从保存的游标继续
+	cursor := target.BackfillCursor
+	if cursor == "" {
+		if scan.stopReason == database.RadarStopEnd {
+			s.finishBackfill(target)
+			return
 		}
-		_ = s.repo.AddLog(radarLog)
+		cursor = scan.cursor
+	}
+	if !s.waitPageInterval() {
 		return
 	}
 
-	// 2. 解析返回列表数据
-	var rawResp struct {
-		Data struct {
-			BaseResponse struct {
-				Ret int `json:"Ret"`
-			} `json:"BaseResponse"`
-			ObjectList []interface{} `json:"objectList"`
-			Object     []interface{} `json:"object"`
-		} `json:"data"`
+	backfill := s.scanFeedPages(target, cursor, target.PageDepth, false)
+	s.saveScanLog(target, time.Now(), database.RadarLogModeBackfill, backfill)
+	scans = append(scans, backfill)
+	switch {
+	case backfill.stopReason == database.RadarStopEnd:
+		s.finishBackfill(target)
+	case backfill.cursor != "":
+		if err := s.repo.UpdateBackfill(target.ID, true, backfill.cursor); err != nil {
+			utils.LogError("[Radar] 保存回填游标失败 [%s]: %v", target.ID, err)
+		}
 	}
+}
 
-	if err := json.Unmarshal(data, &rawResp); err != nil {
-		radarLog.Status = "error"
-		radarLog.ErrorMessage = "解析返回数据失败: " + err.Error()
-		utils.LogError("[Radar] 解析视频列表失败 [%s]: %v", target.AuthorName, err)
-		_ = s.repo.AddLog(radarLog)
-		return
+// scheduleNext 根据本次检测结果计算下次检测时间
+// 被微信拒绝时单个目标按连续失败次数指数退避，同时全局暂停调用；
+// 成功时按最新一页视频的发布时间更新观测到的发布间隔
+func (s *RadarService) scheduleNext(target database.RadarTarget, now time.Time, scans []*radarScan) {
+	rejected := false
+	for _, scan := range scans {
+		if errors.Is(scan.err, ErrFeedListRejected) {
+			rejected = true
+		}
 	}
 
-	if rawResp.Data.BaseResponse.Ret != 0 {
-		radarLog.Status = "error"
-		radarLog.ErrorMessage = fmt.Sprintf("微信接口返回失败，状态码: %d (可能是请求过于频繁或账号异常)", rawResp.Data.BaseResponse.Ret)
-		utils.LogWarn("[Radar] 账号 [%s] 获取数据被微信拒绝(Ret:%d)", target.AuthorName, rawResp.Data.BaseResponse.Ret)
-		_ = s.repo.AddLog(radarLog)
+	if rejected {
+		target.FailCount++
+		cooldown := s.budget.Reject(now)
+		interval := exponentialDelay(radarCheckInterval(target), target.FailCount, radarMaxBackoff)
+		utils.LogWarn("[Radar] 账号 [%s] 被微信拒绝 %d 次，%v 后重试，全局暂停 %v", target.AuthorName, target.FailCount, interval, cooldown)
+		if err := s.repo.UpdateSchedule(target.ID, now.Add(radarJitter(interval)), target.PostIntervalMinutes, target.FailCount); err != nil {
+			utils.LogError("[Radar] 更新检测计划失败 [%s]: %v", target.ID, err)
+		}
 		return
 	}
 
-	// 兼容老版本或新版本 WeChat 可能返回的字段
-	allObjects := rawResp.Data.ObjectList
-	if len(allObjects) == 0 && len(rawResp.Data.Object) > 0 {
-		allObjects = rawResp.Data.Object
+	if scans[0].err == nil {
+		s.budget.Succeed()
+		target.FailCount = 0
+		if interval := estimatePostInterval(scans[0].createTimes); interval > 0 {
+			target.PostIntervalMinutes = interval
+		}
 	}
+	next := now.Add(radarJitter(radarCheckInterval(target)))
+	if err := s.repo.UpdateSchedule(target.ID, next, target.PostIntervalMinutes, target.FailCount); err != nil {
+		utils.LogError("[Radar] 更新检测计划失败 [%s]: %v", target.ID, err)
+	}
+}
 
-	radarLog.FoundVideos = len(allObjects)
-
-	if radarLog.FoundVideos == 0 {
-		utils.LogInfo("[Radar] 账号 [%s] 暂无视频数据(Raw Data Size: %d)", target.AuthorName, len(data))
-		_ = s.repo.AddLog(radarLog)
-		return
+// finishBackfill 回填到最后一页后关闭回填模式
+func (s *RadarService) finishBackfill(target database.RadarTarget) {
+	utils.LogInfo("[Radar] 账号 [%s] 历史视频回填完成", target.AuthorName)
+	if err := s.repo.UpdateBackfill(target.ID, false, ""); err != nil {
+		utils.LogError("[Radar] 更新回填状态失败 [%s]: %v", target.ID, err)
 	}
+}
 
-	// 3. 提取视频 ID 并检查库中是否已存在
-	newVideoCount := 0
-	settings, _ := s.settings.Load()
-	if settings == nil {
-		settings = database.DefaultSettings()
+// radarPageInterval 连续翻页之间的间隔，避免请求过于频繁被微信拒绝
+var radarPageInterval = 3 * time.Second
+
+// radarScan 记录一次翻页扫描的结果
+type radarScan struct {
+	pages       int
+	found       int
+	newVideos   int
+	summaries   []database.RadarVideoSummary
+	queued      []database.RadarVideoSummary // 本次加入下载队列的新视频
+	createTimes []int64                      // 视频发布时间（Unix 秒），用于估算发布频率
+	cursor      string                       // 下一页的游标，已到最后一页时为空
+	stopReason  string
+	err         error
+}
+
+// waitPageInterval 等待翻页间隔，服务停止时返回 false
+func (s *RadarService) waitPageInterval() bool {
+	select {
+	case <-s.ctx.Done():
+		return false
+	case <-time.After(radarPageInterval):
+		return true
 	}
+}
 
-	// 获取下载记录的 Repo
-	downloadRepo := database.NewDownloadRecordRepository()
+// scanFeedPages 从 cursor 开始最多翻 maxPages 页
+// stopAtKnown 为 true 时，当前页出现已知视频或被过滤规则跳过的视频即停止
+func (s *RadarService) scanFeedPages(target database.RadarTarget, cursor string, maxPages int, stopAtKnown bool) *radarScan {
+	scan := &radarScan{cursor: cursor, stopReason: database.RadarStopDepth}
 
-	// 用于记录本次扫描的所有视频摘要
-	var videoSummaries []database.RadarVideoSummary
+	// 规则在保存时已校验，编译失败时不做过滤
+	filter, err := target.Filters.Compile()
+	if err != nil {
+		utils.LogWarn("[Radar] 账号 [%s] 过滤规则无效，已忽略: %v", target.AuthorName, err)
+		filter = nil
+	}
 
-	for _, objInter := range allObjects {
-		objMap, ok := objInter.(map[string]interface{})
-		if !ok {
-			continue
+	for scan.pages < maxPages {
+		if scan.pages > 0 && !s.waitPageInterval() {
+			scan.stopReason = database.RadarStopCancelled
+			return scan
 		}
 
-		// 检查必要字段
-		idInter, ok := objMap["id"]
-		if !ok || idInter == "" {
-			continue
+		objects, nextCursor, err := s.fetchFeedPage(target, scan.cursor)
+		if err != nil {
+			if s.ctx.Err() != nil {
+				scan.stopReason = database.RadarStopCancelled
+				return scan
+			}
+			scan.err = err
+			scan.stopReason = database.RadarStopError
+			return scan
 		}
-		videoID := fmt.Sprintf("%v", idInter)
-
-		// 从 objectDesc 里提取标题和媒体信息（与订阅功能一致，无需再调 feed_profile）
-		title := ""
-		videoURL := ""
-		coverURL := ""
-		decodeKey := ""
-		var fileSize int64
-		var duration int64
-		resolution := ""
-
-		if descInter, ok := objMap["objectDesc"]; ok {
-			if descMap, ok := descInter.(map[string]interface{}); ok {
-				if t, ok := descMap["description"].(string); ok {
-					title = t
-				}
-				// 遍历媒体列表，取第一条视频媒体
-				if mediaList, ok := descMap["media"].([]interface{}); ok && len(mediaList) > 0 {
-					if m, ok := mediaList[0].(map[string]interface{}); ok {
-						rawURL, _ := m["url"].(string)
-						urlToken, _ := m["urlToken"].(string)
-						if rawURL != "" {
-							videoURL = rawURL + urlToken
-						}
-						coverURL, _ = m["thumbUrl"].(string)
-						decodeKey, _ = m["decodeKey"].(string)
-						if fs, ok := m["fileSize"].(float64); ok {
-							fileSize = int64(fs)
-						}
-						if dur, ok := m["videoDuration"].(float64); ok {
-							duration = int64(dur)
-						}
-						if r, ok := m["videoResolution"].(string); ok {
-							resolution = r
-						}
-					}
-				}
+		scan.pages++
+		scan.found += len(objects)
+
+		reachedKnown := false
+		for _, objInter := range objects {
+			feed, ok := ParseFeedObject(objInter)
+			if !ok {
+				continue
+			}
+			scan.createTimes = append(scan.createTimes, feed.CreateTime)
+			summary, queued := s.processFeedObject(target, filter, feed)
+			logEngagementError(summary.VideoID, s.engagement.RecordFeedObject(target, feed))
+			scan.summaries = append(scan.summaries, summary)
+			if queued {
+				scan.newVideos++
+				scan.queued = append(scan.queued, summary)
 			}
+			// 被过滤规则跳过的视频不会入队，每次检测都会再次出现，同样视为已知，
+			// 避免最新视频全部被过滤时每次都翻到最大深度
+			if !summary.IsNew || summary.SkipRule != "" {
+				reachedKnown = true
+			}
+		}
+
+		scan.cursor = nextCursor
+		if nextCursor == "" || len(objects) == 0 {
+			scan.cursor = ""
+			scan.stopReason = database.RadarStopEnd
+			return scan
 		}
+		if stopAtKnown && reachedKnown {
+			scan.stopReason = database.RadarStopReachedKnown
+			return scan
+		}
+	}
+	return scan
+}
 
-		if title == "" {
-			title = fmt.Sprintf("RadarV_%s", videoID)
+// fetchFeedPage 拉取一页 feed_list，返回视频列表和下一页游标
+func (s *RadarService) fetchFeedPage(target database.RadarTarget, cursor string) ([]interface{}, string, error) {
+	objects, nextCursor, err := FetchFeedListPage(func(body websocket.FeedListBody) ([]byte, error) {
+		// 所有目标共享调用预算，避免同一轮到期的目标连续请求
+		if err := s.budget.Wait(s.ctx); err != nil {
+			return nil, err
 		}
+		return s.callAPI("key:channels:feed_list", body, 30*time.Second)
+	}, target.Username, cursor)
+	if err != nil {
+		if strings.Contains(err.Error(), "no available client") {
+			return nil, "", fmt.Errorf("微信客户端未连接或已退出")
+		}
+		return nil, "", err
+	}
+	if len(objects) == 0 {
+		utils.LogInfo("[Radar] 账号 [%s] 暂无视频数据", target.AuthorName)
+	}
+	return objects, nextCursor, nil
+}
+
+// fetchFeedProfileCounts 通过 feed_profile 获取单个视频的最新互动计数
+func (s *RadarService) fetchFeedProfileCounts(objectID, nonceID string) (EngagementCounts, error) {
+	if err := s.budget.Wait(s.ctx); err != nil {
+		return EngagementCounts{}, err
+	}
+
+	body := websocket.FeedProfileBody{ObjectID: objectID, NonceID: nonceID}
+	data, err := s.callAPI("key:channels:feed_profile", body, 30*time.Second)
+	if err != nil {
+		return EngagementCounts{}, err
+	}
+
+	var rawResp struct {
+		ErrCode int    `json:"errCode"`
+		ErrMsg  string `json:"errMsg"`
+		Data    struct {
+			Object map[string]interface{} `json:"object"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &rawResp); err != nil {
+		return EngagementCounts{}, fmt.Errorf("解析返回数据失败: %w", err)
+	}
+	if rawResp.ErrCode != 0 {
+		return EngagementCounts{}, fmt.Errorf("获取视频信息失败，状态码: %d %s", rawResp.ErrCode, rawResp.ErrMsg)
+	}
+	if rawResp.Data.Object == nil {
+		return EngagementCounts{}, fmt.Errorf("返回数据缺少视频信息")
+	}
+	return parseEngagementCounts(rawResp.Data.Object), nil
+}
+
+// processFeedObject 判断视频是否已知，通过过滤规则的新视频直接加入下载队列
+// 返回视频摘要和是否成功入队
+func (s *RadarService) processFeedObject(target database.RadarTarget, filter *database.RadarFilter, feed FeedObject) (database.RadarVideoSummary, bool) {
+	videoID := feed.ID
+	title := feed.Title
+
+	if title == "" {
+		title = fmt.Sprintf("RadarV_%s", videoID)
+	}
+
+	// 判断是否需要下载
+	isNew := true
 
-		// 4. 判断是否需要下载
-		isNew := true
+	record, _ := s.downloads.GetByVideoID(videoID)
+	if record != nil && (record.Status == database.DownloadStatusCompleted || record.Status == database.DownloadStatusInProgress) {
+		isNew = false
+	}
 
-		record, _ := downloadRepo.GetByVideoID(videoID)
-		if record != nil && (record.Status == database.DownloadStatusCompleted || record.Status == database.DownloadStatusInProgress) {
+	if isNew {
+		queueItem, _ := s.queueService.GetByVideoID(videoID)
+		if queueItem != nil && (queueItem.Status == database.QueueStatusPending || queueItem.Status == database.QueueStatusDownloading || queueItem.Status == database.QueueStatusCompleted) {
 			isNew = false
 		}
+	}
 
-		if isNew {
-			queueItem, _ := s.queueService.GetByVideoID(videoID)
-			if queueItem != nil && (queueItem.Status == database.QueueStatusPending || queueItem.Status == database.QueueStatusDownloading || queueItem.Status == database.QueueStatusCompleted) {
-				isNew = false
-			}
-		}
+	// 记录视频摘要
+	summary := database.RadarVideoSummary{
+		VideoID: videoID,
+		Title:   title,
+		IsNew:   isNew,
+	}
+	if !isNew {
+		return summary, false
+	}
 
-		// 记录视频摘要
-		videoSummaries = append(videoSummaries, database.RadarVideoSummary{
-			VideoID: videoID,
-			Title:   title,
-			IsNew:   isNew,
-		})
+	// feed_list 的 videoDuration 单位为毫秒，过滤规则按秒比较
+	summary.SkipRule = filter.Check(database.RadarFilterInput{
+		Title:      title,
+		Duration:   feed.Duration / 1000,
+		FileSize:   feed.FileSize,
+		Resolution: feed.Resolution,
+	})
+	if summary.SkipRule != "" {
+		utils.LogInfo("[Radar] 新视频 [%s] 未通过过滤规则 %s，跳过: %s", target.AuthorName, summary.SkipRule, title)
+		return summary, false
+	}
 
-		if isNew {
-			if videoURL == "" {
-				utils.LogWarn("[Radar] 新视频 [%s] 无法提取 URL，跳过: %s", target.AuthorName, videoID)
-				continue
-			}
-			utils.LogInfo("[Radar] 发现新视频 [%s]: %s (%s)", target.AuthorName, title, videoID)
-			newVideoCount++
-
-			// 直接从 feed_list 数据入队，无需额外请求 feed_profile
-			req := []VideoInfo{{
-				VideoID:    videoID,
-				Title:      title,
-				Author:     target.AuthorName,
-				VideoURL:   videoURL,
-				CoverURL:   coverURL,
-				Size:       fileSize,
-				DecryptKey: decodeKey,
-				Duration:   duration,
-				Resolution: resolution,
-			}}
-			if _, err := s.queueService.AddToQueue(req); err != nil {
-				utils.LogError("[Radar] 添加视频到下载队列失败 [%s]-[%s]: %v", target.AuthorName, title, err)
-			} else {
-				utils.LogInfo("[Radar] 成功加入队列: %s", title)
-			}
-		}
+	if feed.VideoURL == "" {
+		utils.LogWarn("[Radar] 新视频 [%s] 无法提取 URL，跳过: %s", target.AuthorName, videoID)
+		return summary, false
+	}
+	utils.LogInfo("[Radar] 发现新视频 [%s]: %s (%s)", target.AuthorName, title, videoID)
+
+	// 直接从 feed_list 数据入队，无需额外请求 feed_profile
+	req := []VideoInfo{{
+		VideoID:    videoID,
+		Title:      title,
+		Author:     target.AuthorName,
+		VideoURL:   feed.VideoURL,
+		CoverURL:   feed.CoverURL,
+		Size:       feed.FileSize,
+		DecryptKey: feed.DecryptKey,
+		Duration:   feed.Duration,
+		Resolution: feed.Resolution,
+	}}
+	if _, err := s.queueService.AddToQueue(req); err != nil {
+		utils.LogError("[Radar] 添加视频到下载队列失败 [%s]-[%s]: %v", target.AuthorName, title, err)
+		return summary, false
 	}
+	utils.LogInfo("[Radar] 成功加入队列: %s", title)
+	return summary, true
+}
 
-	radarLog.NewVideos = newVideoCount
+// saveScanLog 将一次翻页扫描的进度写入 radar_logs
+func (s *RadarService) saveScanLog(target database.RadarTarget, checkTime time.Time, mode string, scan *radarScan) {
+	radarLog := &database.RadarLog{
+		TargetID:    target.ID,
+		CheckTime:   checkTime,
+		FoundVideos: scan.found,
+		NewVideos:   scan.newVideos,
+		Status:      "success",
+		Mode:        mode,
+		Pages:       scan.pages,
+		StopReason:  scan.stopReason,
+	}
+	if scan.err != nil {
+		radarLog.Status = "error"
+		radarLog.ErrorMessage = scan.err.Error()
+		utils.LogWarn("[Radar] 检测失败 [%s]: %v", target.AuthorName, scan.err)
+	}
 
 	// 将视频摘要序列化后存入日志
-	if len(videoSummaries) > 0 {
-		if b, err := json.Marshal(videoSummaries); err == nil {
+	if len(scan.summaries) > 0 {
+		if b, err := json.Marshal(scan.summaries); err == nil {
 			radarLog.VideoList = string(b)
 		}
 	}
 
-	_ = s.repo.AddLog(radarLog)
-
-	if newVideoCount > 0 {
-		utils.LogInfo("[Radar] 账号 [%s] 检测完毕，新增 %d 个视频并加入下载队列", target.AuthorName, newVideoCount)
+	if err := s.repo.AddLog(radarLog); err != nil {
+		utils.LogError("[Radar] 写入检测日志失败 [%s]: %v", target.ID, err)
 	}
 }
@@ -0,0 +1,448 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"wx_channel/internal/database"
+	"wx_channel/internal/websocket"
+)
+
+// fakeFeedPages 模拟 feed_list 分页接口，key 为游标，value 为该页视频 ID 和下一页游标
+type fakeFeedPages struct {
+	pages     map[string][]string
+	next      map[string]string
+	markers   []string
+	ret       int              // 非 0 时模拟微信拒绝请求
+	likes     map[string]int64 // 视频点赞数
+	durations map[string]int64 // 视频时长（毫秒），与 feed_list 的 videoDuration 一致
+}
+
+func (f *fakeFeedPages) call(key string, body interface{}, timeout time.Duration) (json.RawMessage, error) {
+	if profile, ok := body.(websocket.FeedProfileBody); ok {
+		return json.Marshal(map[string]interface{}{
+			"errCode": 0,
+			"data": map[string]interface{}{"object": map[string]interface{}{
+				"id":        profile.ObjectID,
+				"likeCount": f.likes[profile.ObjectID],
+			}},
+		})
+	}
+	req := body.(websocket.FeedListBody)
+	marker, err := url.QueryUnescape(req.NextMarker)
+	if err != nil {
+		return nil, err
+	}
+	f.markers = append(f.markers, marker)
+	if f.ret != 0 {
+		return json.Marshal(map[string]interface{}{
+			"data": map[string]interface{}{"BaseResponse": map[string]interface{}{"Ret": f.ret}},
+		})
+	}
+
+	var objects []map[string]interface{}
+	for i, id := range f.pages[marker] {
+		objects = append(objects, map[string]interface{}{
+			"id":            id,
+			"objectNonceId": "nonce-" + id,
+			"createtime":    fakeFeedNewest - int64(len(f.markers)*2+i)*fakeFeedPostGap,
+			"likeCount":     f.likes[id],
+			"commentCount":  "3",
+			"objectDesc": map[string]interface{}{
+				"description": "title " + id,
+				"media": []interface{}{map[string]interface{}{
+					"url":           "http://example.invalid/" + id,
+					"urlToken":      "?t=1",
+					"videoDuration": f.durations[id],
+				}},
+			},
+		})
+	}
+	return json.Marshal(map[string]interface{}{
+		"data": map[string]interface{}{
+			"BaseResponse": map[string]interface{}{"Ret": 0},
+			"objectList":   objects,
+			"lastBuffer":   f.next[marker],
+		},
+	})
+}
+
+// 模拟账号每 12 小时发布一个视频
+const (
+	fakeFeedNewest  = int64(1715760000)
+	fakeFeedPostGap = int64(12 * 3600)
+)
+
+func newFakeFeed() *fakeFeedPages {
+	f := &fakeFeedPages{pages: map[string][]string{}, next: map[string]string{}, likes: map[string]int64{}, durations: map[string]int64{}}
+	cursor := ""
+	for page := 0; page < 4; page++ {
+		next := fmt.Sprintf("cursor/%d", page+1)
+		if page == 3 {
+			next = ""
+		}
+		f.pages[cursor] = []string{fmt.Sprintf("v%d-a", page), fmt.Sprintf("v%d-b", page)}
+		f.next[cursor] = next
+		cursor = next
+	}
+	return f
+}
+
+func setupRadarServiceTest(t *testing.T, target *database.RadarTarget) (*RadarService, *fakeFeedPages) {
+	t.Helper()
+	setupQueueWorkerTest(t)
+
+	oldInterval := radarPageInterval
+	radarPageInterval = time.Millisecond
+	t.Cleanup(func() { radarPageInterval = oldInterval })
+
+	repo := database.NewRadarRepository()
+	if err := repo.Add(target); err != nil {
+		t.Fatalf("Add target: %v", err)
+	}
+
+	feed := newFakeFeed()
+	s := NewRadarService(repo, NewQueueService(), nil)
+	s.callAPI = feed.call
+	s.budget.SetRate(60000)
+	return s, feed
+}
+
+func TestRadarService_FollowsPagesUntilKnownVideo(t *testing.T) {
+	target := &database.RadarTarget{Username: "u1", AuthorName: "作者", IntervalMinutes: 5, Status: database.RadarStatusActive, PageDepth: 10}
+	s, feed := setupRadarServiceTest(t, target)
+
+	// 第二页的视频已在队列中
+	if _, err := s.queueService.AddToQueue([]VideoInfo{{VideoID: "v1-b", Title: "known", VideoURL: "http://example.invalid/v1-b"}}); err != nil {
+		t.Fatalf("AddToQueue: %v", err)
+	}
+
+	s.processTarget(*target)
+
+	if len(feed.markers) != 2 {
+		t.Fatalf("expected 2 pages fetched, got %v", feed.markers)
+	}
+	if feed.markers[1] != "cursor/1" {
+		t.Fatalf("second page should use lastBuffer as marker, got %q", feed.markers[1])
+	}
+
+	logs, err := s.repo.GetLogsByTargetID(target.ID, 10)
+	if err != nil || len(logs) != 1 {
+		t.Fatalf("GetLogsByTargetID: %v, %d logs", err, len(logs))
+	}
+	log := logs[0]
+	if log.Mode != database.RadarLogModeLatest || log.Pages != 2 || log.StopReason != database.RadarStopReachedKnown {
+		t.Fatalf("unexpected log: mode=%s pages=%d stop=%s", log.Mode, log.Pages, log.StopReason)
+	}
+	if log.FoundVideos != 4 || log.NewVideos != 3 {
+		t.Fatalf("expected 4 found / 3 new, got %d / %d", log.FoundVideos, log.NewVideos)
+	}
+}
+
+func TestRadarService_StopsAtPageDepth(t *testing.T) {
+	target := &database.RadarTarget{Username: "u2", AuthorName: "作者", IntervalMinutes: 5, Status: database.RadarStatusActive, PageDepth: 3}
+	s, feed := setupRadarServiceTest(t, target)
+
+	s.processTarget(*target)
+
+	if len(feed.markers) != 3 {
+		t.Fatalf("expected 3 pages fetched, got %v", feed.markers)
+	}
+	logs, _ := s.repo.GetLogsByTargetID(target.ID, 10)
+	if len(logs) != 1 || logs[0].StopReason != database.RadarStopDepth || logs[0].NewVideos != 6 {
+		t.Fatalf("unexpected logs: %+v", logs)
+	}
+}
+
+func TestRadarService_BackfillAdvancesCursor(t *testing.T) {
+	target := &database.RadarTarget{Username: "u3", AuthorName: "作者", IntervalMinutes: 5, Status: database.RadarStatusActive, PageDepth: 1, Backfill: true}
+	s, _ := setupRadarServiceTest(t, target)
+
+	// 第一轮：最新检测 1 页，回填从 cursor/1 开始再翻 1 页
+	s.processTarget(*target)
+	got, err := s.repo.GetByID(target.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if !got.Backfill || got.BackfillCursor != "cursor/2" {
+		t.Fatalf("expected backfill cursor cursor/2, got backfill=%v cursor=%q", got.Backfill, got.BackfillCursor)
+	}
+
+	// 继续回填直到最后一页
+	for i := 0; i < 3 && got.Backfill; i++ {
+		s.processTarget(*got)
+		got, _ = s.repo.GetByID(target.ID)
+	}
+	if got.Backfill || got.BackfillCursor != "" {
+		t.Fatalf("expected backfill finished, got backfill=%v cursor=%q", got.Backfill, got.BackfillCursor)
+	}
+
+	queued, err := s.queueService.GetByStatus(database.QueueStatusPending)
+	if err != nil {
+		t.Fatalf("GetByStatus: %v", err)
+	}
+	if len(queued) != 8 {
+		t.Fatalf("expected all 8 videos queued, got %d", len(queued))
+	}
+
+	logs, _ := s.repo.GetLogsByTargetID(target.ID, 50)
+	backfillLogs := 0
+	for _, l := range logs {
+		if l.Mode == database.RadarLogModeBackfill {
+			backfillLogs++
+		}
+	}
+	if backfillLogs == 0 {
+		t.Fatalf("expected backfill logs, got %+v", logs)
+	}
+}
+
+func TestRadarService_SkipsVideosByFilterRules(t *testing.T) {
+	target := &database.RadarTarget{
+		Username:        "u4",
+		AuthorName:      "作者",
+		IntervalMinutes: 5,
+		Status:          database.RadarStatusActive,
+		PageDepth:       1,
+		Filters:         database.RadarFilterRules{ExcludeKeywords: []string{"v0-b"}},
+	}
+	s, _ := setupRadarServiceTest(t, target)
+
+	s.processTarget(*target)
+
+	queued, _ := s.queueService.GetByStatus(database.QueueStatusPending)
+	if len(queued) != 1 || queued[0].VideoID != "v0-a" {
+		t.Fatalf("expected only v0-a queued, got %+v", queued)
+	}
+
+	logs, _ := s.repo.GetLogsByTargetID(target.ID, 10)
+	if len(logs) != 1 {
+		t.Fatalf("expected 1 log, got %d", len(logs))
+	}
+	var summaries []database.RadarVideoSummary
+	if err := json.Unmarshal([]byte(logs[0].VideoList), &summaries); err != nil {
+		t.Fatalf("unmarshal video list: %v", err)
+	}
+	skipped := 0
+	for _, v := range summaries {
+		if v.SkipRule != "" {
+			skipped++
+			if v.VideoID != "v0-b" || v.SkipRule != database.RadarRuleExcludeKeywords {
+				t.Fatalf("unexpected skipped summary: %+v", v)
+			}
+		}
+	}
+	if skipped != 1 || logs[0].NewVideos != 1 {
+		t.Fatalf("expected 1 skipped and 1 new, got %d skipped, %d new", skipped, logs[0].NewVideos)
+	}
+}
+
+func TestRadarService_FiltersDurationInSeconds(t *testing.T) {
+	target := &database.RadarTarget{
+		Username:        "u6",
+		AuthorName:      "作者",
+		IntervalMinutes: 5,
+		Status:          database.RadarStatusActive,
+		PageDepth:       1,
+		Filters:         database.RadarFilterRules{MinDuration: 60, MaxDuration: 600},
+	}
+	s, feed := setupRadarServiceTest(t, target)
+	feed.durations["v0-a"] = 30000  // 30 秒
+	feed.durations["v0-b"] = 125000 // 2 分 5 秒
+
+	s.processTarget(*target)
+
+	queued, _ := s.queueService.GetByStatus(database.QueueStatusPending)
+	if len(queued) != 1 || queued[0].VideoID != "v0-b" {
+		t.Fatalf("expected only v0-b queued, got %+v", queued)
+	}
+	if queued[0].Duration != 125000 {
+		t.Fatalf("queued duration = %d, want milliseconds", queued[0].Duration)
+	}
+}
+
+func TestRadarService_StopsAtFilteredVideos(t *testing.T) {
+	target := &database.RadarTarget{
+		Username:        "u7",
+		AuthorName:      "作者",
+		IntervalMinutes: 5,
+		Status:          database.RadarStatusActive,
+		PageDepth:       10,
+		Filters:         database.RadarFilterRules{MinDuration: 60},
+	}
+	s, feed := setupRadarServiceTest(t, target)
+	// 第一页的视频都短于 60 秒
+	feed.durations["v0-a"] = 30000
+	feed.durations["v0-b"] = 30000
+
+	s.processTarget(*target)
+
+	if len(feed.markers) != 1 {
+		t.Fatalf("expected scan to stop after the first page, fetched %v", feed.markers)
+	}
+	logs, err := s.repo.GetLogsByTargetID(target.ID, 10)
+	if err != nil || len(logs) != 1 || logs[0].StopReason != database.RadarStopReachedKnown {
+		t.Fatalf("unexpected logs: %+v, %v", logs, err)
+	}
+	if queued, _ := s.queueService.GetByStatus(database.QueueStatusPending); len(queued) != 0 {
+		t.Fatalf("filtered videos should not be queued: %+v", queued)
+	}
+}
+
+func TestRadarService_AdaptiveIntervalAndBackoff(t *testing.T) {
+	target := &database.RadarTarget{Username: "u5", AuthorName: "作者", IntervalMinutes: 30, Status: database.RadarStatusActive, PageDepth: 1}
+	s, feed := setupRadarServiceTest(t, target)
+
+	// 每 12 小时发布一次，检测间隔为发布间隔的 1/4 (3 小时)
+	start := time.Now()
+	s.processTarget(*target)
+	got, _ := s.repo.GetByID(target.ID)
+	if got.PostIntervalMinutes != 12*60 || got.FailCount != 0 || got.NextCheckTime == nil {
+		t.Fatalf("unexpected schedule: %+v", got)
+	}
+	if next := got.NextCheckTime.Sub(start); next < 2*time.Hour || next > 4*time.Hour {
+		t.Fatalf("expected next check in about 3h, got %v", next)
+	}
+
+	// 被微信拒绝后指数退避，并全局暂停调用
+	feed.ret = -1
+	s.processTarget(*got)
+	got, _ = s.repo.GetByID(target.ID)
+	if got.FailCount != 1 {
+		t.Fatalf("expected fail count 1, got %d", got.FailCount)
+	}
+	if next := got.NextCheckTime.Sub(start); next < 5*time.Hour {
+		t.Fatalf("expected backoff beyond 5h, got %v", next)
+	}
+	if !s.budget.CoolingDown(time.Now()) {
+		t.Fatal("expected global cooldown after rejection")
+	}
+	logs, _ := s.repo.GetLogsByTargetID(target.ID, 10)
+	errorLogs := 0
+	for _, l := range logs {
+		if l.Status == "error" && l.StopReason == database.RadarStopError {
+			errorLogs++
+		}
+	}
+	if errorLogs != 1 {
+		t.Fatalf("expected 1 error log, got %+v", logs)
+	}
+
+	// 恢复后清零失败次数
+	feed.ret = 0
+	s.budget.cooldownUntil = time.Time{}
+	s.processTarget(*got)
+	got, _ = s.repo.GetByID(target.ID)
+	if got.FailCount != 0 {
+		t.Fatalf("expected fail count reset, got %d", got.FailCount)
+	}
+}
+
+func TestRadarService_RecordsEngagementSnapshots(t *testing.T) {
+	target := &database.RadarTarget{Username: "u1", AuthorName: "作者", IntervalMinutes: 5, Status: database.RadarStatusActive, PageDepth: 1}
+	s, feed := setupRadarServiceTest(t, target)
+	feed.likes["v0-a"] = 50
+	feed.likes["v0-b"] = 20
+
+	s.processTarget(*target)
+
+	repo := database.NewEngagementRepository()
+	latest, err := repo.GetLatest("v0-a")
+	if err != nil || latest == nil {
+		t.Fatalf("GetLatest: %v, %v", latest, err)
+	}
+	if latest.LikeCount != 50 || latest.CommentCount != 3 || latest.NonceID != "nonce-v0-a" || latest.Author != "作者" || latest.TargetID != target.ID {
+		t.Fatalf("unexpected snapshot: %+v", latest)
+	}
+
+	// 一小时内再次检测不重复记录
+	s.processTarget(*target)
+	if snapshots, _ := repo.ListByVideo("v0-a", time.Now().Add(-time.Hour)); len(snapshots) != 1 {
+		t.Fatalf("expected 1 snapshot within the snapshot interval, got %d", len(snapshots))
+	}
+
+	// 两天前的快照作为曲线起点
+	twoDaysAgo := time.Now().AddDate(0, 0, -2)
+	for id, likes := range map[string]int64{"v0-a": 10, "v0-b": 5} {
+		if err := repo.Add(&database.EngagementSnapshot{VideoID: id, Author: "作者", LikeCount: likes, CapturedAt: twoDaysAgo}); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+
+	video, err := s.engagement.VideoSeries("v0-a", 7)
+	if err != nil {
+		t.Fatalf("VideoSeries: %v", err)
+	}
+	if len(video.Points) != 2 || video.Growth.LikeCount != 40 || video.Title != "title v0-a" {
+		t.Fatalf("unexpected video series: %+v", video)
+	}
+
+	author, err := s.engagement.AuthorSeries("作者", 7)
+	if err != nil {
+		t.Fatalf("AuthorSeries: %v", err)
+	}
+	if author.Videos != 2 || len(author.Points) != 2 || author.Points[0].LikeCount != 15 || author.Points[1].LikeCount != 70 || author.Growth.LikeCount != 55 {
+		t.Fatalf("unexpected author series: %+v", author)
+	}
+
+	// 较长时间未出现在 feed_list 中的视频通过 feed_profile 刷新
+	if err := repo.Add(&database.EngagementSnapshot{VideoID: "old", NonceID: "n-old", Author: "作者", LikeCount: 1, CapturedAt: time.Now().AddDate(0, 0, -1)}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	feed.likes["old"] = 9
+	s.refreshEngagement()
+	if latest, _ := repo.GetLatest("old"); latest == nil || latest.LikeCount != 9 || latest.NonceID != "n-old" {
+		t.Fatalf("expected refreshed snapshot, got %+v", latest)
+	}
+}
+
+func TestRadarSchedule_Helpers(t *testing.T) {
+	target := database.RadarTarget{IntervalMinutes: 10}
+	if got := radarCheckInterval(target); got != 10*time.Minute {
+		t.Fatalf("unknown post interval should use IntervalMinutes, got %v", got)
+	}
+	target.PostIntervalMinutes = 20
+	if got := radarCheckInterval(target); got != 10*time.Minute {
+		t.Fatalf("adaptive interval must not go below IntervalMinutes, got %v", got)
+	}
+	target.PostIntervalMinutes = 7 * 24 * 60
+	if got := radarCheckInterval(target); got != radarMaxAdaptiveInterval {
+		t.Fatalf("adaptive interval should be capped, got %v", got)
+	}
+	target.FixedInterval = true
+	if got := radarCheckInterval(target); got != 10*time.Minute {
+		t.Fatalf("fixed interval should ignore post frequency, got %v", got)
+	}
+
+	for i := 0; i < 100; i++ {
+		if d := radarJitter(time.Hour); d < 54*time.Minute || d > 66*time.Minute {
+			t.Fatalf("jitter out of range: %v", d)
+		}
+	}
+
+	if got := estimatePostInterval([]int64{7200, 0, 3600, 10800}); got != 60 {
+		t.Fatalf("expected 60 minutes between posts, got %d", got)
+	}
+	if got := estimatePostInterval([]int64{3600}); got != 0 {
+		t.Fatalf("expected 0 with a single sample, got %d", got)
+	}
+
+	budget := newRadarCallBudget()
+	budget.SetRate(600) // 每 100ms 一次
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := budget.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Fatalf("expected calls to be spaced by the budget, took %v", elapsed)
+	}
+	if first, second := budget.Reject(start), budget.Reject(start); first != radarCooldownBase || second != 2*radarCooldownBase {
+		t.Fatalf("expected exponential cooldown, got %v then %v", first, second)
+	}
+}
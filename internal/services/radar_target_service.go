@@ -0,0 +1,156 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"wx_channel/internal/database"
+)
+
+var (
+	// ErrRadarTargetNotFound 监控目标不存在
+	ErrRadarTargetNotFound = errors.New("监控目标不存在")
+	// ErrRadarTargetExists 账号已在监控列表中
+	ErrRadarTargetExists = errors.New("该账号已在监控列表中")
+)
+
+// ValidationError 请求参数无效，Error() 返回可直接展示给用户的提示
+type ValidationError struct {
+	Err error
+}
+
+func (e *ValidationError) Error() string { return e.Err.Error() }
+func (e *ValidationError) Unwrap() error { return e.Err }
+
+// RadarTargetService 处理监控目标的增删改查，供控制台 API 和云端指令共用
+type RadarTargetService struct {
+	repo *database.RadarRepository
+}
+
+// NewRadarTargetService 创建一个新的 RadarTargetService
+func NewRadarTargetService() *RadarTargetService {
+	return &RadarTargetService{repo: database.NewRadarRepository()}
+}
+
+// List 返回所有监控目标
+func (s *RadarTargetService) List() ([]database.RadarTarget, error) {
+	targets, err := s.repo.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	if targets == nil {
+		targets = []database.RadarTarget{}
+	}
+	return targets, nil
+}
+
+// Get 返回单个监控目标
+func (s *RadarTargetService) Get(id string) (*database.RadarTarget, error) {
+	target, err := s.repo.GetByID(id)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && target == nil) {
+		return nil, ErrRadarTargetNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+// Add 校验并添加监控目标
+func (s *RadarTargetService) Add(target *database.RadarTarget) error {
+	target.Username = strings.TrimSpace(target.Username)
+	target.AuthorName = strings.TrimSpace(target.AuthorName)
+	if target.Username == "" || target.AuthorName == "" {
+		return &ValidationError{Err: errors.New("账号ID和账号名称不能为空")}
+	}
+	// 新目标没有已保存的值，清空提交的占位值
+	target.Notify.RestoreSecrets(database.RadarNotifyConfig{})
+	if err := normalizeRadarTarget(target); err != nil {
+		return err
+	}
+	target.BackfillCursor = ""
+	if target.Status == "" {
+		target.Status = database.RadarStatusActive
+	}
+
+	if err := s.repo.Add(target); err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return ErrRadarTargetExists
+		}
+		return fmt.Errorf("failed to add radar target: %w", err)
+	}
+	return nil
+}
+
+// Update 校验并更新监控目标，保留检测时间和未修改的通知密钥；回填未关闭时保留回填进度
+func (s *RadarTargetService) Update(id string, target *database.RadarTarget) error {
+	target.ID = id
+	target.BackfillCursor = ""
+	existing, err := s.repo.GetByID(id)
+	if err == nil && existing != nil {
+		// 响应中的密钥、请求头和地址参数已隐藏，提交占位值时沿用已保存的值
+		target.Notify.RestoreSecrets(existing.Notify)
+		target.LastCheckTime = existing.LastCheckTime
+		if target.Backfill && existing.Backfill {
+			target.BackfillCursor = existing.BackfillCursor
+		}
+	} else {
+		target.Notify.RestoreSecrets(database.RadarNotifyConfig{})
+	}
+	if err := normalizeRadarTarget(target); err != nil {
+		return err
+	}
+
+	if err := s.repo.Update(target); err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return ErrRadarTargetExists
+		}
+		return fmt.Errorf("failed to update radar target: %w", err)
+	}
+	return nil
+}
+
+// SetStatus 暂停或恢复监控目标
+func (s *RadarTargetService) SetStatus(id string, status database.RadarTargetStatus) error {
+	if status != database.RadarStatusActive && status != database.RadarStatusPaused {
+		return &ValidationError{Err: errors.New("无效的状态值")}
+	}
+	return s.repo.UpdateStatus(id, status)
+}
+
+// Delete 删除监控目标
+func (s *RadarTargetService) Delete(id string) error {
+	return s.repo.Delete(id)
+}
+
+// Logs 返回监控目标最近的执行日志
+func (s *RadarTargetService) Logs(id string, limit int) ([]database.RadarLog, error) {
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	logs, err := s.repo.GetLogsByTargetID(id, limit)
+	if err != nil {
+		return nil, err
+	}
+	if logs == nil {
+		logs = []database.RadarLog{}
+	}
+	return logs, nil
+}
+
+// normalizeRadarTarget 补齐默认值并校验过滤规则和通知配置
+func normalizeRadarTarget(target *database.RadarTarget) error {
+	if target.IntervalMinutes < 5 {
+		target.IntervalMinutes = 5 // 最少5分钟
+	}
+	target.NormalizePageDepth()
+	if err := target.Filters.Validate(); err != nil {
+		return &ValidationError{Err: err}
+	}
+	if err := target.Notify.Validate(); err != nil {
+		return &ValidationError{Err: err}
+	}
+	return nil
+}
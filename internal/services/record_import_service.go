@@ -0,0 +1,323 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"wx_channel/internal/database"
+)
+
+// 支持导入的下载记录格式
+const (
+	// RecordImportFormatLegacyCSV 旧版 CSVManager 写入的 download_records.csv
+	RecordImportFormatLegacyCSV = "legacy_csv"
+	// RecordImportFormatExportJSON ExportService 导出的下载记录 JSON
+	RecordImportFormatExportJSON = "export_json"
+)
+
+// maxRecordImportErrors 导入报告中最多保留的错误明细数
+const maxRecordImportErrors = 50
+
+// RecordImportResult 下载记录导入报告
+type RecordImportResult struct {
+	Source      string   `json:"source"`
+	Format      string   `json:"format"`
+	Total       int      `json:"total"`
+	Imported    int      `json:"imported"`
+	Duplicates  int      `json:"duplicates"`
+	Invalid     int      `json:"invalid"`
+	Failed      int      `json:"failed"`
+	ImportedIDs []string `json:"importedIds"`
+	Errors      []string `json:"errors,omitempty"`
+}
+
+func (r *RecordImportResult) addError(format string, args ...interface{}) {
+	if len(r.Errors) < maxRecordImportErrors {
+		r.Errors = append(r.Errors, fmt.Sprintf(format, args...))
+	}
+}
+
+// RecordImportService 将数据库之前的 CSV 记录和导出的 JSON 导入 download_records
+type RecordImportService struct {
+	repo *database.DownloadRecordRepository
+}
+
+// NewRecordImportService 创建一个新的 RecordImportService
+func NewRecordImportService() *RecordImportService {
+	return &RecordImportService{
+		repo: database.NewDownloadRecordRepository(),
+	}
+}
+
+// ImportFile 导入文件中的下载记录，格式由扩展名和内容判断
+func (s *RecordImportService) ImportFile(path string) (*RecordImportResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read records file: %w", err)
+	}
+	return s.Import(data, path)
+}
+
+// Import 导入下载记录，name 仅用于判断格式和生成报告
+// 已存在相同视频 ID 的记录跳过，文件内重复的视频只导入第一条
+func (s *RecordImportService) Import(data []byte, name string) (*RecordImportResult, error) {
+	result := &RecordImportResult{
+		Source:      name,
+		Format:      detectRecordImportFormat(data, name),
+		ImportedIDs: []string{},
+	}
+
+	var records []database.DownloadRecord
+	var err error
+	switch result.Format {
+	case RecordImportFormatExportJSON:
+		records, err = ParseDownloadRecordsFromJSON(bytes.TrimPrefix(data, utf8BOM))
+	default:
+		records, err = parseLegacyRecordsCSV(data, result)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(records))
+	for i := range records {
+		record := &records[i]
+		result.Total++
+		if err := normalizeImportedRecord(record); err != nil {
+			result.Invalid++
+			result.addError("record %d: %v", i+1, err)
+			continue
+		}
+		if seen[record.VideoID] {
+			result.Duplicates++
+			continue
+		}
+		seen[record.VideoID] = true
+
+		existing, err := s.repo.GetByVideoID(record.VideoID)
+		if err == nil && existing == nil {
+			existing, err = s.repo.GetByID(record.ID)
+		}
+		if err != nil {
+			result.Failed++
+			result.addError("record %s: %v", record.VideoID, err)
+			continue
+		}
+		if existing != nil {
+			result.Duplicates++
+			continue
+		}
+
+		if err := s.repo.Create(record); err != nil {
+			result.Failed++
+			result.addError("record %s: %v", record.VideoID, err)
+			continue
+		}
+		result.Imported++
+		result.ImportedIDs = append(result.ImportedIDs, record.VideoID)
+	}
+	return result, nil
+}
+
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// detectRecordImportFormat 根据扩展名判断格式，扩展名未知时根据内容判断
+func detectRecordImportFormat(data []byte, name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		return RecordImportFormatExportJSON
+	case ".csv":
+		return RecordImportFormatLegacyCSV
+	}
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(data, utf8BOM))
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		return RecordImportFormatExportJSON
+	}
+	return RecordImportFormatLegacyCSV
+}
+
+// normalizeImportedRecord 校验记录并补全导入时缺省的字段
+func normalizeImportedRecord(record *database.DownloadRecord) error {
+	record.VideoID = strings.TrimSpace(record.VideoID)
+	if record.VideoID == "" {
+		record.VideoID = strings.TrimSpace(record.ID)
+	}
+	if record.VideoID == "" {
+		return fmt.Errorf("missing video id")
+	}
+	if record.ID == "" {
+		record.ID = record.VideoID
+	}
+	if record.Status == "" {
+		record.Status = database.DownloadStatusCompleted
+	}
+	if record.DownloadTime.IsZero() {
+		record.DownloadTime = time.Now()
+	}
+	return nil
+}
+
+// legacy CSV 的列顺序，与 models.VideoDownloadRecord.ToCSVRow 一致
+const (
+	legacyColID = iota
+	legacyColTitle
+	legacyColAuthor
+	legacyColAuthorType
+	legacyColOfficialName
+	legacyColURL
+	legacyColPageURL
+	legacyColFileSize
+	legacyColDuration
+	legacyColPlayCount
+	legacyColLikeCount
+	legacyColCommentCount
+	legacyColFavCount
+	legacyColForwardCount
+	legacyColCreateTime
+	legacyColIPRegion
+	legacyColDownloadAt
+)
+
+// parseLegacyRecordsCSV 解析旧版 download_records.csv，格式错误的行计入 Invalid
+func parseLegacyRecordsCSV(data []byte, result *RecordImportResult) ([]database.DownloadRecord, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, utf8BOM)))
+	// 与 CSVManager 读取时一致：允许字段数量不一致
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	records := []database.DownloadRecord{}
+	for line := 1; ; line++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if _, ok := err.(*csv.ParseError); ok {
+				result.Total++
+				result.Invalid++
+				result.addError("line %d: %v", line, err)
+				continue
+			}
+			return nil, fmt.Errorf("failed to read legacy CSV: %w", err)
+		}
+		// 第一行是表头；数据行的 ID 列带 ID_ 前缀
+		if line == 1 && !strings.HasPrefix(legacyField(row, legacyColID), "ID_") {
+			if legacyField(row, 1) == "VideoID" {
+				return nil, fmt.Errorf("CSV exported by /api/export is not supported, use /api/import/downloads instead")
+			}
+			continue
+		}
+		if len(row) == 1 && strings.TrimSpace(row[0]) == "" {
+			continue
+		}
+		records = append(records, legacyRowToRecord(row))
+	}
+	return records, nil
+}
+
+func legacyField(row []string, index int) string {
+	if index < len(row) {
+		return strings.TrimSpace(row[index])
+	}
+	return ""
+}
+
+// legacyRowToRecord 将旧版 CSV 行映射为下载记录，计数和大小从展示文本中解析
+func legacyRowToRecord(row []string) database.DownloadRecord {
+	videoID := strings.TrimPrefix(legacyField(row, legacyColID), "ID_")
+	record := database.DownloadRecord{
+		ID:           videoID,
+		VideoID:      videoID,
+		Title:        legacyField(row, legacyColTitle),
+		Author:       legacyField(row, legacyColAuthor),
+		FileSize:     parseSizeText(legacyField(row, legacyColFileSize)),
+		Duration:     parseDurationText(legacyField(row, legacyColDuration)),
+		Format:       "mp4",
+		Status:       database.DownloadStatusCompleted,
+		PlayCount:    parseCountText(legacyField(row, legacyColPlayCount)),
+		LikeCount:    parseCountText(legacyField(row, legacyColLikeCount)),
+		CommentCount: parseCountText(legacyField(row, legacyColCommentCount)),
+		FavCount:     parseCountText(legacyField(row, legacyColFavCount)),
+		ForwardCount: parseCountText(legacyField(row, legacyColForwardCount)),
+		IPRegion:     legacyField(row, legacyColIPRegion),
+	}
+	if record.Author == "" {
+		record.Author = legacyField(row, legacyColOfficialName)
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", legacyField(row, legacyColDownloadAt), time.Local); err == nil {
+		record.DownloadTime = t
+	}
+	return record
+}
+
+// parseCountText 解析 "1234"、"1,234"、"1.2万"、"10万+"、"3亿" 等计数文本
+func parseCountText(text string) int64 {
+	text = strings.TrimSuffix(strings.ReplaceAll(strings.TrimSpace(text), ",", ""), "+")
+	if text == "" {
+		return 0
+	}
+	multiplier := 1.0
+	switch {
+	case strings.HasSuffix(text, "万"):
+		multiplier, text = 1e4, strings.TrimSuffix(text, "万")
+	case strings.HasSuffix(text, "亿"):
+		multiplier, text = 1e8, strings.TrimSuffix(text, "亿")
+	case strings.HasSuffix(text, "w"), strings.HasSuffix(text, "W"):
+		multiplier, text = 1e4, text[:len(text)-1]
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+	if err != nil || value < 0 {
+		return 0
+	}
+	return int64(value*multiplier + 0.5)
+}
+
+// parseSizeText 解析 "10.5 MB"、"28.77MB"、"512KB" 或字节数
+func parseSizeText(text string) int64 {
+	text = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(text), " ", ""))
+	if text == "" {
+		return 0
+	}
+	units := []struct {
+		suffix string
+		size   float64
+	}{
+		{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1},
+	}
+	multiplier := 1.0
+	for _, unit := range units {
+		if strings.HasSuffix(text, unit.suffix) {
+			multiplier, text = unit.size, strings.TrimSuffix(text, unit.suffix)
+			break
+		}
+	}
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil || value < 0 {
+		return 0
+	}
+	return int64(value * multiplier)
+}
+
+// parseDurationText 解析 "02:30"、"1:02:30" 格式的时长为毫秒
+func parseDurationText(text string) int64 {
+	if text == "" {
+		return 0
+	}
+	var seconds int64
+	for _, part := range strings.Split(text, ":") {
+		value, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+		if err != nil {
+			return 0
+		}
+		seconds = seconds*60 + value
+	}
+	return seconds * 1000
+}
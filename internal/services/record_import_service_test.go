@@ -0,0 +1,150 @@
+package services
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"wx_channel/internal/database"
+	"wx_channel/internal/models"
+)
+
+func setupRecordImportTest(t *testing.T) *RecordImportService {
+	t.Helper()
+	if err := database.Initialize(&database.Config{DBPath: filepath.Join(t.TempDir(), "records.db")}); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+	return NewRecordImportService()
+}
+
+func legacyCSVRow(record *models.VideoDownloadRecord) string {
+	row := record.ToCSVRow()
+	for i, field := range row {
+		if strings.ContainsAny(field, ",\"") {
+			row[i] = `"` + strings.ReplaceAll(field, `"`, `""`) + `"`
+		}
+	}
+	return strings.Join(row, ",")
+}
+
+func TestRecordImportService_LegacyCSV(t *testing.T) {
+	svc := setupRecordImportTest(t)
+
+	if err := database.NewDownloadRecordRepository().Create(&database.DownloadRecord{
+		ID: "existing", VideoID: "existing", Title: "已存在", Status: database.DownloadStatusCompleted, DownloadTime: time.Now(),
+	}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	downloadAt := time.Date(2024, 5, 1, 12, 30, 0, 0, time.Local)
+	first := &models.VideoDownloadRecord{
+		ID: "v1", Title: "标题, 带逗号", Author: "作者", FileSize: "10.5 MB", Duration: "02:30",
+		PlayCount: "1.2万", LikeCount: "10万+", CommentCount: "1,234", FavCount: "5", ForwardCount: "",
+		IPRegion: "广东", DownloadAt: downloadAt,
+	}
+	lines := []string{
+		"\ufeffID,标题,作者,作者类型,公众号,链接,页面,大小,时长,播放量,点赞,评论,收藏,转发,创建时间,IP属地,下载时间,来源,关键词",
+		legacyCSVRow(first),
+		legacyCSVRow(first),
+		legacyCSVRow(&models.VideoDownloadRecord{ID: "existing", Title: "重复", DownloadAt: downloadAt}),
+		"ID_,没有ID",
+		// 旧版本没有 PageSource 和 SearchKeyword 列
+		"ID_v2,短行,作者2,,,,,28.77MB,1:02:03",
+	}
+
+	result, err := svc.Import([]byte(strings.Join(lines, "\n")), "download_records.csv")
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if result.Format != RecordImportFormatLegacyCSV || result.Total != 5 || result.Imported != 2 ||
+		result.Duplicates != 2 || result.Invalid != 1 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	got, err := database.NewDownloadRecordRepository().GetByVideoID("v1")
+	if err != nil || got == nil {
+		t.Fatalf("GetByVideoID: %v, %v", got, err)
+	}
+	if got.Title != "标题, 带逗号" || got.PlayCount != 12000 || got.LikeCount != 100000 || got.CommentCount != 1234 ||
+		got.IPRegion != "广东" || got.Duration != 150000 || got.FileSize != int64(10.5*(1<<20)) {
+		t.Fatalf("unexpected record: %+v", got)
+	}
+	if !got.DownloadTime.Equal(downloadAt) {
+		t.Fatalf("DownloadTime = %v, want %v", got.DownloadTime, downloadAt)
+	}
+
+	short, _ := database.NewDownloadRecordRepository().GetByVideoID("v2")
+	if short == nil || short.Duration != 3723000 || short.FileSize != 30167531 {
+		t.Fatalf("unexpected short record: %+v", short)
+	}
+
+	// 再次导入时全部跳过
+	result, err = svc.Import([]byte(strings.Join(lines, "\n")), "download_records.csv")
+	if err != nil {
+		t.Fatalf("Import again: %v", err)
+	}
+	if result.Imported != 0 || result.Duplicates != 4 {
+		t.Fatalf("unexpected result on re-import: %+v", result)
+	}
+}
+
+func TestRecordImportService_ExportJSON(t *testing.T) {
+	svc := setupRecordImportTest(t)
+
+	records := []database.DownloadRecord{
+		{ID: "a", VideoID: "va", Title: "A", Author: "作者", Status: database.DownloadStatusCompleted, LikeCount: 3, DownloadTime: time.Now()},
+		{ID: "b", VideoID: "", Title: "B"},
+	}
+	data, err := json.Marshal(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 扩展名未知时根据内容判断
+	result, err := svc.Import(data, "upload")
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if result.Format != RecordImportFormatExportJSON || result.Imported != 2 || len(result.ImportedIDs) != 2 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	got, _ := database.NewDownloadRecordRepository().GetByID("a")
+	if got == nil || got.VideoID != "va" || got.LikeCount != 3 {
+		t.Fatalf("unexpected record: %+v", got)
+	}
+	// 没有 VideoID 时使用记录 ID
+	if got, _ := database.NewDownloadRecordRepository().GetByVideoID("b"); got == nil {
+		t.Fatal("expected record b to be imported by id")
+	}
+}
+
+func TestRecordImportService_RejectsExportCSV(t *testing.T) {
+	svc := setupRecordImportTest(t)
+
+	csvData := "ID,VideoID,Title\nx,y,z\n"
+	if _, err := svc.Import([]byte(csvData), "download_records_20240501.csv"); err == nil {
+		t.Fatal("expected export CSV to be rejected")
+	}
+}
+
+func TestParseCountText(t *testing.T) {
+	tests := map[string]int64{
+		"":      0,
+		"123":   123,
+		"1,234": 1234,
+		"1.2万":  12000,
+		"10万+":  100000,
+		"3亿":    300000000,
+		"2.5w":  25000,
+		"abc":   0,
+	}
+	for input, want := range tests {
+		if got := parseCountText(input); got != want {
+			t.Errorf("parseCountText(%q) = %d, want %d", input, got, want)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"wx_channel/internal/config"
+	"wx_channel/internal/database"
+	"wx_channel/internal/utils"
+)
+
+const (
+	// retentionCheckInterval 检查磁盘空间和保留策略是否到期的间隔
+	retentionCheckInterval = 30 * time.Second
+	// retentionLowDiskInterval 磁盘空间不足时提前执行保留策略的最短间隔
+	retentionLowDiskInterval = 5 * time.Minute
+)
+
+// QueuePauser 可以暂停和恢复的下载队列，由 QueueWorker 实现
+type QueuePauser interface {
+	Pause()
+	Resume()
+	IsPaused() bool
+}
+
+// DiskStatus 下载目录所在磁盘的空间状态
+type DiskStatus struct {
+	Path         string    `json:"path"`
+	FreeBytes    uint64    `json:"freeBytes"`
+	TotalBytes   uint64    `json:"totalBytes"`
+	MinFreeBytes int64     `json:"minFreeBytes"`
+	Low          bool      `json:"low"`
+	QueuePaused  bool      `json:"queuePaused"` // 下载队列是否因磁盘空间不足被暂停
+	Error        string    `json:"error,omitempty"`
+	CheckedAt    time.Time `json:"checkedAt"`
+}
+
+// RetentionStatus 保留策略和磁盘保护的当前状态
+type RetentionStatus struct {
+	Policy     *database.RetentionPolicy `json:"policy"`
+	Disk       *DiskStatus               `json:"disk,omitempty"`
+	LastRunAt  *time.Time                `json:"lastRunAt,omitempty"`
+	LastResult *CleanupResult            `json:"lastResult,omitempty"`
+	LastError  string                    `json:"lastError,omitempty"`
+}
+
+// RetentionService 按计划执行自动清理和下载目录保留策略，并在磁盘空间不足时暂停下载队列
+type RetentionService struct {
+	cleanup  *CleanupService
+	settings *database.SettingsRepository
+	dirFunc  func() (string, error)
+
+	mu          sync.Mutex
+	runMu       sync.Mutex
+	queue       QueuePauser
+	ctx         context.Context
+	cancel      context.CancelFunc
+	ticker      *time.Ticker
+	wg          sync.WaitGroup
+	guardPaused bool
+	disk        *DiskStatus
+	lastRun     time.Time
+	lastResult  *CleanupResult
+	lastError   string
+}
+
+var (
+	retentionService     *RetentionService
+	retentionServiceOnce sync.Once
+)
+
+// GetRetentionService 返回全局保留策略服务，首次调用前需要初始化数据库
+func GetRetentionService() *RetentionService {
+	retentionServiceOnce.Do(func() {
+		retentionService = NewRetentionService(resolveDownloadsDir)
+	})
+	return retentionService
+}
+
+// NewRetentionService 创建一个新的 RetentionService，dirFunc 返回下载目录
+func NewRetentionService(dirFunc func() (string, error)) *RetentionService {
+	return &RetentionService{
+		cleanup:  NewCleanupService(),
+		settings: database.NewSettingsRepository(),
+		dirFunc:  dirFunc,
+	}
+}
+
+// resolveDownloadsDir 从当前配置解析下载目录
+func resolveDownloadsDir() (string, error) {
+	cfg := config.Get()
+	if cfg == nil {
+		return "", fmt.Errorf("config not loaded")
+	}
+	return cfg.GetResolvedDownloadsDir()
+}
+
+// Start 启动调度，queue 为空时只执行保留策略、不做磁盘保护
+func (s *RetentionService) Start(queue QueuePauser) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ticker != nil {
+		return
+	}
+	s.queue = queue
+	if s.ctx == nil || s.ctx.Err() != nil {
+		s.ctx, s.cancel = context.WithCancel(context.Background())
+	}
+
+	s.ticker = time.NewTicker(retentionCheckInterval)
+	ticker := s.ticker
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.tick()
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+				s.tick()
+			}
+		}
+	}()
+}
+
+// Stop 停止调度，等待执行中的清理结束
+func (s *RetentionService) Stop() {
+	s.mu.Lock()
+	if s.ticker == nil {
+		s.mu.Unlock()
+		return
+	}
+	ticker := s.ticker
+	s.ticker = nil
+	cancel := s.cancel
+	s.mu.Unlock()
+
+	ticker.Stop()
+	if cancel != nil {
+		cancel()
+	}
+	s.wg.Wait()
+}
+
+// tick 检查磁盘空间，并在保留策略到期（或磁盘空间不足）时执行自动清理
+func (s *RetentionService) tick() {
+	policy, err := s.settings.LoadRetentionPolicy()
+	if err != nil {
+		utils.LogWarn("[Retention] 读取保留策略失败: %v", err)
+		return
+	}
+	dir, err := s.dirFunc()
+	if err != nil {
+		utils.LogWarn("[Retention] 解析下载目录失败: %v", err)
+		return
+	}
+
+	disk := s.CheckDisk(dir, policy)
+
+	s.mu.Lock()
+	lastRun := s.lastRun
+	s.mu.Unlock()
+
+	since := time.Since(lastRun)
+	due := since >= time.Duration(policy.IntervalMinutes)*time.Minute
+	if disk != nil && disk.Low && policy.Enabled {
+		due = due || since >= retentionLowDiskInterval
+	}
+	if !due {
+		return
+	}
+	if _, err := s.RunNow(); err != nil {
+		utils.LogWarn("[Retention] 自动清理失败: %v", err)
+		return
+	}
+	// 清理后立即重新检查，空间足够时恢复队列
+	if disk != nil && disk.Low {
+		s.CheckDisk(dir, policy)
+	}
+}
+
+// RunNow 立即执行一次自动清理（浏览记录和下载目录保留策略）
+func (s *RetentionService) RunNow() (*CleanupResult, error) {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	dir, err := s.dirFunc()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve downloads dir: %w", err)
+	}
+	result, err := s.cleanup.RunAutoCleanup(dir)
+
+	s.mu.Lock()
+	s.lastRun = time.Now()
+	if err != nil {
+		s.lastError = err.Error()
+	} else {
+		s.lastError = ""
+		s.lastResult = result
+	}
+	s.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+	if result.FilesDeleted > 0 || result.DownloadRecordsDeleted > 0 {
+		utils.LogInfo("[Retention] 已按保留策略删除 %d 个视频，释放 %d 字节", result.FilesDeleted, result.SpaceFreed)
+	}
+	return result, nil
+}
+
+// Preview 按策略评估下载目录但不删除，policy 为空时使用已保存的策略
+func (s *RetentionService) Preview(policy *database.RetentionPolicy) (*RetentionReport, error) {
+	if policy == nil {
+		var err error
+		if policy, err = s.settings.LoadRetentionPolicy(); err != nil {
+			return nil, err
+		}
+	}
+	dir, err := s.dirFunc()
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve downloads dir: %w", err)
+	}
+
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+	return s.cleanup.ApplyRetention(dir, policy, true)
+}
+
+// CheckDisk 检查下载目录所在磁盘的剩余空间
+// 低于 MinFreeBytes 时暂停下载队列，空间恢复到阈值的 110% 后恢复由此暂停的队列
+func (s *RetentionService) CheckDisk(dir string, policy *database.RetentionPolicy) *DiskStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if policy.MinFreeBytes <= 0 {
+		s.releaseQueueLocked()
+		s.disk = nil
+		return nil
+	}
+
+	status := &DiskStatus{Path: dir, MinFreeBytes: policy.MinFreeBytes, CheckedAt: time.Now()}
+	free, total, err := utils.DiskUsage(dir)
+	if err != nil {
+		status.Error = err.Error()
+		status.QueuePaused = s.guardPaused
+		s.disk = status
+		return status
+	}
+	status.FreeBytes = free
+	status.TotalBytes = total
+	status.Low = free < uint64(policy.MinFreeBytes)
+
+	switch {
+	case status.Low && !s.guardPaused && s.queue != nil && !s.queue.IsPaused():
+		// 用户已手动暂停时不接管暂停状态
+		s.queue.Pause()
+		s.guardPaused = true
+		utils.LogWarn("[Retention] 磁盘剩余空间不足 (%d 字节)，已暂停下载队列", free)
+	case !status.Low && s.guardPaused && free >= uint64(policy.MinFreeBytes+policy.MinFreeBytes/10):
+		s.releaseQueueLocked()
+		utils.LogInfo("[Retention] 磁盘剩余空间已恢复 (%d 字节)，已恢复下载队列", free)
+	}
+
+	status.QueuePaused = s.guardPaused
+	s.disk = status
+	return status
+}
+
+// releaseQueueLocked 恢复由磁盘保护暂停的队列，调用方需持有锁
+func (s *RetentionService) releaseQueueLocked() {
+	if s.guardPaused && s.queue != nil {
+		s.queue.Resume()
+	}
+	s.guardPaused = false
+}
+
+// Status 返回已保存的策略和最近一次检查的结果
+func (s *RetentionService) Status() (*RetentionStatus, error) {
+	policy, err := s.settings.LoadRetentionPolicy()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := &RetentionStatus{
+		Policy:     policy,
+		Disk:       s.disk,
+		LastResult: s.lastResult,
+		LastError:  s.lastError,
+	}
+	if !s.lastRun.IsZero() {
+		lastRun := s.lastRun
+		status.LastRunAt = &lastRun
+	}
+	return status, nil
+}
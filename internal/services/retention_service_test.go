@@ -0,0 +1,266 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"wx_channel/internal/database"
+)
+
+func TestPlanRetention(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	inode := 0
+	file := func(id, author string, daysAgo int, size int64) retentionFile {
+		inode++
+		return retentionFile{
+			record: database.DownloadRecord{ID: id, DownloadTime: now.AddDate(0, 0, -daysAgo)},
+			author: author,
+			size:   size,
+			inode:  inode,
+		}
+	}
+	files := []retentionFile{
+		file("a1", "A", 1, 100),
+		file("a2", "A", 2, 100),
+		file("a3", "A", 3, 100),
+		file("b1", "B", 10, 100),
+		file("b2", "B", 40, 100),
+		file("c1", "C", 40, 100),
+		file("c2", "C", 50, 100),
+	}
+	policy := &database.RetentionPolicy{
+		KeepLastPerAuthor: 2,
+		MaxAgeDays:        30,
+		AuthorMaxAgeDays:  map[string]int{"C": 0}, // C 的视频不按天数过期
+		MaxTotalBytes:     250,
+		IntervalMinutes:   database.DefaultRetentionIntervalMinutes,
+	}
+
+	report := planRetention(policy, files, map[string]bool{"b1": true}, now)
+	got := map[string]string{}
+	for _, c := range report.Candidates {
+		got[c.RecordID] = c.Reason
+	}
+	want := map[string]string{
+		"a3": RetentionReasonKeepLast,
+		"b2": RetentionReasonMaxAge,
+		"c2": RetentionReasonMaxTotalSize,
+		"c1": RetentionReasonMaxTotalSize,
+		"a2": RetentionReasonMaxTotalSize,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("candidates = %v, want %v", got, want)
+	}
+	for id, reason := range want {
+		if got[id] != reason {
+			t.Errorf("candidate %s reason = %q, want %q", id, got[id], reason)
+		}
+	}
+	if report.Candidates[0].RecordID != "c2" {
+		t.Errorf("candidates should be listed oldest first: %+v", report.Candidates)
+	}
+	// b1 超出总大小时因置顶被保留
+	if report.PinnedKept != 1 || report.TotalBytes != 700 || report.RemainingBytes != 200 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+}
+
+func TestCleanupService_ApplyRetention(t *testing.T) {
+	if err := database.Initialize(&database.Config{DBPath: filepath.Join(t.TempDir(), "records.db")}); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	dir := t.TempDir()
+	repo := database.NewDownloadRecordRepository()
+	for i, id := range []string{"new", "old", "pinned"} {
+		path := writeLibraryFile(t, filepath.Join(dir, "作者", id+".mp4"), []byte(id))
+		if err := repo.Create(&database.DownloadRecord{
+			ID: id, VideoID: "v-" + id, FilePath: path, Status: database.DownloadStatusCompleted,
+			DownloadTime: time.Now().AddDate(0, 0, -i*10),
+		}); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+	if err := database.NewDownloadPinRepository().Pin("pinned"); err != nil {
+		t.Fatalf("Pin: %v", err)
+	}
+
+	svc := NewCleanupService()
+	policy := &database.RetentionPolicy{MaxAgeDays: 5, IntervalMinutes: database.DefaultRetentionIntervalMinutes}
+
+	// 预览不删除文件
+	report, err := svc.ApplyRetention(dir, policy, true)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if len(report.Candidates) != 1 || report.Candidates[0].RecordID != "old" || report.PinnedKept != 1 {
+		t.Fatalf("unexpected dry run report: %+v", report)
+	}
+	if _, err := os.Stat(report.Candidates[0].FilePath); err != nil {
+		t.Fatalf("dry run removed file: %v", err)
+	}
+
+	report, err = svc.ApplyRetention(dir, policy, false)
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if report.RecordsDeleted != 1 || report.FilesDeleted != 1 || report.SpaceFreed != int64(len("old")) {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if got, _ := repo.GetByID("old"); got != nil {
+		t.Fatalf("record old should be deleted: %+v", got)
+	}
+	if got, _ := repo.GetByID("pinned"); got == nil {
+		t.Fatal("pinned record should be kept")
+	}
+}
+
+func TestCleanupService_ApplyRetentionHardlinks(t *testing.T) {
+	if err := database.Initialize(&database.Config{DBPath: filepath.Join(t.TempDir(), "records.db")}); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	dir := t.TempDir()
+	data := make([]byte, 1000)
+	original := writeLibraryFile(t, filepath.Join(dir, "作者A", "original.mp4"), data)
+	linked := filepath.Join(dir, "作者B", "linked.mp4")
+	if err := os.MkdirAll(filepath.Dir(linked), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.Link(original, linked); err != nil {
+		t.Skipf("hardlinks not supported: %v", err)
+	}
+	newest := writeLibraryFile(t, filepath.Join(dir, "作者C", "newest.mp4"), data)
+
+	repo := database.NewDownloadRecordRepository()
+	for i, path := range []string{newest, linked, original} {
+		if err := repo.Create(&database.DownloadRecord{
+			ID: filepath.Base(path), VideoID: filepath.Base(path), FilePath: path, Status: database.DownloadStatusCompleted,
+			DownloadTime: time.Now().Add(-time.Duration(i) * time.Hour),
+		}); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+
+	svc := NewCleanupService()
+	// 两个硬链接只占用一份空间，总大小 2000 未超出上限
+	policy := &database.RetentionPolicy{MaxTotalBytes: 2000, IntervalMinutes: database.DefaultRetentionIntervalMinutes}
+	report, err := svc.ApplyRetention(dir, policy, true)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if report.TotalBytes != 2000 || len(report.Candidates) != 0 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+
+	// 超出上限时同一文件的所有链接一起删除，释放的空间只计算一次
+	policy.MaxTotalBytes = 1500
+	report, err = svc.ApplyRetention(dir, policy, false)
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if len(report.Candidates) != 2 || report.RemainingBytes != 1000 ||
+		report.FilesDeleted != 2 || report.SpaceFreed != 1000 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if got, _ := repo.GetByID("newest.mp4"); got == nil {
+		t.Fatal("newest record should be kept")
+	}
+
+	// 只删除其中一个链接时不计入释放的空间
+	other := filepath.Join(dir, "作者C", "other.mp4")
+	if err := os.Link(newest, other); err != nil {
+		t.Fatalf("link: %v", err)
+	}
+	result, err := svc.DeleteSelectedDownloadRecords([]string{"newest.mp4"}, true)
+	if err != nil {
+		t.Fatalf("DeleteSelectedDownloadRecords: %v", err)
+	}
+	if result.FilesDeleted != 1 || result.SpaceFreed != 0 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+}
+
+func TestCleanupService_RunAutoCleanupPrunesEngagementSnapshots(t *testing.T) {
+	if err := database.Initialize(&database.Config{DBPath: filepath.Join(t.TempDir(), "records.db")}); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	repo := database.NewEngagementRepository()
+	for _, age := range []int{10, 100, 400} {
+		if err := repo.Add(&database.EngagementSnapshot{
+			VideoID: "v1", LikeCount: int64(age), CapturedAt: time.Now().AddDate(0, 0, -age),
+		}); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+
+	svc := NewCleanupService()
+	// 默认保留 365 天
+	result, err := svc.RunAutoCleanup("")
+	if err != nil || result.EngagementSnapshotsDeleted != 1 {
+		t.Fatalf("RunAutoCleanup: %+v, %v", result, err)
+	}
+
+	settings := database.DefaultSettings()
+	settings.EngagementRetentionDays = 60
+	if err := database.NewSettingsRepository().SaveAndValidate(settings); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	result, err = svc.RunAutoCleanup("")
+	if err != nil || result.EngagementSnapshotsDeleted != 1 {
+		t.Fatalf("RunAutoCleanup: %+v, %v", result, err)
+	}
+	if latest, _ := repo.GetLatest("v1"); latest == nil || latest.LikeCount != 10 {
+		t.Fatalf("unexpected latest snapshot: %+v", latest)
+	}
+
+	settings.EngagementRetentionDays = 7
+	if err := database.NewSettingsRepository().Validate(settings); err == nil {
+		t.Fatal("expected retention shorter than the tracking window to be rejected")
+	}
+}
+
+type fakeQueue struct {
+	mu     sync.Mutex
+	paused bool
+}
+
+func (q *fakeQueue) Pause()  { q.mu.Lock(); q.paused = true; q.mu.Unlock() }
+func (q *fakeQueue) Resume() { q.mu.Lock(); q.paused = false; q.mu.Unlock() }
+func (q *fakeQueue) IsPaused() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.paused
+}
+
+func TestRetentionService_DiskGuard(t *testing.T) {
+	dir := t.TempDir()
+	svc := &RetentionService{queue: &fakeQueue{}}
+	queue := svc.queue.(*fakeQueue)
+
+	// 阈值远大于磁盘剩余空间时暂停队列
+	status := svc.CheckDisk(dir, &database.RetentionPolicy{MinFreeBytes: 1 << 62})
+	if status == nil || status.Error != "" || !status.Low || !status.QueuePaused || !queue.IsPaused() {
+		t.Fatalf("expected queue to be paused: %+v", status)
+	}
+
+	// 空间恢复后恢复队列
+	status = svc.CheckDisk(dir, &database.RetentionPolicy{MinFreeBytes: 1})
+	if status.Low || status.QueuePaused || queue.IsPaused() {
+		t.Fatalf("expected queue to be resumed: %+v", status)
+	}
+
+	// 用户手动暂停的队列不会被恢复
+	queue.Pause()
+	svc.CheckDisk(dir, &database.RetentionPolicy{MinFreeBytes: 1 << 62})
+	if svc.CheckDisk(dir, &database.RetentionPolicy{}) != nil || !queue.IsPaused() {
+		t.Fatal("manually paused queue should stay paused")
+	}
+}
@@ -0,0 +1,458 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"wx_channel/internal/database"
+	"wx_channel/internal/utils"
+	"wx_channel/internal/websocket"
+)
+
+var (
+	// ErrSavedSearchNotFound 保存的搜索不存在
+	ErrSavedSearchNotFound = errors.New("保存的搜索不存在")
+	// ErrSavedSearchExists 相同关键词和类型的搜索已存在
+	ErrSavedSearchExists = errors.New("该关键词的同类搜索已存在")
+)
+
+const (
+	// DefaultSavedSearchMaxResults 每次执行默认收集的结果数
+	DefaultSavedSearchMaxResults = 50
+	// MaxSavedSearchResults 每次执行最多收集的结果数
+	MaxSavedSearchResults = 500
+	// MinSavedSearchInterval 定期执行的最小间隔 (分钟)
+	MinSavedSearchInterval = 30
+	// savedSearchMaxPages 单次执行最多翻页数
+	savedSearchMaxPages = 20
+)
+
+// savedSearchPageInterval 翻页间隔，测试中可调小
+var savedSearchPageInterval = 2 * time.Second
+
+var htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// SavedSearchService 管理保存的关键词搜索，并按计划翻页执行 contact_list 搜索
+type SavedSearchService struct {
+	repo *database.SavedSearchRepository
+
+	// callAPI 调用注入脚本的 API，默认转发到 hub，测试中可替换
+	callAPI func(key string, body interface{}, timeout time.Duration) (json.RawMessage, error)
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	mu     sync.Mutex
+	wg     sync.WaitGroup
+	ticker *time.Ticker
+}
+
+// NewSavedSearchService 创建一个新的 SavedSearchService
+func NewSavedSearchService(hub *websocket.Hub) *SavedSearchService {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &SavedSearchService{
+		repo:   database.NewSavedSearchRepository(),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	if hub != nil {
+		s.callAPI = func(key string, body interface{}, timeout time.Duration) (json.RawMessage, error) {
+			data, err := hub.CallAPI(key, body, timeout)
+			return json.RawMessage(data), err
+		}
+	}
+	return s
+}
+
+// List 返回所有保存的搜索
+func (s *SavedSearchService) List() ([]database.SavedSearch, error) {
+	return s.repo.List()
+}
+
+// Get 获取保存的搜索
+func (s *SavedSearchService) Get(id string) (*database.SavedSearch, error) {
+	search, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if search == nil {
+		return nil, ErrSavedSearchNotFound
+	}
+	return search, nil
+}
+
+// Create 校验并添加保存的搜索，启用时立即安排第一次执行
+func (s *SavedSearchService) Create(search *database.SavedSearch) error {
+	if err := normalizeSavedSearch(search); err != nil {
+		return err
+	}
+	search.NextRunAt = nil
+	if search.Enabled {
+		now := time.Now()
+		search.NextRunAt = &now
+	}
+	if err := s.repo.Create(search); err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return ErrSavedSearchExists
+		}
+		return err
+	}
+	return nil
+}
+
+// Update 修改搜索条件和调度设置，保留执行状态
+func (s *SavedSearchService) Update(id string, search *database.SavedSearch) error {
+	existing, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	if err := normalizeSavedSearch(search); err != nil {
+		return err
+	}
+
+	existing.Keyword = search.Keyword
+	existing.SearchType = search.SearchType
+	existing.MaxResults = search.MaxResults
+	if existing.IntervalMinutes != search.IntervalMinutes {
+		existing.NextRunAt = nil
+	}
+	existing.IntervalMinutes = search.IntervalMinutes
+	existing.Enabled = search.Enabled
+	scheduleSavedSearch(existing, time.Now())
+
+	if err := s.repo.Update(existing); err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return ErrSavedSearchExists
+		}
+		return err
+	}
+	*search = *existing
+	return nil
+}
+
+// Delete 删除保存的搜索及其结果
+func (s *SavedSearchService) Delete(id string) error {
+	return s.repo.Delete(id)
+}
+
+// RunNow 安排搜索在调度器下一次检查时执行
+func (s *SavedSearchService) RunNow(id string) (*database.SavedSearch, error) {
+	search, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if err := s.repo.SetNextRun(id, now); err != nil {
+		return nil, err
+	}
+	search.NextRunAt = &now
+	return search, nil
+}
+
+// Results 分页返回搜索结果；newOnly 为 true 时只返回最近一次执行首次出现的结果
+func (s *SavedSearchService) Results(id string, newOnly bool, page, pageSize int) (*database.PagedResult[database.SavedSearchResult], error) {
+	search, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+	var since *time.Time
+	if newOnly {
+		if search.LastRunAt == nil {
+			return database.NewPagedResult([]database.SavedSearchResult{}, 0, 1, 20), nil
+		}
+		since = search.LastRunAt
+	}
+	return s.repo.ListResults(id, since, &database.PaginationParams{Page: page, PageSize: pageSize})
+}
+
+// normalizeSavedSearch 校验搜索条件并补全默认值
+func normalizeSavedSearch(search *database.SavedSearch) error {
+	search.Keyword = strings.TrimSpace(search.Keyword)
+	if search.Keyword == "" {
+		return &ValidationError{Err: errors.New("关键词不能为空")}
+	}
+	if len(search.Keyword) > 100 {
+		return &ValidationError{Err: errors.New("关键词过长 (最多 100 个字符)")}
+	}
+	if search.SearchType < database.SavedSearchTypeAccount || search.SearchType > database.SavedSearchTypeVideo {
+		return &ValidationError{Err: errors.New("搜索类型必须为 1 (账号)、2 (直播) 或 3 (视频)")}
+	}
+	if search.MaxResults <= 0 {
+		search.MaxResults = DefaultSavedSearchMaxResults
+	}
+	if search.MaxResults > MaxSavedSearchResults {
+		search.MaxResults = MaxSavedSearchResults
+	}
+	if search.IntervalMinutes < 0 || (search.IntervalMinutes > 0 && search.IntervalMinutes < MinSavedSearchInterval) {
+		return &ValidationError{Err: fmt.Errorf("执行间隔必须为 0 (仅手动执行) 或不少于 %d 分钟", MinSavedSearchInterval)}
+	}
+	return nil
+}
+
+// scheduleSavedSearch 为已启用但没有下次执行时间的搜索安排执行：从未执行过的立即执行，其余按间隔顺延
+func scheduleSavedSearch(search *database.SavedSearch, now time.Time) {
+	if !search.Enabled || search.NextRunAt != nil {
+		return
+	}
+	next := now
+	if search.LastRunAt != nil {
+		if search.IntervalMinutes == 0 {
+			return
+		}
+		if t := search.LastRunAt.Add(time.Duration(search.IntervalMinutes) * time.Minute); t.After(now) {
+			next = t
+		}
+	}
+	search.NextRunAt = &next
+}
+
+// Start 启动调度器，每分钟检查一次到期的搜索
+func (s *SavedSearchService) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ticker != nil {
+		return
+	}
+	if s.ctx == nil || s.ctx.Err() != nil {
+		s.ctx, s.cancel = context.WithCancel(context.Background())
+	}
+
+	s.ticker = time.NewTicker(time.Minute)
+	ticker := s.ticker
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+				s.runDue()
+			}
+		}
+	}()
+}
+
+// Stop 停止调度器，等待执行中的搜索结束
+func (s *SavedSearchService) Stop() {
+	s.mu.Lock()
+	if s.ticker == nil {
+		s.mu.Unlock()
+		return
+	}
+	ticker := s.ticker
+	s.ticker = nil
+	cancel := s.cancel
+	s.mu.Unlock()
+
+	ticker.Stop()
+	if cancel != nil {
+		cancel()
+	}
+	s.wg.Wait()
+}
+
+// runDue 依次执行所有到期的搜索
+func (s *SavedSearchService) runDue() {
+	searches, err := s.repo.ListDue(time.Now())
+	if err != nil {
+		utils.LogWarn("[SavedSearch] 读取待执行的搜索失败: %v", err)
+		return
+	}
+	for i := range searches {
+		if s.ctx.Err() != nil {
+			return
+		}
+		s.runSearch(&searches[i])
+	}
+}
+
+// runSearch 执行一次搜索并记录结果，返回首次出现的结果数
+// 翻页中途失败时保存已收集的结果并记录错误
+func (s *SavedSearchService) runSearch(search *database.SavedSearch) (int, error) {
+	runAt := time.Now()
+	results, runErr := s.collectResults(search)
+
+	newCount, err := s.repo.SaveResults(search, runAt, results)
+	if err != nil && runErr == nil {
+		runErr = err
+	}
+
+	var next *time.Time
+	if search.IntervalMinutes > 0 {
+		t := runAt.Add(time.Duration(search.IntervalMinutes) * time.Minute)
+		next = &t
+	}
+	errMsg := ""
+	if runErr != nil {
+		errMsg = runErr.Error()
+		utils.LogWarn("[SavedSearch] 搜索 [%s] 执行失败: %v", search.Keyword, runErr)
+	} else {
+		utils.LogInfo("[SavedSearch] 搜索 [%s] 完成: %d 个结果，%d 个新结果", search.Keyword, len(results), newCount)
+	}
+	if err := s.repo.RecordRun(search.ID, runAt, len(results), newCount, errMsg, next); err != nil {
+		utils.LogWarn("[SavedSearch] 保存搜索 [%s] 执行状态失败: %v", search.Keyword, err)
+	}
+	return newCount, runErr
+}
+
+// collectResults 翻页收集结果直到达到 MaxResults 或没有更多结果
+func (s *SavedSearchService) collectResults(search *database.SavedSearch) ([]database.SavedSearchResult, error) {
+	if s.callAPI == nil {
+		return nil, fmt.Errorf("search API caller is not configured")
+	}
+
+	results := make([]database.SavedSearchResult, 0, search.MaxResults)
+	seen := make(map[string]struct{})
+	marker := ""
+	for page := 0; page < savedSearchMaxPages && len(results) < search.MaxResults; page++ {
+		if page > 0 {
+			select {
+			case <-s.ctx.Done():
+				return results, s.ctx.Err()
+			case <-time.After(savedSearchPageInterval):
+			}
+		}
+
+		items, nextMarker, hasMore, err := s.fetchSearchPage(search, marker)
+		if err != nil {
+			return results, err
+		}
+		for _, item := range items {
+			result, ok := parseSavedSearchResult(search.SearchType, item)
+			if !ok {
+				continue
+			}
+			if _, exists := seen[result.ResultKey]; exists {
+				continue
+			}
+			seen[result.ResultKey] = struct{}{}
+			results = append(results, result)
+			if len(results) >= search.MaxResults {
+				break
+			}
+		}
+
+		// 直播搜索不支持翻页
+		if !hasMore || nextMarker == "" || len(items) == 0 || search.SearchType == database.SavedSearchTypeLive {
+			break
+		}
+		marker = nextMarker
+	}
+	return results, nil
+}
+
+// fetchSearchPage 拉取一页 contact_list 结果
+func (s *SavedSearchService) fetchSearchPage(search *database.SavedSearch, marker string) ([]map[string]interface{}, string, bool, error) {
+	body := websocket.SearchContactBody{
+		Keyword:    search.Keyword,
+		Type:       search.SearchType,
+		NextMarker: EncodeNextMarker(marker),
+	}
+	data, err := s.callAPI("key:channels:contact_list", body, 60*time.Second)
+	if err != nil {
+		if strings.Contains(err.Error(), "no available client") || strings.Contains(err.Error(), "no ready client") {
+			return nil, "", false, fmt.Errorf("微信客户端未连接或已退出")
+		}
+		return nil, "", false, err
+	}
+
+	var rawResp struct {
+		ErrCode      int    `json:"errCode"`
+		ErrMsg       string `json:"errMsg"`
+		BaseResponse struct {
+			Ret int `json:"Ret"`
+		} `json:"BaseResponse"`
+		Data struct {
+			InfoList   []map[string]interface{} `json:"infoList"`
+			ObjectList []map[string]interface{} `json:"objectList"`
+			LastBuff   string                   `json:"lastBuff"`
+			Continue   int                      `json:"continueFlag"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &rawResp); err != nil {
+		return nil, "", false, fmt.Errorf("解析返回数据失败: %w", err)
+	}
+	if rawResp.ErrCode != 0 {
+		return nil, "", false, fmt.Errorf("搜索失败，状态码: %d %s", rawResp.ErrCode, rawResp.ErrMsg)
+	}
+	if rawResp.BaseResponse.Ret != 0 {
+		return nil, "", false, fmt.Errorf("搜索被拒绝，状态码: %d (可能是请求过于频繁)", rawResp.BaseResponse.Ret)
+	}
+
+	items := rawResp.Data.ObjectList
+	if search.SearchType == database.SavedSearchTypeAccount {
+		items = rawResp.Data.InfoList
+	}
+	return items, rawResp.Data.LastBuff, rawResp.Data.Continue != 0, nil
+}
+
+// parseSavedSearchResult 提取结果的去重键和展示字段：账号取 contact，视频和直播取作品对象
+func parseSavedSearchResult(searchType int, item map[string]interface{}) (database.SavedSearchResult, bool) {
+	var result database.SavedSearchResult
+	contact, _ := item["contact"].(map[string]interface{})
+
+	if searchType == database.SavedSearchTypeAccount {
+		if contact == nil {
+			return result, false
+		}
+		result.Username = jsonString(contact["username"])
+		result.Author = stripHTMLTags(jsonString(contact["nickname"]))
+		result.Title = result.Author
+		result.CoverURL = jsonString(contact["headUrl"])
+		result.ResultKey = result.Username
+	} else {
+		result.ObjectID = jsonString(item["id"])
+		result.NonceID = jsonString(item["objectNonceId"])
+		result.Author = stripHTMLTags(jsonString(item["nickname"]))
+		if contact != nil {
+			result.Username = jsonString(contact["username"])
+			if result.Author == "" {
+				result.Author = stripHTMLTags(jsonString(contact["nickname"]))
+			}
+		}
+		if desc, ok := item["objectDesc"].(map[string]interface{}); ok {
+			result.Title = stripHTMLTags(jsonString(desc["description"]))
+			if mediaList, ok := desc["media"].([]interface{}); ok && len(mediaList) > 0 {
+				if media, ok := mediaList[0].(map[string]interface{}); ok {
+					result.CoverURL = jsonString(media["thumbUrl"])
+				}
+			}
+		}
+		result.ResultKey = result.ObjectID
+	}
+	if result.ResultKey == "" {
+		return result, false
+	}
+	if raw, err := json.Marshal(item); err == nil {
+		result.Raw = string(raw)
+	}
+	return result, true
+}
+
+func jsonString(v interface{}) string {
+	switch s := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return s
+	case float64:
+		return fmt.Sprintf("%.0f", s)
+	default:
+		return fmt.Sprintf("%v", s)
+	}
+}
+
+// stripHTMLTags 去掉搜索结果中的高亮标签，例如 <em class="highlight">关键词</em>
+func stripHTMLTags(s string) string {
+	return strings.TrimSpace(htmlTagPattern.ReplaceAllString(s, ""))
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"wx_channel/internal/database"
+	"wx_channel/internal/websocket"
+)
+
+// fakeSearchPages 模拟 contact_list 搜索接口，key 为游标，value 为该页视频 ID
+type fakeSearchPages struct {
+	pages   map[string][]string
+	next    map[string]string
+	markers []string
+}
+
+func (f *fakeSearchPages) call(key string, body interface{}, timeout time.Duration) (json.RawMessage, error) {
+	req := body.(websocket.SearchContactBody)
+	marker, err := url.QueryUnescape(req.NextMarker)
+	if err != nil {
+		return nil, err
+	}
+	f.markers = append(f.markers, marker)
+
+	var objects []map[string]interface{}
+	for _, id := range f.pages[marker] {
+		objects = append(objects, map[string]interface{}{
+			"id":            id,
+			"objectNonceId": "nonce-" + id,
+			"contact":       map[string]interface{}{"username": "author@finder", "nickname": "作者"},
+			"objectDesc":    map[string]interface{}{"description": fmt.Sprintf(`<em class="highlight">%s</em> 视频 %s`, req.Keyword, id)},
+		})
+	}
+	continueFlag := 0
+	if f.next[marker] != "" {
+		continueFlag = 1
+	}
+	return json.Marshal(map[string]interface{}{
+		"BaseResponse": map[string]interface{}{"Ret": 0},
+		"data": map[string]interface{}{
+			"objectList":   objects,
+			"lastBuff":     f.next[marker],
+			"continueFlag": continueFlag,
+		},
+	})
+}
+
+func TestSavedSearchService_RunDeduplicatesAcrossRuns(t *testing.T) {
+	setupQueueWorkerTest(t)
+	oldInterval := savedSearchPageInterval
+	savedSearchPageInterval = time.Millisecond
+	t.Cleanup(func() { savedSearchPageInterval = oldInterval })
+
+	fake := &fakeSearchPages{
+		pages: map[string][]string{"": {"v1", "v2"}, "p 2": {"v2", "v3"}},
+		next:  map[string]string{"": "p 2"},
+	}
+	s := NewSavedSearchService(nil)
+	s.callAPI = fake.call
+
+	var invalid *ValidationError
+	if err := s.Create(&database.SavedSearch{Keyword: "美食", SearchType: 4}); !errors.As(err, &invalid) {
+		t.Fatalf("expected validation error for an unknown type, got %v", err)
+	}
+	if err := s.Create(&database.SavedSearch{Keyword: "美食", SearchType: 3, IntervalMinutes: 5}); !errors.As(err, &invalid) {
+		t.Fatalf("expected validation error for a short interval, got %v", err)
+	}
+
+	search := &database.SavedSearch{Keyword: " 美食 ", SearchType: database.SavedSearchTypeVideo, IntervalMinutes: 60, Enabled: true}
+	if err := s.Create(search); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if search.Keyword != "美食" || search.MaxResults != DefaultSavedSearchMaxResults || search.NextRunAt == nil {
+		t.Fatalf("unexpected normalized search: %+v", search)
+	}
+
+	newCount, err := s.runSearch(search)
+	if err != nil || newCount != 3 {
+		t.Fatalf("first run: %d new, %v", newCount, err)
+	}
+	if len(fake.markers) != 2 || fake.markers[1] != "p 2" {
+		t.Fatalf("unexpected markers: %q", fake.markers)
+	}
+
+	// 第二次执行只出现一个新结果
+	fake.pages[""] = []string{"v4", "v1"}
+	fake.next = map[string]string{}
+	newCount, err = s.runSearch(search)
+	if err != nil || newCount != 1 {
+		t.Fatalf("second run: %d new, %v", newCount, err)
+	}
+
+	stored, err := s.Get(search.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if stored.LastResultCount != 2 || stored.LastNewCount != 1 || stored.NextRunAt == nil ||
+		stored.NextRunAt.Sub(*stored.LastRunAt) != time.Hour {
+		t.Fatalf("unexpected run state: %+v", stored)
+	}
+
+	fresh, err := s.Results(search.ID, true, 1, 10)
+	if err != nil || fresh.Total != 1 || fresh.Items[0].ObjectID != "v4" {
+		t.Fatalf("expected only the new result, got %+v, %v", fresh, err)
+	}
+	all, err := s.Results(search.ID, false, 1, 10)
+	if err != nil || all.Total != 4 {
+		t.Fatalf("expected 4 results, got %+v, %v", all, err)
+	}
+	for _, item := range all.Items {
+		if item.Title != "美食 视频 "+item.ObjectID || item.Username != "author@finder" || item.Author != "作者" {
+			t.Fatalf("unexpected result: %+v", item)
+		}
+	}
+
+	// 每次执行最多收集 MaxResults 个结果
+	search.MaxResults = 1
+	fake.pages[""] = []string{"v5", "v6"}
+	if newCount, err := s.runSearch(search); err != nil || newCount != 1 {
+		t.Fatalf("limited run: %d new, %v", newCount, err)
+	}
+
+	if _, err := s.Results("missing", false, 1, 10); !errors.Is(err, ErrSavedSearchNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
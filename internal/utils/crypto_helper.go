@@ -32,34 +32,11 @@ func DecryptFileInPlace(filePath string, key string, decryptorPrefixStr string,
 	}
 	defer f.Close()
 
-	var decryptorPrefix []byte
-	var prefixLen int
-
-	// Priority 1: Use Key to generate decryptor array
-	if key != "" {
-		seed, err := ParseKey(key)
-		if err != nil {
-			return fmt.Errorf("failed to parse key: %v", err)
-		}
-		prefixLen = 131072 // 128KB default for generated arrays
-		decryptorPrefix = util.GenerateDecryptorArray(seed, prefixLen)
-	} else if decryptorPrefixStr != "" && prefixLenInput > 0 {
-		// Priority 2: Use provided decryptor prefix string (Base64)
-		var err error
-		decryptorPrefix, err = base64.StdEncoding.DecodeString(decryptorPrefixStr)
-		if err != nil {
-			return fmt.Errorf("failed to decode decryptor prefix: %v", err)
-		}
-		prefixLen = prefixLenInput
-	} else {
-		return fmt.Errorf("missing decryption key or prefix")
-	}
-
-	// Double check prefix length consistency
-	if len(decryptorPrefix) < prefixLen {
-		// If generated/decoded is shorter, adjust len
-		prefixLen = len(decryptorPrefix)
+	decryptorPrefix, err := BuildDecryptorPrefix(key, decryptorPrefixStr, prefixLenInput)
+	if err != nil {
+		return err
 	}
+	prefixLen := len(decryptorPrefix)
 
 	// Read file header
 	chunk := make([]byte, prefixLen)
@@ -95,6 +72,53 @@ func DecryptFileInPlace(filePath string, key string, decryptorPrefixStr string,
 	return nil
 }
 
+// BuildDecryptorPrefix returns the XOR decryptor for the encrypted file prefix.
+// A key takes priority over a Base64 decryptor array, same as DecryptFileInPlace.
+func BuildDecryptorPrefix(key string, decryptorPrefixStr string, prefixLenInput int) ([]byte, error) {
+	// Priority 1: Use Key to generate decryptor array
+	if key != "" {
+		seed, err := ParseKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse key: %v", err)
+		}
+		return util.GenerateDecryptorArray(seed, EncryptedPrefixLen), nil
+	}
+
+	// Priority 2: Use provided decryptor prefix string (Base64)
+	if decryptorPrefixStr != "" && prefixLenInput > 0 {
+		decryptorPrefix, err := base64.StdEncoding.DecodeString(decryptorPrefixStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode decryptor prefix: %v", err)
+		}
+		// If decoded is longer than declared, only the declared length is encrypted
+		if len(decryptorPrefix) > prefixLenInput {
+			decryptorPrefix = decryptorPrefix[:prefixLenInput]
+		}
+		return decryptorPrefix, nil
+	}
+
+	return nil, fmt.Errorf("missing decryption key or prefix")
+}
+
+// ValidateDecryptedFile checks that a file decrypted while streaming starts with a media header
+func ValidateDecryptedFile(filePath string) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	header := make([]byte, 32)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return fmt.Errorf("failed to read file header: %v", err)
+	}
+	if err := validateDecryptedVideoHeader(header[:n]); err != nil {
+		return fmt.Errorf("decrypted header validation failed: %v", err)
+	}
+	return nil
+}
+
 // ParseKey parses a key string into uint64 seed
 func ParseKey(key string) (uint64, error) {
 	if seed, err := strconv.ParseUint(key, 10, 64); err == nil {
@@ -1,7 +1,9 @@
 package utils
 
 import (
+	"bytes"
 	"encoding/base64"
+	"io"
 	"os"
 	"path/filepath"
 	"testing"
@@ -111,3 +113,32 @@ func TestLooksLikeMediaHeader(t *testing.T) {
 		})
 	}
 }
+
+func TestPrefixDecryptReaderMatchesDecryptReaderAtOffsets(t *testing.T) {
+	t.Parallel()
+
+	const seed uint64 = 123456789
+	decryptor, err := BuildDecryptorPrefix("123456789", "", 0)
+	if err != nil {
+		t.Fatalf("BuildDecryptorPrefix: %v", err)
+	}
+
+	data := make([]byte, EncryptedPrefixLen+4096)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+
+	for _, offset := range []int{0, 5, 8, 65541, EncryptedPrefixLen - 3, EncryptedPrefixLen + 100} {
+		want, err := io.ReadAll(NewDecryptReader(bytes.NewReader(data[offset:]), seed, uint64(offset), EncryptedPrefixLen))
+		if err != nil {
+			t.Fatalf("DecryptReader offset %d: %v", offset, err)
+		}
+		got, err := io.ReadAll(NewPrefixDecryptReader(bytes.NewReader(data[offset:]), decryptor, uint64(offset)))
+		if err != nil {
+			t.Fatalf("PrefixDecryptReader offset %d: %v", offset, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("offset %d: prefix decrypt mismatch", offset)
+		}
+	}
+}
@@ -19,6 +19,42 @@ type DecryptReader struct {
 	ksPos    int         // 密钥块中的当前位置
 }
 
+// EncryptedPrefixLen 视频文件加密区域大小（128KB）
+const EncryptedPrefixLen = 131072
+
+// PrefixDecryptReader 使用预生成的解密数组对文件前缀做 XOR 解密
+// 与 DecryptReader 相同支持任意偏移，分片乱序写入时每个分片独立解密
+type PrefixDecryptReader struct {
+	reader io.Reader
+	prefix []byte
+	pos    uint64 // 当前数据在文件中的偏移
+}
+
+// NewPrefixDecryptReader 创建一个前缀解密读取器
+// offset: 读取数据在文件中的起始偏移（用于 Range 请求）
+func NewPrefixDecryptReader(reader io.Reader, prefix []byte, offset uint64) *PrefixDecryptReader {
+	return &PrefixDecryptReader{
+		reader: reader,
+		prefix: prefix,
+		pos:    offset,
+	}
+}
+
+// Read 实现 io.Reader 接口
+func (pr *PrefixDecryptReader) Read(p []byte) (int, error) {
+	n, err := pr.reader.Read(p)
+	if n <= 0 {
+		return n, err
+	}
+
+	limit := uint64(len(pr.prefix))
+	for i := 0; i < n && pr.pos+uint64(i) < limit; i++ {
+		p[i] ^= pr.prefix[pr.pos+uint64(i)]
+	}
+	pr.pos += uint64(n)
+	return n, err
+}
+
 // Isaac64Ctx 是 ISAAC64 伪随机数生成器的上下文
 // 用于生成解密密钥流
 type Isaac64Ctx struct {
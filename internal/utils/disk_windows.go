@@ -0,0 +1,31 @@
+//go:build windows
+// +build windows
+
+package utils
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+var procGetDiskFreeSpaceExW = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+// DiskUsage 返回路径所在磁盘的可用空间和总空间（字节）
+func DiskUsage(path string) (free, total uint64, err error) {
+	pathPtr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid path: %w", err)
+	}
+	var available, totalBytes, totalFree uint64
+	ret, _, callErr := procGetDiskFreeSpaceExW.Call(
+		uintptr(unsafe.Pointer(pathPtr)),
+		uintptr(unsafe.Pointer(&available)),
+		uintptr(unsafe.Pointer(&totalBytes)),
+		uintptr(unsafe.Pointer(&totalFree)),
+	)
+	if ret == 0 {
+		return 0, 0, fmt.Errorf("failed to get disk free space: %w", callErr)
+	}
+	return available, totalBytes, nil
+}
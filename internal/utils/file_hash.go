@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// fingerprintSampleSize 快速指纹读取的首尾数据长度
+const fingerprintSampleSize = 64 * 1024
+
+// FileFingerprint 计算文件的快速指纹：文件大小 + 首尾各 64KB 数据的 SHA-256
+// 指纹相同只代表可能重复，需再用 FileSHA256 确认
+func FileFingerprint(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	size := stat.Size()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, io.LimitReader(f, fingerprintSampleSize)); err != nil {
+		return "", fmt.Errorf("failed to read file head: %w", err)
+	}
+	if size > fingerprintSampleSize {
+		tailStart := size - fingerprintSampleSize
+		if tailStart < fingerprintSampleSize {
+			tailStart = fingerprintSampleSize
+		}
+		if _, err := io.Copy(h, io.NewSectionReader(f, tailStart, size-tailStart)); err != nil {
+			return "", fmt.Errorf("failed to read file tail: %w", err)
+		}
+	}
+	return fmt.Sprintf("%d:%s", size, hex.EncodeToString(h.Sum(nil)[:16])), nil
+}
+
+// FileSHA256 计算整个文件的 SHA-256
+func FileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to hash file: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ReplaceWithHardlink 用指向 srcPath 的硬链接原子替换 dstPath
+// 两个路径需位于同一文件系统，已是同一文件时直接返回
+func ReplaceWithHardlink(srcPath, dstPath string) error {
+	srcInfo, err := os.Stat(srcPath)
+	if err != nil {
+		return err
+	}
+	if dstInfo, err := os.Stat(dstPath); err == nil && os.SameFile(srcInfo, dstInfo) {
+		return nil
+	}
+
+	tmpPath := filepath.Join(filepath.Dir(dstPath), fmt.Sprintf(".%s.%s.link", filepath.Base(dstPath), RandomString(8)))
+	if err := os.Link(srcPath, tmpPath); err != nil {
+		return fmt.Errorf("failed to create hardlink: %w", err)
+	}
+	if err := os.Rename(tmpPath, dstPath); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace file with hardlink: %w", err)
+	}
+	return nil
+}
+
+// SameFile 判断两个路径是否指向同一文件（硬链接）
+func SameFile(a, b string) bool {
+	aInfo, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	bInfo, err := os.Stat(b)
+	if err != nil {
+		return false
+	}
+	return os.SameFile(aInfo, bInfo)
+}
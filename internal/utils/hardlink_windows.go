@@ -0,0 +1,30 @@
+//go:build windows
+// +build windows
+
+package utils
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// HardlinkCount 返回文件的硬链接数量
+func HardlinkCount(path string) (uint64, error) {
+	pathPtr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, fmt.Errorf("invalid path: %w", err)
+	}
+	handle, err := syscall.CreateFile(pathPtr, 0,
+		syscall.FILE_SHARE_READ|syscall.FILE_SHARE_WRITE|syscall.FILE_SHARE_DELETE,
+		nil, syscall.OPEN_EXISTING, syscall.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer syscall.CloseHandle(handle)
+
+	var info syscall.ByHandleFileInformation
+	if err := syscall.GetFileInformationByHandle(handle, &info); err != nil {
+		return 0, fmt.Errorf("failed to get file information: %w", err)
+	}
+	return uint64(info.NumberOfLinks), nil
+}
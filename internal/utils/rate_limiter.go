@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+)
+
+// RateLimiter 是按字节计数的令牌桶限速器
+// limit 为每秒字节数，<=0 表示不限速；桶容量为一秒的流量
+type RateLimiter struct {
+	mu     sync.Mutex
+	limit  int64
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter 创建一个限速器
+func NewRateLimiter(bytesPerSec int64) *RateLimiter {
+	return &RateLimiter{
+		limit:  bytesPerSec,
+		tokens: float64(bytesPerSec),
+		last:   time.Now(),
+	}
+}
+
+// Limit 返回当前限速（字节/秒）
+func (l *RateLimiter) Limit() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+// SetLimit 调整限速，正在等待的读取会在下一次取令牌时生效
+func (l *RateLimiter) SetLimit(bytesPerSec int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.limit == bytesPerSec {
+		return
+	}
+	l.limit = bytesPerSec
+	if l.tokens > float64(bytesPerSec) {
+		l.tokens = float64(bytesPerSec)
+	}
+	l.last = time.Now()
+}
+
+// WaitN 阻塞直到可以消费 n 个字节，或 ctx 被取消
+func (l *RateLimiter) WaitN(ctx context.Context, n int) error {
+	for {
+		wait := l.reserve(n)
+		if wait <= 0 {
+			return nil
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve 尝试取出 n 个令牌，不足时返回需要等待的时间
+func (l *RateLimiter) reserve(n int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.limit <= 0 {
+		return 0
+	}
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * float64(l.limit)
+	l.last = now
+	if l.tokens > float64(l.limit) {
+		l.tokens = float64(l.limit)
+	}
+
+	// 单次请求超过桶容量时允许透支，避免永远等不到足够的令牌
+	need := float64(n)
+	if need > float64(l.limit) {
+		need = float64(l.limit)
+	}
+	if l.tokens >= need {
+		l.tokens -= float64(n)
+		return 0
+	}
+	return time.Duration((need - l.tokens) / float64(l.limit) * float64(time.Second))
+}
+
+// RateLimitedReader 读取时依次向所有限速器申请令牌
+type RateLimitedReader struct {
+	ctx      context.Context
+	reader   io.Reader
+	limiters []*RateLimiter
+}
+
+// rateLimitedReadSize 单次读取上限，避免大缓冲区造成突发流量
+const rateLimitedReadSize = 32 * 1024
+
+// NewRateLimitedReader 创建一个限速读取器，nil 限速器会被忽略
+func NewRateLimitedReader(ctx context.Context, reader io.Reader, limiters ...*RateLimiter) io.Reader {
+	active := make([]*RateLimiter, 0, len(limiters))
+	for _, l := range limiters {
+		if l != nil {
+			active = append(active, l)
+		}
+	}
+	if len(active) == 0 {
+		return reader
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return &RateLimitedReader{ctx: ctx, reader: reader, limiters: active}
+}
+
+// Read 实现 io.Reader 接口
+func (r *RateLimitedReader) Read(p []byte) (int, error) {
+	if len(p) > rateLimitedReadSize {
+		p = p[:rateLimitedReadSize]
+	}
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		for _, l := range r.limiters {
+			if waitErr := l.WaitN(r.ctx, n); waitErr != nil {
+				return n, waitErr
+			}
+		}
+	}
+	return n, err
+}
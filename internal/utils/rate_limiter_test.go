@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestRateLimitedReaderThrottles(t *testing.T) {
+	t.Parallel()
+
+	const limit = 64 * 1024
+	data := bytes.Repeat([]byte("x"), limit+limit/2)
+	limiter := NewRateLimiter(limit)
+
+	start := time.Now()
+	got, err := io.ReadAll(NewRateLimitedReader(context.Background(), bytes.NewReader(data), limiter))
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatal("data mismatch")
+	}
+	// 桶内初始有一秒的令牌，剩余半秒的流量需要等待
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Fatalf("elapsed = %v, expected throttling", elapsed)
+	}
+}
+
+func TestRateLimiterUnlimitedAndCancel(t *testing.T) {
+	t.Parallel()
+
+	if err := NewRateLimiter(0).WaitN(context.Background(), 1<<30); err != nil {
+		t.Fatalf("unlimited WaitN: %v", err)
+	}
+
+	limiter := NewRateLimiter(1)
+	_ = limiter.WaitN(context.Background(), 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := limiter.WaitN(ctx, 1); err != context.Canceled {
+		t.Fatalf("WaitN after cancel = %v, want context.Canceled", err)
+	}
+
+	if r := NewRateLimitedReader(context.Background(), bytes.NewReader(nil), nil); r == nil {
+		t.Fatal("expected passthrough reader")
+	}
+}
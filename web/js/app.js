@@ -341,6 +341,10 @@
             return await this.request('PUT', `/queue/${id}/resume`);
         },
 
+        async startQueueItem(id) {
+            return await this.request('PUT', `/queue/${id}/start`);
+        },
+
         async removeFromQueue(id) {
             return await this.request('DELETE', `/queue/${id}`);
         },
@@ -921,7 +925,8 @@
             'failed': '失败',
             'in_progress': '下载中',
             'pending': '等待中',
-            'paused': '已暂停'
+            'paused': '已暂停',
+            'missing': '文件丢失'
         };
         return statusMap[status] || status || '未知';
     }
@@ -3049,157 +3054,28 @@
         }
     }
 
-    // Start download for a queue item - uses existing batch download API
-    // Note: Video download requires decrypt key which is only available in the injected script
+    // Start download for a queue item
+    // The server-side queue worker downloads the item; this only moves it to the front and wakes the worker
     async function startQueueItemDownload(id) {
         const item = queueState.items.find(i => i.id === id);
         if (!item) {
             showMessage('找不到队列项', 'error');
             return;
         }
-        
-        if (!item.videoUrl) {
-            showMessage('视频链接不可用，无法下载', 'error');
-            return;
-        }
-        
-        // Check if we have the decrypt key
-        if (!item.decryptKey) {
-            showMessage('缺少解密密钥，请从微信视频号页面使用批量下载功能', 'warning');
-            console.warn('下载队列项缺少解密密钥。视频号视频是加密的，需要从浏览器页面获取解密密钥。');
-            return;
-        }
-        
+
         try {
-            // Update local status first for immediate UI feedback
-            item.status = 'downloading';
+            const result = await ApiClient.startQueueItem(id);
+            const updated = result && result.data;
+            item.status = (updated && updated.status) || 'pending';
+            item.priority = updated ? updated.priority : item.priority;
             renderQueueList();
             updateQueueStats();
-            showMessage('开始下载: ' + item.title, 'info');
-            
-            // Use existing batch download API with the video URL and decrypt key
-            // Field mapping: authorName (backend) = author (frontend)
-            const videos = [{
-                id: item.videoId || item.id,
-                title: item.title,
-                url: item.videoUrl,
-                authorName: item.author,
-                key: item.decryptKey,  // Decrypt key for encrypted videos
-                durationMs: item.duration || 0,
-                size: item.totalSize || item.size || 0,
-                createTime: item.addedTime || '',
-                resolution: item.resolution || ''
-            }];
-            
-            const result = await ApiClient.startBatchDownload(videos, false);
-            
-            if (result.success) {
-                showMessage('下载已开始，请在终端查看进度', 'success');
-                // Mark as completed after batch download starts
-                // The actual download happens in the background
-                // Poll for batch progress
-                pollBatchProgress(id);
-            } else {
-                item.status = 'pending';
-                renderQueueList();
-                updateQueueStats();
-                showMessage('下载启动失败: ' + (result.error || '未知错误'), 'error');
-            }
+            showMessage('已排到队列最前，即将开始下载: ' + item.title, 'success');
         } catch (e) {
-            item.status = 'pending';
-            renderQueueList();
-            updateQueueStats();
-            showMessage('下载失败: ' + e.message, 'error');
+            showMessage('启动下载失败: ' + e.message, 'error');
+            loadDownloadQueue(); // Reload to get correct state
         }
     }
-    
-    // Poll batch download progress
-    // batch_progress API returns: { success, total, done, failed, running, currentTask }
-    async function pollBatchProgress(queueItemId) {
-        const item = queueState.items.find(i => i.id === queueItemId);
-        if (!item) return;
-        
-        let pollCount = 0;
-        const maxPolls = 600; // 10 minutes max (1 second intervals)
-        let lastDone = 0;
-        
-        const pollInterval = setInterval(async () => {
-            pollCount++;
-            
-            try {
-                const progress = await ApiClient.getBatchProgress();
-                
-                // Check if download completed (done increased or all tasks done)
-                if (progress.success && progress.total > 0) {
-                    // Download completed
-                    if (progress.done > lastDone || (progress.running === 0 && progress.done > 0)) {
-                        clearInterval(pollInterval);
-                        // Note: Backend automatically completes download, no need to call API
-                        // Removed ApiClient.completeDownload() to prevent duplicate records
-                        item.status = 'completed';
-                        item.downloadedSize = item.totalSize;
-                        renderQueueList();
-                        updateQueueStats();
-                        showMessage('下载完成: ' + item.title, 'success');
-                        return;
-                    }
-                    
-                    // Download failed
-                    if (progress.failed > 0 && progress.running === 0) {
-                        clearInterval(pollInterval);
-                        // Update backend status
-                        try {
-                            await ApiClient.failDownload(queueItemId, '下载失败');
-                        } catch (e) {
-                            console.warn('Failed to update backend status:', e);
-                        }
-                        item.status = 'failed';
-                        item.errorMessage = '下载失败';
-                        renderQueueList();
-                        updateQueueStats();
-                        showMessage('下载失败: ' + item.title, 'error');
-                        return;
-                    }
-                    
-                    // Update progress from currentTask
-                    if (progress.currentTask && progress.currentTask.progress) {
-                        const percent = progress.currentTask.progress;
-                        item.downloadedSize = Math.floor((percent / 100) * item.totalSize);
-                        renderQueueList();
-                    }
-                    
-                    lastDone = progress.done;
-                }
-                
-                // No tasks running and no done - batch might have finished or not started
-                if (progress.success && progress.total === 0) {
-                    // Batch download finished, mark as completed
-                    clearInterval(pollInterval);
-                    // Note: Backend automatically completes download, no need to call API
-                    // Removed ApiClient.completeDownload() to prevent duplicate records
-                    item.status = 'completed';
-                    item.downloadedSize = item.totalSize;
-                    renderQueueList();
-                    updateQueueStats();
-                    showMessage('下载完成: ' + item.title, 'success');
-                    return;
-                }
-                
-                if (pollCount >= maxPolls) {
-                    clearInterval(pollInterval);
-                    item.status = 'pending';
-                    renderQueueList();
-                    updateQueueStats();
-                }
-            } catch (e) {
-                // Ignore polling errors, just continue
-                console.warn('Poll progress error:', e);
-                if (pollCount >= maxPolls) {
-                    clearInterval(pollInterval);
-                }
-            }
-        }, 1000);
-    }
 
     // Pause all downloads
     async function pauseAllDownloads() {
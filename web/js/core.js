@@ -298,6 +298,7 @@ const ApiClient = {
     async addToQueue(videos) { return await this.request('POST', '/queue', { videos }); },
     async pauseDownload(id) { return await this.request('PUT', `/queue/${id}/pause`); },
     async resumeDownload(id) { return await this.request('PUT', `/queue/${id}/resume`); },
+    async startQueueItem(id) { return await this.request('PUT', `/queue/${id}/start`); },
     async removeFromQueue(id) { return await this.request('DELETE', `/queue/${id}`); },
     async reorderQueue(ids) { return await this.request('PUT', '/queue/reorder', { ids }); },
     async completeDownload(id) { return await this.request('PUT', `/queue/${id}/complete`); },
@@ -189,6 +189,16 @@ async function saveRadarTarget() {
         interval_minutes: intervalMinutes
     };
 
+    // 编辑时保留对话框中未展示的翻页、回填、过滤规则和通知设置
+    const existing = id ? radarTargets.find(t => t.id === id) : null;
+    if (existing) {
+        data.page_depth = existing.page_depth;
+        data.backfill = existing.backfill;
+        data.filters = existing.filters;
+        data.notify = existing.notify;
+        data.fixed_interval = existing.fixed_interval;
+    }
+
     try {
         let url = '/api/v1/radar/targets';
         let method = 'POST';
@@ -320,7 +330,7 @@ async function showRadarLogs(id, authorName) {
                                                 <tbody>${videos.map(v => `
                                                 <tr style="border-top:1px solid var(--border-color);">
                                                     <td style="padding:4px 8px; overflow:hidden; text-overflow:ellipsis; white-space:nowrap; max-width:350px;" title="${escapeHtml(v.title)}">${escapeHtml(v.title)}</td>
-                                                    <td style="padding:4px 8px; text-align:center;">${v.is_new ? '<span style="color:var(--success-color); font-weight:bold;">🆕新增</span>' : '<span style="color:var(--text-muted);">已有</span>'}</td>
+                                                    <td style="padding:4px 8px; text-align:center;">${v.skip_rule ? `<span style="color:var(--warning-color);" title="${escapeHtml(v.skip_rule)}">已过滤</span>` : v.is_new ? '<span style="color:var(--success-color); font-weight:bold;">🆕新增</span>' : '<span style="color:var(--text-muted);">已有</span>'}</td>
                                                 </tr>`).join('')}</tbody>
                                             </table>
                                         </div>
@@ -105,7 +105,8 @@ function getStatusText(status) {
         'failed': '失败',
         'in_progress': '下载中',
         'pending': '等待中',
-        'paused': '已暂停'
+        'paused': '已暂停',
+        'missing': '文件丢失'
     };
     return statusMap[status] || status || '未知';
 }